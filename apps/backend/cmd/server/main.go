@@ -29,6 +29,15 @@ func main() {
 	}
 	defer db.Close()
 
+	// Full-text coffee search needs a build with -tags sqlite_fts5
+	searchEnabled, err := database.EnableCoffeeSearch(db)
+	if err != nil {
+		log.Fatalf("Failed to set up coffee search: %v", err)
+	}
+	if !searchEnabled {
+		log.Println("SQLite was built without FTS5; coffee search falls back to substring matching")
+	}
+
 	// Setup routes
 	router := routes.Setup(db, cfg)
 
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/services"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type AccountHandler struct {
+	accounts *services.AccountService
+	cfg      *config.Config
+}
+
+func NewAccountHandler(accounts *services.AccountService, cfg *config.Config) *AccountHandler {
+	return &AccountHandler{
+		accounts: accounts,
+		cfg:      cfg,
+	}
+}
+
+// RequestVerification handles POST /api/v1/auth/verify-email/request
+// Mails the caller a new verification link and returns 204. Returns 400 when the
+// address is already verified.
+func (h *AccountHandler) RequestVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.accounts.SendVerification(r.Context(), userID); err != nil {
+		writeServiceError(w, err, "failed to send verification e-mail")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// VerifyEmail handles POST /api/v1/auth/verify-email
+// Request: JSON { "token": string } from the verification link. Returns 204.
+func (h *AccountHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token string `json:"token"`
+	}
+	if !decodeAccountBody(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Token) == "" {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "token is required")
+		return
+	}
+	if err := h.accounts.VerifyEmail(r.Context(), body.Token); err != nil {
+		writeServiceError(w, err, "failed to verify e-mail")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RequestPasswordReset handles POST /api/v1/auth/password-reset/request
+// Request: JSON { "email": string }. Always returns 202, whether or not the address
+// has an account, so the endpoint cannot be used to find out who is registered.
+// Addresses and client IPs asking too often get the same answer and no e-mail.
+func (h *AccountHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if !decodeAccountBody(w, r, &body) {
+		return
+	}
+	email := strings.TrimSpace(strings.ToLower(body.Email))
+	if email == "" {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "email is required")
+		return
+	}
+	h.accounts.RequestPasswordReset(r.Context(), email, clientIP(h.cfg, r))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// ResetPassword handles POST /api/v1/auth/password-reset
+// Request: JSON { "token": string, "password": string }. Sets the new password, signs
+// out every session of the user and returns 204.
+func (h *AccountHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if !decodeAccountBody(w, r, &body) {
+		return
+	}
+	if strings.TrimSpace(body.Token) == "" {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "token is required")
+		return
+	}
+	if err := h.accounts.ResetPassword(r.Context(), body.Token, body.Password); err != nil {
+		writeServiceError(w, err, "failed to reset password")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func decodeAccountBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid JSON body")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,181 @@
+package handlers_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"coffeeee/backend/internal/config"
+)
+
+// newMailTestServer is newTestServer with e-mails written to the returned file.
+func newMailTestServer(t *testing.T) (*sql.DB, http.Handler, string) {
+	t.Helper()
+	mailFile := filepath.Join(t.TempDir(), "mail.log")
+	db, handler := newConfiguredTestServer(t, func(cfg *config.Config) {
+		cfg.Mail = config.MailConfig{
+			Driver:   "file",
+			From:     "Coffeeee <noreply@example.com>",
+			FilePath: mailFile,
+			AppURL:   "http://app.example.com/",
+		}
+	})
+	return db, handler, mailFile
+}
+
+var mailedLink = regexp.MustCompile(`http://app\.example\.com/([a-z-]+)\?token=(\S+)`)
+
+// lastMailedToken returns the token of the last link to path in the mail
+// file. Reset e-mails are sent in the background, so it waits a while for a
+// first link to appear.
+func lastMailedToken(t *testing.T, mailFile, path string) string {
+	t.Helper()
+	var b []byte
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		b, _ = os.ReadFile(mailFile)
+		token := ""
+		for _, m := range mailedLink.FindAllStringSubmatch(string(b), -1) {
+			if m[1] == path {
+				token, _ = url.QueryUnescape(m[2])
+			}
+		}
+		if token != "" {
+			return token
+		}
+	}
+	t.Fatalf("no %s link mailed in %q", path, b)
+	return ""
+}
+
+func profile(t *testing.T, handler http.Handler, method, accessToken string, body any) map[string]any {
+	t.Helper()
+	b, _ := json.Marshal(body)
+	req := httptest.NewRequest(method, "/api/v1/users/me", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("%s /users/me: expected 200, got %d: %s", method, rr.Code, rr.Body.String())
+	}
+	var resp map[string]any
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	return resp
+}
+
+func TestVerifyEmail(t *testing.T) {
+	db, handler, mailFile := newMailTestServer(t)
+	defer db.Close()
+	if code, _ := authPost(t, handler, "/api/v1/users", "", map[string]string{"email": "test@example.com", "password": "secret123"}); code != http.StatusCreated {
+		t.Fatalf("register expected 201, got %d", code)
+	}
+	token := lastMailedToken(t, mailFile, "verify-email")
+	access, _ := loginTokens(t, handler)
+	if resp := profile(t, handler, http.MethodGet, access, nil); resp["emailVerified"] != false {
+		t.Fatalf("expected an unverified address, got %#v", resp)
+	}
+
+	// Only the stored hash of the token is kept
+	var stored string
+	_ = db.QueryRow(`SELECT token_hash FROM user_tokens`).Scan(&stored)
+	if stored == "" || strings.Contains(token, stored) {
+		t.Fatalf("expected the token stored hashed, got %q", stored)
+	}
+
+	// A verification token is no reset token, and a forged signature fails
+	random, _, _ := strings.Cut(token, ".")
+	if code, _ := authPost(t, handler, "/api/v1/auth/password-reset", "", map[string]string{"token": token, "password": "hijacked"}); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 resetting with a verification token, got %d", code)
+	}
+	if code, _ := authPost(t, handler, "/api/v1/auth/verify-email", "", map[string]string{"token": random + ".forged"}); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a forged token, got %d", code)
+	}
+
+	if code, resp := authPost(t, handler, "/api/v1/auth/verify-email", "", map[string]string{"token": token}); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d %#v", code, resp)
+	}
+	if resp := profile(t, handler, http.MethodGet, access, nil); resp["emailVerified"] != true {
+		t.Fatalf("expected a verified address, got %#v", resp)
+	}
+	// Each token works once
+	if code, _ := authPost(t, handler, "/api/v1/auth/verify-email", "", map[string]string{"token": token}); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 reusing a token, got %d", code)
+	}
+	if code, _ := authPost(t, handler, "/api/v1/auth/verify-email/request", access, nil); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 asking to verify a verified address, got %d", code)
+	}
+
+	// A new address needs verifying, and a link sent to the old one no longer does it
+	if code, _ := authPost(t, handler, "/api/v1/auth/verify-email/request", "", nil); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", code)
+	}
+	if resp := profile(t, handler, http.MethodPut, access, map[string]string{"email": "new@example.com"}); resp["emailVerified"] != false {
+		t.Fatalf("expected the new address unverified, got %#v", resp)
+	}
+	if code, _ := authPost(t, handler, "/api/v1/auth/verify-email/request", access, nil); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	newToken := lastMailedToken(t, mailFile, "verify-email")
+	profile(t, handler, http.MethodPut, access, map[string]string{"email": "other@example.com"})
+	if code, _ := authPost(t, handler, "/api/v1/auth/verify-email", "", map[string]string{"token": newToken}); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 verifying an address the user no longer has, got %d", code)
+	}
+}
+
+func TestPasswordReset(t *testing.T) {
+	db, handler, mailFile := newMailTestServer(t)
+	defer db.Close()
+	if code, _ := authPost(t, handler, "/api/v1/users", "", map[string]string{"email": "test@example.com", "password": "secret123"}); code != http.StatusCreated {
+		t.Fatalf("register expected 201, got %d", code)
+	}
+	access, refresh := loginTokens(t, handler)
+
+	// Unknown addresses get the same answer and no e-mail
+	if code, _ := authPost(t, handler, "/api/v1/auth/password-reset/request", "", map[string]string{"email": "nobody@example.com"}); code != http.StatusAccepted {
+		t.Fatalf("expected 202 for an unknown address, got %d", code)
+	}
+	if code, _ := authPost(t, handler, "/api/v1/auth/password-reset/request", "", map[string]string{"email": " Test@Example.com "}); code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", code)
+	}
+	token := lastMailedToken(t, mailFile, "reset-password")
+	if b, _ := os.ReadFile(mailFile); strings.Contains(string(b), "nobody@example.com") || strings.Count(string(b), "/reset-password?") != 1 {
+		t.Fatalf("expected a single reset e-mail, to the known address, got %q", b)
+	}
+
+	if code, _ := authPost(t, handler, "/api/v1/auth/password-reset", "", map[string]string{"token": token, "password": ""}); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty password, got %d", code)
+	}
+	if code, resp := authPost(t, handler, "/api/v1/auth/password-reset", "", map[string]string{"token": token, "password": "newsecret"}); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d %#v", code, resp)
+	}
+	if code, _ := authPost(t, handler, "/api/v1/auth/password-reset", "", map[string]string{"token": token, "password": "again"}); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 reusing a reset token, got %d", code)
+	}
+
+	// Every session is signed out, and only the new password works
+	if code := profileStatus(handler, access); code != http.StatusUnauthorized {
+		t.Fatalf("expected the old access token rejected, got %d", code)
+	}
+	if code, _ := authPost(t, handler, "/api/v1/auth/refresh", "", map[string]string{"refreshToken": refresh}); code != http.StatusUnauthorized {
+		t.Fatalf("expected the old refresh token rejected, got %d", code)
+	}
+	if code := loginStatus(t, handler, "test@example.com", "secret123"); code != http.StatusUnauthorized {
+		t.Fatalf("expected the old password rejected, got %d", code)
+	}
+	if code := loginStatus(t, handler, "test@example.com", "newsecret"); code != http.StatusOK {
+		t.Fatalf("expected the new password accepted, got %d", code)
+	}
+	var verified sql.NullTime
+	_ = db.QueryRow(`SELECT email_verified_at FROM users`).Scan(&verified)
+	if !verified.Valid {
+		t.Fatalf("expected a reset through the mailed link to verify the address")
+	}
+}
@@ -3,26 +3,123 @@ package handlers
 import (
     "database/sql"
     "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "mime"
     "net/http"
     "strings"
 
+    "coffeeee/backend/internal/api/middleware"
     "coffeeee/backend/internal/config"
+    "coffeeee/backend/internal/services"
 )
 
 type AIHandler struct {
-	db  *sql.DB
-	cfg *config.Config
+	db                *sql.DB
+	extractionService *services.CoffeeExtractionService
+	recommender       *services.BrewRecommender
+	cfg               *config.Config
 }
 
-func NewAIHandler(db *sql.DB, cfg *config.Config) *AIHandler {
-	return &AIHandler{db: db, cfg: cfg}
+func NewAIHandler(db *sql.DB, extractionService *services.CoffeeExtractionService, recommender *services.BrewRecommender, cfg *config.Config) *AIHandler {
+	return &AIHandler{db: db, extractionService: extractionService, recommender: recommender, cfg: cfg}
 }
 
+// ExtractCoffee handles POST /api/v1/ai/extract-coffee
+// Request: JSON { "text": string } with label text, or multipart/form-data with a "photo"
+// part (JPEG, PNG or GIF, at most Server.MaxFileSize bytes) and/or a "text" part.
+// Returns JSON: { "coffee": { name, roaster?, roasterId?, origin?, process?, description?, roastDate? },
+// "confidence": { "<field>": 0-1 }, "provider": string }. The coffee is a draft for the
+// client to review and send to POST /api/v1/coffees; nothing is stored.
 func (h *AIHandler) ExtractCoffee(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement AI coffee extraction logic
-	w.WriteHeader(http.StatusNotImplemented)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if _, ok := requireUserID(w, r); !ok {
+		return
+	}
+
+	var label services.CoffeeLabel
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		var ok bool
+		if label, ok = h.readLabelParts(w, r); !ok {
+			return
+		}
+	} else {
+		var body struct {
+			Text string `json:"text"`
+		}
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid JSON body")
+			return
+		}
+		label.Text = body.Text
+	}
+
+	extracted, err := h.extractionService.Extract(r.Context(), label)
+	if err != nil {
+		writeServiceError(w, err, "failed to extract coffee")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(extracted)
+}
+
+// readLabelParts reads the "photo" and "text" parts of a multipart request.
+// It writes an error response and returns false when they cannot be read.
+func (h *AIHandler) readLabelParts(w http.ResponseWriter, r *http.Request) (services.CoffeeLabel, bool) {
+	var label services.CoffeeLabel
+	maxSize := h.cfg.Server.MaxFileSize
+	tooLarge := fmt.Sprintf("photo must be at most %d bytes", maxSize)
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverhead)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid multipart body")
+		return label, false
+	}
+	for {
+		part, err := mr.NextPart()
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, io.EOF):
+			return label, true
+		case errors.As(err, &maxBytesErr):
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", tooLarge)
+			return label, false
+		case err != nil:
+			writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid multipart body")
+			return label, false
+		}
+		switch part.FormName() {
+		case "photo":
+			if !services.IsAcceptedPhotoType(part.Header.Get("Content-Type")) {
+				writeJSONError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "photo must be a JPEG, PNG or GIF image")
+				return label, false
+			}
+			label.Image, err = io.ReadAll(io.LimitReader(part, maxSize+1))
+			if errors.As(err, &maxBytesErr) || int64(len(label.Image)) > maxSize {
+				writeJSONError(w, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", tooLarge)
+				return label, false
+			}
+		case "text":
+			var text []byte
+			text, err = io.ReadAll(part)
+			label.Text = string(text)
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid multipart body")
+			return label, false
+		}
+	}
 }
 
+// GetRecommendation handles POST /api/v1/ai/recommendation
+// With a goal it returns JSON { "change": { variable, delta, target? }, "explanation", "provider",
+// "evidence"? }. When brewLog.coffeeId names one of the caller's coffees the change is learned
+// from their rated brews of it with the same method, and evidence lists the brews compared.
+// Without a goal it runs the tasting assistant and returns the next question.
 func (h *AIHandler) GetRecommendation(w http.ResponseWriter, r *http.Request) {
     type Answer struct {
         ID    string `json:"id"`
@@ -37,6 +134,7 @@ func (h *AIHandler) GetRecommendation(w http.ResponseWriter, r *http.Request) {
         CoffeeWeight     *float64 `json:"coffeeWeight,omitempty"`
         WaterWeight      *float64 `json:"waterWeight,omitempty"`
         GrindSize        *string  `json:"grindSize,omitempty"`
+        GrindSetting     *float64 `json:"grindSetting,omitempty"`
         WaterTemperature *float64 `json:"waterTemperature,omitempty"`
         BrewTime         *int     `json:"brewTime,omitempty"`
         TastingNotes     *string  `json:"tastingNotes,omitempty"`
@@ -60,15 +158,6 @@ func (h *AIHandler) GetRecommendation(w http.ResponseWriter, r *http.Request) {
         Options    []Option `json:"options"`
         Hint       *string  `json:"hint,omitempty"`
     }
-    type Change struct {
-        Variable string `json:"variable"`
-        Delta    string `json:"delta"`
-    }
-    type RecResp struct {
-        Change      Change `json:"change"`
-        Explanation string `json:"explanation"`
-    }
-
     w.Header().Set("Content-Type", "application/json")
 
     var body Req
@@ -86,26 +175,33 @@ func (h *AIHandler) GetRecommendation(w http.ResponseWriter, r *http.Request) {
             _ = json.NewEncoder(w).Encode(map[string]string{"code": "BAD_REQUEST", "message": "goal is required"})
             return
         }
-        goal := strings.ToLower(trimmed)
-        // Simple deterministic rules for demo/testing
-        var change Change
-        var explanation string
-        switch {
-        case strings.Contains(goal, "sweet"):
-            change = Change{Variable: "grind", Delta: "2 clicks finer"}
-            explanation = "A slightly finer grind increases extraction and can enhance perceived sweetness."
-        case strings.Contains(goal, "bitter"):
-            change = Change{Variable: "waterTemperature", Delta: "-2°C"}
-            explanation = "Lowering water temperature slightly can reduce over-extraction bitterness."
-        case strings.Contains(goal, "strong") || strings.Contains(goal, "strength"):
-            change = Change{Variable: "coffeeWeight", Delta: "+1g per 15g"}
-            explanation = "Increasing dose relative to water raises strength without extending brew time."
-        default:
-            change = Change{Variable: "grind", Delta: "1 click coarser"}
-            explanation = "A small grind adjustment is a safe single-variable test to move flavor balance."
+        // History is only read for a signed-in user's own coffee
+        input := services.BrewRecommendationInput{Goal: trimmed}
+        input.UserID, _ = middleware.GetAuthenticatedUserID(r.Context())
+        if body.BrewLog != nil {
+            if body.BrewLog.CoffeeID != nil {
+                coffeeID := int64(*body.BrewLog.CoffeeID)
+                input.CoffeeID = &coffeeID
+            }
+            input.BrewLog = services.BrewSnapshot{
+                BrewMethod:       body.BrewLog.BrewMethod,
+                CoffeeWeight:     body.BrewLog.CoffeeWeight,
+                WaterWeight:      body.BrewLog.WaterWeight,
+                GrindSize:        body.BrewLog.GrindSize,
+                GrindSetting:     body.BrewLog.GrindSetting,
+                WaterTemperature: body.BrewLog.WaterTemperature,
+                BrewTime:         body.BrewLog.BrewTime,
+                TastingNotes:     body.BrewLog.TastingNotes,
+                Rating:           body.BrewLog.Rating,
+            }
+        }
+        recommendation, err := h.recommender.Recommend(r.Context(), input)
+        if err != nil {
+            writeServiceError(w, err, "failed to get recommendation")
+            return
         }
         w.WriteHeader(http.StatusOK)
-        _ = json.NewEncoder(w).Encode(RecResp{Change: change, Explanation: explanation})
+        _ = json.NewEncoder(w).Encode(recommendation)
         return
     }
 
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/database"
+	"coffeeee/backend/internal/services"
+)
+
+// fakeExtractor answers every label with the same reading and remembers
+// what it was given.
+type fakeExtractor struct {
+	result *services.CoffeeExtraction
+	err    error
+	got    []services.CoffeeLabel
+}
+
+func (f *fakeExtractor) Name() string { return "fake" }
+
+func (f *fakeExtractor) ExtractCoffee(ctx context.Context, label services.CoffeeLabel) (*services.CoffeeExtraction, error) {
+	f.got = append(f.got, label)
+	return f.result, f.err
+}
+
+func setupExtractHandler(t *testing.T, extractor services.CoffeeExtractor) *AIHandler {
+	t.Helper()
+	db := setupCoffeeTestDB(t)
+	t.Cleanup(func() { _ = db.Close() })
+	_, _ = db.Exec(`INSERT INTO roasters(id, name, match_key) VALUES (7, 'Onyx Coffee Lab', 'onyxlab')`)
+	service := services.NewCoffeeExtractionService(database.NewQueries(db), extractor)
+	return NewAIHandler(db, service, services.NewBrewRecommender(nil, services.NewRulesAIService()), &config.Config{Server: config.ServerConfig{MaxFileSize: 1 << 20}})
+}
+
+func extractRequest(t *testing.T, h *AIHandler, contentType string, body []byte) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/api/v1/ai/extract-coffee", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), 1))
+	w := httptest.NewRecorder()
+	h.ExtractCoffee(w, req)
+	return w.Code, decodeBody(t, w)
+}
+
+func multipartLabel(t *testing.T, photo []byte, text string) (string, []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if photo != nil {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", `form-data; name="photo"; filename="bag.png"`)
+		header.Set("Content-Type", "image/png")
+		part, _ := mw.CreatePart(header)
+		_, _ = part.Write(photo)
+	}
+	if text != "" {
+		_ = mw.WriteField("text", text)
+	}
+	_ = mw.Close()
+	return mw.FormDataContentType(), buf.Bytes()
+}
+
+func TestExtractCoffee_DraftFromProvider(t *testing.T) {
+	fake := &fakeExtractor{result: &services.CoffeeExtraction{
+		Name:         services.ExtractedValue{Value: " Southern  Weather ", Confidence: 0.95},
+		Roaster:      services.ExtractedValue{Value: "ONYX COFFEE LAB", Confidence: 1.7},
+		Origin:       services.ExtractedValue{Value: "Colombia, Ethiopia", Confidence: 0.8},
+		Process:      services.ExtractedValue{Value: "Fully Washed", Confidence: 0.7},
+		TastingNotes: services.ExtractedValue{Value: "milk chocolate, lemon", Confidence: 0.6},
+		RoastDate:    services.ExtractedValue{Value: "March 4, 2025", Confidence: 0.5},
+	}}
+	h := setupExtractHandler(t, fake)
+
+	var img bytes.Buffer
+	_ = png.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4)))
+	contentType, body := multipartLabel(t, img.Bytes(), "")
+	code, resp := extractRequest(t, h, contentType, body)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %#v", code, resp)
+	}
+	if len(fake.got) != 1 || fake.got[0].ImageType != "image/png" || !bytes.Equal(fake.got[0].Image, img.Bytes()) {
+		t.Fatalf("expected the photo to reach the provider, got %#v", fake.got)
+	}
+	coffee, _ := resp["coffee"].(map[string]any)
+	for key, want := range map[string]any{
+		"name":        "Southern Weather",
+		"roaster":     "Onyx Coffee Lab",
+		"roasterId":   float64(7),
+		"origin":      "Colombia, Ethiopia",
+		"process":     "washed",
+		"description": "milk chocolate, lemon",
+		"roastDate":   "2025-03-04",
+	} {
+		if coffee[key] != want {
+			t.Fatalf("%s: expected %v, got %#v", key, want, coffee)
+		}
+	}
+	confidence, _ := resp["confidence"].(map[string]any)
+	if confidence["roaster"] != float64(1) || confidence["process"] != 0.7 || resp["provider"] != "fake" {
+		t.Fatalf("unexpected confidence: %#v", resp)
+	}
+
+	// Readings that cannot be right are left out
+	fake.result = &services.CoffeeExtraction{
+		Process:   services.ExtractedValue{Value: "steamed", Confidence: 0.9},
+		RoastDate: services.ExtractedValue{Value: "2999-01-01", Confidence: 0.9},
+	}
+	code, resp = extractRequest(t, h, "application/json", []byte(`{"text":"label"}`))
+	if coffee, _ := resp["coffee"].(map[string]any); code != http.StatusOK || coffee["process"] != nil || coffee["roastDate"] != nil ||
+		len(resp["confidence"].(map[string]any)) != 0 {
+		t.Fatalf("expected an empty draft, got %d %#v", code, resp)
+	}
+
+	fake.err = errors.New("provider timed out")
+	if code, _ := extractRequest(t, h, "application/json", []byte(`{"text":"label"}`)); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 when the provider fails, got %d", code)
+	}
+}
+
+func TestExtractCoffee_LabelTextRules(t *testing.T) {
+	h := setupExtractHandler(t, services.NewLabelTextExtractor())
+
+	code, resp := extractRequest(t, h, "application/json", []byte(`{"text":"Geometry\nOnyx Lab Coffee Roasters\nEthiopia, Guji - Natural\nTasting notes: blueberry, cocoa\nRoasted on: 2 Jan 2025\n250g"}`))
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %#v", code, resp)
+	}
+	coffee, _ := resp["coffee"].(map[string]any)
+	for key, want := range map[string]any{
+		"name":        "Geometry",
+		"roaster":     "Onyx Coffee Lab",
+		"origin":      "Ethiopia, Guji - Natural",
+		"process":     "natural",
+		"description": "blueberry, cocoa",
+		"roastDate":   "2025-01-02",
+	} {
+		if coffee[key] != want {
+			t.Fatalf("%s: expected %v, got %#v", key, want, coffee)
+		}
+	}
+	confidence, _ := resp["confidence"].(map[string]any)
+	if confidence["description"] != 0.9 || confidence["name"] != 0.3 || resp["provider"] != "rules" {
+		t.Fatalf("unexpected confidence: %#v", resp)
+	}
+
+	// Photos need a provider that can read them
+	var img bytes.Buffer
+	_ = png.Encode(&img, image.NewGray(image.Rect(0, 0, 4, 4)))
+	contentType, body := multipartLabel(t, img.Bytes(), "")
+	if code, resp := extractRequest(t, h, contentType, body); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a photo alone, got %d %#v", code, resp)
+	}
+	contentType, body = multipartLabel(t, img.Bytes(), "Process: washed")
+	if code, resp := extractRequest(t, h, contentType, body); code != http.StatusOK || resp["coffee"].(map[string]any)["process"] != "washed" {
+		t.Fatalf("expected the text to be read, got %d %#v", code, resp)
+	}
+
+	for _, body := range []string{`{}`, `{"text":"  "}`, `{"image":"x"}`} {
+		if code, _ := extractRequest(t, h, "application/json", []byte(body)); code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, code)
+		}
+	}
+	contentType, body = multipartLabel(t, []byte("not an image"), "")
+	if code, _ := extractRequest(t, h, contentType, body); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a fake image, got %d", code)
+	}
+}
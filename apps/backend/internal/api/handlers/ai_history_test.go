@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/database"
+	"coffeeee/backend/internal/services"
+)
+
+func historyRecommendationRequest(t *testing.T, h *AIHandler, body string, userID int64) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ai/recommendation", bytes.NewBufferString(body))
+	if userID != 0 {
+		req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), userID))
+	}
+	w := httptest.NewRecorder()
+	h.GetRecommendation(w, req)
+	return w.Code, decodeBody(t, w)
+}
+
+func TestGetRecommendation_FromBrewHistory(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	// Hotter water went with better cups; the dose change did not help, and
+	// the AeroPress brew and the unrated one are not compared
+	_, err := db.Exec(`INSERT INTO brew_logs(id, user_id, coffee_id, brew_method, coffee_weight, water_temperature, rating, created_at) VALUES
+        (1, 1, 1, 'v60',       15, 90, 2,    '2025-01-01 08:00:00'),
+        (2, 1, 1, 'v60',       15, 92, 4,    '2025-01-02 08:00:00'),
+        (3, 1, 1, 'aeropress', 20, 80, 1,    '2025-01-03 08:00:00'),
+        (4, 1, 1, 'v60',       15, 94, 5,    '2025-01-04 08:00:00'),
+        (5, 1, 1, 'v60',       16, 94, 4,    '2025-01-05 08:00:00'),
+        (6, 1, 1, 'v60',       18, 70, NULL, '2025-01-06 08:00:00'),
+        (7, 2, 2, 'v60',       15, 80, 5,    '2025-01-01 08:00:00')`)
+	if err != nil {
+		t.Fatalf("seed brew logs: %v", err)
+	}
+	recommender := services.NewBrewRecommender(database.NewQueries(db), services.NewRulesAIService())
+	h := NewAIHandler(db, nil, recommender, &config.Config{})
+
+	code, resp := historyRecommendationRequest(t, h, `{"brewLog":{"coffeeId":1,"brewMethod":"V60","waterTemperature":93},"goal":"better"}`, 1)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %#v", code, resp)
+	}
+	change, _ := resp["change"].(map[string]any)
+	if resp["provider"] != "history" || change["variable"] != "waterTemperature" || change["delta"] != "+2°C" || change["target"] != float64(95) {
+		t.Fatalf("unexpected recommendation: %#v", resp)
+	}
+	evidence, _ := resp["evidence"].(map[string]any)
+	points, _ := evidence["dataPoints"].([]any)
+	if evidence["brewLogs"] != float64(4) || evidence["effect"] != 1.5 || evidence["agreement"] != float64(1) || len(points) != 2 {
+		t.Fatalf("unexpected evidence: %#v", evidence)
+	}
+	first, _ := points[0].(map[string]any)
+	if first["fromBrewLogId"] != float64(1) || first["toBrewLogId"] != float64(2) || first["from"] != float64(90) || first["ratingChange"] != float64(2) {
+		t.Fatalf("unexpected data point: %#v", first)
+	}
+
+	// The method of the latest brew is used when none is given, and the
+	// latest brew is the starting point
+	code, resp = historyRecommendationRequest(t, h, `{"brewLog":{"coffeeId":1},"goal":"better"}`, 1)
+	if change, _ := resp["change"].(map[string]any); code != http.StatusOK || resp["provider"] != "history" || change["target"] != float64(96) {
+		t.Fatalf("expected a target from the latest brew, got %d %#v", code, resp)
+	}
+
+	// Too little history, someone else's coffee or no caller: the AI provider answers
+	for _, tc := range []struct {
+		body   string
+		userID int64
+	}{
+		{`{"brewLog":{"coffeeId":1,"brewMethod":"aeropress"},"goal":"sweeter"}`, 1},
+		{`{"brewLog":{"coffeeId":2,"brewMethod":"v60"},"goal":"sweeter"}`, 1},
+		{`{"brewLog":{"coffeeId":1,"brewMethod":"v60"},"goal":"sweeter"}`, 0},
+	} {
+		code, resp := historyRecommendationRequest(t, h, tc.body, tc.userID)
+		if change, _ := resp["change"].(map[string]any); code != http.StatusOK || resp["provider"] != "rules" || change["variable"] != "grind" || resp["evidence"] != nil {
+			t.Fatalf("%s: expected the rules to answer, got %d %#v", tc.body, code, resp)
+		}
+	}
+}
+
+func TestGetRecommendation_HistoryFollowsGoal(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	// Hotter water went with the biggest gains, shorter brews with smaller ones
+	_, err := db.Exec(`INSERT INTO brew_logs(id, user_id, coffee_id, brew_method, coffee_weight, water_temperature, brew_time, rating, created_at) VALUES
+        (1, 1, 1, 'v60', 15, 90, 200, 1, '2025-01-01 08:00:00'),
+        (2, 1, 1, 'v60', 15, 92, 200, 3, '2025-01-02 08:00:00'),
+        (3, 1, 1, 'v60', 15, 94, 200, 5, '2025-01-03 08:00:00'),
+        (4, 1, 1, 'v60', 15, 94, 200, 2, '2025-01-04 08:00:00'),
+        (5, 1, 1, 'v60', 15, 94, 190, 3, '2025-01-05 08:00:00'),
+        (6, 1, 1, 'v60', 15, 94, 180, 4, '2025-01-06 08:00:00')`)
+	if err != nil {
+		t.Fatalf("seed brew logs: %v", err)
+	}
+	recommender := services.NewBrewRecommender(database.NewQueries(db), services.NewRulesAIService())
+	h := NewAIHandler(db, nil, recommender, &config.Config{})
+
+	for _, tc := range []struct {
+		goal     string
+		provider string
+		variable string
+		delta    string
+	}{
+		// No direction in the goal: the largest gain wins
+		{"better", "history", "waterTemperature", "+2°C"},
+		{"sweeter", "history", "waterTemperature", "+2°C"},
+		// Hotter water would not make it less bitter; the shorter brews do
+		{"less bitter", "history", "brewTime", "-10s"},
+		// Nothing in the history changed the strength, so the rules answer
+		{"stronger", "rules", "coffeeWeight", "+1g per 15g"},
+	} {
+		code, resp := historyRecommendationRequest(t, h, `{"brewLog":{"coffeeId":1,"brewMethod":"v60"},"goal":"`+tc.goal+`"}`, 1)
+		change, _ := resp["change"].(map[string]any)
+		if code != http.StatusOK || resp["provider"] != tc.provider || change["variable"] != tc.variable || change["delta"] != tc.delta {
+			t.Fatalf("%s: expected %s to change %s by %s, got %d %#v", tc.goal, tc.provider, tc.variable, tc.delta, code, resp)
+		}
+	}
+}
+
+func TestGetRecommendation_GoalAgainstHistory(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	// Every hotter brew was rated higher, but hotter water is more bitter
+	_, err := db.Exec(`INSERT INTO brew_logs(id, user_id, coffee_id, brew_method, coffee_weight, water_temperature, rating, created_at) VALUES
+        (1, 1, 1, 'v60', 15, 90, 2, '2025-01-01 08:00:00'),
+        (2, 1, 1, 'v60', 15, 92, 3, '2025-01-02 08:00:00'),
+        (3, 1, 1, 'v60', 15, 94, 4, '2025-01-03 08:00:00')`)
+	if err != nil {
+		t.Fatalf("seed brew logs: %v", err)
+	}
+	recommender := services.NewBrewRecommender(database.NewQueries(db), services.NewRulesAIService())
+	h := NewAIHandler(db, nil, recommender, &config.Config{})
+
+	code, resp := historyRecommendationRequest(t, h, `{"brewLog":{"coffeeId":1,"brewMethod":"v60"},"goal":"less bitter"}`, 1)
+	change, _ := resp["change"].(map[string]any)
+	if code != http.StatusOK || resp["provider"] != "rules" || change["variable"] != "waterTemperature" || change["delta"] != "-2°C" || resp["evidence"] != nil {
+		t.Fatalf("expected the rules to lower the temperature, got %d %#v", code, resp)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/services"
+)
+
+// fakeProvider stands in for a hosted AI API. Each request gets the next
+// status in statuses (200 once they run out) and, when it is 200, reply.
+func fakeProvider(t *testing.T, check func(r *http.Request), statuses []int, reply any) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(&calls, 1))
+		if check != nil {
+			check(r)
+		}
+		if n <= len(statuses) && statuses[n-1] != http.StatusOK {
+			http.Error(w, `{"error":"unavailable"}`, statuses[n-1])
+			return
+		}
+		_ = json.NewEncoder(w).Encode(reply)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func openAIReply(content string) any {
+	return map[string]any{"choices": []any{map[string]any{"message": map[string]any{"content": content}}}}
+}
+
+func recommendationRequest(t *testing.T, aiService services.AIService) (int, map[string]any) {
+	t.Helper()
+	h := NewAIHandler(nil, nil, services.NewBrewRecommender(nil, aiService), &config.Config{})
+	body := []byte(`{"brewLog":{"brewMethod":"V60","coffeeWeight":15,"waterWeight":250},"goal":"less bitter"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ai/recommendation", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	h.GetRecommendation(w, req)
+	return w.Code, decodeBody(t, w)
+}
+
+func TestGetRecommendation_OpenAIProvider(t *testing.T) {
+	var sent map[string]any
+	srv, calls := fakeProvider(t, func(r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("unexpected request %s %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+	}, []int{http.StatusServiceUnavailable}, openAIReply("```json\n{\"variable\":\"waterTemperature\",\"delta\":\"-3°C\",\"explanation\":\"Cooler water extracts fewer bitter compounds.\"}\n```"))
+
+	openAI := services.NewOpenAIService(services.AIClientOptions{
+		APIKey: "sk-test", BaseURL: srv.URL + "/v1/", Model: "test-model", MaxRetries: 1, RetryBackoff: time.Millisecond,
+	})
+	code, resp := recommendationRequest(t, openAI)
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %#v", code, resp)
+	}
+	change, _ := resp["change"].(map[string]any)
+	if change["variable"] != "waterTemperature" || change["delta"] != "-3°C" || resp["provider"] != "openai" {
+		t.Fatalf("unexpected recommendation: %#v", resp)
+	}
+	if *calls != 2 {
+		t.Fatalf("expected one retry after a 503, got %d calls", *calls)
+	}
+	if sent["model"] != "test-model" || sent["response_format"] == nil {
+		t.Fatalf("unexpected request body: %#v", sent)
+	}
+}
+
+func TestGetRecommendation_ProviderFailures(t *testing.T) {
+	// A bad request is not retried, and with no fallback the caller sees 503
+	srv, calls := fakeProvider(t, nil, []int{http.StatusBadRequest}, nil)
+	openAI := services.NewOpenAIService(services.AIClientOptions{BaseURL: srv.URL, MaxRetries: 3, RetryBackoff: time.Millisecond})
+	if code, resp := recommendationRequest(t, openAI); code != http.StatusServiceUnavailable || *calls != 1 {
+		t.Fatalf("expected 503 after one call, got %d %#v after %d calls", code, resp, *calls)
+	}
+
+	// The timeout covers the whole call
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer slow.Close()
+	openAI = services.NewOpenAIService(services.AIClientOptions{BaseURL: slow.URL, Timeout: 50 * time.Millisecond, MaxRetries: 5})
+	start := time.Now()
+	if code, _ := recommendationRequest(t, openAI); code != http.StatusServiceUnavailable || time.Since(start) > time.Second {
+		t.Fatalf("expected a prompt 503, got %d after %v", code, time.Since(start))
+	}
+
+	// Outages and answers that are not a single known change fall back to the rules
+	for _, tc := range []struct {
+		statuses []int
+		reply    any
+	}{
+		{[]int{http.StatusInternalServerError}, nil},
+		{nil, openAIReply(`{"variable":"mood","delta":"happier","explanation":"Smile."}`)},
+		{nil, openAIReply(`I would grind finer.`)},
+	} {
+		srv, _ := fakeProvider(t, nil, tc.statuses, tc.reply)
+		openAI := services.NewOpenAIService(services.AIClientOptions{BaseURL: srv.URL})
+		code, resp := recommendationRequest(t, services.NewFallbackAIService(openAI, services.NewRulesAIService()))
+		change, _ := resp["change"].(map[string]any)
+		if code != http.StatusOK || resp["provider"] != "rules" || change["variable"] != "waterTemperature" {
+			t.Fatalf("expected the rules to answer, got %d %#v", code, resp)
+		}
+	}
+}
+
+func TestExtractCoffee_GeminiProvider(t *testing.T) {
+	var sent struct {
+		Contents []struct {
+			Parts []map[string]any `json:"parts"`
+		} `json:"contents"`
+	}
+	reply := map[string]any{"candidates": []any{map[string]any{"content": map[string]any{"parts": []any{
+		map[string]any{"text": `{"name":{"value":"Geometry","confidence":0.9},"roaster":{"value":"Onyx Coffee Lab","confidence":0.95},` +
+			`"process":{"value":"Natural","confidence":0.8},"roastDate":{"value":"","confidence":0}}`},
+	}}}}}
+	srv, _ := fakeProvider(t, func(r *http.Request) {
+		if r.URL.Path != "/models/gemini-test:generateContent" || r.Header.Get("x-goog-api-key") != "g-test" || r.URL.RawQuery != "" {
+			t.Errorf("unexpected request %s?%s", r.URL.Path, r.URL.RawQuery)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&sent)
+	}, nil, reply)
+
+	gemini := services.NewGeminiService(services.AIClientOptions{APIKey: "g-test", BaseURL: srv.URL, Model: "gemini-test"})
+	h := setupExtractHandler(t, gemini)
+	code, resp := extractRequest(t, h, "application/json", []byte(`{"text":"Geometry by Onyx"}`))
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %#v", code, resp)
+	}
+	coffee, _ := resp["coffee"].(map[string]any)
+	if coffee["name"] != "Geometry" || coffee["roasterId"] != float64(7) || coffee["process"] != "natural" || resp["provider"] != "gemini" {
+		t.Fatalf("unexpected draft: %#v", resp)
+	}
+	if len(sent.Contents) != 1 || len(sent.Contents[0].Parts) != 1 || sent.Contents[0].Parts[0]["text"] != "Label text:\nGeometry by Onyx" {
+		t.Fatalf("unexpected request body: %#v", sent)
+	}
+
+	// With the rules behind it, a failing provider still reads label text
+	down, _ := fakeProvider(t, nil, []int{http.StatusBadGateway}, nil)
+	gemini = services.NewGeminiService(services.AIClientOptions{BaseURL: down.URL})
+	h = setupExtractHandler(t, services.NewFallbackAIService(gemini, services.NewRulesAIService()))
+	code, resp = extractRequest(t, h, "application/json", []byte(`{"text":"Process: washed"}`))
+	if coffee, _ := resp["coffee"].(map[string]any); code != http.StatusOK || coffee["process"] != "washed" || resp["provider"] != "rules" {
+		t.Fatalf("expected the rules to answer, got %d %#v", code, resp)
+	}
+}
@@ -8,6 +8,7 @@ import (
     "testing"
 
     "coffeeee/backend/internal/config"
+    "coffeeee/backend/internal/services"
 )
 
 func TestGetRecommendation_BrewSuggestion(t *testing.T) {
@@ -16,7 +17,7 @@ func TestGetRecommendation_BrewSuggestion(t *testing.T) {
         t.Fatalf("failed to load config: %v", err)
     }
 
-    h := NewAIHandler(nil, cfg)
+    h := NewAIHandler(nil, nil, services.NewBrewRecommender(nil, services.NewRulesAIService()), cfg)
 
     body := map[string]any{
         "brewLog": map[string]any{
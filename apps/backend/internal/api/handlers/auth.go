@@ -1,28 +1,41 @@
 package handlers
 
 import (
+	"coffeeee/backend/internal/api/middleware"
 	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/services"
 	"coffeeee/backend/internal/utils"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"log"
+	"net"
 	"net/http"
 	"net/mail"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mattn/go-sqlite3"
 )
 
 type AuthHandler struct {
-	db  *sql.DB
-	cfg *config.Config
+	db        *sql.DB
+	passwords *utils.Passwords
+	sessions  *services.AuthSessionService
+	accounts  *services.AccountService
+	guard     *services.LoginGuard
+	cfg       *config.Config
+
+	// dummyHash is checked for e-mail addresses without an account, so that
+	// they take as long to reject as a wrong password
+	dummyHashOnce sync.Once
+	dummyHash     string
 }
 
-func NewAuthHandler(db *sql.DB, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(db *sql.DB, passwords *utils.Passwords, sessions *services.AuthSessionService, accounts *services.AccountService, guard *services.LoginGuard, cfg *config.Config) *AuthHandler {
 	// NOTE: this is constructor pattern, returning a new instance of AuthHandler
-	return &AuthHandler{db: db, cfg: cfg}
+	return &AuthHandler{db: db, passwords: passwords, sessions: sessions, accounts: accounts, guard: guard, cfg: cfg}
 }
 
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -63,15 +76,34 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The attempt counts as a failure until the password proves right.
+	// Throttled e-mail addresses and IPs get the same answer as a wrong
+	// password, without the password being checked
+	attempt := services.LoginAttempt{Email: email, IPAddress: clientIP(h.cfg, r), UserAgent: r.UserAgent()}
+	allowed, err := h.guard.Reserve(r.Context(), attempt)
+	if err != nil {
+		log.Printf("reserve login attempt: %s", err)
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	if !allowed {
+		http.Error(w, "invalid email or password", http.StatusUnauthorized)
+		return
+	}
+
 	// Query user by email
 	var userID int64
 	var username, passwordHash, passwordSalt string
-	err := h.db.QueryRow(
-		`SELECT id, username, password_hash, password_salt FROM users WHERE email = ?`,
+	var emailVerifiedAt sql.NullTime
+	err = h.db.QueryRow(
+		`SELECT id, username, password_hash, password_salt, email_verified_at FROM users WHERE email = ?`,
 		email,
-	).Scan(&userID, &username, &passwordHash, &passwordSalt)
+	).Scan(&userID, &username, &passwordHash, &passwordSalt, &emailVerifiedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
+			h.verifyDummyPassword(password)
+			attempt.Outcome = services.LoginFailed
+			h.recordLogin(r, attempt)
 			http.Error(w, "invalid email or password", http.StatusUnauthorized)
 			return
 		}
@@ -80,20 +112,37 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify password
-	if !utils.VerifyPassword(password, passwordSalt, passwordHash) {
+	ok, rehash, err := h.passwords.Verify(password, passwordHash, passwordSalt)
+	if err != nil {
+		log.Printf("verify password of user %d: %s", userID, err)
+		http.Error(w, "failed to verify password", http.StatusInternalServerError)
+		return
+	}
+	attempt.UserID = userID
+	if !ok {
+		attempt.Outcome = services.LoginFailed
+		h.recordLogin(r, attempt)
 		http.Error(w, "invalid email or password", http.StatusUnauthorized)
 		return
 	}
+	attempt.Outcome = services.LoginSucceeded
+	h.recordLogin(r, attempt)
+	// Upgrade legacy SHA-256 hashes and hashes with outdated parameters while
+	// the password is at hand. The salt column is only read for legacy hashes.
+	if rehash {
+		h.rehashPassword(userID, password)
+	}
 
-	// Parse JWT expiry
-	expiry, err := time.ParseDuration(h.cfg.JWT.Expiry)
+	// Start a login session; its refresh token renews the short-lived access token
+	session, err := h.sessions.Start(r.Context(), userID)
 	if err != nil {
-		// Default to 24 hours if parsing fails
-		expiry = 24 * time.Hour
+		log.Printf("start session for user %d: %s", userID, err)
+		http.Error(w, "failed to start session", http.StatusInternalServerError)
+		return
 	}
 
 	// Generate JWT token
-	token, err := utils.GenerateToken(userID, email, username, h.cfg.JWT.Secret, expiry)
+	token, expiry, err := h.accessToken(session)
 	if err != nil {
 		http.Error(w, "failed to generate token", http.StatusInternalServerError)
 		return
@@ -103,13 +152,16 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(map[string]any{
-		"token": token,
+		"token":        token,
+		"expiresIn":    int64(expiry.Seconds()),
+		"refreshToken": session.RefreshToken,
 		"user": map[string]any{
-			"id":        userID,
-			"email":     email,
-			"username":  username,
-			"createdAt": time.Now().Format(time.RFC3339),
-			"updatedAt": time.Now().Format(time.RFC3339),
+			"id":            userID,
+			"email":         email,
+			"username":      username,
+			"emailVerified": emailVerifiedAt.Valid,
+			"createdAt":     time.Now().Format(time.RFC3339),
+			"updatedAt":     time.Now().Format(time.RFC3339),
 		},
 	})
 }
@@ -145,19 +197,18 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	salt, err := utils.GenerateSalt(16)
+	hash, err := h.passwords.Hash(password)
 	if err != nil {
-		http.Error(w, "failed to generate salt", http.StatusInternalServerError)
+		http.Error(w, "failed to hash password", http.StatusInternalServerError)
 		return
 	}
-	hash := utils.HashPassword(password, salt)
 
 	// For now, use email as username to satisfy NOT NULL UNIQUE
 	username := email
 
 	res, err := h.db.Exec(
-		`INSERT INTO users (username, email, password_hash, password_salt) VALUES (?, ?, ?, ?)`,
-		username, email, hash, salt,
+		`INSERT INTO users (username, email, password_hash, password_salt) VALUES (?, ?, ?, '')`,
+		username, email, hash,
 	)
 	if err != nil {
 		var sqlErr sqlite3.Error
@@ -179,6 +230,12 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 	id, _ := res.LastInsertId()
 
+	// The account works before the address is verified; a failed e-mail can
+	// be sent again from POST /auth/verify-email/request
+	if err := h.accounts.SendVerification(r.Context(), id); err != nil {
+		log.Printf("send verification e-mail to user %d: %s", id, err)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(map[string]any{
@@ -187,3 +244,125 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		"username": username,
 	})
 }
+
+// rehashPassword stores a hash of password in the configured scheme. A
+// failure is only logged: the old hash still works and is retried at the
+// next login.
+func (h *AuthHandler) rehashPassword(userID int64, password string) {
+	hash, err := h.passwords.Hash(password)
+	if err == nil {
+		_, err = h.db.Exec(`UPDATE users SET password_hash = ?, password_salt = '' WHERE id = ?`, hash, userID)
+	}
+	if err != nil {
+		log.Printf("rehash password of user %d: %s", userID, err)
+	}
+}
+
+// recordLogin audits a reserved sign-in attempt and, on success, clears its
+// throttling. A failure is only logged, so that the answer stays the same.
+func (h *AuthHandler) recordLogin(r *http.Request, attempt services.LoginAttempt) {
+	if err := h.guard.Record(r.Context(), attempt); err != nil {
+		log.Printf("record login attempt for %s: %s", attempt.Email, err)
+	}
+}
+
+// verifyDummyPassword spends the time of a password check.
+func (h *AuthHandler) verifyDummyPassword(password string) {
+	h.dummyHashOnce.Do(func() {
+		h.dummyHash, _ = h.passwords.Hash("not a password")
+	})
+	_, _, _ = h.passwords.Verify(password, h.dummyHash, "")
+}
+
+// clientIP is the address sign-ins and password reset requests are throttled
+// by: the peer address, or the last X-Forwarded-For entry, added by our own
+// proxy, when it is trusted.
+func clientIP(cfg *config.Config, r *http.Request) string {
+	if cfg.Login.TrustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			parts := strings.Split(forwarded, ",")
+			if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Refresh handles POST /api/v1/auth/refresh
+// Request: JSON { "refreshToken": string }. Returns JSON { "token", "expiresIn", "refreshToken" }
+// with a new access token and the refresh token to use next time; the one sent is used up.
+// Sending a used refresh token again signs out the session it belongs to.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil || strings.TrimSpace(body.RefreshToken) == "" {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "refreshToken is required")
+		return
+	}
+
+	session, err := h.sessions.Refresh(r.Context(), strings.TrimSpace(body.RefreshToken))
+	if err != nil {
+		writeServiceError(w, err, "failed to refresh session")
+		return
+	}
+	token, expiry, err := h.accessToken(session)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "INTERNAL_ERROR", "failed to generate token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"token":        token,
+		"expiresIn":    int64(expiry.Seconds()),
+		"refreshToken": session.RefreshToken,
+	})
+}
+
+// Logout handles POST /api/v1/auth/logout
+// Request: optional JSON { "refreshToken": string }; without it the session of the
+// Bearer access token is signed out. Revokes the session's refresh and access tokens
+// and returns 204.
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	// An empty body signs out the access token's session
+	if r.ContentLength != 0 {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid JSON body")
+			return
+		}
+	}
+	var sessionID string
+	if claims, ok := middleware.GetAuthClaims(r.Context()); ok {
+		sessionID = claims.SessionID
+	}
+
+	if err := h.sessions.Logout(r.Context(), strings.TrimSpace(body.RefreshToken), sessionID); err != nil {
+		writeServiceError(w, err, "failed to sign out")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// accessToken signs an access token for the session, valid for JWT_EXPIRY.
+func (h *AuthHandler) accessToken(session *services.IssuedSession) (string, time.Duration, error) {
+	expiry, err := time.ParseDuration(h.cfg.JWT.Expiry)
+	if err != nil {
+		// Default to 15 minutes if parsing fails
+		expiry = 15 * time.Minute
+	}
+	token, err := utils.GenerateAccessToken(session.UserID, session.Email, session.Username, session.SessionID, h.cfg.JWT.Secret, expiry)
+	return token, expiry, err
+}
@@ -2,19 +2,24 @@ package handlers_test
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"coffeeee/backend/internal/api/routes"
 	"coffeeee/backend/internal/config"
 	"coffeeee/backend/internal/migrate"
 
 	_ "github.com/mattn/go-sqlite3"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // helper to get migrations dir relative to this file
@@ -25,6 +30,12 @@ func migrationsDir() string {
 }
 
 func newTestServer(t *testing.T) (*sql.DB, http.Handler) {
+	t.Helper()
+	return newConfiguredTestServer(t, func(*config.Config) {})
+}
+
+// newConfiguredTestServer is newTestServer with configure applied to its config.
+func newConfiguredTestServer(t *testing.T, configure func(*config.Config)) (*sql.DB, http.Handler) {
 	t.Helper()
 	db, err := sql.Open("sqlite3", ":memory:")
 	if err != nil {
@@ -34,12 +45,13 @@ func newTestServer(t *testing.T) (*sql.DB, http.Handler) {
 		t.Fatalf("migrate up: %v", err)
 	}
 	cfg := &config.Config{
-		Server: config.ServerConfig{AllowedOrigins: []string{"*"}},
+		Server: config.ServerConfig{Environment: "development", AllowedOrigins: []string{"*"}},
 		JWT: config.JWTConfig{
 			Secret: "test-secret-key",
 			Expiry: "24h",
 		},
 	}
+	configure(cfg)
 	h := routes.Setup(db, cfg)
 	return db, h
 }
@@ -241,3 +253,294 @@ func TestLogin_MissingFields(t *testing.T) {
 		t.Fatalf("expected 400, got %d: %s", rr.Code, rr.Body.String())
 	}
 }
+
+func loginStatus(t *testing.T, handler http.Handler, email, password string) int {
+	t.Helper()
+	b, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+func TestRegister_StoresArgon2idHash(t *testing.T) {
+	db, handler := newTestServer(t)
+	defer db.Close()
+
+	b, _ := json.Marshal(map[string]string{"email": "test@example.com", "password": "secret123"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users", bytes.NewReader(b))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("register expected 201, got %d", rr.Code)
+	}
+
+	var hash, salt string
+	_ = db.QueryRow(`SELECT password_hash, password_salt FROM users WHERE email = 'test@example.com'`).Scan(&hash, &salt)
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$") || salt != "" {
+		t.Fatalf("expected an Argon2id PHC string and no salt, got %q %q", hash, salt)
+	}
+}
+
+func TestLogin_UpgradesOlderHashes(t *testing.T) {
+	db, handler := newTestServer(t)
+	defer db.Close()
+
+	legacy := sha256.Sum256([]byte("secret123" + "pepper"))
+	bcryptHash, _ := bcrypt.GenerateFromPassword([]byte("secret123"), bcrypt.MinCost)
+	_, err := db.Exec(`INSERT INTO users (username, email, password_hash, password_salt) VALUES
+        ('legacy@example.com', 'legacy@example.com', ?, 'pepper'),
+        ('bcrypt@example.com', 'bcrypt@example.com', ?, '')`, hex.EncodeToString(legacy[:]), string(bcryptHash))
+	if err != nil {
+		t.Fatalf("seed users: %v", err)
+	}
+
+	for _, email := range []string{"legacy@example.com", "bcrypt@example.com"} {
+		var before string
+		_ = db.QueryRow(`SELECT password_hash FROM users WHERE email = ?`, email).Scan(&before)
+
+		// A wrong password leaves the hash alone
+		if code := loginStatus(t, handler, email, "wrongpassword"); code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401, got %d", email, code)
+		}
+		var hash, salt string
+		_ = db.QueryRow(`SELECT password_hash FROM users WHERE email = ?`, email).Scan(&hash)
+		if hash != before {
+			t.Fatalf("%s: hash changed after a failed login", email)
+		}
+
+		if code := loginStatus(t, handler, email, "secret123"); code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d", email, code)
+		}
+		_ = db.QueryRow(`SELECT password_hash, password_salt FROM users WHERE email = ?`, email).Scan(&hash, &salt)
+		if !strings.HasPrefix(hash, "$argon2id$") || salt != "" {
+			t.Fatalf("%s: expected the hash upgraded to Argon2id, got %q %q", email, hash, salt)
+		}
+		// The upgraded hash still signs the user in
+		if code := loginStatus(t, handler, email, "secret123"); code != http.StatusOK {
+			t.Fatalf("%s: expected 200 after the upgrade, got %d", email, code)
+		}
+	}
+}
+
+func authPost(t *testing.T, handler http.Handler, path, accessToken string, body any) (int, map[string]any) {
+	t.Helper()
+	var b []byte
+	if body != nil {
+		b, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(b))
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	var resp map[string]any
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	return rr.Code, resp
+}
+
+func profileStatus(handler http.Handler, accessToken string) int {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/me", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr.Code
+}
+
+func loginTokens(t *testing.T, handler http.Handler) (string, string) {
+	t.Helper()
+	creds := map[string]string{"email": "test@example.com", "password": "secret123"}
+	code, resp := authPost(t, handler, "/api/v1/auth/login", "", creds)
+	access, _ := resp["token"].(string)
+	refresh, _ := resp["refreshToken"].(string)
+	if code != http.StatusOK || access == "" || refresh == "" || resp["expiresIn"] != float64(24*60*60) {
+		t.Fatalf("expected tokens from login, got %d %#v", code, resp)
+	}
+	return access, refresh
+}
+
+func TestRefresh_RotatesAndDetectsReuse(t *testing.T) {
+	db, handler := newTestServer(t)
+	defer db.Close()
+	if code, _ := authPost(t, handler, "/api/v1/users", "", map[string]string{"email": "test@example.com", "password": "secret123"}); code != http.StatusCreated {
+		t.Fatalf("register expected 201, got %d", code)
+	}
+	access, refresh := loginTokens(t, handler)
+
+	code, resp := authPost(t, handler, "/api/v1/auth/refresh", "", map[string]string{"refreshToken": refresh})
+	newAccess, _ := resp["token"].(string)
+	newRefresh, _ := resp["refreshToken"].(string)
+	if code != http.StatusOK || newAccess == "" || newRefresh == "" || newRefresh == refresh {
+		t.Fatalf("expected rotated tokens, got %d %#v", code, resp)
+	}
+	var stored string
+	_ = db.QueryRow(`SELECT token_hash FROM refresh_tokens ORDER BY id DESC LIMIT 1`).Scan(&stored)
+	if stored == newRefresh || len(stored) != 64 {
+		t.Fatalf("expected the refresh token stored hashed, got %q", stored)
+	}
+	if code := profileStatus(handler, newAccess); code != http.StatusOK {
+		t.Fatalf("expected the new access token to work, got %d", code)
+	}
+
+	// Replaying the rotated token kills the whole family
+	if code, resp := authPost(t, handler, "/api/v1/auth/refresh", "", map[string]string{"refreshToken": refresh}); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 reusing a refresh token, got %d %#v", code, resp)
+	}
+	if code, _ := authPost(t, handler, "/api/v1/auth/refresh", "", map[string]string{"refreshToken": newRefresh}); code != http.StatusUnauthorized {
+		t.Fatalf("expected the latest refresh token revoked too, got %d", code)
+	}
+	for _, token := range []string{access, newAccess} {
+		if code := profileStatus(handler, token); code != http.StatusUnauthorized {
+			t.Fatalf("expected access tokens of the revoked session rejected, got %d", code)
+		}
+	}
+	var reason string
+	_ = db.QueryRow(`SELECT revoked_reason FROM auth_sessions`).Scan(&reason)
+	if reason != "reuse" {
+		t.Fatalf("expected the session revoked for reuse, got %q", reason)
+	}
+
+	for _, body := range []any{map[string]string{"refreshToken": "not-a-token"}, map[string]string{}} {
+		if code, _ := authPost(t, handler, "/api/v1/auth/refresh", "", body); code != http.StatusUnauthorized && code != http.StatusBadRequest {
+			t.Fatalf("%v: expected 401 or 400, got %d", body, code)
+		}
+	}
+}
+
+func TestLogout_RevokesSession(t *testing.T) {
+	db, handler := newTestServer(t)
+	defer db.Close()
+	if code, _ := authPost(t, handler, "/api/v1/users", "", map[string]string{"email": "test@example.com", "password": "secret123"}); code != http.StatusCreated {
+		t.Fatalf("register expected 201, got %d", code)
+	}
+
+	// With the refresh token
+	access, refresh := loginTokens(t, handler)
+	if code, _ := authPost(t, handler, "/api/v1/auth/logout", "", map[string]string{"refreshToken": refresh}); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	if code := profileStatus(handler, access); code != http.StatusUnauthorized {
+		t.Fatalf("expected the access token rejected after logout, got %d", code)
+	}
+	if code, _ := authPost(t, handler, "/api/v1/auth/refresh", "", map[string]string{"refreshToken": refresh}); code != http.StatusUnauthorized {
+		t.Fatalf("expected the refresh token rejected after logout, got %d", code)
+	}
+	// Logging out again is harmless
+	if code, _ := authPost(t, handler, "/api/v1/auth/logout", "", map[string]string{"refreshToken": refresh}); code != http.StatusNoContent {
+		t.Fatalf("expected 204 logging out twice, got %d", code)
+	}
+
+	// With the access token only; other sessions stay signed in
+	access, refresh = loginTokens(t, handler)
+	otherAccess, _ := loginTokens(t, handler)
+	if code, _ := authPost(t, handler, "/api/v1/auth/logout", access, nil); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+	if code, _ := authPost(t, handler, "/api/v1/auth/refresh", "", map[string]string{"refreshToken": refresh}); code != http.StatusUnauthorized {
+		t.Fatalf("expected the session's refresh token revoked, got %d", code)
+	}
+	if code := profileStatus(handler, otherAccess); code != http.StatusOK {
+		t.Fatalf("expected the other session untouched, got %d", code)
+	}
+	if code, _ := authPost(t, handler, "/api/v1/auth/logout", "", nil); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 logging out without a token, got %d", code)
+	}
+}
+
+func loginFrom(t *testing.T, handler http.Handler, remoteAddr, email, password string) (int, string) {
+	t.Helper()
+	b, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader(b))
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", "203.0.113.99")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr.Code, rr.Body.String()
+}
+
+func TestLogin_BacksOffAndLocksOut(t *testing.T) {
+	db, handler := newConfiguredTestServer(t, func(cfg *config.Config) {
+		cfg.Login = config.LoginConfig{MaxFailures: 4, BackoffBase: time.Hour, Lockout: time.Hour}
+	})
+	defer db.Close()
+	if code, _ := authPost(t, handler, "/api/v1/users", "", map[string]string{"email": "test@example.com", "password": "secret123"}); code != http.StatusCreated {
+		t.Fatalf("register expected 201, got %d", code)
+	}
+	const addr = "192.0.2.1:1234"
+	_, invalid := loginFrom(t, handler, addr, "test@example.com", "wrongpassword")
+
+	// A couple of typos cost nothing, and signing in starts the count over
+	loginFrom(t, handler, addr, "test@example.com", "wrongpassword")
+	if code, _ := loginFrom(t, handler, addr, "test@example.com", "secret123"); code != http.StatusOK {
+		t.Fatalf("expected 200 after two failures, got %d", code)
+	}
+
+	// The third failure in a row makes the next attempt wait, and addresses
+	// without an account are treated the same
+	for _, email := range []string{"test@example.com", "nobody@example.com"} {
+		for i := 0; i < 3; i++ {
+			if code, body := loginFrom(t, handler, addr, email, "wrongpassword"); code != http.StatusUnauthorized || body != invalid {
+				t.Fatalf("%s: expected the usual 401, got %d %q", email, code, body)
+			}
+		}
+		if code, body := loginFrom(t, handler, addr, email, "secret123"); code != http.StatusUnauthorized || body != invalid {
+			t.Fatalf("%s: expected a throttled attempt to look like a wrong password, got %d %q", email, code, body)
+		}
+	}
+
+	// Once the wait is over, the next failure locks the address
+	_, _ = db.Exec(`UPDATE login_throttles SET blocked_until = NULL`)
+	loginFrom(t, handler, addr, "test@example.com", "wrongpassword")
+	var failures int
+	var blockedUntil sql.NullTime
+	_ = db.QueryRow(`SELECT failures, blocked_until FROM login_throttles WHERE kind = 'email' AND subject = 'test@example.com'`).Scan(&failures, &blockedUntil)
+	if failures != 4 || !blockedUntil.Valid || time.Until(blockedUntil.Time) < 59*time.Minute {
+		t.Fatalf("expected the address locked for an hour, got %d failures until %v", failures, blockedUntil)
+	}
+	if code, _ := loginFrom(t, handler, addr, "test@example.com", "secret123"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 while locked, got %d", code)
+	}
+
+	// Every attempt is audited
+	counts := map[string]int{}
+	rows, _ := db.Query(`SELECT outcome, COUNT(*) FROM login_attempts WHERE email = 'test@example.com' AND ip_address = '192.0.2.1' GROUP BY outcome`)
+	for rows.Next() {
+		var outcome string
+		var n int
+		_ = rows.Scan(&outcome, &n)
+		counts[outcome] = n
+	}
+	rows.Close()
+	if counts["success"] != 1 || counts["failure"] != 6 || counts["blocked"] != 2 {
+		t.Fatalf("unexpected audit trail: %v", counts)
+	}
+	var userID sql.NullInt64
+	_ = db.QueryRow(`SELECT user_id FROM login_attempts WHERE email = 'nobody@example.com' LIMIT 1`).Scan(&userID)
+	if userID.Valid {
+		t.Fatalf("expected no user for an unknown address, got %d", userID.Int64)
+	}
+}
+
+func TestLogin_ThrottlesPerIP(t *testing.T) {
+	db, handler := newConfiguredTestServer(t, func(cfg *config.Config) {
+		cfg.Login = config.LoginConfig{MaxFailuresPerIP: 3}
+	})
+	defer db.Close()
+	if code, _ := authPost(t, handler, "/api/v1/users", "", map[string]string{"email": "test@example.com", "password": "secret123"}); code != http.StatusCreated {
+		t.Fatalf("register expected 201, got %d", code)
+	}
+
+	// One guess each at many addresses locks the IP
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		loginFrom(t, handler, "192.0.2.1:1234", email, "guess")
+	}
+	// loginFrom sends X-Forwarded-For too, which is ignored unless trusted
+	if code, _ := loginFrom(t, handler, "192.0.2.1:5678", "test@example.com", "secret123"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 from a locked IP, got %d", code)
+	}
+	if code, _ := loginFrom(t, handler, "192.0.2.2:1234", "test@example.com", "secret123"); code != http.StatusOK {
+		t.Fatalf("expected 200 from another IP, got %d", code)
+	}
+}
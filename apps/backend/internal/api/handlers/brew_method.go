@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"coffeeee/backend/internal/services"
+	"encoding/json"
+	"net/http"
+)
+
+// ListBrewMethods handles GET /api/v1/brew-methods
+// Returns the brew method catalog: canonical IDs, accepted aliases and the
+// parameter ranges brew logs are validated against.
+// Returns JSON: { "brewMethods": [ ... ] }
+func ListBrewMethods(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(map[string]any{"brewMethods": services.BrewMethods()})
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListBrewMethods(t *testing.T) {
+	w := httptest.NewRecorder()
+	ListBrewMethods(w, httptest.NewRequest(http.MethodGet, "/api/v1/brew-methods", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp struct {
+		BrewMethods []struct {
+			ID       string             `json:"id"`
+			Aliases  []string           `json:"aliases"`
+			BrewTime map[string]float64 `json:"brewTime"`
+		} `json:"brewMethods"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	byID := map[string]int{}
+	for i, m := range resp.BrewMethods {
+		byID[m.ID] = i
+	}
+	espresso, ok := byID["espresso"]
+	if !ok {
+		t.Fatalf("expected espresso in catalog: %#v", resp.BrewMethods)
+	}
+	if r := resp.BrewMethods[espresso].BrewTime; r["min"] != 10 || r["max"] != 90 {
+		t.Fatalf("unexpected espresso brewTime range: %#v", r)
+	}
+	if _, ok := byID["other"]; !ok {
+		t.Fatalf("expected the catch-all method in catalog")
+	}
+}
+
+func TestBrewLogCreate_NormalizesBrewMethod(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	for _, method := range []string{"V60", "v-60", " Hario V60 ", "hario_v60"} {
+		w, resp := createBrewLogForMetrics(t, h, `{"coffeeId":1,"brewMethod":"`+method+`"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201 for %q, got %d: %s", method, w.Code, w.Body.String())
+		}
+		if resp["brewMethod"] != "v60" {
+			t.Fatalf("expected %q to normalize to v60, got %v", method, resp["brewMethod"])
+		}
+	}
+
+	if w, _ := createBrewLogForMetrics(t, h, `{"coffeeId":1,"brewMethod":"Turkish Ibrik"}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown method, got %d", w.Code)
+	}
+}
+
+func TestBrewLogCreate_PerMethodRanges(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"espresso shot", `{"coffeeId":1,"brewMethod":"espresso","coffeeWeight":18,"waterWeight":36,"brewTime":28}`, http.StatusCreated},
+		{"espresso too long", `{"coffeeId":1,"brewMethod":"espresso","brewTime":240}`, http.StatusBadRequest},
+		{"pour-over too short", `{"coffeeId":1,"brewMethod":"V60","brewTime":28}`, http.StatusBadRequest},
+		{"cold brew overnight", `{"coffeeId":1,"brewMethod":"Cold Brew","coffeeWeight":100,"waterWeight":1000,"waterTemperature":20,"brewTime":57600}`, http.StatusCreated},
+		{"cold brew with hot water", `{"coffeeId":1,"brewMethod":"cold-brew","waterTemperature":94}`, http.StatusBadRequest},
+		{"other keeps generic ranges", `{"coffeeId":1,"brewMethod":"other","waterWeight":3000,"brewTime":3600}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w, _ := createBrewLogForMetrics(t, h, tt.body); w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestBrewLogUpdate_MethodChangeRechecksRanges(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedOwnedBrewLogs(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	// Log 1 is a 250 g V60; that is far too much water for an espresso
+	w := httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", "1", `{"brewMethod":"espresso"}`, 1))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", "1", `{"brewMethod":"Kalita","waterWeight":300}`, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	if resp["brewMethod"] != "kalita-wave" {
+		t.Fatalf("expected canonical method, got %v", resp["brewMethod"])
+	}
+}
@@ -11,6 +11,8 @@ import (
 
     "coffeeee/backend/internal/api/middleware"
     "coffeeee/backend/internal/config"
+    "coffeeee/backend/internal/database"
+    "coffeeee/backend/internal/services"
 
     _ "github.com/mattn/go-sqlite3"
 )
@@ -27,15 +29,45 @@ func setupBrewLogTestDB(t *testing.T) *sql.DB {
             password_hash VARCHAR(255) NOT NULL,
             password_salt VARCHAR(255) NOT NULL,
             created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            brew_log_visibility VARCHAR(20) NOT NULL DEFAULT 'private'
         );
         CREATE TABLE coffees (
             user_id INTEGER NOT NULL,
             id INTEGER PRIMARY KEY AUTOINCREMENT,
             name VARCHAR(255) NOT NULL,
+            origin VARCHAR(100),
+            roaster VARCHAR(255),
+            description TEXT,
+            photo_path VARCHAR(500),
             created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            roast_date TEXT,
+            purchase_date TEXT,
+            opened_date TEXT,
+            bag_size REAL,
+            price REAL,
+            remaining_weight REAL,
+            country TEXT,
+            region TEXT,
+            producer TEXT,
+            process TEXT,
+            varietals TEXT,
+            altitude_min INTEGER,
+            altitude_max INTEGER,
+            roaster_id INTEGER
         );
+        CREATE TABLE roasters (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name VARCHAR(255) NOT NULL,
+            website VARCHAR(500),
+            location VARCHAR(255),
+            created_by INTEGER,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            match_key VARCHAR(255) NOT NULL DEFAULT ''
+        );
+        CREATE UNIQUE INDEX idx_roasters_name ON roasters(name COLLATE NOCASE);
         CREATE TABLE brew_logs (
             id INTEGER PRIMARY KEY AUTOINCREMENT,
             user_id INTEGER NOT NULL,
@@ -48,8 +80,66 @@ func setupBrewLogTestDB(t *testing.T) *sql.DB {
             brew_time INTEGER,
             tasting_notes TEXT,
             rating INTEGER CHECK (rating >= 1 AND rating <= 5),
-            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME,
+            visibility VARCHAR(20),
+            tds REAL,
+            beverage_weight REAL,
+            grinder_id INTEGER,
+            brewer_id INTEGER,
+            grind_setting REAL,
+            yield_weight REAL,
+            pressure_profile TEXT,
+            pre_infusion_time INTEGER,
+            basket_size REAL,
+            parent_brew_log_id INTEGER
+        );
+        CREATE TABLE equipment (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            type VARCHAR(20) NOT NULL CHECK (type IN ('grinder', 'brewer', 'kettle', 'filter')),
+            name VARCHAR(255) NOT NULL,
+            brand VARCHAR(255),
+            model VARCHAR(255),
+            notes TEXT,
+            grind_scale_min REAL,
+            grind_scale_max REAL,
+            grind_scale_step REAL CHECK (grind_scale_step > 0),
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
         );
+        CREATE TABLE brew_log_pours (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            brew_log_id INTEGER NOT NULL,
+            position INTEGER NOT NULL,
+            time_offset INTEGER NOT NULL,
+            water_amount REAL NOT NULL,
+            water_temperature REAL,
+            UNIQUE (brew_log_id, position)
+        );
+        CREATE TABLE follows (
+            follower_id INTEGER NOT NULL,
+            followee_id INTEGER NOT NULL,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            approved_at DATETIME,
+            PRIMARY KEY (follower_id, followee_id)
+        );
+        CREATE TABLE tasting_sessions (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            user_id INTEGER NOT NULL,
+            brew_log_id INTEGER,
+            brew_method VARCHAR(50),
+            status VARCHAR(20) NOT NULL DEFAULT 'active',
+            current_question_id VARCHAR(100),
+            answers TEXT NOT NULL DEFAULT '[]',
+            note TEXT,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            completed_at DATETIME
+        );
+        CREATE TRIGGER update_brew_logs_updated_at AFTER UPDATE ON brew_logs BEGIN
+            UPDATE brew_logs SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+        END;
     `)
     if err != nil { t.Fatalf("create schema: %v", err) }
     now := time.Now().Format(time.RFC3339)
@@ -60,10 +150,16 @@ func setupBrewLogTestDB(t *testing.T) *sql.DB {
     return db
 }
 
+func setupBrewLogHandler(t *testing.T, db *sql.DB) *BrewLogHandler {
+    t.Helper()
+    brewLogService := services.NewBrewLogService(database.NewQueries(db))
+    return NewBrewLogHandler(brewLogService, &config.Config{})
+}
+
 func TestBrewLogCreate_Success(t *testing.T) {
     db := setupBrewLogTestDB(t)
     defer db.Close()
-    h := NewBrewLogHandler(db, &config.Config{})
+    h := setupBrewLogHandler(t, db)
 
     payload := map[string]any{
         "coffeeId":  int64(1),
@@ -82,7 +178,7 @@ func TestBrewLogCreate_Success(t *testing.T) {
     }
     var resp map[string]any
     _ = json.NewDecoder(w.Body).Decode(&resp)
-    if resp["id"] == nil || resp["brewMethod"] != "V60" {
+    if resp["id"] == nil || resp["brewMethod"] != "v60" {
         t.Fatalf("unexpected resp: %#v", resp)
     }
 }
@@ -90,7 +186,7 @@ func TestBrewLogCreate_Success(t *testing.T) {
 func TestBrewLogCreate_Validation(t *testing.T) {
     db := setupBrewLogTestDB(t)
     defer db.Close()
-    h := NewBrewLogHandler(db, &config.Config{})
+    h := setupBrewLogHandler(t, db)
     // Missing brewMethod
     req := httptest.NewRequest("POST", "/api/v1/brewlogs", bytes.NewBufferString(`{"coffeeId":1}`))
     req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), 1))
@@ -102,7 +198,7 @@ func TestBrewLogCreate_Validation(t *testing.T) {
 func TestBrewLogCreate_Forbidden_NotOwner(t *testing.T) {
     db := setupBrewLogTestDB(t)
     defer db.Close()
-    h := NewBrewLogHandler(db, &config.Config{})
+    h := setupBrewLogHandler(t, db)
     req := httptest.NewRequest("POST", "/api/v1/brewlogs", bytes.NewBufferString(`{"coffeeId":2,"brewMethod":"V60"}`))
     req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), 1))
     w := httptest.NewRecorder()
@@ -113,7 +209,7 @@ func TestBrewLogCreate_Forbidden_NotOwner(t *testing.T) {
 func TestBrewLogCreate_Unauthorized(t *testing.T) {
     db := setupBrewLogTestDB(t)
     defer db.Close()
-    h := NewBrewLogHandler(db, &config.Config{})
+    h := setupBrewLogHandler(t, db)
     req := httptest.NewRequest("POST", "/api/v1/brewlogs", bytes.NewBufferString(`{"coffeeId":1,"brewMethod":"V60"}`))
     w := httptest.NewRecorder()
     h.Create(w, req)
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const espressoShot = `{"coffeeId":1,"brewMethod":"espresso","coffeeWeight":18,"yieldWeight":36,"brewTime":28,` +
+	`"preInfusionTime":6,"basketSize":18,"waterTemperature":93,` +
+	`"pressureProfile":[{"timeOffset":0,"pressure":3},{"timeOffset":6,"pressure":9},{"timeOffset":25,"pressure":6}]}`
+
+func TestBrewLogCreate_Espresso(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	w, resp := createBrewLogForMetrics(t, h, espressoShot)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp["yieldWeight"] != float64(36) || resp["preInfusionTime"] != float64(6) || resp["basketSize"] != float64(18) {
+		t.Fatalf("unexpected espresso fields: %#v", resp)
+	}
+	// 18 g in, 36 g out
+	if resp["ratio"] != float64(2) {
+		t.Fatalf("expected ratio from yield, got %v", resp["ratio"])
+	}
+	profile, _ := resp["pressureProfile"].([]any)
+	if len(profile) != 3 || profile[1].(map[string]any)["pressure"] != float64(9) {
+		t.Fatalf("unexpected pressure profile: %#v", resp["pressureProfile"])
+	}
+	if _, ok := resp["waterWeight"]; ok {
+		t.Fatalf("expected no water weight on a shot: %#v", resp)
+	}
+}
+
+func TestBrewLogCreate_EspressoValidation(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"espresso fields on pour-over", `{"coffeeId":1,"brewMethod":"V60","yieldWeight":36}`},
+		{"pressure profile on pour-over", `{"coffeeId":1,"brewMethod":"V60","pressureProfile":[{"timeOffset":0,"pressure":9}]}`},
+		{"two minute shot", `{"coffeeId":1,"brewMethod":"espresso","brewTime":120}`},
+		{"pour-over water for a shot", `{"coffeeId":1,"brewMethod":"espresso","waterWeight":250}`},
+		{"yield ratio out of range", `{"coffeeId":1,"brewMethod":"espresso","coffeeWeight":18,"yieldWeight":150}`},
+		{"pre-infusion longer than shot", `{"coffeeId":1,"brewMethod":"espresso","brewTime":20,"preInfusionTime":25}`},
+		{"basket out of range", `{"coffeeId":1,"brewMethod":"espresso","basketSize":60}`},
+		{"pressure out of range", `{"coffeeId":1,"brewMethod":"espresso","pressureProfile":[{"timeOffset":0,"pressure":20}]}`},
+		{"profile out of order", `{"coffeeId":1,"brewMethod":"espresso","pressureProfile":[{"timeOffset":5,"pressure":9},{"timeOffset":2,"pressure":9}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w, _ := createBrewLogForMetrics(t, h, tt.body); w.Code != http.StatusBadRequest {
+				t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestBrewLogUpdate_Espresso(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	_, created := createBrewLogForMetrics(t, h, espressoShot)
+	id := jsonNumber(created["id"].(float64))
+
+	// The yield is checked against the stored dose
+	w := httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", id, `{"yieldWeight":120}`, 1))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", id, `{"yieldWeight":40,"pressureProfile":[]}`, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	if resp["yieldWeight"] != float64(40) || resp["basketSize"] != float64(18) {
+		t.Fatalf("unexpected resp: %#v", resp)
+	}
+	if _, ok := resp["pressureProfile"]; ok {
+		t.Fatalf("expected profile to be removed: %#v", resp["pressureProfile"])
+	}
+
+	// Moving the log to a method without espresso fields drops them
+	w = httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", id, `{"brewMethod":"moka-pot","brewTime":240,"waterWeight":150}`, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resp = map[string]any{}
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	for _, field := range []string{"yieldWeight", "preInfusionTime", "basketSize"} {
+		if _, ok := resp[field]; ok {
+			t.Fatalf("expected %s to be cleared: %#v", field, resp)
+		}
+	}
+}
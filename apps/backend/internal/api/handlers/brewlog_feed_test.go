@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+	"coffeeee/backend/internal/database"
+	"coffeeee/backend/internal/services"
+
+	"github.com/gorilla/mux"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// seedFeed gives user 2 one log at each visibility level plus one that
+// inherits their account setting, adds user 3 as an approved follower of
+// user 2 and has user 1 asking to follow them.
+func seedFeed(t *testing.T, db *sql.DB, ownerSetting string) {
+	t.Helper()
+	_, err := db.Exec(`
+        INSERT INTO users(id, username, email, password_hash, password_salt) VALUES (3, 'u3', 'u3@example.com', 'h', 's');
+        UPDATE users SET brew_log_visibility = ? WHERE id = 2;
+        UPDATE coffees SET roaster = 'Onyx' WHERE id = 2;
+        INSERT INTO follows(follower_id, followee_id, approved_at) VALUES (3, 2, '2025-01-01 07:00:00'), (1, 2, NULL);
+        INSERT INTO brew_logs(id, user_id, coffee_id, brew_method, visibility, created_at) VALUES
+            (10, 2, 2, 'V60', 'public',    '2025-01-01 08:00:00'),
+            (11, 2, 2, 'V60', 'followers', '2025-01-02 08:00:00'),
+            (12, 2, 2, 'V60', 'private',   '2025-01-03 08:00:00'),
+            (13, 2, 2, 'V60', NULL,        '2025-01-04 08:00:00')`, ownerSetting)
+	if err != nil {
+		t.Fatalf("seed feed: %v", err)
+	}
+}
+
+func doBrewLogFeed(t *testing.T, h *BrewLogHandler, ownerID string, viewerID int64, params url.Values) (*httptest.ResponseRecorder, brewLogListResp) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/api/v1/users/"+ownerID+"/brewlogs?"+params.Encode(), nil)
+	req = mux.SetURLVars(req, map[string]string{"userId": ownerID})
+	if viewerID != 0 {
+		req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), viewerID))
+	}
+	w := httptest.NewRecorder()
+	h.ListByUser(w, req)
+	var resp brewLogListResp
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("invalid json: %v", err)
+		}
+	}
+	return w, resp
+}
+
+func feedIDs(resp brewLogListResp) []float64 {
+	ids := make([]float64, 0, len(resp.BrewLogs))
+	for _, bl := range resp.BrewLogs {
+		ids = append(ids, bl["id"].(float64))
+	}
+	return ids
+}
+
+func TestBrewLogListByUser_Visibility(t *testing.T) {
+	tests := []struct {
+		name         string
+		ownerSetting string
+		viewerID     int64
+		want         []float64
+	}{
+		{"anonymous sees public only", "private", 0, []float64{10}},
+		{"pending follower sees public only", "private", 1, []float64{10}},
+		{"follower sees followers logs", "private", 3, []float64{11, 10}},
+		{"owner sees everything", "private", 2, []float64{13, 12, 11, 10}},
+		{"public setting applies to inheriting logs", "public", 0, []float64{13, 10}},
+		{"followers setting applies to inheriting logs", "followers", 3, []float64{13, 11, 10}},
+		{"followers setting needs an approved follow", "followers", 1, []float64{10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := setupBrewLogTestDB(t)
+			defer db.Close()
+			seedFeed(t, db, tt.ownerSetting)
+			h := setupBrewLogHandler(t, db)
+
+			w, resp := doBrewLogFeed(t, h, "2", tt.viewerID, url.Values{})
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			got := feedIDs(resp)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected ids %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected ids %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestBrewLogListByUser_JoinsCoffeeAndHidesEmail(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedFeed(t, db, "private")
+	h := setupBrewLogHandler(t, db)
+
+	w, resp := doBrewLogFeed(t, h, "2", 0, url.Values{})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "@example.com") {
+		t.Fatalf("feed must not expose e-mail addresses: %s", w.Body.String())
+	}
+	bl := resp.BrewLogs[0]
+	if bl["username"] != "u2" || bl["coffeeName"] != "B" || bl["coffeeRoaster"] != "Onyx" || bl["visibility"] != "public" {
+		t.Fatalf("unexpected feed entry: %#v", bl)
+	}
+}
+
+func TestBrewLogListByUser_Pagination(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedFeed(t, db, "private")
+	h := setupBrewLogHandler(t, db)
+
+	_, first := doBrewLogFeed(t, h, "2", 2, url.Values{"limit": {"3"}})
+	if len(first.BrewLogs) != 3 || first.NextCursor == nil {
+		t.Fatalf("expected a full first page with a cursor, got %d logs", len(first.BrewLogs))
+	}
+	_, second := doBrewLogFeed(t, h, "2", 2, url.Values{"limit": {"3"}, "cursor": {*first.NextCursor}})
+	if ids := feedIDs(second); len(ids) != 1 || ids[0] != 10 || second.NextCursor != nil {
+		t.Fatalf("expected only the oldest log on page 2, got %v", ids)
+	}
+}
+
+func TestBrewLogListByUser_Errors(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	if w, _ := doBrewLogFeed(t, h, "99", 0, url.Values{}); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown user, got %d", w.Code)
+	}
+	if w, _ := doBrewLogFeed(t, h, "1", 0, url.Values{"limit": {"0"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad limit, got %d", w.Code)
+	}
+	if w, _ := doBrewLogFeed(t, h, "1", 0, url.Values{"cursor": {"nope"}}); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for bad cursor, got %d", w.Code)
+	}
+}
+
+func TestBrewLogVisibilityOverride_Validation(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedOwnedBrewLogs(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	w := httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", "1", `{"visibility":"everyone"}`, 1))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown visibility, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", "1", `{"visibility":"public"}`, 1))
+	var resp map[string]any
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	if w.Code != http.StatusOK || resp["visibility"] != "public" {
+		t.Fatalf("expected override to be stored, got %d %#v", w.Code, resp)
+	}
+
+	// An empty string clears the override back to the account setting
+	w = httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", "1", `{"visibility":""}`, 1))
+	resp = nil
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	if _, ok := resp["visibility"]; w.Code != http.StatusOK || ok {
+		t.Fatalf("expected override to be cleared, got %d %#v", w.Code, resp)
+	}
+}
+
+func TestFollowHandler(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := NewFollowHandler(services.NewFollowService(database.NewQueries(db)), nil)
+
+	followReq := func(method, userID string, callerID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/v1/users/"+userID+"/follow", nil)
+		req = mux.SetURLVars(req, map[string]string{"userId": userID})
+		req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), callerID))
+		w := httptest.NewRecorder()
+		if method == "POST" {
+			h.Follow(w, req)
+		} else {
+			h.Unfollow(w, req)
+		}
+		return w
+	}
+
+	if w := followReq("POST", "2", 1); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := followReq("POST", "2", 1); w.Code != http.StatusNoContent {
+		t.Fatalf("expected repeat follow to be a no-op, got %d", w.Code)
+	}
+	if w := followReq("POST", "1", 1); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 following self, got %d", w.Code)
+	}
+	if w := followReq("POST", "99", 1); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 following unknown user, got %d", w.Code)
+	}
+	var n int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM follows WHERE follower_id = 1 AND followee_id = 2`).Scan(&n)
+	if n != 1 {
+		t.Fatalf("expected one follow row, got %d", n)
+	}
+	if w := followReq("DELETE", "2", 1); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	_ = db.QueryRow(`SELECT COUNT(*) FROM follows`).Scan(&n)
+	if n != 0 {
+		t.Fatalf("expected follow to be removed, got %d rows", n)
+	}
+}
+
+func TestFollowHandler_Approval(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	if _, err := db.Exec(`INSERT INTO brew_logs(id, user_id, coffee_id, brew_method, visibility, created_at) VALUES (11, 2, 2, 'V60', 'followers', '2025-01-02 08:00:00')`); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	fh := NewFollowHandler(services.NewFollowService(database.NewQueries(db)), nil)
+	bh := setupBrewLogHandler(t, db)
+
+	call := func(handle http.HandlerFunc, method, path, followerID string, callerID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req = mux.SetURLVars(req, map[string]string{"userId": followerID})
+		req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), callerID))
+		w := httptest.NewRecorder()
+		handle(w, req)
+		return w
+	}
+	followers := func() []map[string]any {
+		w := call(fh.ListFollowers, "GET", "/api/v1/users/me/followers", "", 2)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list followers: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var resp struct {
+			Followers []map[string]any `json:"followers"`
+		}
+		_ = json.NewDecoder(w.Body).Decode(&resp)
+		return resp.Followers
+	}
+	visible := func() int {
+		_, resp := doBrewLogFeed(t, bh, "2", 1, url.Values{})
+		return len(resp.BrewLogs)
+	}
+
+	// Following only asks; the followers log stays hidden until approved
+	if w := call(fh.Follow, "POST", "/api/v1/users/2/follow", "2", 1); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if got := followers(); len(got) != 1 || got[0]["userId"] != float64(1) || got[0]["username"] != "u1" || got[0]["status"] != "pending" || got[0]["approvedAt"] != nil {
+		t.Fatalf("expected one pending request from u1, got %v", got)
+	}
+	if n := visible(); n != 0 {
+		t.Fatalf("expected a pending follower to see no followers logs, got %d", n)
+	}
+
+	if w := call(fh.ApproveFollower, "POST", "/api/v1/users/me/followers/3/approve", "3", 2); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 approving a missing request, got %d", w.Code)
+	}
+	if w := call(fh.ApproveFollower, "POST", "/api/v1/users/me/followers/2/approve", "2", 1); w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 approving a follow of someone else, got %d", w.Code)
+	}
+	if w := call(fh.ApproveFollower, "POST", "/api/v1/users/me/followers/1/approve", "1", 2); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := followers(); len(got) != 1 || got[0]["status"] != "approved" || got[0]["approvedAt"] == nil {
+		t.Fatalf("expected an approved follower, got %v", got)
+	}
+	if n := visible(); n != 1 {
+		t.Fatalf("expected an approved follower to see the followers log, got %d", n)
+	}
+	// Following again keeps the approval
+	call(fh.Follow, "POST", "/api/v1/users/2/follow", "2", 1)
+	if n := visible(); n != 1 {
+		t.Fatalf("expected a repeat follow to keep the approval, got %d", n)
+	}
+
+	// Removing a follower revokes access, and asking again needs a new approval
+	if w := call(fh.RemoveFollower, "DELETE", "/api/v1/users/me/followers/1", "1", 2); w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	if got := followers(); len(got) != 0 {
+		t.Fatalf("expected no followers, got %v", got)
+	}
+	call(fh.Follow, "POST", "/api/v1/users/2/follow", "2", 1)
+	if n := visible(); n != 0 {
+		t.Fatalf("expected a removed follower to need approving again, got %d", n)
+	}
+}
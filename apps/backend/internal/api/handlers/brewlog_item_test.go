@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+
+	"github.com/gorilla/mux"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// seedOwnedBrewLogs adds brew log 1 (user 1, coffee 1) and brew log 2 (user 2, coffee 2).
+func seedOwnedBrewLogs(t *testing.T, db *sql.DB) {
+	t.Helper()
+	_, err := db.Exec(`INSERT INTO brew_logs(id, user_id, coffee_id, brew_method, coffee_weight, water_weight, rating, created_at) VALUES
+        (1, 1, 1, 'V60', 15, 250, 3, '2025-01-01 08:00:00'),
+        (2, 2, 2, 'V60', 15, 250, 4, '2025-01-01 08:00:00')`)
+	if err != nil {
+		t.Fatalf("seed brew logs: %v", err)
+	}
+}
+
+func brewLogItemRequest(method, id, body string, userID int64) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/brewlogs/"+id, bytes.NewBufferString(body))
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	if userID != 0 {
+		req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), userID))
+	}
+	return req
+}
+
+func TestBrewLogGet(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedOwnedBrewLogs(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	tests := []struct {
+		name   string
+		id     string
+		userID int64
+		want   int
+	}{
+		{"owner", "1", 1, http.StatusOK},
+		{"other user's log", "2", 1, http.StatusForbidden},
+		{"missing", "99", 1, http.StatusNotFound},
+		{"unauthenticated", "1", 0, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.Get(w, brewLogItemRequest("GET", tt.id, "", tt.userID))
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestBrewLogUpdate_PartialKeepsOtherFields(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedOwnedBrewLogs(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	w := httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", "1", `{"rating":5,"tastingNotes":"jammy"}`, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	_ = json.NewDecoder(w.Body).Decode(&resp)
+	if resp["rating"] != float64(5) || resp["tastingNotes"] != "jammy" {
+		t.Fatalf("expected updated fields, got %#v", resp)
+	}
+	if resp["brewMethod"] != "V60" || resp["waterWeight"] != float64(250) {
+		t.Fatalf("expected untouched fields to be kept, got %#v", resp)
+	}
+	if resp["updatedAt"] == resp["createdAt"] {
+		t.Fatalf("expected updatedAt to move on edit, got %v", resp["updatedAt"])
+	}
+}
+
+func TestBrewLogUpdate_Errors(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedOwnedBrewLogs(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	tests := []struct {
+		name string
+		id   string
+		body string
+		want int
+	}{
+		{"rating out of range", "1", `{"rating":9}`, http.StatusBadRequest},
+		{"water out of range", "1", `{"waterWeight":5000}`, http.StatusBadRequest},
+		{"empty brew method", "1", `{"brewMethod":"  "}`, http.StatusBadRequest},
+		{"no fields", "1", `{}`, http.StatusBadRequest},
+		{"unknown field", "1", `{"userId":2}`, http.StatusBadRequest},
+		{"other user's log", "2", `{"rating":5}`, http.StatusForbidden},
+		{"move to other user's coffee", "1", `{"coffeeId":2}`, http.StatusForbidden},
+		{"move to missing coffee", "1", `{"coffeeId":99}`, http.StatusNotFound},
+		{"missing", "99", `{"rating":5}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.Update(w, brewLogItemRequest("PATCH", tt.id, tt.body, 1))
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestBrewLogDelete(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedOwnedBrewLogs(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	w := httptest.NewRecorder()
+	h.Delete(w, brewLogItemRequest("DELETE", "2", "", 1))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 deleting another user's log, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.Delete(w, brewLogItemRequest("DELETE", "1", "", 1))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.Delete(w, brewLogItemRequest("DELETE", "1", "", 1))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 after delete, got %d", w.Code)
+	}
+}
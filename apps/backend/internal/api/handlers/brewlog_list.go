@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"coffeeee/backend/internal/services"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// List handles GET /api/v1/brewlogs
+// Query: coffeeId, brewMethod, grinderId, brewerId, minRating, maxRating, from, to (YYYY-MM-DD or RFC3339),
+// minRatio, maxRatio, minExtractionYield, maxExtractionYield, minStrength, maxStrength,
+// sort (date|rating|ratio|extractionYield|strength, default date), order (asc|desc, default desc),
+// limit (1-100), cursor.
+// Returns JSON: { "brewLogs": [ ... ], "nextCursor": string|null }
+func (h *BrewLogHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	input, err := parseBrewLogListQuery(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", err.Error())
+		return
+	}
+	input.UserID = userID
+
+	page, err := h.brewLogService.List(r.Context(), input)
+	if err != nil {
+		writeServiceError(w, err, "failed to query brew logs")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+// parseBrewLogListQuery turns query parameters into a service input. Range and
+// cursor rules are enforced by the service; this only rejects malformed values.
+func parseBrewLogListQuery(v url.Values) (services.ListBrewLogsInput, error) {
+	var input services.ListBrewLogsInput
+
+	if s := strings.TrimSpace(v.Get("coffeeId")); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return input, errors.New("coffeeId must be a positive integer")
+		}
+		input.CoffeeID = &id
+	}
+	if s := strings.TrimSpace(v.Get("brewMethod")); s != "" {
+		input.BrewMethod = &s
+	}
+	var err error
+	if input.GrinderID, err = parseIntParam(v, "grinderId"); err != nil {
+		return input, err
+	}
+	if input.BrewerID, err = parseIntParam(v, "brewerId"); err != nil {
+		return input, err
+	}
+	if input.MinRating, err = parseIntParam(v, "minRating"); err != nil {
+		return input, err
+	}
+	if input.MaxRating, err = parseIntParam(v, "maxRating"); err != nil {
+		return input, err
+	}
+	for _, f := range []struct {
+		name string
+		dst  **float64
+	}{
+		{"minRatio", &input.MinRatio},
+		{"maxRatio", &input.MaxRatio},
+		{"minExtractionYield", &input.MinExtractionYield},
+		{"maxExtractionYield", &input.MaxExtractionYield},
+		{"minStrength", &input.MinStrength},
+		{"maxStrength", &input.MaxStrength},
+	} {
+		if *f.dst, err = parseFloatParam(v, f.name); err != nil {
+			return input, err
+		}
+	}
+	if s := strings.TrimSpace(v.Get("from")); s != "" {
+		t, _, err := parseDateParam(s)
+		if err != nil {
+			return input, errors.New("from must be YYYY-MM-DD or RFC3339")
+		}
+		input.CreatedFrom = &t
+	}
+	if s := strings.TrimSpace(v.Get("to")); s != "" {
+		t, dateOnly, err := parseDateParam(s)
+		if err != nil {
+			return input, errors.New("to must be YYYY-MM-DD or RFC3339")
+		}
+		// A bare date includes the whole day; a timestamp is inclusive to the second
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		} else {
+			t = t.Add(time.Second)
+		}
+		input.CreatedBefore = &t
+	}
+	input.Sort = strings.TrimSpace(v.Get("sort"))
+	input.Order = strings.TrimSpace(v.Get("order"))
+	if s := strings.TrimSpace(v.Get("limit")); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return input, errors.New("limit must be between 1 and 100")
+		}
+		input.Limit = n
+	}
+	input.Cursor = strings.TrimSpace(v.Get("cursor"))
+	return input, nil
+}
+
+func parseIntParam(v url.Values, name string) (*int64, error) {
+	s := strings.TrimSpace(v.Get(name))
+	if s == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, errors.New(name + " must be an integer")
+	}
+	return &n, nil
+}
+
+func parseFloatParam(v url.Values, name string) (*float64, error) {
+	s := strings.TrimSpace(v.Get(name))
+	if s == "" {
+		return nil, nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+		return nil, errors.New(name + " must be a number")
+	}
+	return &n, nil
+}
+
+// parseDateParam accepts YYYY-MM-DD or RFC3339 and reports whether only a date was given.
+func parseDateParam(s string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t, false, err
+}
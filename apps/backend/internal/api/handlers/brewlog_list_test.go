@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type brewLogListResp struct {
+	BrewLogs   []map[string]any `json:"brewLogs"`
+	NextCursor *string          `json:"nextCursor"`
+}
+
+func seedBrewLogsForList(t *testing.T, db *sql.DB) {
+	t.Helper()
+	_, err := db.Exec(`INSERT INTO brew_logs(user_id, coffee_id, brew_method, coffee_weight, water_weight, rating, created_at) VALUES
+        (1, 1, 'v60',       15, 250, 3, '2025-01-01 08:00:00'),
+        (1, 1, 'v60',       15, 240, 5, '2025-01-02 08:00:00'),
+        (1, 1, 'aeropress', 15, 200, 4, '2025-01-03 08:00:00'),
+        (1, 1, 'v60',       20, 300, NULL, '2025-01-04 08:00:00'),
+        (2, 2, 'v60',       15, 250, 5, '2025-01-02 09:00:00')`)
+	if err != nil {
+		t.Fatalf("seed brew logs: %v", err)
+	}
+}
+
+func doBrewLogList(t *testing.T, h *BrewLogHandler, userID int64, params url.Values) (*httptest.ResponseRecorder, brewLogListResp) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/api/v1/brewlogs?"+params.Encode(), nil)
+	req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), userID))
+	w := httptest.NewRecorder()
+	h.List(w, req)
+	var resp brewLogListResp
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("invalid json: %v", err)
+		}
+	}
+	return w, resp
+}
+
+func TestBrewLogList_DefaultsToNewestFirstForCaller(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedBrewLogsForList(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	w, resp := doBrewLogList(t, h, 1, url.Values{})
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if len(resp.BrewLogs) != 4 {
+		t.Fatalf("expected 4 brew logs for user 1, got %d", len(resp.BrewLogs))
+	}
+	if resp.BrewLogs[0]["createdAt"] != "2025-01-04T08:00:00.000Z" {
+		t.Fatalf("expected newest first, got %v", resp.BrewLogs[0]["createdAt"])
+	}
+	if resp.NextCursor != nil {
+		t.Fatalf("expected no next cursor, got %v", *resp.NextCursor)
+	}
+}
+
+func TestBrewLogList_Filters(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedBrewLogsForList(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	tests := []struct {
+		name   string
+		params url.Values
+		want   int
+	}{
+		{"brew method", url.Values{"brewMethod": {"V60"}}, 3},
+		{"brew method alias", url.Values{"brewMethod": {"Hario V60"}}, 3},
+		{"coffee id", url.Values{"coffeeId": {"1"}}, 4},
+		{"rating range", url.Values{"minRating": {"4"}, "maxRating": {"5"}}, 2},
+		{"date range", url.Values{"from": {"2025-01-02"}, "to": {"2025-01-03"}}, 2},
+		{"rfc3339 range", url.Values{"from": {"2025-01-02T08:00:00Z"}, "to": {"2025-01-02T08:00:00Z"}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, resp := doBrewLogList(t, h, 1, tt.params)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if len(resp.BrewLogs) != tt.want {
+				t.Fatalf("expected %d brew logs, got %d", tt.want, len(resp.BrewLogs))
+			}
+		})
+	}
+}
+
+func TestBrewLogList_CursorPaginationBySort(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedBrewLogsForList(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	for _, sort := range []string{"date", "rating", "ratio"} {
+		t.Run(sort, func(t *testing.T) {
+			seen := map[float64]bool{}
+			params := url.Values{"sort": {sort}, "limit": {"3"}}
+			pages := 0
+			for {
+				w, resp := doBrewLogList(t, h, 1, params)
+				if w.Code != http.StatusOK {
+					t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+				}
+				pages++
+				for _, bl := range resp.BrewLogs {
+					id := bl["id"].(float64)
+					if seen[id] {
+						t.Fatalf("brew log %v returned twice", id)
+					}
+					seen[id] = true
+				}
+				if resp.NextCursor == nil {
+					break
+				}
+				params.Set("cursor", *resp.NextCursor)
+			}
+			if pages != 2 || len(seen) != 4 {
+				t.Fatalf("expected 4 logs over 2 pages, got %d over %d", len(seen), pages)
+			}
+		})
+	}
+
+	_, resp := doBrewLogList(t, h, 1, url.Values{"sort": {"rating"}, "limit": {"1"}})
+	if len(resp.BrewLogs) != 1 || resp.BrewLogs[0]["rating"] != float64(5) {
+		t.Fatalf("expected highest rating first, got %#v", resp.BrewLogs)
+	}
+}
+
+func TestBrewLogList_InvalidParams(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	for _, params := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"101"}},
+		{"sort": {"name"}},
+		{"minRating": {"6"}},
+		{"minRating": {"4"}, "maxRating": {"2"}},
+		{"from": {"yesterday"}},
+		{"cursor": {"not-a-cursor"}},
+	} {
+		w, _ := doBrewLogList(t, h, 1, params)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %v, got %d", params, w.Code)
+		}
+	}
+}
+
+func TestBrewLogList_Unauthorized(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+	req := httptest.NewRequest("GET", "/api/v1/brewlogs", nil)
+	w := httptest.NewRecorder()
+	h.List(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
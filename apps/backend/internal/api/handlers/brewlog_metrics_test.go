@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func createBrewLogForMetrics(t *testing.T, h *BrewLogHandler, body string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/api/v1/brewlogs", bytes.NewBufferString(body))
+	req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), 1))
+	w := httptest.NewRecorder()
+	h.Create(w, req)
+	var resp map[string]any
+	_ = json.NewDecoder(bytes.NewReader(w.Body.Bytes())).Decode(&resp)
+	return w, resp
+}
+
+func TestBrewLogCreate_DerivedMetrics(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	tests := []struct {
+		name     string
+		body     string
+		ratio    any
+		ey       any
+		strength any
+	}{
+		{
+			"measured beverage",
+			`{"coffeeId":1,"brewMethod":"V60","coffeeWeight":15,"waterWeight":250,"tds":1.35,"beverageWeight":215}`,
+			16.67, 19.35, 1.35,
+		},
+		{
+			"beverage estimated from retention",
+			`{"coffeeId":1,"brewMethod":"V60","coffeeWeight":20,"waterWeight":320,"tds":1.4}`,
+			float64(16), 19.6, 1.4,
+		},
+		{
+			"ratio only without tds",
+			`{"coffeeId":1,"brewMethod":"V60","coffeeWeight":18,"waterWeight":300}`,
+			16.67, nil, nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, resp := createBrewLogForMetrics(t, h, tt.body)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+			}
+			if resp["ratio"] != tt.ratio || resp["extractionYield"] != tt.ey || resp["strength"] != tt.strength {
+				t.Fatalf("expected ratio %v, EY %v, strength %v; got %v, %v, %v",
+					tt.ratio, tt.ey, tt.strength, resp["ratio"], resp["extractionYield"], resp["strength"])
+			}
+		})
+	}
+}
+
+func TestBrewLogCreate_MetricValidation(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	for _, body := range []string{
+		`{"coffeeId":1,"brewMethod":"V60","tds":30}`,
+		`{"coffeeId":1,"brewMethod":"V60","tds":-1}`,
+		`{"coffeeId":1,"brewMethod":"V60","beverageWeight":4000}`,
+		`{"coffeeId":1,"brewMethod":"V60","waterWeight":200,"beverageWeight":250}`,
+	} {
+		if w, _ := createBrewLogForMetrics(t, h, body); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+
+	// A partial update is checked against the stored water weight
+	seedOwnedBrewLogs(t, db)
+	w := httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", "1", `{"beverageWeight":300}`, 1))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for beverage heavier than stored water, got %d", w.Code)
+	}
+}
+
+func TestBrewLogList_MetricFiltersAndSort(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+	_, err := db.Exec(`INSERT INTO brew_logs(user_id, coffee_id, brew_method, coffee_weight, water_weight, tds, beverage_weight) VALUES
+        (1, 1, 'V60', 15, 250, 1.35, 215),
+        (1, 1, 'V60', 20, 320, 1.40, NULL),
+        (1, 1, 'V60', 15, 240, NULL, NULL),
+        (1, 1, 'V60', 20, 320, 1.30, 0)`)
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		params url.Values
+		want   int
+	}{
+		{"min extraction yield", url.Values{"minExtractionYield": {"19.5"}}, 1},
+		{"extraction range", url.Values{"minExtractionYield": {"19"}, "maxExtractionYield": {"20"}}, 2},
+		{"zero beverage weight has no extraction yield", url.Values{"maxExtractionYield": {"20"}}, 2},
+		{"strength", url.Values{"minStrength": {"1.38"}}, 1},
+		{"ratio", url.Values{"minRatio": {"16.5"}, "maxRatio": {"17"}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, resp := doBrewLogList(t, h, 1, tt.params)
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if len(resp.BrewLogs) != tt.want {
+				t.Fatalf("expected %d brew logs, got %d", tt.want, len(resp.BrewLogs))
+			}
+		})
+	}
+
+	_, resp := doBrewLogList(t, h, 1, url.Values{"sort": {"extractionYield"}})
+	if len(resp.BrewLogs) != 4 || resp.BrewLogs[0]["extractionYield"] != 19.6 || resp.BrewLogs[2]["extractionYield"] != nil || resp.BrewLogs[3]["extractionYield"] != nil {
+		t.Fatalf("expected highest extraction first and unmeasured last, got %#v", resp.BrewLogs)
+	}
+
+	for _, params := range []url.Values{
+		{"minStrength": {"abc"}},
+		{"minRatio": {"-1"}},
+		{"minExtractionYield": {"22"}, "maxExtractionYield": {"18"}},
+	} {
+		if w, _ := doBrewLogList(t, h, 1, params); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %v, got %d", params, w.Code)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const bloomAndPulses = `[
+    {"timeOffset":0,"waterAmount":50,"waterTemperature":96},
+    {"timeOffset":45,"waterAmount":100},
+    {"timeOffset":90,"waterAmount":100}
+]`
+
+func TestBrewLogCreate_WithPours(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	w, resp := createBrewLogForMetrics(t, h, `{"coffeeId":1,"brewMethod":"V60","coffeeWeight":15,"pours":`+bloomAndPulses+`}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp["waterWeight"] != float64(250) {
+		t.Fatalf("expected waterWeight to default to the pour total, got %v", resp["waterWeight"])
+	}
+	pours, _ := resp["pours"].([]any)
+	if len(pours) != 3 {
+		t.Fatalf("expected 3 pours, got %#v", resp["pours"])
+	}
+	first := pours[0].(map[string]any)
+	if first["timeOffset"] != float64(0) || first["waterAmount"] != float64(50) || first["waterTemperature"] != float64(96) {
+		t.Fatalf("unexpected bloom stage: %#v", first)
+	}
+	if _, ok := pours[1].(map[string]any)["waterTemperature"]; ok {
+		t.Fatalf("expected optional temperature to be omitted: %#v", pours[1])
+	}
+
+	// Without pours the list is empty rather than missing
+	_, resp = createBrewLogForMetrics(t, h, `{"coffeeId":1,"brewMethod":"V60"}`)
+	if pours, ok := resp["pours"].([]any); !ok || len(pours) != 0 {
+		t.Fatalf("expected empty pours, got %#v", resp["pours"])
+	}
+}
+
+func TestBrewLogCreate_PourValidation(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	for _, body := range []string{
+		`{"coffeeId":1,"brewMethod":"V60","waterWeight":300,"pours":` + bloomAndPulses + `}`,
+		`{"coffeeId":1,"brewMethod":"V60","pours":[{"timeOffset":30,"waterAmount":50},{"timeOffset":10,"waterAmount":50}]}`,
+		`{"coffeeId":1,"brewMethod":"V60","pours":[{"timeOffset":0,"waterAmount":0}]}`,
+		`{"coffeeId":1,"brewMethod":"V60","pours":[{"timeOffset":-1,"waterAmount":50}]}`,
+		`{"coffeeId":1,"brewMethod":"V60","pours":[{"timeOffset":0,"waterAmount":50,"waterTemperature":120}]}`,
+	} {
+		if w, _ := createBrewLogForMetrics(t, h, body); w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+	var n int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM brew_log_pours`).Scan(&n)
+	if n != 0 {
+		t.Fatalf("expected no pours to be stored for rejected logs, got %d", n)
+	}
+}
+
+func TestBrewLogUpdate_Pours(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedOwnedBrewLogs(t, db) // log 1 has waterWeight 250
+	h := setupBrewLogHandler(t, db)
+
+	update := func(body string) (*httptest.ResponseRecorder, map[string]any) {
+		w := httptest.NewRecorder()
+		h.Update(w, brewLogItemRequest("PATCH", "1", body, 1))
+		var resp map[string]any
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return w, resp
+	}
+
+	if w, resp := update(`{"pours":` + bloomAndPulses + `}`); w.Code != http.StatusOK || len(resp["pours"].([]any)) != 3 {
+		t.Fatalf("expected pours to be stored, got %d: %s", w.Code, w.Body.String())
+	}
+	// Changing the water weight alone must still agree with the stored pours
+	if w, _ := update(`{"waterWeight":300}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for water weight that no longer matches pours, got %d", w.Code)
+	}
+	// New pours without waterWeight replace the stages and the total
+	w, resp := update(`{"pours":[{"timeOffset":0,"waterAmount":60},{"timeOffset":40,"waterAmount":240}]}`)
+	if w.Code != http.StatusOK || resp["waterWeight"] != float64(300) || len(resp["pours"].([]any)) != 2 {
+		t.Fatalf("expected pours and waterWeight to be replaced, got %d: %s", w.Code, w.Body.String())
+	}
+	// Other fields leave the stages alone
+	if _, resp := update(`{"rating":5}`); len(resp["pours"].([]any)) != 2 {
+		t.Fatalf("expected pours to be kept, got %#v", resp["pours"])
+	}
+	if w, resp := update(`{"pours":[]}`); w.Code != http.StatusOK || len(resp["pours"].([]any)) != 0 {
+		t.Fatalf("expected pours to be cleared, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBrewLogPours_ListedAndDeleted(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupBrewLogHandler(t, db)
+
+	_, created := createBrewLogForMetrics(t, h, `{"coffeeId":1,"brewMethod":"V60","pours":`+bloomAndPulses+`}`)
+	_, resp := doBrewLogList(t, h, 1, url.Values{})
+	if len(resp.BrewLogs) != 1 || len(resp.BrewLogs[0]["pours"].([]any)) != 3 {
+		t.Fatalf("expected listed brew log to include pours, got %#v", resp.BrewLogs)
+	}
+
+	w := httptest.NewRecorder()
+	h.Delete(w, brewLogItemRequest("DELETE", fmt.Sprint(created["id"]), "", 1))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	var n int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM brew_log_pours`).Scan(&n)
+	if n != 0 {
+		t.Fatalf("expected pours to be deleted with the brew log, got %d", n)
+	}
+}
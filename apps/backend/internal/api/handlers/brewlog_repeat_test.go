@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBrewLogRepeat(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedEquipment(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	w, source := createBrewLogForMetrics(t, h, `{"coffeeId":1,"brewMethod":"v60","coffeeWeight":15,"grinderId":1,"grindSetting":18,"brewerId":2,"tastingNotes":"sour","rating":2,"tds":1.3,"pours":[{"timeOffset":0,"waterAmount":50},{"timeOffset":45,"waterAmount":200}]}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	sourceID := jsonNumber(source["id"].(float64))
+
+	// An empty body copies the recipe but not the outcome
+	w = httptest.NewRecorder()
+	h.Repeat(w, brewLogItemRequest("POST", sourceID, "", 1))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeBody(t, w)
+	if resp["id"] == source["id"] || resp["parentBrewLogId"] != source["id"] {
+		t.Fatalf("expected a new log linked to %v: %#v", source["id"], resp)
+	}
+	if resp["brewMethod"] != "v60" || resp["coffeeWeight"] != float64(15) || resp["waterWeight"] != float64(250) ||
+		resp["grinderId"] != float64(1) || resp["grindSetting"] != float64(18) || resp["brewerId"] != float64(2) {
+		t.Fatalf("recipe not copied: %#v", resp)
+	}
+	if pours, _ := resp["pours"].([]any); len(pours) != 2 {
+		t.Fatalf("expected 2 copied pours, got %#v", resp["pours"])
+	}
+	for _, field := range []string{"tastingNotes", "rating", "tds"} {
+		if _, ok := resp[field]; ok {
+			t.Fatalf("expected %s not to be copied: %#v", field, resp)
+		}
+	}
+
+	// "2 clicks finer" with a rating for the new brew
+	w = httptest.NewRecorder()
+	h.Repeat(w, brewLogItemRequest("POST", sourceID, `{"grindSetting":16,"rating":4}`, 1))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := decodeBody(t, w); resp["grindSetting"] != float64(16) || resp["rating"] != float64(4) || resp["grinderId"] != float64(1) {
+		t.Fatalf("overrides not applied: %#v", resp)
+	}
+
+	// Overrides are validated like a new log
+	for body, want := range map[string]int{
+		`{"grindSetting":41}`:   http.StatusBadRequest,
+		`{"waterWeight":300}`:   http.StatusBadRequest, // copied pours no longer add up
+		`{"brewMethod":"pour"}`: http.StatusBadRequest,
+		`{"coffeeId":2}`:        http.StatusForbidden,
+		`{"clicks":2}`:          http.StatusBadRequest,
+	} {
+		w = httptest.NewRecorder()
+		h.Repeat(w, brewLogItemRequest("POST", sourceID, body, 1))
+		if w.Code != want {
+			t.Fatalf("expected %d for %s, got %d: %s", want, body, w.Code, w.Body.String())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.Repeat(w, brewLogItemRequest("POST", sourceID, "", 2))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 repeating another user's log, got %d", w.Code)
+	}
+}
+
+func TestBrewLogRepeat_DeleteKeepsLineage(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedOwnedBrewLogs(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	// 1 -> 3 -> 4
+	for _, id := range []string{"1", "3"} {
+		w := httptest.NewRecorder()
+		h.Repeat(w, brewLogItemRequest("POST", id, `{}`, 1))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201 repeating %s, got %d: %s", id, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	h.Delete(w, brewLogItemRequest("DELETE", "3", "", 1))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	var parent sql.NullInt64
+	if err := db.QueryRow(`SELECT parent_brew_log_id FROM brew_logs WHERE id = 4`).Scan(&parent); err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if parent.Int64 != 1 {
+		t.Fatalf("expected log 4 to move up to parent 1, got %v", parent)
+	}
+}
@@ -5,18 +5,25 @@ import (
 	"coffeeee/backend/internal/config"
 	"coffeeee/backend/internal/services"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
 )
 
 type CoffeeHandler struct {
 	coffeeService *services.CoffeeService
+	photoService  *services.PhotoService
 	cfg           *config.Config
 }
 
-func NewCoffeeHandler(coffeeService *services.CoffeeService, cfg *config.Config) *CoffeeHandler {
+func NewCoffeeHandler(coffeeService *services.CoffeeService, photoService *services.PhotoService, cfg *config.Config) *CoffeeHandler {
 	return &CoffeeHandler{
 		coffeeService: coffeeService,
+		photoService:  photoService,
 		cfg:           cfg,
 	}
 }
@@ -26,50 +33,46 @@ func (h *CoffeeHandler) List(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
-func (h *CoffeeHandler) Get(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement get coffee logic
-	w.WriteHeader(http.StatusNotImplemented)
-}
-
 func (h *CoffeeHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// TODO: Implement create coffee logic
 	w.WriteHeader(http.StatusNotImplemented)
 }
 
-func (h *CoffeeHandler) Update(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement update coffee logic
-	w.WriteHeader(http.StatusNotImplemented)
-}
-
-func (h *CoffeeHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement delete coffee logic
-	w.WriteHeader(http.StatusNotImplemented)
-}
-
 // ListForUser handles GET /api/v1/coffees
-// Returns JSON: { "coffees": [ {id, name, origin?, roaster?, description?, photoPath?, createdAt, updatedAt}, ... ] }
+// Query: q (full-text search over name, roaster, origin and description), roaster, origin (exact),
+// roasterId (directory roaster), sort (created|name|rating|relevance), order (asc|desc), limit (1-100, default 50), cursor.
+// Returns JSON: { "coffees": [ {id, name, ..., averageRating?, brewCount}, ... ], "nextCursor": string|null,
+// "total": number, "facets": { "roasters": [ {value, count} ], "origins": [ {value, count} ] } }
 func (h *CoffeeHandler) ListForUser(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	userID, _ := middleware.GetAuthenticatedUserID(r.Context())
 
-	coffees, err := h.coffeeService.ListForUser(r.Context(), userID)
-	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		log.Printf("failed to query coffees for user %d: %v", userID, err)
-		_ = json.NewEncoder(w).Encode(map[string]string{
-			"code":    "DATABASE_ERROR",
-			"message": "failed to query coffees",
-		})
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	input, ok := listCoffeesInputFrom(w, r, userID)
+	if !ok {
 		return
 	}
 
-	_ = json.NewEncoder(w).Encode(map[string]any{"coffees": coffees})
+	page, err := h.coffeeService.List(r.Context(), input)
+	if err != nil {
+		writeServiceError(w, err, "failed to query coffees")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(page)
 }
 
 // CreateForUser handles POST /api/v1/coffees
-// Request JSON: { "name": string, "origin"?: string, "roaster"?: string, "description"?: string, "photoPath"?: string }
+// Request JSON: { "name": string, "origin"?: string, "roaster"?: string, "roasterId"?: number, "description"?: string, "photoPath"?: string,
+// "roastDate"?, "purchaseDate"?, "openedDate"?: "YYYY-MM-DD", "bagSize"?, "price"?, "remainingWeight"?: number }
 // Behavior: find-or-create a coffee owned by the current user (coffees.user_id),
-// optionally updating photo_path for the owner's record. Returns 201 with { "coffee": { ... } }.
+// optionally updating photo_path for the owner's record. Bag fields sent for an
+// existing coffee record a new bag and replace the old bag details. Structured
+// origin fields left out are read from the free-text origin. The roaster is linked
+// to the closest roaster directory entry, or a new one, and takes its name.
+// Returns 201 with { "coffee": { ... } }.
 func (h *CoffeeHandler) CreateForUser(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 
@@ -79,8 +82,11 @@ func (h *CoffeeHandler) CreateForUser(w http.ResponseWriter, r *http.Request) {
 		Name        string  `json:"name"`
 		Origin      *string `json:"origin,omitempty"`
 		Roaster     *string `json:"roaster,omitempty"`
+		RoasterID   *int64  `json:"roasterId,omitempty"`
 		Description *string `json:"description,omitempty"`
 		PhotoPath   *string `json:"photoPath,omitempty"`
+		services.CoffeeOrigin
+		services.CoffeeBag
 	}
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
@@ -97,12 +103,15 @@ func (h *CoffeeHandler) CreateForUser(w http.ResponseWriter, r *http.Request) {
 	log.Printf("Receiving %v\n", body)
 
 	input := services.CreateCoffeeInput{
-		UserID:      userID,
-		Name:        body.Name,
-		Origin:      body.Origin,
-		Roaster:     body.Roaster,
-		Description: body.Description,
-		PhotoPath:   body.PhotoPath,
+		UserID:       userID,
+		Name:         body.Name,
+		Origin:       body.Origin,
+		Roaster:      body.Roaster,
+		RoasterID:    body.RoasterID,
+		Description:  body.Description,
+		PhotoPath:    body.PhotoPath,
+		CoffeeOrigin: body.CoffeeOrigin,
+		CoffeeBag:    body.CoffeeBag,
 	}
 
 	coffee, err := h.coffeeService.CreateForUser(r.Context(), input)
@@ -128,3 +137,171 @@ func (h *CoffeeHandler) CreateForUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusCreated)
 	_ = json.NewEncoder(w).Encode(map[string]any{"coffee": coffee})
 }
+
+// Get handles GET /api/v1/coffees/{id}
+// Returns JSON: { "coffee": { ... } }. Other users' coffees are reported as 404.
+func (h *CoffeeHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := coffeeIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	coffee, err := h.coffeeService.Get(r.Context(), userID, id)
+	if err != nil {
+		writeServiceError(w, err, "failed to read coffee")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"coffee": coffee})
+}
+
+// Update handles PUT/PATCH /api/v1/coffees/{id}
+// Only the fields present in the body are changed; "" clears an optional field,
+// roasterId 0 unlinks the roaster and bagSize 0 stops tracking the bag.
+// Returns JSON: { "coffee": { ... } }
+func (h *CoffeeHandler) Update(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := coffeeIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	type reqBody struct {
+		Name        *string `json:"name,omitempty"`
+		Origin      *string `json:"origin,omitempty"`
+		Roaster     *string `json:"roaster,omitempty"`
+		RoasterID   *int64  `json:"roasterId,omitempty"`
+		Description *string `json:"description,omitempty"`
+		PhotoPath   *string `json:"photoPath,omitempty"`
+		services.CoffeeOrigin
+		services.CoffeeBag
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	var body reqBody
+	if err := dec.Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid JSON body")
+		return
+	}
+
+	coffee, err := h.coffeeService.Update(r.Context(), services.UpdateCoffeeInput{
+		ID:           id,
+		UserID:       userID,
+		Name:         body.Name,
+		Origin:       body.Origin,
+		Roaster:      body.Roaster,
+		RoasterID:    body.RoasterID,
+		Description:  body.Description,
+		PhotoPath:    body.PhotoPath,
+		CoffeeOrigin: body.CoffeeOrigin,
+		CoffeeBag:    body.CoffeeBag,
+	})
+	if err != nil {
+		writeServiceError(w, err, "failed to update coffee")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"coffee": coffee})
+}
+
+// Delete handles DELETE /api/v1/coffees/{id}
+// Query: force=true to also delete the coffee's brew logs.
+// Returns 204 on success; uploaded photos no other coffee uses are removed. Without force, a coffee that still has brew logs is
+// left alone and 409 reports { "code": "COFFEE_HAS_BREW_LOGS", "message", "brewLogCount" }.
+func (h *CoffeeHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := coffeeIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	force := false
+	if v := r.URL.Query().Get("force"); v != "" {
+		var err error
+		if force, err = strconv.ParseBool(v); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "force must be true or false")
+			return
+		}
+	}
+
+	err := h.coffeeService.Delete(r.Context(), userID, id, force)
+	var hasLogs *services.CoffeeHasBrewLogsError
+	if errors.As(err, &hasLogs) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusConflict)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"code":         "COFFEE_HAS_BREW_LOGS",
+			"message":      hasLogs.Error() + "; repeat with ?force=true to delete them",
+			"brewLogCount": hasLogs.BrewLogCount,
+		})
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "failed to delete coffee")
+		return
+	}
+	// The coffee is gone either way; a failed sweep is retried by the next one
+	if _, err := h.photoService.CollectGarbage(r.Context()); err != nil {
+		log.Printf("failed to collect orphaned photos: %v", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listCoffeesInputFrom reads the search, filter and paging parameters shared
+// by the coffee list endpoints. It writes a 400 and returns false when they
+// do not parse.
+func listCoffeesInputFrom(w http.ResponseWriter, r *http.Request, userID int64) (services.ListCoffeesInput, bool) {
+	v := r.URL.Query()
+	input := services.ListCoffeesInput{
+		UserID: userID,
+		Query:  v.Get("q"),
+		Sort:   strings.TrimSpace(v.Get("sort")),
+		Order:  strings.TrimSpace(v.Get("order")),
+		Cursor: strings.TrimSpace(v.Get("cursor")),
+	}
+	if v.Has("roaster") {
+		roaster := v.Get("roaster")
+		input.Roaster = &roaster
+	}
+	if v.Has("origin") {
+		origin := v.Get("origin")
+		input.Origin = &origin
+	}
+	if s := strings.TrimSpace(v.Get("roasterId")); s != "" {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || id <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid roasterId")
+			return input, false
+		}
+		input.RoasterID = &id
+	}
+	if s := strings.TrimSpace(v.Get("limit")); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "limit must be between 1 and 100")
+			return input, false
+		}
+		input.Limit = n
+	}
+	return input, true
+}
+
+func coffeeIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid coffee id")
+		return 0, false
+	}
+	return id, true
+}
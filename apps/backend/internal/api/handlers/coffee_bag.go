@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Bag handles GET /api/v1/coffees/{id}/bag
+// Returns JSON: { "bag": { coffeeId, name, roaster?, roastDate?, purchaseDate?, openedDate?,
+// bagSize?, price?, remainingWeight?, daysOffRoast?, daysSinceOpened?, averageDose?,
+// estimatedBrewsLeft?, costPerGram?, costPerCup?, needsReorder } }
+func (h *CoffeeHandler) Bag(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := coffeeIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.coffeeService.BagReport(r.Context(), userID, id)
+	if err != nil {
+		writeServiceError(w, err, "failed to read coffee bag")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"bag": report})
+}
+
+// Inventory handles GET /api/v1/coffees/inventory
+// Returns JSON: { "bags": [ ...same shape as Bag... ], "needsReorder": number }
+// Only coffees with a bagSize are listed, the ones running out first leading.
+func (h *CoffeeHandler) Inventory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	bags, err := h.coffeeService.Inventory(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, err, "failed to read coffee inventory")
+		return
+	}
+	reorder := 0
+	for _, b := range bags {
+		if b.NeedsReorder {
+			reorder++
+		}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"bags": bags, "needsReorder": reorder})
+}
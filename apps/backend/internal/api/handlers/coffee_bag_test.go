@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"coffeeee/backend/internal/api/middleware"
+)
+
+func daysAgo(n int) string {
+	return time.Now().AddDate(0, 0, -n).Format("2006-01-02")
+}
+
+func TestCoffeeBag_TracksBrewsAndReports(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupCoffeeHandler(t, db)
+	brewLogs := setupBrewLogHandler(t, db)
+
+	body := fmt.Sprintf(`{"roastDate":%q,"openedDate":%q,"bagSize":250,"price":20}`, daysAgo(10), daysAgo(2))
+	w := httptest.NewRecorder()
+	h.Update(w, coffeeItemRequest("PATCH", "1", body, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	coffee, _ := decodeBody(t, w)["coffee"].(map[string]any)
+	if coffee["bagSize"] != float64(250) || coffee["remainingWeight"] != float64(250) || coffee["roastDate"] != daysAgo(10) {
+		t.Fatalf("expected a full 250 g bag, got %#v", coffee)
+	}
+
+	// Each brew takes its dose out of the bag; edits and deletes give it back
+	for _, dose := range []int{15, 18} {
+		w, _ := createBrewLogForMetrics(t, brewLogs, fmt.Sprintf(`{"coffeeId":1,"brewMethod":"V60","coffeeWeight":%d,"waterWeight":250}`, dose))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	w = httptest.NewRecorder()
+	brewLogs.Update(w, brewLogItemRequest("PATCH", "2", `{"coffeeWeight":20}`, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	brewLogs.Delete(w, brewLogItemRequest("DELETE", "1", "", 1))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.Bag(w, coffeeItemRequest("GET", "1", "", 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	bag, _ := decodeBody(t, w)["bag"].(map[string]any)
+	for key, want := range map[string]any{
+		"remainingWeight":    float64(230),
+		"daysOffRoast":       float64(10),
+		"daysSinceOpened":    float64(2),
+		"averageDose":        float64(20),
+		"estimatedBrewsLeft": float64(11),
+		"costPerGram":        0.08,
+		"costPerCup":         1.6,
+		"needsReorder":       false,
+	} {
+		if bag[key] != want {
+			t.Fatalf("%s: expected %v, got %#v", key, want, bag)
+		}
+	}
+
+	// Another user's coffee is not found
+	w = httptest.NewRecorder()
+	h.Bag(w, coffeeItemRequest("GET", "2", "", 1))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestCoffeeBag_Validation(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupCoffeeHandler(t, db)
+
+	for _, body := range []string{
+		`{"roastDate":"14/03/2025"}`,
+		fmt.Sprintf(`{"roastDate":%q}`, time.Now().AddDate(0, 0, 5).Format("2006-01-02")),
+		fmt.Sprintf(`{"roastDate":%q,"openedDate":%q}`, daysAgo(1), daysAgo(3)),
+		`{"bagSize":-1}`,
+		`{"price":-5}`,
+		`{"remainingWeight":100}`,
+		`{"bagSize":250,"remainingWeight":300}`,
+	} {
+		w := httptest.NewRecorder()
+		h.Update(w, coffeeItemRequest("PATCH", "1", body, 1))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d: %s", body, w.Code, w.Body.String())
+		}
+	}
+
+	// Resizing keeps the grams already used; bagSize 0 stops tracking
+	for _, step := range []struct {
+		body string
+		want any
+	}{
+		{`{"bagSize":250,"remainingWeight":200}`, float64(200)},
+		{`{"bagSize":500}`, float64(450)},
+		{`{"bagSize":0}`, nil},
+	} {
+		w := httptest.NewRecorder()
+		h.Update(w, coffeeItemRequest("PATCH", "1", step.body, 1))
+		coffee, _ := decodeBody(t, w)["coffee"].(map[string]any)
+		if w.Code != http.StatusOK || coffee["remainingWeight"] != step.want {
+			t.Fatalf("%s: expected remainingWeight %v, got %d %#v", step.body, step.want, w.Code, coffee)
+		}
+	}
+}
+
+func TestCoffeeInventory(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	_, err := db.Exec(`INSERT INTO coffees(id, user_id, name, bag_size, remaining_weight) VALUES
+        (3, 1, 'Nearly gone', 250, 40),
+        (4, 1, 'Fresh', 1000, 1000),
+        (5, 1, 'Untracked', NULL, NULL);
+        INSERT INTO brew_logs(user_id, coffee_id, brew_method, coffee_weight) VALUES
+        (1, 3, 'V60', 15), (1, 4, 'V60', 15)`)
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	h := setupCoffeeHandler(t, db)
+
+	req := httptest.NewRequest("GET", "/api/v1/coffees/inventory", nil)
+	req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), 1))
+	w := httptest.NewRecorder()
+	h.Inventory(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeBody(t, w)
+	bags, _ := resp["bags"].([]any)
+	if len(bags) != 2 || resp["needsReorder"] != float64(1) {
+		t.Fatalf("expected the two tracked bags, one to reorder, got %#v", resp)
+	}
+	first, _ := bags[0].(map[string]any)
+	if first["name"] != "Nearly gone" || first["estimatedBrewsLeft"] != float64(2) || first["needsReorder"] != true {
+		t.Fatalf("expected the nearly empty bag first, got %#v", first)
+	}
+}
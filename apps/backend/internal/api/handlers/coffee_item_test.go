@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+func coffeeItemRequest(method, id, body string, userID int64) *http.Request {
+	req := httptest.NewRequest(method, "/api/v1/coffees/"+id, bytes.NewBufferString(body))
+	req = mux.SetURLVars(req, map[string]string{"id": id})
+	return req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), userID))
+}
+
+func TestCoffeeGet(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupCoffeeHandler(t, db)
+
+	w := httptest.NewRecorder()
+	h.Get(w, coffeeItemRequest("GET", "1", "", 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	coffee, _ := decodeBody(t, w)["coffee"].(map[string]any)
+	if coffee["id"] != float64(1) || coffee["name"] != "A" {
+		t.Fatalf("unexpected coffee: %#v", coffee)
+	}
+
+	// Other users' coffees do not exist as far as the caller is concerned
+	w = httptest.NewRecorder()
+	h.Get(w, coffeeItemRequest("GET", "2", "", 1))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestCoffeeUpdate(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	_, _ = db.Exec(`UPDATE coffees SET origin = 'Kenya', roaster = 'Square Mile' WHERE id = 1`)
+	_, _ = db.Exec(`INSERT INTO coffees(id, user_id, name, origin, roaster) VALUES (3, 1, 'Red Brick', 'Blend', 'Square Mile')`)
+	h := setupCoffeeHandler(t, db)
+
+	w := httptest.NewRecorder()
+	h.Update(w, coffeeItemRequest("PATCH", "1", `{"name":" Kamwangi AA ","description":"blackcurrant","origin":""}`, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	coffee, _ := decodeBody(t, w)["coffee"].(map[string]any)
+	if coffee["name"] != "Kamwangi AA" || coffee["description"] != "blackcurrant" || coffee["roaster"] != "Square Mile" {
+		t.Fatalf("unexpected coffee: %#v", coffee)
+	}
+	if _, ok := coffee["origin"]; ok {
+		t.Fatalf("expected origin to be cleared: %#v", coffee)
+	}
+
+	for body, want := range map[string]int{
+		`{}`:            http.StatusBadRequest,
+		`{"name":"  "}`: http.StatusBadRequest,
+		`{"origin":"` + string(bytes.Repeat([]byte("x"), 101)) + `"}`: http.StatusBadRequest,
+		`{"name":"Red Brick","origin":"Blend"}`:                       http.StatusConflict, // would duplicate coffee 3
+		`{"grams":12}`:                                                http.StatusBadRequest,
+	} {
+		w = httptest.NewRecorder()
+		h.Update(w, coffeeItemRequest("PATCH", "1", body, 1))
+		if w.Code != want {
+			t.Fatalf("expected %d for %s, got %d: %s", want, body, w.Code, w.Body.String())
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.Update(w, coffeeItemRequest("PUT", "2", `{"name":"mine now"}`, 1))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 updating another user's coffee, got %d", w.Code)
+	}
+}
+
+func TestCoffeeDelete_ReportsBrewLogsUnlessForced(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	_, _ = db.Exec(`INSERT INTO coffees(id, user_id, name) VALUES (3, 1, 'C')`)
+	_, err := db.Exec(`INSERT INTO brew_logs(id, user_id, coffee_id, brew_method, parent_brew_log_id) VALUES
+        (1, 1, 1, 'v60', NULL),
+        (2, 1, 1, 'v60', 1),
+        (3, 1, 3, 'v60', 2)`)
+	if err != nil {
+		t.Fatalf("seed brew logs: %v", err)
+	}
+	_, _ = db.Exec(`INSERT INTO brew_log_pours(brew_log_id, position, time_offset, water_amount) VALUES (1, 1, 0, 250)`)
+	h := setupCoffeeHandler(t, db)
+
+	w := httptest.NewRecorder()
+	h.Delete(w, coffeeItemRequest("DELETE", "1", "", 1))
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := decodeBody(t, w); resp["code"] != "COFFEE_HAS_BREW_LOGS" || resp["brewLogCount"] != float64(2) {
+		t.Fatalf("unexpected resp: %#v", resp)
+	}
+
+	w = httptest.NewRecorder()
+	req := coffeeItemRequest("DELETE", "1", "", 1)
+	req.URL.RawQuery = "force=true"
+	h.Delete(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	var coffees, logs, pours int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM coffees WHERE id = 1`).Scan(&coffees)
+	_ = db.QueryRow(`SELECT COUNT(*) FROM brew_logs WHERE coffee_id = 1`).Scan(&logs)
+	_ = db.QueryRow(`SELECT COUNT(*) FROM brew_log_pours`).Scan(&pours)
+	if coffees != 0 || logs != 0 || pours != 0 {
+		t.Fatalf("expected cascade, got coffees=%d logs=%d pours=%d", coffees, logs, pours)
+	}
+	var parent sql.NullInt64
+	if err := db.QueryRow(`SELECT parent_brew_log_id FROM brew_logs WHERE id = 3`).Scan(&parent); err != nil || parent.Valid {
+		t.Fatalf("expected repeat on another coffee to survive unlinked: %v %v", parent, err)
+	}
+
+	// A coffee without brew logs needs no confirmation
+	w = httptest.NewRecorder()
+	_, _ = db.Exec(`DELETE FROM brew_logs WHERE coffee_id = 3`)
+	h.Delete(w, coffeeItemRequest("DELETE", "3", "", 1))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	h.Delete(w, coffeeItemRequest("DELETE", "2", "", 1))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting another user's coffee, got %d", w.Code)
+	}
+}
@@ -42,8 +42,38 @@ func setupListTestDB(t *testing.T) *sql.DB {
             photo_path VARCHAR(500),
             created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
             updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            roast_date TEXT,
+            purchase_date TEXT,
+            opened_date TEXT,
+            bag_size REAL,
+            price REAL,
+            remaining_weight REAL,
+            country TEXT,
+            region TEXT,
+            producer TEXT,
+            process TEXT,
+            varietals TEXT,
+            altitude_min INTEGER,
+            altitude_max INTEGER,
+            roaster_id INTEGER,
             FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
         );
+        CREATE TABLE roasters (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name VARCHAR(255) NOT NULL,
+            website VARCHAR(500),
+            location VARCHAR(255),
+            created_by INTEGER,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            match_key VARCHAR(255) NOT NULL DEFAULT ''
+        );
+        CREATE UNIQUE INDEX idx_roasters_name ON roasters(name COLLATE NOCASE);
+        CREATE TABLE brew_logs (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            coffee_id INTEGER NOT NULL,
+            rating INTEGER
+        );
     `)
 	if err != nil {
 		t.Fatalf("create schema: %v", err)
@@ -62,7 +92,7 @@ func TestListForUser_ReturnsOnlyUserCoffees(t *testing.T) {
 
 	queries := database.NewQueries(db)
 	coffeeService := services.NewCoffeeService(queries)
-	h := NewCoffeeHandler(coffeeService, &config.Config{})
+	h := NewCoffeeHandler(coffeeService, services.NewPhotoService(queries, t.TempDir(), 1<<20), &config.Config{})
 
 	req := httptest.NewRequest("GET", "/api/v1/coffees", nil)
 	req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), 1))
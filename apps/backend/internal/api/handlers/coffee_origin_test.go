@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+)
+
+func postCoffee(t *testing.T, h *CoffeeHandler, body string) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/api/v1/coffees", bytes.NewBufferString(body))
+	req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), 1))
+	w := httptest.NewRecorder()
+	h.CreateForUser(w, req)
+	coffee, _ := decodeBody(t, w)["coffee"].(map[string]any)
+	return w.Code, coffee
+}
+
+func TestCoffeeOrigin_ReadFromLegacyText(t *testing.T) {
+	db := setupCoffeeTestDB(t)
+	defer db.Close()
+	h := setupCoffeeHandler(t, db)
+
+	code, coffee := postCoffee(t, h, `{"name":"Guji","origin":"Ethiopia, Guji - Natural, 1900-2100 masl"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d %#v", code, coffee)
+	}
+	for key, want := range map[string]any{
+		"origin":      "Ethiopia, Guji - Natural, 1900-2100 masl",
+		"country":     "ET",
+		"region":      "Guji",
+		"process":     "natural",
+		"altitudeMin": float64(1900),
+		"altitudeMax": float64(2100),
+	} {
+		if coffee[key] != want {
+			t.Fatalf("%s: expected %v, got %#v", key, want, coffee)
+		}
+	}
+
+	// Explicit fields win over the text; blends name no country
+	cases := []struct {
+		body string
+		want map[string]any
+	}{
+		{`{"name":"Kiambu","origin":"Kenya Washed","process":"natural"}`, map[string]any{"country": "KE", "process": "natural"}},
+		{`{"name":"Hambela","origin":"Yirgacheffe"}`, map[string]any{"country": "ET", "region": "Yirgacheffe"}},
+		{`{"name":"Espresso","origin":"Ethiopia & Brazil"}`, map[string]any{"country": nil, "region": nil}},
+		{`{"name":"Blend","origin":"Seasonal blend"}`, map[string]any{"country": nil, "region": nil}},
+	}
+	for _, c := range cases {
+		code, coffee := postCoffee(t, h, c.body)
+		if code != http.StatusCreated {
+			t.Fatalf("%s: expected 201, got %d", c.body, code)
+		}
+		for key, want := range c.want {
+			if coffee[key] != want {
+				t.Fatalf("%s: %s expected %v, got %#v", c.body, key, want, coffee)
+			}
+		}
+	}
+}
+
+func TestCoffeeOrigin_StructuredFields(t *testing.T) {
+	db := setupCoffeeTestDB(t)
+	defer db.Close()
+	h := setupCoffeeHandler(t, db)
+
+	// Older clients still get a readable origin
+	code, coffee := postCoffee(t, h, `{"name":"Paraíso","country":"co","region":"Huila","producer":"Finca El Paraíso",
+        "process":"Anaerobic","varietals":["Castillo"," castillo ","Gesha"],"altitudeMin":1900}`)
+	if code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d %#v", code, coffee)
+	}
+	if coffee["origin"] != "Huila, Colombia" || coffee["country"] != "CO" || coffee["process"] != "anaerobic" ||
+		coffee["altitudeMax"] != float64(1900) || !reflect.DeepEqual(coffee["varietals"], []any{"Castillo", "Gesha"}) {
+		t.Fatalf("unexpected coffee: %#v", coffee)
+	}
+	id := jsonNumber(coffee["id"].(float64))
+
+	// A text built from the fields follows them
+	w := httptest.NewRecorder()
+	h.Update(w, coffeeItemRequest("PATCH", id, `{"region":"Nariño"}`, 1))
+	coffee, _ = decodeBody(t, w)["coffee"].(map[string]any)
+	if w.Code != http.StatusOK || coffee["origin"] != "Nariño, Colombia" {
+		t.Fatalf("expected the origin rebuilt, got %d %#v", w.Code, coffee)
+	}
+
+	// Fields read from the old text follow a new text; the others stay
+	code, coffee = postCoffee(t, h, `{"name":"Kochere","origin":"Ethiopia, Guji - Natural","producer":"Hambela"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", code)
+	}
+	w = httptest.NewRecorder()
+	h.Update(w, coffeeItemRequest("PATCH", jsonNumber(coffee["id"].(float64)), `{"origin":"Kenya, Nyeri"}`, 1))
+	coffee, _ = decodeBody(t, w)["coffee"].(map[string]any)
+	if w.Code != http.StatusOK || coffee["country"] != "KE" || coffee["region"] != "Nyeri" || coffee["process"] != nil || coffee["producer"] != "Hambela" {
+		t.Fatalf("unexpected coffee after origin change: %d %#v", w.Code, coffee)
+	}
+
+	for _, body := range []string{
+		`{"name":"X","country":"Ethiopia"}`,
+		`{"name":"X","country":"ZZ"}`,
+		`{"name":"X","process":"steamed"}`,
+		`{"name":"X","altitudeMin":2000,"altitudeMax":1500}`,
+		`{"name":"X","altitudeMax":9000}`,
+		`{"name":"X","varietals":[""]}`,
+		`{"name":"X","altitude":1500}`,
+	} {
+		if code, coffee := postCoffee(t, h, body); code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d %#v", body, code, coffee)
+		}
+	}
+}
+
+func TestCoffeeOrigin_FindOrCreate(t *testing.T) {
+	db := setupCoffeeTestDB(t)
+	defer db.Close()
+	h := setupCoffeeHandler(t, db)
+
+	ids := map[string]any{}
+	for _, body := range []string{
+		`{"name":"House"}`,
+		`{"name":"Gesha","origin":"Panama","process":"washed"}`,
+		`{"name":"Gesha","origin":"Panama","process":"natural"}`,
+		`{"name":"Gesha","origin":"Panama Natural"}`,
+	} {
+		code, coffee := postCoffee(t, h, body)
+		if code != http.StatusCreated {
+			t.Fatalf("%s: expected 201, got %d", body, code)
+		}
+		ids[body] = coffee["id"]
+		// The same details again are the same coffee, also without origin or roaster
+		if _, again := postCoffee(t, h, body); again["id"] != coffee["id"] {
+			t.Fatalf("%s: expected id %v again, got %v", body, coffee["id"], again["id"])
+		}
+	}
+	if ids[`{"name":"Gesha","origin":"Panama","process":"washed"}`] == ids[`{"name":"Gesha","origin":"Panama","process":"natural"}`] {
+		t.Fatal("expected different processes to be different coffees")
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"coffeeee/backend/internal/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// multipartOverhead is the allowance for multipart framing on top of the photo itself.
+const multipartOverhead = 1 << 20
+
+// UploadPhoto handles POST /api/v1/coffees/{id}/photo
+// Request: multipart/form-data with the image in a "photo" part (JPEG, PNG or GIF,
+// at most Server.MaxFileSize bytes). Replaces the coffee's current photo.
+// Returns JSON: { "coffee": { ..., "photoUrl", "thumbnailUrl" } }
+func (h *CoffeeHandler) UploadPhoto(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := coffeeIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	maxSize := h.photoService.MaxFileSize()
+	tooLarge := fmt.Sprintf("photo must be at most %d bytes", maxSize)
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+multipartOverhead)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "expected a multipart/form-data body with a photo field")
+		return
+	}
+	var data []byte
+	for data == nil {
+		part, err := mr.NextPart()
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, io.EOF):
+			writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "photo field is required")
+			return
+		case errors.As(err, &maxBytesErr):
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", tooLarge)
+			return
+		case err != nil:
+			writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid multipart body")
+			return
+		}
+		if part.FormName() != "photo" {
+			continue
+		}
+		if !services.IsAcceptedPhotoType(part.Header.Get("Content-Type")) {
+			writeJSONError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "photo must be a JPEG, PNG or GIF image")
+			return
+		}
+		data, err = io.ReadAll(io.LimitReader(part, maxSize+1))
+		if errors.As(err, &maxBytesErr) || int64(len(data)) > maxSize {
+			writeJSONError(w, http.StatusRequestEntityTooLarge, "FILE_TOO_LARGE", tooLarge)
+			return
+		}
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid multipart body")
+			return
+		}
+	}
+
+	coffee, err := h.photoService.Upload(r.Context(), userID, id, data)
+	if err != nil {
+		writeServiceError(w, err, "failed to store photo")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"coffee": coffee})
+}
+
+// Photo handles GET /api/v1/coffees/{id}/photo and serves the uploaded image (owner only).
+func (h *CoffeeHandler) Photo(w http.ResponseWriter, r *http.Request) {
+	h.servePhoto(w, r, false)
+}
+
+// PhotoThumbnail handles GET /api/v1/coffees/{id}/photo/thumbnail and serves a
+// JPEG at most 320 pixels on its longer side (owner only).
+func (h *CoffeeHandler) PhotoThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.servePhoto(w, r, true)
+}
+
+func (h *CoffeeHandler) servePhoto(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := coffeeIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	path, err := h.photoService.Path(r.Context(), userID, id, thumbnail)
+	if err != nil {
+		writeServiceError(w, err, "failed to read photo")
+		return
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		writeJSONError(w, http.StatusNotFound, "NOT_FOUND", "photo file is missing")
+		return
+	}
+	if err != nil {
+		writeServiceError(w, err, "failed to read photo")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		writeServiceError(w, err, "failed to read photo")
+		return
+	}
+
+	// Names are content hashes, so they make exact validators; the URL stays
+	// the same when the photo is replaced, so clients must revalidate
+	name := filepath.Base(path)
+	w.Header().Set("ETag", `"`+strings.TrimSuffix(name, filepath.Ext(name))+`"`)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
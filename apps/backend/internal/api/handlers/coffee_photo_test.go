@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/database"
+	"coffeeee/backend/internal/services"
+)
+
+func setupCoffeePhotoHandler(t *testing.T, db *sql.DB, maxFileSize int64) (*CoffeeHandler, string) {
+	t.Helper()
+	root := t.TempDir()
+	queries := database.NewQueries(db)
+	return NewCoffeeHandler(services.NewCoffeeService(queries), services.NewPhotoService(queries, root, maxFileSize), &config.Config{}), root
+}
+
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 120, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func photoUploadRequest(t *testing.T, id, contentType string, data []byte, userID int64) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="photo"; filename="bag"`)
+	header.Set("Content-Type", contentType)
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	_, _ = part.Write(data)
+	_ = mw.Close()
+	req := coffeeItemRequest("POST", id, body.String(), userID)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestCoffeePhotoUploadAndServe(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h, root := setupCoffeePhotoHandler(t, db, 1<<20)
+	photo := testPNG(t, 640, 480)
+
+	w := httptest.NewRecorder()
+	h.UploadPhoto(w, photoUploadRequest(t, "1", "image/png", photo, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	coffee, _ := decodeBody(t, w)["coffee"].(map[string]any)
+	if coffee["photoUrl"] != "/api/v1/coffees/1/photo" || coffee["thumbnailUrl"] != "/api/v1/coffees/1/photo/thumbnail" {
+		t.Fatalf("unexpected coffee: %#v", coffee)
+	}
+	name, _ := coffee["photoPath"].(string)
+	if !strings.HasSuffix(name, ".png") || len(name) != 68 {
+		t.Fatalf("expected a content-addressed name, got %q", name)
+	}
+	if _, err := os.Stat(filepath.Join(root, name)); err != nil {
+		t.Fatalf("photo not stored: %v", err)
+	}
+
+	w = httptest.NewRecorder()
+	h.Photo(w, coffeeItemRequest("GET", "1", "", 1))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), photo) || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("unexpected photo response: %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	w = httptest.NewRecorder()
+	h.PhotoThumbnail(w, coffeeItemRequest("GET", "1", "", 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	thumb, err := jpeg.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	if err != nil || thumb.Width != 320 || thumb.Height != 240 {
+		t.Fatalf("expected a 320x240 JPEG thumbnail, got %+v (%v)", thumb, err)
+	}
+
+	// Only the owner can read it
+	w = httptest.NewRecorder()
+	h.Photo(w, coffeeItemRequest("GET", "1", "", 2))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another user, got %d", w.Code)
+	}
+}
+
+func TestCoffeePhotoUpload_Rejects(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h, _ := setupCoffeePhotoHandler(t, db, 4096)
+	small := testPNG(t, 8, 8)
+
+	for _, tc := range []struct {
+		name        string
+		id          string
+		contentType string
+		data        []byte
+		want        int
+	}{
+		{"declared type", "1", "application/pdf", small, http.StatusUnsupportedMediaType},
+		{"sniffed type", "1", "image/png", []byte("definitely not an image"), http.StatusBadRequest},
+		{"too large", "1", "image/png", append(small, make([]byte, 5000)...), http.StatusRequestEntityTooLarge},
+		{"other user's coffee", "2", "image/png", small, http.StatusNotFound},
+	} {
+		w := httptest.NewRecorder()
+		h.UploadPhoto(w, photoUploadRequest(t, tc.id, tc.contentType, tc.data, 1))
+		if w.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.name, tc.want, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req := coffeeItemRequest("POST", "1", `{"photo":"x"}`, 1)
+	req.Header.Set("Content-Type", "application/json")
+	h.UploadPhoto(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a non-multipart body, got %d", w.Code)
+	}
+}
+
+func TestCoffeeDelete_CollectsOrphanedPhotos(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	_, _ = db.Exec(`INSERT INTO coffees(id, user_id, name) VALUES (3, 1, 'C'), (4, 1, 'D')`)
+	h, root := setupCoffeePhotoHandler(t, db, 1<<20)
+
+	shared, own := testPNG(t, 16, 16), testPNG(t, 24, 24)
+	for _, upload := range []struct {
+		id   string
+		data []byte
+	}{{"1", shared}, {"3", shared}, {"4", own}} {
+		w := httptest.NewRecorder()
+		h.UploadPhoto(w, photoUploadRequest(t, upload.id, "image/png", upload.data, 1))
+		if w.Code != http.StatusOK {
+			t.Fatalf("upload to %s: expected 200, got %d: %s", upload.id, w.Code, w.Body.String())
+		}
+	}
+	// Age everything past the grace period that protects in-flight uploads
+	old := time.Now().Add(-time.Hour)
+	files, _ := filepath.Glob(filepath.Join(root, "*.png"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 stored photos, got %v", files)
+	}
+	for _, f := range files {
+		_ = os.Chtimes(f, old, old)
+	}
+
+	// The shared photo survives while coffee 3 still uses it
+	for _, id := range []string{"1", "4"} {
+		w := httptest.NewRecorder()
+		h.Delete(w, coffeeItemRequest("DELETE", id, "", 1))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	files, _ = filepath.Glob(filepath.Join(root, "*.png"))
+	thumbs, _ := filepath.Glob(filepath.Join(root, "thumbs", "*.jpg"))
+	if len(files) != 1 || len(thumbs) != 1 {
+		t.Fatalf("expected only the shared photo and its thumbnail to remain, got %v %v", files, thumbs)
+	}
+
+	w := httptest.NewRecorder()
+	h.Photo(w, coffeeItemRequest("GET", "3", "", 1))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), shared) {
+		t.Fatalf("expected coffee 3 to keep its photo, got %d", w.Code)
+	}
+}
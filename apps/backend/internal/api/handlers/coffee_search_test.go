@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+	"coffeeee/backend/internal/database"
+)
+
+func seedSearchCoffees(t *testing.T, db *sql.DB) {
+	t.Helper()
+	_, err := db.Exec(`INSERT INTO coffees(id, user_id, name, roaster, origin, description, created_at) VALUES
+        (3, 1, 'Kochere', 'Square Mile', 'Ethiopia', 'jasmine and bergamot', '2025-01-03 08:00:00'),
+        (4, 1, 'Red Brick', 'Square Mile', 'Blend', 'chocolate, from Ethiopian and Brazilian lots', '2025-01-04 08:00:00'),
+        (5, 1, 'Gichathaini', 'Tim Wendelboe', 'Kenya', 'blackcurrant', '2025-01-05 08:00:00'),
+        (6, 1, 'Daterra 100%', 'Tim Wendelboe', 'Brazil', 'nutty_sweet', '2025-01-06 08:00:00');
+        INSERT INTO brew_logs(user_id, coffee_id, brew_method, rating) VALUES
+        (1, 3, 'V60', 5), (1, 3, 'V60', 4), (1, 5, 'V60', 3), (1, 4, 'V60', NULL)`)
+	if err != nil {
+		t.Fatalf("seed coffees: %v", err)
+	}
+}
+
+func doCoffeeList(t *testing.T, h *CoffeeHandler, query url.Values) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest("GET", "/api/v1/coffees?"+query.Encode(), nil)
+	req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), 1))
+	w := httptest.NewRecorder()
+	h.ListForUser(w, req)
+	return w.Code, decodeBody(t, w)
+}
+
+func coffeeNames(resp map[string]any) []string {
+	var names []string
+	coffees, _ := resp["coffees"].([]any)
+	for _, c := range coffees {
+		names = append(names, c.(map[string]any)["name"].(string))
+	}
+	return names
+}
+
+func TestCoffeeList_SearchFiltersAndFacets(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedSearchCoffees(t, db)
+	h := setupCoffeeHandler(t, db)
+
+	// Without a query: newest first, only the caller's coffees
+	code, resp := doCoffeeList(t, h, url.Values{})
+	if code != http.StatusOK || resp["total"] != float64(5) {
+		t.Fatalf("expected the caller's 5 coffees, got %d %#v", code, resp)
+	}
+	// Coffee A was seeded with the current time
+	if names := coffeeNames(resp); names[0] != "A" || names[1] != "Daterra 100%" {
+		t.Fatalf("expected newest first, got %v", names)
+	}
+
+	// Words are matched as prefixes across all the text fields, all of them required
+	code, resp = doCoffeeList(t, h, url.Values{"q": {"ethiop"}})
+	if code != http.StatusOK || resp["total"] != float64(2) {
+		t.Fatalf("expected 2 matches for ethiop, got %d %#v", code, resp)
+	}
+	code, resp = doCoffeeList(t, h, url.Values{"q": {"ethiop chocolate"}})
+	if names := coffeeNames(resp); code != http.StatusOK || len(names) != 1 || names[0] != "Red Brick" {
+		t.Fatalf("expected only Red Brick, got %d %v", code, names)
+	}
+	// LIKE wildcards and FTS5 syntax in the query are plain text
+	for _, q := range []string{"100%", "nutty_", `"NEAR(`, "jasmine OR"} {
+		code, resp = doCoffeeList(t, h, url.Values{"q": {q}})
+		if code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d %#v", q, code, resp)
+		}
+	}
+	if _, resp = doCoffeeList(t, h, url.Values{"q": {"100%"}}); resp["total"] != float64(1) {
+		t.Fatalf("expected 100%% to match only Daterra, got %#v", resp)
+	}
+
+	// Each facet ignores its own filter
+	code, resp = doCoffeeList(t, h, url.Values{"roaster": {"Square Mile"}})
+	if code != http.StatusOK || resp["total"] != float64(2) {
+		t.Fatalf("expected 2 Square Mile coffees, got %d %#v", code, resp)
+	}
+	facets, _ := resp["facets"].(map[string]any)
+	roasters, _ := facets["roasters"].([]any)
+	origins, _ := facets["origins"].([]any)
+	if len(roasters) != 2 || len(origins) != 2 {
+		t.Fatalf("expected both roasters and the two Square Mile origins, got %#v", facets)
+	}
+	first, _ := roasters[0].(map[string]any)
+	if first["value"] != "Square Mile" || first["count"] != float64(2) {
+		t.Fatalf("unexpected roaster facet: %#v", roasters)
+	}
+}
+
+func TestCoffeeList_SortByRatingAndPaginate(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedSearchCoffees(t, db)
+	h := setupCoffeeHandler(t, db)
+
+	var names []string
+	query := url.Values{"sort": {"rating"}, "limit": {"2"}}
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		code, resp := doCoffeeList(t, h, query)
+		if code != http.StatusOK {
+			t.Fatalf("expected 200, got %d %#v", code, resp)
+		}
+		if pages == 0 {
+			top, _ := resp["coffees"].([]any)[0].(map[string]any)
+			if top["name"] != "Kochere" || top["averageRating"] != 4.5 || top["brewCount"] != float64(2) {
+				t.Fatalf("expected Kochere first with a 4.5 average, got %#v", top)
+			}
+		}
+		names = append(names, coffeeNames(resp)...)
+		next, _ := resp["nextCursor"].(string)
+		if next == "" {
+			break
+		}
+		query.Set("cursor", next)
+	}
+	if len(names) != 5 || names[1] != "Gichathaini" {
+		t.Fatalf("expected all 5 coffees best rated first, got %v", names)
+	}
+
+	for _, q := range []url.Values{
+		{"sort": {"price"}},
+		{"sort": {"relevance"}},
+		{"order": {"sideways"}},
+		{"limit": {"101"}},
+		{"limit": {"two"}},
+		{"cursor": {"bogus"}},
+	} {
+		if code, resp := doCoffeeList(t, h, q); code != http.StatusBadRequest {
+			t.Fatalf("%v: expected 400, got %d %#v", q, code, resp)
+		}
+	}
+}
+
+func TestCoffeeList_FullTextIndex(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedSearchCoffees(t, db)
+	enabled, err := database.EnableCoffeeSearch(db)
+	if err != nil {
+		t.Fatalf("enable search: %v", err)
+	}
+	if !enabled {
+		t.Skip("go-sqlite3 built without FTS5; run with -tags sqlite_fts5")
+	}
+	h := setupCoffeeHandler(t, db)
+
+	// A name match outranks a description match
+	code, resp := doCoffeeList(t, h, url.Values{"q": {"kochere"}})
+	if names := coffeeNames(resp); code != http.StatusOK || len(names) != 1 || names[0] != "Kochere" {
+		t.Fatalf("expected Kochere, got %d %v", code, names)
+	}
+	_, _ = db.Exec(`UPDATE coffees SET description = 'like Kochere' WHERE id = 5`)
+	if _, resp = doCoffeeList(t, h, url.Values{"q": {"kochere"}}); len(coffeeNames(resp)) != 2 || coffeeNames(resp)[0] != "Kochere" {
+		t.Fatalf("expected the updated row indexed and ranked below the name match, got %v", coffeeNames(resp))
+	}
+
+	// Deleted coffees leave the index
+	w := httptest.NewRecorder()
+	h.Delete(w, coffeeItemRequest("DELETE", "6", "", 1))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, resp = doCoffeeList(t, h, url.Values{"q": {"daterra"}}); resp["total"] != float64(0) {
+		t.Fatalf("expected no match after delete, got %#v", resp)
+	}
+}
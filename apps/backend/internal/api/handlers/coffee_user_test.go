@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -48,8 +49,33 @@ func setupCoffeeTestDB(t *testing.T) *sql.DB {
             photo_path VARCHAR(500),
             created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
             updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            roast_date TEXT,
+            purchase_date TEXT,
+            opened_date TEXT,
+            bag_size REAL,
+            price REAL,
+            remaining_weight REAL,
+            country TEXT,
+            region TEXT,
+            producer TEXT,
+            process TEXT,
+            varietals TEXT,
+            altitude_min INTEGER,
+            altitude_max INTEGER,
+            roaster_id INTEGER,
             FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
         );
+        CREATE TABLE roasters (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            name VARCHAR(255) NOT NULL,
+            website VARCHAR(500),
+            location VARCHAR(255),
+            created_by INTEGER,
+            created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            match_key VARCHAR(255) NOT NULL DEFAULT ''
+        );
+        CREATE UNIQUE INDEX idx_roasters_name ON roasters(name COLLATE NOCASE);
         CREATE TRIGGER update_coffees_updated_at AFTER UPDATE ON coffees BEGIN
             UPDATE coffees SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
         END;
@@ -76,7 +102,7 @@ func setupCoffeeHandler(t *testing.T, db *sql.DB) *CoffeeHandler {
 	t.Helper()
 	queries := database.NewQueries(db)
 	coffeeService := services.NewCoffeeService(queries)
-	return NewCoffeeHandler(coffeeService, &config.Config{})
+	return NewCoffeeHandler(coffeeService, services.NewPhotoService(queries, t.TempDir(), 1<<20), &config.Config{})
 }
 
 func TestCreateForUser_Validation(t *testing.T) {
@@ -116,8 +142,23 @@ func TestCreateForUser_IdempotentPerUser_AndPhotoUpdate(t *testing.T) {
 	if !ok || resp.Coffee["name"] != "Ethiopia Yirgacheffe" {
 		t.Fatalf("unexpected response: %#v", resp.Coffee)
 	}
+	// A photo path must be a photo uploaded to one of the caller's coffees
+	own, other := strings.Repeat("a", 64)+".jpg", strings.Repeat("b", 64)+".jpg"
+	if _, err := db.Exec(`INSERT INTO coffees(user_id, name, photo_path) VALUES (1, 'Mine', ?), (2, 'Theirs', ?)`, own, other); err != nil {
+		t.Fatalf("seed photos: %v", err)
+	}
+	for _, photoPath := range []string{other, "uploads/p.jpg"} {
+		req := httptest.NewRequest("POST", "/api/v1/coffees", bytes.NewBufferString(`{"name":"Ethiopia Yirgacheffe","roaster":"Blue Bottle","origin":"Ethiopia","photoPath":"`+photoPath+`"}`))
+		req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), 1))
+		w := httptest.NewRecorder()
+		h.CreateForUser(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for photoPath %q, got %d", photoPath, w.Code)
+		}
+	}
+
 	// Idempotent per user: second call returns same coffee; also updates photo_path
-	payload2 := `{"name":"Ethiopia Yirgacheffe","roaster":"Blue Bottle","origin":"Ethiopia","description":"Floral","photoPath":"uploads/coffee-photos/1/` + time.Now().Format("20060102150405") + `/p.jpg"}`
+	payload2 := `{"name":"Ethiopia Yirgacheffe","roaster":"Blue Bottle","origin":"Ethiopia","description":"Floral","photoPath":"` + own + `"}`
 	req2 := httptest.NewRequest("POST", "/api/v1/coffees", bytes.NewBufferString(payload2))
 	req2 = req2.WithContext(middleware.WithAuthenticatedUserID(req2.Context(), 1))
 	w2 := httptest.NewRecorder()
@@ -135,7 +176,7 @@ func TestCreateForUser_IdempotentPerUser_AndPhotoUpdate(t *testing.T) {
 	if !ok || int64(id2) != int64(id1) {
 		t.Fatalf("expected same coffee id, got %v vs %v", id1, id2)
 	}
-	if resp2.Coffee["photoPath"] == nil {
+	if resp2.Coffee["photoPath"] != own {
 		t.Fatalf("expected photoPath to be set on second call")
 	}
 }
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/services"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type EquipmentHandler struct {
+	equipmentService *services.EquipmentService
+	cfg              *config.Config
+}
+
+func NewEquipmentHandler(equipmentService *services.EquipmentService, cfg *config.Config) *EquipmentHandler {
+	return &EquipmentHandler{
+		equipmentService: equipmentService,
+		cfg:              cfg,
+	}
+}
+
+// List handles GET /api/v1/equipment
+// Query: type (grinder|brewer|kettle|filter), optional.
+// Returns JSON: { "equipment": [ ... ] }
+func (h *EquipmentHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.equipmentService.List(r.Context(), userID, strings.TrimSpace(r.URL.Query().Get("type")))
+	if err != nil {
+		writeServiceError(w, err, "failed to query equipment")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"equipment": items})
+}
+
+// Get handles GET /api/v1/equipment/{id}
+func (h *EquipmentHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := equipmentIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	out, err := h.equipmentService.Get(r.Context(), userID, id)
+	if err != nil {
+		writeServiceError(w, err, "failed to read equipment")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// Create handles POST /api/v1/equipment
+// Grinders may declare grindScaleMin/grindScaleMax/grindScaleStep so brew logs
+// can record a numeric grindSetting on that scale.
+func (h *EquipmentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	type reqBody struct {
+		Type  string  `json:"type"`
+		Name  string  `json:"name"`
+		Brand *string `json:"brand,omitempty"`
+		Model *string `json:"model,omitempty"`
+		Notes *string `json:"notes,omitempty"`
+		services.GrindScale
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	var body reqBody
+	if err := dec.Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid JSON body")
+		return
+	}
+
+	out, err := h.equipmentService.Create(r.Context(), services.CreateEquipmentInput{
+		UserID:     userID,
+		Type:       body.Type,
+		Name:       body.Name,
+		Brand:      body.Brand,
+		Model:      body.Model,
+		Notes:      body.Notes,
+		GrindScale: body.GrindScale,
+	})
+	if err != nil {
+		writeServiceError(w, err, "failed to create equipment")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// Update handles PUT/PATCH /api/v1/equipment/{id}
+// Only the fields present in the body are changed; the type is fixed at creation.
+func (h *EquipmentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := equipmentIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	type reqBody struct {
+		Name  *string `json:"name,omitempty"`
+		Brand *string `json:"brand,omitempty"`
+		Model *string `json:"model,omitempty"`
+		Notes *string `json:"notes,omitempty"`
+		services.GrindScale
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	var body reqBody
+	if err := dec.Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid JSON body")
+		return
+	}
+
+	out, err := h.equipmentService.Update(r.Context(), services.UpdateEquipmentInput{
+		ID:         id,
+		UserID:     userID,
+		Name:       body.Name,
+		Brand:      body.Brand,
+		Model:      body.Model,
+		Notes:      body.Notes,
+		GrindScale: body.GrindScale,
+	})
+	if err != nil {
+		writeServiceError(w, err, "failed to update equipment")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// Delete handles DELETE /api/v1/equipment/{id} and returns 204 on success.
+// Brew logs that used the equipment are kept but unlinked from it.
+func (h *EquipmentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := equipmentIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.equipmentService.Delete(r.Context(), userID, id); err != nil {
+		writeServiceError(w, err, "failed to delete equipment")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func equipmentIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid equipment id")
+		return 0, false
+	}
+	return id, true
+}
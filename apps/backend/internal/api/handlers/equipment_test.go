@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/database"
+	"coffeeee/backend/internal/services"
+
+	"github.com/gorilla/mux"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// seedEquipment gives user 1 a grinder with a 0-40 click scale (1), a brewer
+// (2) and a kettle (3); user 2 owns a grinder (4).
+func seedEquipment(t *testing.T, db *sql.DB) {
+	t.Helper()
+	_, err := db.Exec(`INSERT INTO equipment(id, user_id, type, name, grind_scale_min, grind_scale_max, grind_scale_step) VALUES
+        (1, 1, 'grinder', 'Comandante', 0, 40, 1),
+        (2, 1, 'brewer', 'V60', NULL, NULL, NULL),
+        (3, 1, 'kettle', 'Stagg', NULL, NULL, NULL),
+        (4, 2, 'grinder', 'Ode', 1, 11, 0.5)`)
+	if err != nil {
+		t.Fatalf("seed equipment: %v", err)
+	}
+}
+
+func setupEquipmentHandler(t *testing.T, db *sql.DB) *EquipmentHandler {
+	t.Helper()
+	return NewEquipmentHandler(services.NewEquipmentService(database.NewQueries(db)), &config.Config{})
+}
+
+func equipmentRequest(method, id, body string, userID int64) *http.Request {
+	target := "/api/v1/equipment"
+	if id != "" {
+		target += "/" + id
+	}
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	if id != "" {
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+	}
+	return req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), userID))
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var resp map[string]any
+	if err := json.NewDecoder(bytes.NewReader(w.Body.Bytes())).Decode(&resp); err != nil {
+		t.Fatalf("invalid json: %v (%s)", err, w.Body.String())
+	}
+	return resp
+}
+
+func TestEquipmentCreateAndList(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedEquipment(t, db)
+	h := setupEquipmentHandler(t, db)
+
+	w := httptest.NewRecorder()
+	h.Create(w, equipmentRequest("POST", "", `{"type":"grinder","name":" 1Zpresso K-Ultra ","grindScaleMin":0,"grindScaleMax":100,"grindScaleStep":0.5}`, 1))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeBody(t, w)
+	if resp["name"] != "1Zpresso K-Ultra" || resp["grindScaleMax"] != float64(100) || resp["grindScaleStep"] != 0.5 {
+		t.Fatalf("unexpected resp: %#v", resp)
+	}
+
+	// Only the caller's equipment, filtered by type
+	w = httptest.NewRecorder()
+	req := equipmentRequest("GET", "", "", 1)
+	req.URL.RawQuery = url.Values{"type": {"grinder"}}.Encode()
+	h.List(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	items, _ := decodeBody(t, w)["equipment"].([]any)
+	if len(items) != 2 {
+		t.Fatalf("expected 2 grinders for user 1, got %#v", items)
+	}
+
+	w = httptest.NewRecorder()
+	req = equipmentRequest("GET", "", "", 1)
+	req.URL.RawQuery = "type=espresso-machine"
+	h.List(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown type, got %d", w.Code)
+	}
+}
+
+func TestEquipmentCreate_Validation(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	h := setupEquipmentHandler(t, db)
+
+	for _, body := range []string{
+		`{"type":"scale","name":"Acaia"}`,
+		`{"type":"grinder","name":"  "}`,
+		`{"type":"brewer","name":"V60","grindScaleMin":0,"grindScaleMax":10}`,
+		`{"type":"grinder","name":"C40","grindScaleMin":10,"grindScaleMax":5}`,
+		`{"type":"grinder","name":"C40","grindScaleMax":40}`,
+		`{"type":"grinder","name":"C40","grindScaleMin":0,"grindScaleMax":40,"grindScaleStep":0}`,
+		`{"type":"grinder","name":"C40","clicks":40}`,
+	} {
+		w := httptest.NewRecorder()
+		h.Create(w, equipmentRequest("POST", "", body, 1))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestEquipmentItem(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedEquipment(t, db)
+	h := setupEquipmentHandler(t, db)
+
+	// Other users' equipment does not exist as far as the caller is concerned
+	w := httptest.NewRecorder()
+	h.Get(w, equipmentRequest("GET", "4", "", 1))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.Update(w, equipmentRequest("PATCH", "1", `{"notes":"burrs replaced","grindScaleMax":50}`, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resp := decodeBody(t, w)
+	if resp["name"] != "Comandante" || resp["notes"] != "burrs replaced" || resp["grindScaleMax"] != float64(50) || resp["grindScaleMin"] != float64(0) {
+		t.Fatalf("unexpected resp: %#v", resp)
+	}
+
+	// The merged scale is checked, not just the fields sent
+	w = httptest.NewRecorder()
+	h.Update(w, equipmentRequest("PATCH", "1", `{"grindScaleMin":60}`, 1))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	h.Update(w, equipmentRequest("PATCH", "1", `{}`, 1))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for empty update, got %d", w.Code)
+	}
+}
+
+func TestEquipmentDelete_UnlinksBrewLogs(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedEquipment(t, db)
+	_, err := db.Exec(`INSERT INTO brew_logs(id, user_id, coffee_id, brew_method, grinder_id, brewer_id, grind_setting) VALUES (1, 1, 1, 'V60', 1, 2, 18)`)
+	if err != nil {
+		t.Fatalf("seed brew log: %v", err)
+	}
+	h := setupEquipmentHandler(t, db)
+
+	w := httptest.NewRecorder()
+	h.Delete(w, equipmentRequest("DELETE", "1", "", 1))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	var grinderID, brewerID sql.NullInt64
+	var setting sql.NullFloat64
+	if err := db.QueryRow(`SELECT grinder_id, brewer_id, grind_setting FROM brew_logs WHERE id = 1`).Scan(&grinderID, &brewerID, &setting); err != nil {
+		t.Fatalf("brew log should survive: %v", err)
+	}
+	if grinderID.Valid || setting.Valid || brewerID.Int64 != 2 {
+		t.Fatalf("expected grinder and setting cleared, brewer kept: %v %v %v", grinderID, setting, brewerID)
+	}
+
+	w = httptest.NewRecorder()
+	h.Delete(w, equipmentRequest("DELETE", "4", "", 1))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting another user's grinder, got %d", w.Code)
+	}
+}
+
+func TestBrewLogEquipmentLinks(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	seedEquipment(t, db)
+	h := setupBrewLogHandler(t, db)
+
+	w, resp := createBrewLogForMetrics(t, h, `{"coffeeId":1,"brewMethod":"V60","grinderId":1,"brewerId":2,"grindSetting":18}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp["grinderId"] != float64(1) || resp["brewerId"] != float64(2) || resp["grindSetting"] != float64(18) {
+		t.Fatalf("unexpected resp: %#v", resp)
+	}
+
+	for body, want := range map[string]int{
+		`{"coffeeId":1,"brewMethod":"V60","grinderId":4}`:                     http.StatusNotFound,
+		`{"coffeeId":1,"brewMethod":"V60","grinderId":2}`:                     http.StatusBadRequest,
+		`{"coffeeId":1,"brewMethod":"V60","brewerId":3}`:                      http.StatusBadRequest,
+		`{"coffeeId":1,"brewMethod":"V60","grindSetting":18}`:                 http.StatusBadRequest,
+		`{"coffeeId":1,"brewMethod":"V60","grinderId":1,"grindSetting":41}`:   http.StatusBadRequest,
+		`{"coffeeId":1,"brewMethod":"V60","grinderId":1,"grindSetting":18.5}`: http.StatusBadRequest,
+	} {
+		if w, _ := createBrewLogForMetrics(t, h, body); w.Code != want {
+			t.Fatalf("expected %d for %s, got %d: %s", want, body, w.Code, w.Body.String())
+		}
+	}
+
+	// "2 clicks finer" on the same grinder keeps the link
+	id := resp["id"].(float64)
+	idStr := jsonNumber(id)
+	w = httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", idStr, `{"grindSetting":16}`, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if resp := decodeBody(t, w); resp["grindSetting"] != float64(16) || resp["grinderId"] != float64(1) {
+		t.Fatalf("unexpected resp: %#v", resp)
+	}
+
+	// Unlinking the grinder drops the setting that was relative to it
+	w = httptest.NewRecorder()
+	h.Update(w, brewLogItemRequest("PATCH", idStr, `{"grinderId":0}`, 1))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	resp = decodeBody(t, w)
+	if _, ok := resp["grinderId"]; ok {
+		t.Fatalf("expected grinder to be unlinked: %#v", resp)
+	}
+	if _, ok := resp["grindSetting"]; ok {
+		t.Fatalf("expected grindSetting to be cleared: %#v", resp)
+	}
+
+	_, list := doBrewLogList(t, h, 1, url.Values{"brewerId": {"2"}})
+	if len(list.BrewLogs) != 1 {
+		t.Fatalf("expected 1 brew log for brewer 2, got %d", len(list.BrewLogs))
+	}
+}
+
+func jsonNumber(f float64) string {
+	b, _ := json.Marshal(f)
+	return string(b)
+}
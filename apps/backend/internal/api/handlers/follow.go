@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/services"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type FollowHandler struct {
+	followService *services.FollowService
+	cfg           *config.Config
+}
+
+func NewFollowHandler(followService *services.FollowService, cfg *config.Config) *FollowHandler {
+	return &FollowHandler{
+		followService: followService,
+		cfg:           cfg,
+	}
+}
+
+// Follow handles POST /api/v1/users/{userId}/follow and returns 204. The
+// follow is a request until the user approves it.
+func (h *FollowHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	followeeID, ok := followeeIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.followService.Follow(r.Context(), userID, followeeID); err != nil {
+		writeServiceError(w, err, "failed to follow user")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Unfollow handles DELETE /api/v1/users/{userId}/follow and returns 204.
+func (h *FollowHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	followeeID, ok := followeeIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.followService.Unfollow(r.Context(), userID, followeeID); err != nil {
+		writeServiceError(w, err, "failed to unfollow user")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListFollowers handles GET /api/v1/users/me/followers
+func (h *FollowHandler) ListFollowers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	followers, err := h.followService.ListFollowers(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, err, "failed to list followers")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"followers": followers})
+}
+
+// ApproveFollower handles POST /api/v1/users/me/followers/{userId}/approve and returns 204.
+func (h *FollowHandler) ApproveFollower(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	followerID, ok := followeeIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.followService.ApproveFollower(r.Context(), userID, followerID); err != nil {
+		writeServiceError(w, err, "failed to approve follower")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveFollower handles DELETE /api/v1/users/me/followers/{userId} and returns 204.
+func (h *FollowHandler) RemoveFollower(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	followerID, ok := followeeIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.followService.RemoveFollower(r.Context(), userID, followerID); err != nil {
+		writeServiceError(w, err, "failed to remove follower")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// followeeIDFromPath reads the {userId} path variable, which is the followee
+// on /users/{userId}/follow and the follower on /users/me/followers/{userId}.
+func followeeIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["userId"], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid user id")
+		return 0, false
+	}
+	return id, true
+}
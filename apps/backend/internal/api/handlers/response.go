@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"coffeeee/backend/internal/services"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// writeJSONError writes the standard { "code", "message" } error body with the given status.
+func writeJSONError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"code":    code,
+		"message": message,
+	})
+}
+
+// writeServiceError maps service error types onto HTTP responses. Anything
+// unrecognised is logged and reported as a DATABASE_ERROR with fallbackMessage.
+func writeServiceError(w http.ResponseWriter, err error, fallbackMessage string) {
+	var validationErr *services.ValidationError
+	var notFoundErr *services.NotFoundError
+	var unauthorizedErr *services.UnauthorizedError
+	var forbiddenErr *services.ForbiddenError
+	var conflictErr *services.ConflictError
+	var unavailableErr *services.UnavailableError
+	switch {
+	case errors.As(err, &validationErr):
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", validationErr.Message)
+	case errors.As(err, &notFoundErr):
+		writeJSONError(w, http.StatusNotFound, "NOT_FOUND", notFoundErr.Message)
+	case errors.As(err, &unauthorizedErr):
+		writeJSONError(w, http.StatusUnauthorized, "AUTHENTICATION_ERROR", unauthorizedErr.Message)
+	case errors.As(err, &forbiddenErr):
+		writeJSONError(w, http.StatusForbidden, "FORBIDDEN", forbiddenErr.Message)
+	case errors.As(err, &conflictErr):
+		writeJSONError(w, http.StatusConflict, "CONFLICT", conflictErr.Message)
+	case errors.As(err, &unavailableErr):
+		log.Printf("%s: %v", fallbackMessage, err)
+		writeJSONError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", unavailableErr.Message)
+	default:
+		log.Printf("%s: %v", fallbackMessage, err)
+		writeJSONError(w, http.StatusInternalServerError, "DATABASE_ERROR", fallbackMessage)
+	}
+}
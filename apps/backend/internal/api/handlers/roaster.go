@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/services"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+type RoasterHandler struct {
+	roasterService *services.RoasterService
+	coffeeService  *services.CoffeeService
+	cfg            *config.Config
+}
+
+func NewRoasterHandler(roasterService *services.RoasterService, coffeeService *services.CoffeeService, cfg *config.Config) *RoasterHandler {
+	return &RoasterHandler{
+		roasterService: roasterService,
+		coffeeService:  coffeeService,
+		cfg:            cfg,
+	}
+}
+
+// List handles GET /api/v1/roasters
+// Query: q, optional; with it only roasters with similar names are returned, closest first.
+// Returns JSON: { "roasters": [ {id, name, website?, location?, createdAt}, ... ] }
+func (h *RoasterHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if _, ok := requireUserID(w, r); !ok {
+		return
+	}
+
+	roasters, err := h.roasterService.List(r.Context(), r.URL.Query().Get("q"))
+	if err != nil {
+		writeServiceError(w, err, "failed to query roasters")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"roasters": roasters})
+}
+
+// Get handles GET /api/v1/roasters/{id}
+func (h *RoasterHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if _, ok := requireUserID(w, r); !ok {
+		return
+	}
+	id, ok := roasterIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	roaster, err := h.roasterService.Get(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err, "failed to read roaster")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"roaster": roaster})
+}
+
+// Create handles POST /api/v1/roasters
+// Request JSON: { "name": string, "website"?: string, "location"?: string }
+// The directory is shared, so a name close to an existing roaster returns that
+// roaster, with a missing website or location filled in, instead of adding a duplicate.
+// Returns 201 with { "roaster": { ... } }
+func (h *RoasterHandler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	type reqBody struct {
+		Name     string  `json:"name"`
+		Website  *string `json:"website,omitempty"`
+		Location *string `json:"location,omitempty"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	var body reqBody
+	if err := dec.Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid JSON body")
+		return
+	}
+
+	roaster, err := h.roasterService.Create(r.Context(), services.CreateRoasterInput{
+		UserID:   userID,
+		Name:     body.Name,
+		Website:  body.Website,
+		Location: body.Location,
+	})
+	if err != nil {
+		writeServiceError(w, err, "failed to create roaster")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]any{"roaster": roaster})
+}
+
+// Coffees handles GET /api/v1/roasters/{id}/coffees
+// Query: the same search, sort and paging parameters as GET /api/v1/coffees; sort defaults to rating.
+// Returns JSON: { "roaster": { ... }, "averageRating"?: number, "brewCount": number,
+// "coffees": [ ... ], "nextCursor": string|null, "total": number }. The average and
+// count cover all of the caller's brew logs of coffees from this roaster.
+func (h *RoasterHandler) Coffees(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := roasterIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	input, ok := listCoffeesInputFrom(w, r, userID)
+	if !ok {
+		return
+	}
+
+	page, err := h.coffeeService.ListForRoaster(r.Context(), id, input)
+	if err != nil {
+		writeServiceError(w, err, "failed to query roaster coffees")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(page)
+}
+
+func roasterIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid roaster id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/database"
+	"coffeeee/backend/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+func setupRoasterHandler(db *sql.DB) *RoasterHandler {
+	queries := database.NewQueries(db)
+	return NewRoasterHandler(services.NewRoasterService(queries), services.NewCoffeeService(queries), &config.Config{})
+}
+
+func roasterRequest(method, target, id, body string, userID int64) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	if id != "" {
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+	}
+	return req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), userID))
+}
+
+func TestRoasters_CoffeesLinkToClosestRoaster(t *testing.T) {
+	db := setupCoffeeTestDB(t)
+	defer db.Close()
+	h := setupCoffeeHandler(t, db)
+
+	code, first := postCoffee(t, h, `{"name":"Geometry","roaster":"Onyx Coffee Lab"}`)
+	if code != http.StatusCreated || first["roasterId"] == nil {
+		t.Fatalf("expected a linked coffee, got %d %#v", code, first)
+	}
+	// Spelling variants join the same roaster, but keep the text as typed
+	for _, roaster := range []string{"onyx coffee lab", "The Onyx Lab", "Onyx Coffee Labs"} {
+		code, coffee := postCoffee(t, h, `{"name":"Southern Weather","roaster":"`+roaster+`"}`)
+		if code != http.StatusCreated || coffee["roasterId"] != first["roasterId"] || coffee["roaster"] != roaster {
+			t.Fatalf("%s: expected roaster %v, got %d %#v", roaster, first["roasterId"], code, coffee)
+		}
+	}
+	code, other := postCoffee(t, h, `{"name":"Decaf","roaster":"Heart Roasters"}`)
+	if code != http.StatusCreated || other["roasterId"] == nil || other["roasterId"] == first["roasterId"] {
+		t.Fatalf("expected a new roaster, got %d %#v", code, other)
+	}
+
+	// A name that only starts like another is another roaster
+	_, barn := postCoffee(t, h, `{"name":"Kenya","roaster":"The Barn"}`)
+	code, owl := postCoffee(t, h, `{"name":"Kenya","roaster":"Barn Owl Roasters"}`)
+	if code != http.StatusCreated || owl["roasterId"] == barn["roasterId"] || owl["roaster"] != "Barn Owl Roasters" {
+		t.Fatalf("expected Barn Owl Roasters kept apart from The Barn, got %d %#v", code, owl)
+	}
+
+	var count int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM roasters`).Scan(&count)
+	if count != 4 {
+		t.Fatalf("expected 4 roasters, got %d", count)
+	}
+
+	// An explicit roasterId wins; 0 unlinks
+	id := jsonNumber(other["id"].(float64))
+	w := httptest.NewRecorder()
+	h.Update(w, coffeeItemRequest("PATCH", id, `{"roasterId":`+jsonNumber(first["roasterId"].(float64))+`}`, 1))
+	coffee, _ := decodeBody(t, w)["coffee"].(map[string]any)
+	if w.Code != http.StatusOK || coffee["roaster"] != "Onyx Coffee Lab" {
+		t.Fatalf("expected relinked coffee, got %d %#v", w.Code, coffee)
+	}
+	w = httptest.NewRecorder()
+	h.Update(w, coffeeItemRequest("PATCH", id, `{"roasterId":0}`, 1))
+	coffee, _ = decodeBody(t, w)["coffee"].(map[string]any)
+	if w.Code != http.StatusOK || coffee["roaster"] != nil || coffee["roasterId"] != nil {
+		t.Fatalf("expected unlinked coffee, got %d %#v", w.Code, coffee)
+	}
+	if code, coffee := postCoffee(t, h, `{"name":"X","roasterId":99}`); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown roasterId, got %d %#v", code, coffee)
+	}
+}
+
+func TestRoasters_CreateAndSearch(t *testing.T) {
+	db := setupCoffeeTestDB(t)
+	defer db.Close()
+	h := setupRoasterHandler(db)
+
+	w := httptest.NewRecorder()
+	h.Create(w, roasterRequest("POST", "/api/v1/roasters", "", `{"name":"Square Mile Coffee Roasters","location":"London"}`, 1))
+	created, _ := decodeBody(t, w)["roaster"].(map[string]any)
+	if w.Code != http.StatusCreated || created["location"] != "London" {
+		t.Fatalf("expected 201, got %d %#v", w.Code, created)
+	}
+
+	// A near-duplicate returns the existing roaster; only the user who added
+	// it fills in what it lacked
+	const website = `"website":"https://shop.squaremilecoffee.com","location":"Bristol"`
+	w = httptest.NewRecorder()
+	h.Create(w, roasterRequest("POST", "/api/v1/roasters", "", `{"name":"square mile",`+website+`}`, 2))
+	again, _ := decodeBody(t, w)["roaster"].(map[string]any)
+	if w.Code != http.StatusCreated || again["id"] != created["id"] || again["website"] != nil {
+		t.Fatalf("expected the existing roaster unchanged, got %d %#v", w.Code, again)
+	}
+	w = httptest.NewRecorder()
+	h.Create(w, roasterRequest("POST", "/api/v1/roasters", "", `{"name":"square mile",`+website+`}`, 1))
+	again, _ = decodeBody(t, w)["roaster"].(map[string]any)
+	if w.Code != http.StatusCreated || again["id"] != created["id"] || again["location"] != "London" ||
+		again["website"] != "https://shop.squaremilecoffee.com" {
+		t.Fatalf("expected the existing roaster filled in, got %d %#v", w.Code, again)
+	}
+
+	for _, body := range []string{`{"name":" "}`, `{"name":"X","website":"ftp://x"}`, `{"name":"X","founded":1}`} {
+		w = httptest.NewRecorder()
+		h.Create(w, roasterRequest("POST", "/api/v1/roasters", "", body, 1))
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+
+	_, _ = db.Exec(`INSERT INTO roasters(name) VALUES ('Sey'), ('Tim Wendelboe')`)
+	w = httptest.NewRecorder()
+	h.List(w, roasterRequest("GET", "/api/v1/roasters?q=squaremile", "", "", 1))
+	roasters, _ := decodeBody(t, w)["roasters"].([]any)
+	if w.Code != http.StatusOK || len(roasters) != 1 || roasters[0].(map[string]any)["id"] != created["id"] {
+		t.Fatalf("expected one suggestion, got %d %#v", w.Code, roasters)
+	}
+	w = httptest.NewRecorder()
+	h.List(w, roasterRequest("GET", "/api/v1/roasters", "", "", 1))
+	if roasters, _ := decodeBody(t, w)["roasters"].([]any); len(roasters) != 3 {
+		t.Fatalf("expected the whole directory, got %#v", roasters)
+	}
+}
+
+func TestRoasters_CoffeesWithRatings(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	_, err := db.Exec(`
+        INSERT INTO roasters(id, name) VALUES (1, 'Onyx Coffee Lab'), (2, 'Sey');
+        UPDATE coffees SET roaster = 'Onyx Coffee Lab', roaster_id = 1;
+        INSERT INTO coffees(id, user_id, name, roaster, roaster_id) VALUES (3, 1, 'C', 'Onyx Coffee Lab', 1), (4, 1, 'D', 'Sey', 2);
+        INSERT INTO brew_logs(user_id, coffee_id, brew_method, rating) VALUES
+            (1, 1, 'v60', 3), (1, 3, 'v60', 5), (1, 3, 'v60', 4), (1, 4, 'v60', 1), (2, 2, 'v60', 1)`)
+	if err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+	h := setupRoasterHandler(db)
+
+	w := httptest.NewRecorder()
+	h.Coffees(w, roasterRequest("GET", "/api/v1/roasters/1/coffees", "1", "", 1))
+	body := decodeBody(t, w)
+	coffees, _ := body["coffees"].([]any)
+	if w.Code != http.StatusOK || body["averageRating"] != float64(4) || body["brewCount"] != float64(3) || body["total"] != float64(2) {
+		t.Fatalf("unexpected page: %d %#v", w.Code, body)
+	}
+	// Only the caller's coffees, best rated first
+	if len(coffees) != 2 || coffees[0].(map[string]any)["name"] != "C" || coffees[1].(map[string]any)["name"] != "A" {
+		t.Fatalf("unexpected coffees: %#v", coffees)
+	}
+
+	w = httptest.NewRecorder()
+	h.Coffees(w, roasterRequest("GET", "/api/v1/roasters/2/coffees", "2", "", 2))
+	if body := decodeBody(t, w); w.Code != http.StatusOK || body["averageRating"] != nil || body["total"] != float64(0) {
+		t.Fatalf("expected no coffees for user 2, got %d %#v", w.Code, body)
+	}
+	w = httptest.NewRecorder()
+	h.Coffees(w, roasterRequest("GET", "/api/v1/roasters/9/coffees", "9", "", 1))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/services"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type TastingHandler struct {
+	tastingService *services.TastingService
+	cfg            *config.Config
+}
+
+func NewTastingHandler(tastingService *services.TastingService, cfg *config.Config) *TastingHandler {
+	return &TastingHandler{
+		tastingService: tastingService,
+		cfg:            cfg,
+	}
+}
+
+// List handles GET /api/v1/tasting-sessions?brewLogId=
+// Returns JSON: { "sessions": [ ... ] } with the caller's sessions attached to the brew log.
+func (h *TastingHandler) List(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	brewLogID, err := strconv.ParseInt(strings.TrimSpace(r.URL.Query().Get("brewLogId")), 10, 64)
+	if err != nil || brewLogID <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "brewLogId is required")
+		return
+	}
+
+	sessions, err := h.tastingService.ListForBrewLog(r.Context(), userID, brewLogID)
+	if err != nil {
+		writeServiceError(w, err, "failed to query tasting sessions")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(map[string]any{"sessions": sessions})
+}
+
+// Create handles POST /api/v1/tasting-sessions
+// Request: JSON { "brewLogId"?: number, "brewMethod"?: string }, where the brew method
+// tailors the hints. Returns 201 with the session and its first question.
+func (h *TastingHandler) Create(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		BrewLogID  *int64  `json:"brewLogId,omitempty"`
+		BrewMethod *string `json:"brewMethod,omitempty"`
+	}
+	// An empty body starts a session for no brew log in particular
+	if r.ContentLength != 0 {
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid JSON body")
+			return
+		}
+	}
+
+	out, err := h.tastingService.Create(r.Context(), services.CreateTastingSessionInput{
+		UserID:     userID,
+		BrewLogID:  body.BrewLogID,
+		BrewMethod: body.BrewMethod,
+	})
+	if err != nil {
+		writeServiceError(w, err, "failed to start tasting session")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// Get handles GET /api/v1/tasting-sessions/{id}
+func (h *TastingHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := tastingSessionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	out, err := h.tastingService.Get(r.Context(), userID, id)
+	if err != nil {
+		writeServiceError(w, err, "failed to read tasting session")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// Answer handles POST /api/v1/tasting-sessions/{id}/answers
+// Request: JSON { "questionId": string, "value": string } answering the current question.
+// Returns the session with the next question, or completed with its tasting note.
+func (h *TastingHandler) Answer(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := tastingSessionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var body services.TastingAnswer
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid JSON body")
+		return
+	}
+
+	out, err := h.tastingService.Answer(r.Context(), userID, id, body)
+	if err != nil {
+		writeServiceError(w, err, "failed to answer tasting question")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// Back handles DELETE /api/v1/tasting-sessions/{id}/answers/last
+// Undoes the last answer of an active session and returns the session.
+func (h *TastingHandler) Back(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := tastingSessionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	out, err := h.tastingService.Back(r.Context(), userID, id)
+	if err != nil {
+		writeServiceError(w, err, "failed to undo tasting answer")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+// Attach handles PUT /api/v1/tasting-sessions/{id}/brew-log
+// Request: JSON { "brewLogId": number }. A completed session's note fills in the brew
+// log's tastingNotes when they are empty.
+func (h *TastingHandler) Attach(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	userID, ok := requireUserID(w, r)
+	if !ok {
+		return
+	}
+	id, ok := tastingSessionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var body struct {
+		BrewLogID int64 `json:"brewLogId"`
+	}
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil || body.BrewLogID <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "brewLogId is required")
+		return
+	}
+
+	out, err := h.tastingService.Attach(r.Context(), userID, id, body.BrewLogID)
+	if err != nil {
+		writeServiceError(w, err, "failed to attach tasting session")
+		return
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func tastingSessionIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil || id <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid tasting session id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"coffeeee/backend/internal/api/middleware"
+	"coffeeee/backend/internal/config"
+	"coffeeee/backend/internal/database"
+	"coffeeee/backend/internal/services"
+
+	"github.com/gorilla/mux"
+)
+
+func setupTastingHandler(t *testing.T, db *sql.DB) *TastingHandler {
+	t.Helper()
+	tastingService := services.NewTastingService(database.NewQueries(db), services.DefaultTastingGraph())
+	return NewTastingHandler(tastingService, &config.Config{})
+}
+
+func tastingRequest(t *testing.T, handle http.HandlerFunc, method, target, id, body string, userID int64) (int, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	if id != "" {
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+	}
+	req = req.WithContext(middleware.WithAuthenticatedUserID(req.Context(), userID))
+	w := httptest.NewRecorder()
+	handle(w, req)
+	return w.Code, decodeBody(t, w)
+}
+
+func tastingAnswer(t *testing.T, h *TastingHandler, id, questionID, value string) map[string]any {
+	t.Helper()
+	body := fmt.Sprintf(`{"questionId":%q,"value":%q}`, questionID, value)
+	code, resp := tastingRequest(t, h.Answer, http.MethodPost, "/api/v1/tasting-sessions/"+id+"/answers", id, body, 1)
+	if code != http.StatusOK {
+		t.Fatalf("answer %s=%s: expected 200, got %d %#v", questionID, value, code, resp)
+	}
+	return resp
+}
+
+func currentQuestion(resp map[string]any) string {
+	q, _ := resp["question"].(map[string]any)
+	id, _ := q["questionId"].(string)
+	return id
+}
+
+func TestTastingSession_WalksTheFlavorWheel(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	_, err := db.Exec(`INSERT INTO brew_logs(id, user_id, coffee_id, brew_method, tasting_notes) VALUES
+        (1, 1, 1, 'espresso', NULL),
+        (2, 1, 1, 'v60', 'my own words'),
+        (3, 2, 2, 'v60', NULL)`)
+	if err != nil {
+		t.Fatalf("seed brew logs: %v", err)
+	}
+	h := setupTastingHandler(t, db)
+
+	// The brew log gives the method, which picks the hint
+	code, resp := tastingRequest(t, h.Create, http.MethodPost, "/api/v1/tasting-sessions", "", `{"brewLogId":1}`, 1)
+	if code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d %#v", code, resp)
+	}
+	id := fmt.Sprint(resp["id"])
+	q, _ := resp["question"].(map[string]any)
+	if resp["status"] != "active" || resp["brewMethod"] != "espresso" || q["questionId"] != "aroma" || !strings.HasPrefix(fmt.Sprint(q["hint"]), "Espresso") {
+		t.Fatalf("unexpected new session: %#v", resp)
+	}
+
+	// Only the current question can be answered, with one of its options
+	for _, body := range []string{`{"questionId":"acidity","value":"high"}`, `{"questionId":"aroma","value":"smoky"}`} {
+		if code, resp := tastingRequest(t, h.Answer, http.MethodPost, "/", id, body, 1); code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d %#v", body, code, resp)
+		}
+	}
+
+	tastingAnswer(t, h, id, "aroma", "floral")
+	tastingAnswer(t, h, id, "flavor", "fruity")
+	resp = tastingAnswer(t, h, id, "flavor-fruity", "citrus-fruit")
+	if currentQuestion(resp) != "flavor-citrus-fruit" {
+		t.Fatalf("expected the citrus question, got %#v", resp)
+	}
+	// Going back returns to the broader question
+	code, resp = tastingRequest(t, h.Back, http.MethodDelete, "/", id, "", 1)
+	if code != http.StatusOK || currentQuestion(resp) != "flavor-fruity" {
+		t.Fatalf("expected to be back at flavor-fruity, got %d %#v", code, resp)
+	}
+	tastingAnswer(t, h, id, "flavor-fruity", "berry")
+	tastingAnswer(t, h, id, "flavor-berry", "blueberry")
+	tastingAnswer(t, h, id, "another-flavor", "yes")
+	tastingAnswer(t, h, id, "flavor", "nutty-cocoa")
+	tastingAnswer(t, h, id, "flavor-nutty-cocoa", "cocoa")
+	tastingAnswer(t, h, id, "flavor-cocoa", "dark-chocolate")
+	tastingAnswer(t, h, id, "another-flavor", "no")
+	tastingAnswer(t, h, id, "acidity", "high")
+	tastingAnswer(t, h, id, "sweetness", "medium")
+	tastingAnswer(t, h, id, "body", "light")
+	resp = tastingAnswer(t, h, id, "finish", "long")
+
+	note, _ := resp["note"].(map[string]any)
+	flavors, _ := note["flavors"].([]any)
+	wantSummary := "Aroma: floral. Flavors: blueberry, dark chocolate. Acidity: high. Sweetness: medium. Body: light. Finish: long."
+	if resp["status"] != "completed" || resp["question"] != nil || resp["completedAt"] == nil || len(flavors) != 2 || note["summary"] != wantSummary {
+		t.Fatalf("unexpected completed session: %#v", resp)
+	}
+	first, _ := flavors[0].(map[string]any)
+	if fmt.Sprint(first["path"]) != "[Fruity Berry Blueberry]" || first["value"] != "blueberry" {
+		t.Fatalf("unexpected refined flavor: %#v", first)
+	}
+	var tastingNotes sql.NullString
+	_ = db.QueryRow(`SELECT tasting_notes FROM brew_logs WHERE id = 1`).Scan(&tastingNotes)
+	if tastingNotes.String != wantSummary {
+		t.Fatalf("expected the note in the brew log, got %q", tastingNotes.String)
+	}
+
+	// A completed session takes no more answers
+	if code, _ := tastingRequest(t, h.Back, http.MethodDelete, "/", id, "", 1); code != http.StatusBadRequest {
+		t.Fatalf("expected 400 going back on a completed session, got %d", code)
+	}
+
+	// Attaching to a brew log with notes of its own keeps them
+	code, resp = tastingRequest(t, h.Attach, http.MethodPut, "/", id, `{"brewLogId":2}`, 1)
+	if code != http.StatusOK || resp["brewLogId"] != float64(2) {
+		t.Fatalf("expected the session attached, got %d %#v", code, resp)
+	}
+	_ = db.QueryRow(`SELECT tasting_notes FROM brew_logs WHERE id = 2`).Scan(&tastingNotes)
+	if tastingNotes.String != "my own words" {
+		t.Fatalf("expected the user's notes kept, got %q", tastingNotes.String)
+	}
+	code, resp = tastingRequest(t, h.List, http.MethodGet, "/api/v1/tasting-sessions?brewLogId=2", "", "", 1)
+	if sessions, _ := resp["sessions"].([]any); code != http.StatusOK || len(sessions) != 1 {
+		t.Fatalf("expected one session for brew log 2, got %d %#v", code, resp)
+	}
+}
+
+func TestTastingSession_OwnerOnly(t *testing.T) {
+	db := setupBrewLogTestDB(t)
+	defer db.Close()
+	_, _ = db.Exec(`INSERT INTO brew_logs(id, user_id, coffee_id, brew_method) VALUES (1, 1, 1, 'v60'), (3, 2, 2, 'v60')`)
+	h := setupTastingHandler(t, db)
+
+	// Another user's brew log cannot be tasted or listed
+	if code, _ := tastingRequest(t, h.Create, http.MethodPost, "/", "", `{"brewLogId":3}`, 1); code != http.StatusNotFound {
+		t.Fatalf("expected 404 for another user's brew log, got %d", code)
+	}
+	if code, _ := tastingRequest(t, h.List, http.MethodGet, "/api/v1/tasting-sessions?brewLogId=3", "", "", 1); code != http.StatusNotFound {
+		t.Fatalf("expected 404 listing another user's brew log, got %d", code)
+	}
+
+	// An empty body starts a session with the general hints
+	code, resp := tastingRequest(t, h.Create, http.MethodPost, "/", "", "", 1)
+	q, _ := resp["question"].(map[string]any)
+	if code != http.StatusCreated || resp["brewLogId"] != nil || !strings.HasPrefix(fmt.Sprint(q["hint"]), "Smell the dry grounds") {
+		t.Fatalf("unexpected session: %d %#v", code, resp)
+	}
+	id := fmt.Sprint(resp["id"])
+
+	for name, handle := range map[string]http.HandlerFunc{"get": h.Get, "back": h.Back} {
+		if code, _ := tastingRequest(t, handle, http.MethodGet, "/", id, "", 2); code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404 for another user's session, got %d", name, code)
+		}
+	}
+	if code, _ := tastingRequest(t, h.Attach, http.MethodPut, "/", id, `{"brewLogId":3}`, 1); code != http.StatusNotFound {
+		t.Fatalf("expected 404 attaching to another user's brew log, got %d", code)
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
     "coffeeee/backend/internal/api/middleware"
     "coffeeee/backend/internal/config"
+    "coffeeee/backend/internal/services"
     "database/sql"
     "encoding/json"
     "net/http"
@@ -34,12 +35,13 @@ func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Query user from database
-	var username, email string
+	var username, email, brewLogVisibility string
 	var createdAt, updatedAt time.Time
+	var emailVerifiedAt sql.NullTime
 	err := h.db.QueryRow(
-		`SELECT username, email, created_at, updated_at FROM users WHERE id = ?`,
+		`SELECT username, email, email_verified_at, brew_log_visibility, created_at, updated_at FROM users WHERE id = ?`,
 		userID,
-	).Scan(&username, &email, &createdAt, &updatedAt)
+	).Scan(&username, &email, &emailVerifiedAt, &brewLogVisibility, &createdAt, &updatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// User not found in database
@@ -65,11 +67,13 @@ func (h *UserHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(map[string]interface{}{
-		"id":        userID,
-		"username":  username,
-		"email":     email,
-		"createdAt": createdAt.Format(time.RFC3339),
-		"updatedAt": updatedAt.Format(time.RFC3339),
+		"id":                userID,
+		"username":          username,
+		"email":             email,
+		"emailVerified":     emailVerifiedAt.Valid,
+		"brewLogVisibility": brewLogVisibility,
+		"createdAt":         createdAt.Format(time.RFC3339),
+		"updatedAt":         updatedAt.Format(time.RFC3339),
 	})
 }
 
@@ -90,8 +94,9 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 
     // Parse request body
     type reqBody struct {
-        Username *string `json:"username,omitempty"`
-        Email    *string `json:"email,omitempty"`
+        Username          *string `json:"username,omitempty"`
+        Email             *string `json:"email,omitempty"`
+        BrewLogVisibility *string `json:"brewLogVisibility,omitempty"`
     }
 
     dec := json.NewDecoder(r.Body)
@@ -107,7 +112,7 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
     }
 
     // At least one field must be provided
-    if body.Username == nil && body.Email == nil {
+    if body.Username == nil && body.Email == nil && body.BrewLogVisibility == nil {
         w.WriteHeader(http.StatusBadRequest)
         _ = json.NewEncoder(w).Encode(map[string]string{
             "code":    "VALIDATION_ERROR",
@@ -177,16 +182,30 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
         // if err == sql.ErrNoRows -> OK; other errors ignored here
     }
 
+    if body.BrewLogVisibility != nil && !services.IsValidVisibility(*body.BrewLogVisibility) {
+        w.WriteHeader(http.StatusBadRequest)
+        _ = json.NewEncoder(w).Encode(map[string]string{
+            "code":    "VALIDATION_ERROR",
+            "message": "brewLogVisibility must be one of private, followers, public",
+        })
+        return
+    }
+
     // Build update statement dynamically
-    setParts := make([]string, 0, 2)
-    args := make([]any, 0, 3)
+    setParts := make([]string, 0, 4)
+    args := make([]any, 0, 5)
     if body.Username != nil {
         setParts = append(setParts, "username = ?")
         args = append(args, newUsername)
     }
     if body.Email != nil {
-        setParts = append(setParts, "email = ?")
-        args = append(args, newEmail)
+        // A new address is unverified until its own verification link is used
+        setParts = append(setParts, "email_verified_at = CASE WHEN email = ? THEN email_verified_at END", "email = ?")
+        args = append(args, newEmail, newEmail)
+    }
+    if body.BrewLogVisibility != nil {
+        setParts = append(setParts, "brew_log_visibility = ?")
+        args = append(args, *body.BrewLogVisibility)
     }
     if len(setParts) == 0 {
         w.WriteHeader(http.StatusBadRequest)
@@ -209,12 +228,13 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
     }
 
     // Read back updated user
-    var username, email string
+    var username, email, brewLogVisibility string
     var createdAt, updatedAt time.Time
+    var emailVerifiedAt sql.NullTime
     if err := h.db.QueryRow(
-        `SELECT username, email, created_at, updated_at FROM users WHERE id = ?`,
+        `SELECT username, email, email_verified_at, brew_log_visibility, created_at, updated_at FROM users WHERE id = ?`,
         userID,
-    ).Scan(&username, &email, &createdAt, &updatedAt); err != nil {
+    ).Scan(&username, &email, &emailVerifiedAt, &brewLogVisibility, &createdAt, &updatedAt); err != nil {
         w.WriteHeader(http.StatusInternalServerError)
         _ = json.NewEncoder(w).Encode(map[string]string{
             "code":    "INTERNAL_ERROR",
@@ -226,11 +246,13 @@ func (h *UserHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
     // Respond with updated user
     w.WriteHeader(http.StatusOK)
     _ = json.NewEncoder(w).Encode(map[string]any{
-        "id":        userID,
-        "username":  username,
-        "email":     email,
-        "createdAt": createdAt.Format(time.RFC3339),
-        "updatedAt": updatedAt.Format(time.RFC3339),
+        "id":                userID,
+        "username":          username,
+        "email":             email,
+        "emailVerified":     emailVerifiedAt.Valid,
+        "brewLogVisibility": brewLogVisibility,
+        "createdAt":         createdAt.Format(time.RFC3339),
+        "updatedAt":         updatedAt.Format(time.RFC3339),
     })
 }
 
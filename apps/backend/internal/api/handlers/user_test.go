@@ -28,8 +28,10 @@ func TestUserHandler_GetProfile(t *testing.T) {
 			email VARCHAR(255) NOT NULL UNIQUE,
 			password_hash VARCHAR(255) NOT NULL,
 			password_salt VARCHAR(255) NOT NULL,
+			email_verified_at DATETIME,
 			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+			updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			brew_log_visibility VARCHAR(20) NOT NULL DEFAULT 'private'
 		)
 	`)
 	if err != nil {
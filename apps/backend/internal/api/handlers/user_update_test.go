@@ -26,8 +26,10 @@ func setupTestDB(t *testing.T) *sql.DB {
             email VARCHAR(255) NOT NULL UNIQUE,
             password_hash VARCHAR(255) NOT NULL,
             password_salt VARCHAR(255) NOT NULL,
+            email_verified_at DATETIME,
             created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
-            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+            updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+            brew_log_visibility VARCHAR(20) NOT NULL DEFAULT 'private'
         );
         CREATE TRIGGER update_users_updated_at 
             AFTER UPDATE ON users
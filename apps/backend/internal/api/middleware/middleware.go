@@ -3,6 +3,7 @@ package middleware
 import (
 	"bytes"
 	"coffeeee/backend/internal/utils"
+	"context"
 	"encoding/json"
 	"io"
 	"log"
@@ -27,65 +28,83 @@ func (w *CustomResponseWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
-// Write captures the response body
+// Write captures the response body, unless it is binary
 func (w *CustomResponseWriter) Write(b []byte) (int, error) {
-	w.body.Write(b)
+	if isTextContent(w.Header().Get("Content-Type")) {
+		w.body.Write(b)
+	}
 	return w.ResponseWriter.Write(b)
 }
 
-// AuthMiddleware validates JWT from Authorization header and injects auth context
-func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
+// isTextContent reports whether a body of the content type is worth logging.
+// Bodies without a type yet are taken to be text.
+func isTextContent(contentType string) bool {
+	return contentType == "" || strings.HasPrefix(contentType, "text/") || strings.Contains(contentType, "json")
+}
+
+// SessionChecker reports whether the login session an access token was
+// issued for is still active, i.e. not logged out or revoked.
+type SessionChecker interface {
+	SessionActive(ctx context.Context, sessionID string) (bool, error)
+}
+
+// DevImpersonationHeader names the user, by ID or e-mail address, that a
+// request acts as when development impersonation is enabled.
+const DevImpersonationHeader = "X-Dev-Impersonate"
+
+// Impersonator resolves the user named in DevImpersonationHeader. It is only
+// set up in development with DEV_IMPERSONATION=true.
+type Impersonator interface {
+	Impersonate(ctx context.Context, user string) (int64, error)
+}
+
+// AuthMiddleware validates JWT from Authorization header and injects auth context.
+// With sessions set, tokens must belong to an active login session. With
+// impersonator set, a DevImpersonationHeader stands in for the token.
+func AuthMiddleware(jwtSecret string, sessions SessionChecker, impersonator Impersonator) func(http.Handler) http.Handler {
 	// NOTE: it's a higher-order function that returns a middleware function
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			devHeader := r.Header.Get("X-Dev-User")
-			if devHeader == "Baggie" {
-				userID := int64(1)
-				ctx := r.Context()
-				ctx = WithAuthenticatedUserID(ctx, userID)
-				next.ServeHTTP(w, r.WithContext(ctx))
+			if impersonator != nil && r.Header.Get(DevImpersonationHeader) != "" {
+				serveImpersonated(w, r, next, impersonator)
 				return
 			}
 
-			// Extract token from Authorization header
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
+			claims, userID, ok := authenticateRequest(r, jwtSecret, sessions)
+			if !ok {
 				writeAuthError(w, "Invalid or missing authentication token")
 				return
 			}
 
-			// Check Bearer format
-			if !strings.HasPrefix(authHeader, "Bearer ") {
-				writeAuthError(w, "Invalid or missing authentication token")
-				return
-			}
+			// Inject into context for downstream handlers
+			ctx := WithAuthClaims(r.Context(), claims)
+			ctx = WithAuthenticatedUserID(ctx, userID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-			tokenString := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
-			if tokenString == "" {
-				writeAuthError(w, "Invalid or missing authentication token")
+// OptionalAuthMiddleware is for public routes whose output depends on who is
+// asking. Requests without an Authorization header pass through anonymously;
+// a header that is present but invalid is still rejected with 401.
+func OptionalAuthMiddleware(jwtSecret string, sessions SessionChecker, impersonator Impersonator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if impersonator != nil && r.Header.Get(DevImpersonationHeader) != "" {
+				serveImpersonated(w, r, next, impersonator)
 				return
 			}
-
-			// Validate token with small leeway for clock skew
-			claims, err := utils.ValidateTokenWithLeeway(tokenString, jwtSecret, 60*time.Second)
-			if err != nil {
-				writeAuthError(w, "Invalid or missing authentication token")
+			if r.Header.Get("Authorization") == "" {
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			// Derive user ID from `sub` claim
-			var userID int64
-			if claims != nil && claims.Subject != "" {
-				if uid, parseErr := strconv.ParseInt(claims.Subject, 10, 64); parseErr == nil {
-					userID = uid
-				}
-			}
-			if userID == 0 {
+			claims, userID, ok := authenticateRequest(r, jwtSecret, sessions)
+			if !ok {
 				writeAuthError(w, "Invalid or missing authentication token")
 				return
 			}
 
-			// Inject into context for downstream handlers
 			ctx := WithAuthClaims(r.Context(), claims)
 			ctx = WithAuthenticatedUserID(ctx, userID)
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -93,6 +112,75 @@ func AuthMiddleware(jwtSecret string) func(http.Handler) http.Handler {
 	}
 }
 
+// serveImpersonated serves r as the user named in DevImpersonationHeader.
+// Every impersonated request is logged, so it cannot go unnoticed.
+func serveImpersonated(w http.ResponseWriter, r *http.Request, next http.Handler, impersonator Impersonator) {
+	user := r.Header.Get(DevImpersonationHeader)
+	userID, err := impersonator.Impersonate(r.Context(), user)
+	if err != nil {
+		log.Printf("DEV IMPERSONATION refused for %q on %s %s: %v", user, r.Method, r.URL.Path, err)
+		writeAuthError(w, "Unknown user to impersonate")
+		return
+	}
+	log.Printf("DEV IMPERSONATION: %s %s as user %d (%s)", r.Method, r.URL.Path, userID, user)
+	next.ServeHTTP(w, r.WithContext(WithAuthenticatedUserID(r.Context(), userID)))
+}
+
+// authenticateRequest validates the Bearer token on r and returns its claims
+// and the user ID from the `sub` claim. With sessions set, the token's `sid`
+// must name an active session.
+func authenticateRequest(r *http.Request, jwtSecret string, sessions SessionChecker) (*utils.Claims, int64, bool) {
+	// Extract token from Authorization header
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return nil, 0, false
+	}
+
+	// Check Bearer format
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil, 0, false
+	}
+
+	tokenString := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+	if tokenString == "" {
+		return nil, 0, false
+	}
+
+	// Validate token with small leeway for clock skew
+	claims, err := utils.ValidateTokenWithLeeway(tokenString, jwtSecret, 60*time.Second)
+	if err != nil {
+		return nil, 0, false
+	}
+
+	// Derive user ID from `sub` claim
+	var userID int64
+	if claims != nil && claims.Subject != "" {
+		if uid, parseErr := strconv.ParseInt(claims.Subject, 10, 64); parseErr == nil {
+			userID = uid
+		}
+	}
+	if userID == 0 {
+		return nil, 0, false
+	}
+
+	// Tokens without a session cannot be revoked, so they are not accepted
+	// once sessions are checked
+	if sessions != nil {
+		if claims.SessionID == "" {
+			return nil, 0, false
+		}
+		active, err := sessions.SessionActive(r.Context(), claims.SessionID)
+		if err != nil {
+			log.Printf("check session %s: %v", claims.SessionID, err)
+			return nil, 0, false
+		}
+		if !active {
+			return nil, 0, false
+		}
+	}
+	return claims, userID, true
+}
+
 func writeAuthError(w http.ResponseWriter, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusUnauthorized)
@@ -102,20 +190,37 @@ func writeAuthError(w http.ResponseWriter, message string) {
 	})
 }
 
-// LoggingMiddleware logs the requested URL and body
+// carriesCredentials reports whether the request or response body of r holds
+// passwords or tokens: everything under /auth/ and sign-up.
+func carriesCredentials(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, "/api/v1/auth/") || (r.URL.Path == "/api/v1/users" && r.Method == http.MethodPost)
+}
+
+// LoggingMiddleware logs the requested URL and body. Bodies with credentials
+// are not logged. Multipart uploads are passed through unread, so the
+// handler's size limit still bounds memory, and binary response bodies are
+// left out.
 func LoggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("<<<<<<< Requested URL: %s", r.URL.String())
-		bodyBytes, err := io.ReadAll(r.Body)
-		if err != nil {
-			log.Printf("Error reading body: %v", err)
-			http.Error(w, "Unable to read request body", http.StatusBadRequest)
-			return
-		}
+		redact := carriesCredentials(r)
+		switch {
+		case redact:
+			log.Printf("Request Body: <credentials, not logged>")
+		case strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/"):
+			log.Printf("Request Body: <multipart, %d bytes, not logged>", r.ContentLength)
+		default:
+			bodyBytes, err := io.ReadAll(r.Body)
+			if err != nil {
+				log.Printf("Error reading body: %v", err)
+				http.Error(w, "Unable to read request body", http.StatusBadRequest)
+				return
+			}
 
-		// Restore the body for downstream handlers
-		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-		log.Printf("Request Body: %s", string(bodyBytes))
+			// Restore the body for downstream handlers
+			r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+			log.Printf("Request Body: %s", string(bodyBytes))
+		}
 
 		// Wrap the ResponseWriter to capture response data
 		customWriter := &CustomResponseWriter{
@@ -130,7 +235,14 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		log.Printf("Response Status: %d %s", customWriter.statusCode,
 			http.StatusText(customWriter.statusCode))
 		log.Printf("Response Headers: %v", customWriter.Header())
-		log.Printf("Response Body: %s", customWriter.body.String())
+		switch contentType := customWriter.Header().Get("Content-Type"); {
+		case redact:
+			log.Printf("Response Body: <credentials, not logged>")
+		case isTextContent(contentType):
+			log.Printf("Response Body: %s", customWriter.body.String())
+		default:
+			log.Printf("Response Body: <%s, not logged>", contentType)
+		}
 		log.Printf(">>>>>>> End of Request: %s", r.URL.String())
 	})
 }
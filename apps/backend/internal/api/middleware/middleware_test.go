@@ -1,9 +1,16 @@
 package middleware
 
 import (
+    "bytes"
+    "context"
     "encoding/json"
+    "errors"
+    "io"
+    "log"
     "net/http"
     "net/http/httptest"
+    "os"
+    "strings"
     "testing"
     "time"
 
@@ -26,7 +33,7 @@ func TestMissingAuthorizationHeaderReturns401(t *testing.T) {
         w.WriteHeader(http.StatusOK)
     })
 
-    handler := AuthMiddleware(testSecret)(next)
+    handler := AuthMiddleware(testSecret, nil, nil)(next)
 
     req := httptest.NewRequest(http.MethodGet, "/protected", nil)
     rr := httptest.NewRecorder()
@@ -49,7 +56,7 @@ func TestMalformedTokenReturns401(t *testing.T) {
     next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         w.WriteHeader(http.StatusOK)
     })
-    handler := AuthMiddleware(testSecret)(next)
+    handler := AuthMiddleware(testSecret, nil, nil)(next)
 
     req := httptest.NewRequest(http.MethodGet, "/protected", nil)
     req.Header.Set("Authorization", "Bearer not-a-valid-jwt")
@@ -70,7 +77,7 @@ func TestExpiredTokenReturns401(t *testing.T) {
     next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
         w.WriteHeader(http.StatusOK)
     })
-    handler := AuthMiddleware(testSecret)(next)
+    handler := AuthMiddleware(testSecret, nil, nil)(next)
 
     req := httptest.NewRequest(http.MethodGet, "/protected", nil)
     req.Header.Set("Authorization", "Bearer "+token)
@@ -100,7 +107,7 @@ func TestValidTokenPassesAndContextPopulated(t *testing.T) {
         }
         w.WriteHeader(http.StatusOK)
     })
-    handler := AuthMiddleware(testSecret)(next)
+    handler := AuthMiddleware(testSecret, nil, nil)(next)
 
     req := httptest.NewRequest(http.MethodGet, "/protected", nil)
     req.Header.Set("Authorization", "Bearer "+token)
@@ -122,7 +129,7 @@ func TestIntegrationProtectedRoute(t *testing.T) {
     router := mux.NewRouter()
     api := router.PathPrefix("/api/v1").Subrouter()
     protected := api.PathPrefix("").Subrouter()
-    protected.Use(AuthMiddleware(testSecret))
+    protected.Use(AuthMiddleware(testSecret, nil, nil))
     protected.HandleFunc("/protected", func(w http.ResponseWriter, r *http.Request) {
         w.WriteHeader(http.StatusOK)
     }).Methods("GET")
@@ -149,3 +156,195 @@ func TestIntegrationProtectedRoute(t *testing.T) {
     }
 }
 
+
+func TestOptionalAuthMiddleware(t *testing.T) {
+    var gotUserID int64
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        gotUserID, _ = GetAuthenticatedUserID(r.Context())
+        w.WriteHeader(http.StatusOK)
+    })
+    handler := OptionalAuthMiddleware(testSecret, nil, nil)(next)
+
+    // Anonymous requests pass through without a user
+    req := httptest.NewRequest(http.MethodGet, "/public", nil)
+    rr := httptest.NewRecorder()
+    handler.ServeHTTP(rr, req)
+    if rr.Code != http.StatusOK || gotUserID != 0 {
+        t.Fatalf("expected anonymous 200, got %d with user %d", rr.Code, gotUserID)
+    }
+
+    // A valid token identifies the caller
+    token, err := utils.GenerateToken(321, "u@example.com", "user", testSecret, 1*time.Hour)
+    if err != nil {
+        t.Fatalf("failed generating token: %v", err)
+    }
+    req = httptest.NewRequest(http.MethodGet, "/public", nil)
+    req.Header.Set("Authorization", "Bearer "+token)
+    rr = httptest.NewRecorder()
+    handler.ServeHTTP(rr, req)
+    if rr.Code != http.StatusOK || gotUserID != 321 {
+        t.Fatalf("expected 200 for user 321, got %d with user %d", rr.Code, gotUserID)
+    }
+
+    // A bad token is rejected rather than silently treated as anonymous
+    req = httptest.NewRequest(http.MethodGet, "/public", nil)
+    req.Header.Set("Authorization", "Bearer not-a-token")
+    rr = httptest.NewRecorder()
+    handler.ServeHTTP(rr, req)
+    if rr.Code != http.StatusUnauthorized {
+        t.Fatalf("expected 401 for invalid token, got %d", rr.Code)
+    }
+}
+
+type fakeSessions map[string]bool
+
+func (s fakeSessions) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+    return s[sessionID], nil
+}
+
+func TestAuthMiddlewareChecksSessions(t *testing.T) {
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.WriteHeader(http.StatusOK)
+    })
+    handler := AuthMiddleware(testSecret, fakeSessions{"live": true, "revoked": false}, nil)(next)
+
+    for _, tc := range []struct {
+        sessionID string
+        want      int
+    }{
+        {"live", http.StatusOK},
+        {"revoked", http.StatusUnauthorized},
+        {"unknown", http.StatusUnauthorized},
+        // Tokens without a session cannot be revoked
+        {"", http.StatusUnauthorized},
+    } {
+        token, err := utils.GenerateAccessToken(456, "u@example.com", "user", tc.sessionID, testSecret, time.Hour)
+        if err != nil {
+            t.Fatalf("failed generating token: %v", err)
+        }
+        req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+        req.Header.Set("Authorization", "Bearer "+token)
+        rr := httptest.NewRecorder()
+        handler.ServeHTTP(rr, req)
+        if rr.Code != tc.want {
+            t.Fatalf("session %q: expected %d, got %d", tc.sessionID, tc.want, rr.Code)
+        }
+    }
+}
+
+type fakeImpersonator map[string]int64
+
+func (f fakeImpersonator) Impersonate(ctx context.Context, user string) (int64, error) {
+    if id, ok := f[user]; ok {
+        return id, nil
+    }
+    return 0, errors.New("no such user")
+}
+
+func TestDevImpersonation(t *testing.T) {
+    var gotUserID int64
+    next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        gotUserID, _ = GetAuthenticatedUserID(r.Context())
+        w.WriteHeader(http.StatusOK)
+    })
+    serve := func(handler http.Handler, headers map[string]string) int {
+        gotUserID = 0
+        req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+        for k, v := range headers {
+            req.Header.Set(k, v)
+        }
+        rr := httptest.NewRecorder()
+        handler.ServeHTTP(rr, req)
+        return rr.Code
+    }
+
+    // Without an impersonator no header gets past authentication
+    for _, mw := range []func(http.Handler) http.Handler{AuthMiddleware(testSecret, nil, nil), OptionalAuthMiddleware(testSecret, nil, nil)} {
+        for _, headers := range []map[string]string{{"X-Dev-User": "Baggie"}, {DevImpersonationHeader: "1"}} {
+            if code := serve(mw(next), headers); gotUserID != 0 {
+                t.Fatalf("%v: expected no user, got %d as user %d", headers, code, gotUserID)
+            }
+        }
+    }
+
+    users := fakeImpersonator{"2": 2, "seed@example.com": 3}
+    for _, mw := range []func(http.Handler) http.Handler{AuthMiddleware(testSecret, nil, users), OptionalAuthMiddleware(testSecret, nil, users)} {
+        handler := mw(next)
+        if code := serve(handler, map[string]string{DevImpersonationHeader: "2"}); code != http.StatusOK || gotUserID != 2 {
+            t.Fatalf("expected to act as user 2, got %d as user %d", code, gotUserID)
+        }
+        if code := serve(handler, map[string]string{DevImpersonationHeader: "seed@example.com"}); code != http.StatusOK || gotUserID != 3 {
+            t.Fatalf("expected to act as user 3, got %d as user %d", code, gotUserID)
+        }
+        if code := serve(handler, map[string]string{DevImpersonationHeader: "nobody@example.com"}); code != http.StatusUnauthorized {
+            t.Fatalf("expected 401 impersonating an unknown user, got %d", code)
+        }
+        // The old backdoor stays shut
+        if code := serve(handler, map[string]string{"X-Dev-User": "Baggie"}); gotUserID != 0 {
+            t.Fatalf("expected X-Dev-User ignored, got %d as user %d", code, gotUserID)
+        }
+    }
+}
+
+// captureLog sends the log to a buffer until the test ends.
+func captureLog(t *testing.T) *bytes.Buffer {
+    t.Helper()
+    var buf bytes.Buffer
+    log.SetOutput(&buf)
+    t.Cleanup(func() { log.SetOutput(os.Stderr) })
+    return &buf
+}
+
+func TestLoggingMiddlewareSkipsUploadsAndBinaryBodies(t *testing.T) {
+    logged := captureLog(t)
+    body := io.NopCloser(strings.NewReader("--b\r\nphoto-bytes\r\n--b--\r\n"))
+    handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        // The upload reaches the handler unread, so its size limit applies
+        if r.Body != body {
+            t.Errorf("expected the multipart body passed through unread")
+        }
+        w.Header().Set("Content-Type", "image/jpeg")
+        _, _ = w.Write([]byte("jpeg-bytes"))
+    }))
+
+    req := httptest.NewRequest(http.MethodPost, "/api/v1/coffees/1/photo", nil)
+    req.Body = body
+    req.Header.Set("Content-Type", "multipart/form-data; boundary=b")
+    rr := httptest.NewRecorder()
+    handler.ServeHTTP(rr, req)
+
+    if rr.Body.String() != "jpeg-bytes" {
+        t.Fatalf("expected the photo served, got %q", rr.Body.String())
+    }
+    if strings.Contains(logged.String(), "photo-bytes") || strings.Contains(logged.String(), "jpeg-bytes") {
+        t.Fatalf("expected no binary bodies logged, got %q", logged.String())
+    }
+}
+
+func TestLoggingMiddlewareRedactsCredentials(t *testing.T) {
+    logged := captureLog(t)
+    handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        w.Header().Set("Content-Type", "application/json")
+        _, _ = w.Write([]byte(`{"echo":` + string(body) + `,"token":"access-token"}`))
+    }))
+
+    for _, target := range []string{"/api/v1/auth/login", "/api/v1/auth/refresh", "/api/v1/auth/password-reset", "/api/v1/auth/verify-email", "/api/v1/users"} {
+        req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{"password":"hunter2"}`))
+        rr := httptest.NewRecorder()
+        handler.ServeHTTP(rr, req)
+        // The handler still gets the body
+        if !strings.Contains(rr.Body.String(), "hunter2") {
+            t.Fatalf("%s: expected the body passed on, got %q", target, rr.Body.String())
+        }
+    }
+    if strings.Contains(logged.String(), "hunter2") || strings.Contains(logged.String(), "access-token") {
+        t.Fatalf("expected no credentials logged, got %q", logged.String())
+    }
+
+    // Other bodies are still logged
+    handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/coffees", strings.NewReader(`{"name":"Geometry"}`)))
+    if !strings.Contains(logged.String(), "Geometry") {
+        t.Fatalf("expected the coffee body logged, got %q", logged.String())
+    }
+}
@@ -6,8 +6,11 @@ import (
 	"coffeeee/backend/internal/config"
 	"coffeeee/backend/internal/database"
 	"coffeeee/backend/internal/services"
+	"coffeeee/backend/internal/utils"
 	"database/sql"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -19,13 +22,54 @@ func Setup(db *sql.DB, cfg *config.Config) http.Handler {
 	// Initialize database queries and services
 	queries := database.NewQueries(db)
 	coffeeService := services.NewCoffeeService(queries)
+	photoService := services.NewPhotoService(queries, cfg.Server.UploadPath, cfg.Server.MaxFileSize)
+	brewLogService := services.NewBrewLogService(queries)
+	followService := services.NewFollowService(queries)
+	equipmentService := services.NewEquipmentService(queries)
+	roasterService := services.NewRoasterService(queries)
+	aiService := newAIService(cfg.AI)
+	extractionService := services.NewCoffeeExtractionService(queries, aiService)
+	recommender := services.NewBrewRecommender(queries, aiService)
+	tastingGraph := services.DefaultTastingGraph()
+	if cfg.AI.TastingGraphPath != "" {
+		var err error
+		if tastingGraph, err = services.LoadTastingGraph(cfg.AI.TastingGraphPath); err != nil {
+			log.Fatalf("Failed to load tasting questions: %v", err)
+		}
+	}
+	tastingService := services.NewTastingService(queries, tastingGraph)
+	refreshExpiry, err := time.ParseDuration(cfg.JWT.RefreshExpiry)
+	if err != nil {
+		// Default to 30 days if parsing fails
+		refreshExpiry = 30 * 24 * time.Hour
+	}
+	authSessions := services.NewAuthSessionService(queries, refreshExpiry)
+	passwords := newPasswords(cfg.Password)
+	accountService := services.NewAccountService(queries, newMailer(cfg.Mail), passwords, cfg.JWT.Secret, cfg.Mail.AppURL)
+	loginGuard := services.NewLoginGuard(queries, services.LoginPolicy{
+		MaxFailures:      cfg.Login.MaxFailures,
+		MaxFailuresPerIP: cfg.Login.MaxFailuresPerIP,
+		BackoffBase:      cfg.Login.BackoffBase,
+		Lockout:          cfg.Login.Lockout,
+	})
+
+	var impersonator middleware.Impersonator
+	if cfg.Server.DevImpersonation {
+		log.Printf("WARNING: DEV_IMPERSONATION is on; any request with an %s header acts as that user without signing in", middleware.DevImpersonationHeader)
+		impersonator = services.NewDevImpersonator(queries)
+	}
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db, cfg)
+	authHandler := handlers.NewAuthHandler(db, passwords, authSessions, accountService, loginGuard, cfg)
+	accountHandler := handlers.NewAccountHandler(accountService, cfg)
 	userHandler := handlers.NewUserHandler(db, cfg)
-	coffeeHandler := handlers.NewCoffeeHandler(coffeeService, cfg)
-	brewLogHandler := handlers.NewBrewLogHandler(db, cfg)
-	aiHandler := handlers.NewAIHandler(db, cfg)
+	coffeeHandler := handlers.NewCoffeeHandler(coffeeService, photoService, cfg)
+	brewLogHandler := handlers.NewBrewLogHandler(brewLogService, cfg)
+	followHandler := handlers.NewFollowHandler(followService, cfg)
+	equipmentHandler := handlers.NewEquipmentHandler(equipmentService, cfg)
+	roasterHandler := handlers.NewRoasterHandler(roasterService, coffeeService, cfg)
+	aiHandler := handlers.NewAIHandler(db, extractionService, recommender, cfg)
+	tastingHandler := handlers.NewTastingHandler(tastingService, cfg)
 
 	// Health check endpoint
 	router.HandleFunc("/health", handlers.HealthCheck).Methods("GET")
@@ -35,38 +79,80 @@ func Setup(db *sql.DB, cfg *config.Config) http.Handler {
 
 	// Public routes
 	api.HandleFunc("/auth/login", authHandler.Login).Methods("POST")
+	api.HandleFunc("/auth/refresh", authHandler.Refresh).Methods("POST")
+	api.HandleFunc("/auth/verify-email", accountHandler.VerifyEmail).Methods("POST")
+	api.HandleFunc("/auth/password-reset/request", accountHandler.RequestPasswordReset).Methods("POST")
+	api.HandleFunc("/auth/password-reset", accountHandler.ResetPassword).Methods("POST")
 	api.HandleFunc("/users", authHandler.Register).Methods("POST")
+	api.HandleFunc("/brew-methods", handlers.ListBrewMethods).Methods("GET")
 
 	// Protected routes
 	// NOTE: `protected` inherits from `api`, i.e., it will have the same prefix `/api/v1`
 	protected := api.PathPrefix("").Subrouter()
 	// NOTE: everything under `protected` will require authentication
-	protected.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+	protected.Use(middleware.AuthMiddleware(cfg.JWT.Secret, authSessions, impersonator))
 
 	// User routes
 	protected.HandleFunc("/users/me", userHandler.GetProfile).Methods("GET")
 	protected.HandleFunc("/users/me", userHandler.UpdateProfile).Methods("PUT")
 	protected.HandleFunc("/users/me", userHandler.DeleteProfile).Methods("DELETE")
+	protected.HandleFunc("/auth/verify-email/request", accountHandler.RequestVerification).Methods("POST")
+	protected.HandleFunc("/users/{userId:[0-9]+}/follow", followHandler.Follow).Methods("POST")
+	protected.HandleFunc("/users/{userId:[0-9]+}/follow", followHandler.Unfollow).Methods("DELETE")
+	protected.HandleFunc("/users/me/followers", followHandler.ListFollowers).Methods("GET")
+	protected.HandleFunc("/users/me/followers/{userId:[0-9]+}/approve", followHandler.ApproveFollower).Methods("POST")
+	protected.HandleFunc("/users/me/followers/{userId:[0-9]+}", followHandler.RemoveFollower).Methods("DELETE")
 	// User coffees
 	protected.HandleFunc("/coffees", coffeeHandler.ListForUser).Methods("GET")
 	protected.HandleFunc("/coffees", coffeeHandler.CreateForUser).Methods("POST")
+	protected.HandleFunc("/coffees/inventory", coffeeHandler.Inventory).Methods("GET")
 	protected.HandleFunc("/coffees/{id:[0-9]+}", coffeeHandler.Get).Methods("GET")
-	protected.HandleFunc("/coffees/{id:[0-9]+}", coffeeHandler.Update).Methods("PUT")
+	protected.HandleFunc("/coffees/{id:[0-9]+}", coffeeHandler.Update).Methods("PUT", "PATCH")
 	protected.HandleFunc("/coffees/{id:[0-9]+}", coffeeHandler.Delete).Methods("DELETE")
+	protected.HandleFunc("/coffees/{id:[0-9]+}/bag", coffeeHandler.Bag).Methods("GET")
+	protected.HandleFunc("/coffees/{id:[0-9]+}/photo", coffeeHandler.UploadPhoto).Methods("POST")
+	protected.HandleFunc("/coffees/{id:[0-9]+}/photo", coffeeHandler.Photo).Methods("GET")
+	protected.HandleFunc("/coffees/{id:[0-9]+}/photo/thumbnail", coffeeHandler.PhotoThumbnail).Methods("GET")
 
 	// Brew log routes
 	protected.HandleFunc("/brewlogs", brewLogHandler.List).Methods("GET")
 	protected.HandleFunc("/brewlogs", brewLogHandler.Create).Methods("POST")
 	protected.HandleFunc("/brewlogs/{id:[0-9]+}", brewLogHandler.Get).Methods("GET")
-	protected.HandleFunc("/brewlogs/{id:[0-9]+}", brewLogHandler.Update).Methods("PUT")
+	protected.HandleFunc("/brewlogs/{id:[0-9]+}", brewLogHandler.Update).Methods("PUT", "PATCH")
 	protected.HandleFunc("/brewlogs/{id:[0-9]+}", brewLogHandler.Delete).Methods("DELETE")
+	protected.HandleFunc("/brewlogs/{id:[0-9]+}/repeat", brewLogHandler.Repeat).Methods("POST")
+
+	// Equipment routes
+	protected.HandleFunc("/equipment", equipmentHandler.List).Methods("GET")
+	protected.HandleFunc("/equipment", equipmentHandler.Create).Methods("POST")
+	protected.HandleFunc("/equipment/{id:[0-9]+}", equipmentHandler.Get).Methods("GET")
+	protected.HandleFunc("/equipment/{id:[0-9]+}", equipmentHandler.Update).Methods("PUT", "PATCH")
+	protected.HandleFunc("/equipment/{id:[0-9]+}", equipmentHandler.Delete).Methods("DELETE")
+
+	// Roaster directory, shared by all users
+	protected.HandleFunc("/roasters", roasterHandler.List).Methods("GET")
+	protected.HandleFunc("/roasters", roasterHandler.Create).Methods("POST")
+	protected.HandleFunc("/roasters/{id:[0-9]+}", roasterHandler.Get).Methods("GET")
+	protected.HandleFunc("/roasters/{id:[0-9]+}/coffees", roasterHandler.Coffees).Methods("GET")
 
 	// AI routes
 	protected.HandleFunc("/ai/extract-coffee", aiHandler.ExtractCoffee).Methods("POST")
 	protected.HandleFunc("/ai/recommendation", aiHandler.GetRecommendation).Methods("POST")
 
-	// Public user brew logs
-	api.HandleFunc("/users/{userId:[0-9]+}/brewlogs", brewLogHandler.ListByUser).Methods("GET")
+	// Tasting assistant sessions
+	protected.HandleFunc("/tasting-sessions", tastingHandler.List).Methods("GET")
+	protected.HandleFunc("/tasting-sessions", tastingHandler.Create).Methods("POST")
+	protected.HandleFunc("/tasting-sessions/{id:[0-9]+}", tastingHandler.Get).Methods("GET")
+	protected.HandleFunc("/tasting-sessions/{id:[0-9]+}/answers", tastingHandler.Answer).Methods("POST")
+	protected.HandleFunc("/tasting-sessions/{id:[0-9]+}/answers/last", tastingHandler.Back).Methods("DELETE")
+	protected.HandleFunc("/tasting-sessions/{id:[0-9]+}/brew-log", tastingHandler.Attach).Methods("PUT")
+
+	// Public user brew logs; a token is optional and only widens what is visible
+	public := api.PathPrefix("").Subrouter()
+	public.Use(middleware.OptionalAuthMiddleware(cfg.JWT.Secret, authSessions, impersonator))
+	public.HandleFunc("/users/{userId:[0-9]+}/brewlogs", brewLogHandler.ListByUser).Methods("GET")
+	// Signing out takes the refresh token, or the access token when it is still valid
+	public.HandleFunc("/auth/logout", authHandler.Logout).Methods("POST")
 
 	// Apply middleware
 	router.Use(middleware.LoggingMiddleware)
@@ -74,10 +160,14 @@ func Setup(db *sql.DB, cfg *config.Config) http.Handler {
 	router.Use(middleware.RecoveryMiddleware)
 
 	// CORS configuration
+	allowedHeaders := []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"}
+	if impersonator != nil {
+		allowedHeaders = append(allowedHeaders, middleware.DevImpersonationHeader)
+	}
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   cfg.Server.AllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   allowedHeaders,
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
@@ -85,3 +175,61 @@ func Setup(db *sql.DB, cfg *config.Config) http.Handler {
 
 	return corsHandler.Handler(router)
 }
+
+// newAIService picks the AI provider from config. A hosted provider falls
+// back to the offline rules when it fails.
+func newAIService(cfg config.AIConfig) services.AIService {
+	provider := cfg.Provider
+	if provider == "" {
+		switch {
+		case cfg.OpenAIAPIKey != "":
+			provider = "openai"
+		case cfg.GeminiAPIKey != "":
+			provider = "gemini"
+		}
+	}
+	rules := services.NewRulesAIService()
+	opts := services.AIClientOptions{Timeout: cfg.Timeout, MaxRetries: cfg.MaxRetries}
+	switch provider {
+	case "openai":
+		opts.APIKey, opts.BaseURL, opts.Model = cfg.OpenAIAPIKey, cfg.OpenAIBaseURL, cfg.OpenAIModel
+		return services.NewFallbackAIService(services.NewOpenAIService(opts), rules)
+	case "gemini":
+		opts.APIKey, opts.BaseURL, opts.Model = cfg.GeminiAPIKey, cfg.GeminiBaseURL, cfg.GeminiModel
+		return services.NewFallbackAIService(services.NewGeminiService(opts), rules)
+	default:
+		return rules
+	}
+}
+
+// newPasswords picks the password hashing scheme from config. An unset
+// algorithm, as in tests, means Argon2id.
+func newPasswords(cfg config.PasswordConfig) *utils.Passwords {
+	if cfg.Algorithm == "bcrypt" {
+		return utils.NewPasswords(utils.BcryptHasher{Cost: cfg.BcryptCost})
+	}
+	return utils.NewPasswords(utils.Argon2idHasher{
+		Memory:      cfg.Argon2Memory,
+		Iterations:  cfg.Argon2Iterations,
+		Parallelism: cfg.Argon2Parallelism,
+	})
+}
+
+// newMailer picks how account e-mails are sent from config. An unset driver,
+// as in tests, logs them.
+func newMailer(cfg config.MailConfig) services.Mailer {
+	switch cfg.Driver {
+	case "smtp":
+		return &services.SMTPMailer{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}
+	case "file":
+		return &services.FileMailer{Path: cfg.FilePath, From: cfg.From}
+	default:
+		return &services.LogMailer{From: cfg.From}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +15,9 @@ type Config struct {
 	Database DatabaseConfig
 	AI       AIConfig
 	JWT      JWTConfig
+	Password PasswordConfig
+	Mail     MailConfig
+	Login    LoginConfig
 }
 
 type ServerConfig struct {
@@ -23,6 +27,10 @@ type ServerConfig struct {
 	AllowedOrigins []string
 	UploadPath     string
 	MaxFileSize    int64
+	// DevImpersonation lets requests act as any user with the
+	// X-Dev-Impersonate header instead of a token. Only allowed in
+	// development.
+	DevImpersonation bool
 }
 
 type DatabaseConfig struct {
@@ -31,13 +39,76 @@ type DatabaseConfig struct {
 }
 
 type AIConfig struct {
-	OpenAIAPIKey string
-	GeminiAPIKey string
+	// Provider is "openai", "gemini" or "rules". Empty picks the first
+	// provider with an API key, then the offline rules.
+	Provider      string
+	OpenAIAPIKey  string
+	OpenAIBaseURL string
+	OpenAIModel   string
+	GeminiAPIKey  string
+	GeminiBaseURL string
+	GeminiModel   string
+	Timeout       time.Duration
+	MaxRetries    int
+	// TastingGraphPath is a JSON question graph for the tasting assistant;
+	// empty uses the built-in flavor wheel.
+	TastingGraphPath string
 }
 
 type JWTConfig struct {
 	Secret string
-	Expiry string
+	// Expiry is the lifetime of access tokens; clients renew them with a
+	// refresh token, which lasts RefreshExpiry.
+	Expiry        string
+	RefreshExpiry string
+}
+
+// PasswordConfig picks how new passwords are hashed. Stored hashes of the
+// other scheme or older parameters still verify and are upgraded at the next
+// login. Zero parameters take the hasher's defaults.
+type PasswordConfig struct {
+	// Algorithm is "argon2id" or "bcrypt".
+	Algorithm string
+	// Argon2Memory is in KiB.
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	BcryptCost        int
+}
+
+// MailConfig is how account e-mails, such as e-mail verification and password
+// reset, are sent.
+type MailConfig struct {
+	// Driver is "smtp", "file" (append to FilePath) or "log". file and log
+	// keep whole e-mails, sign-in links included, so they are only allowed
+	// in development.
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	FilePath     string
+	// AppURL is the frontend address that links in e-mails point to.
+	AppURL string
+}
+
+// LoginConfig throttles failed sign-ins per e-mail address and per client
+// IP. Zero values take the defaults.
+type LoginConfig struct {
+	// MaxFailures failed attempts for one e-mail address lock it for
+	// Lockout. The attempts before it back off exponentially from
+	// BackoffBase.
+	MaxFailures int
+	// MaxFailuresPerIP failed attempts from one IP, for any addresses,
+	// lock the IP for Lockout.
+	MaxFailuresPerIP int
+	BackoffBase      time.Duration
+	// Lockout is also how long failures are remembered.
+	Lockout time.Duration
+	// TrustProxyHeaders takes the client IP from X-Forwarded-For, which
+	// is only safe behind a proxy that sets it.
+	TrustProxyHeaders bool
 }
 
 func Load() (*Config, error) {
@@ -49,25 +120,90 @@ func Load() (*Config, error) {
 
 	config := &Config{
 		Server: ServerConfig{
-			Port:           getEnv("PORT", "8080"),
-			Host:           getEnv("HOST", "0.0.0.0"),
-			Environment:    getEnv("ENV", "development"),
-			AllowedOrigins: getEnvSlice("ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
-			UploadPath:     getEnv("UPLOAD_PATH", "./uploads"),
-			MaxFileSize:    getEnvAsInt64("MAX_FILE_SIZE", 10*1024*1024), // 10MB default
+			Port:             getEnv("PORT", "8080"),
+			Host:             getEnv("HOST", "0.0.0.0"),
+			Environment:      getEnv("ENV", "development"),
+			AllowedOrigins:   getEnvSlice("ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
+			UploadPath:       getEnv("UPLOAD_PATH", "./uploads"),
+			MaxFileSize:      getEnvAsInt64("MAX_FILE_SIZE", 10*1024*1024), // 10MB default
+			DevImpersonation: getEnv("DEV_IMPERSONATION", "false") == "true",
 		},
 		Database: DatabaseConfig{
 			URL:            getEnv("DATABASE_URL", "./data/coffee.db"),
 			MigrationsPath: getEnv("DATABASE_MIGRATIONS_PATH", "./migrations"),
 		},
 		AI: AIConfig{
-			OpenAIAPIKey: getEnv("OPENAI_API_KEY", ""),
-			GeminiAPIKey: getEnv("GEMINI_API_KEY", ""),
+			Provider:         strings.ToLower(getEnv("AI_PROVIDER", "")),
+			OpenAIAPIKey:     getEnv("OPENAI_API_KEY", ""),
+			OpenAIBaseURL:    getEnv("OPENAI_BASE_URL", ""),
+			OpenAIModel:      getEnv("OPENAI_MODEL", ""),
+			GeminiAPIKey:     getEnv("GEMINI_API_KEY", ""),
+			GeminiBaseURL:    getEnv("GEMINI_BASE_URL", ""),
+			GeminiModel:      getEnv("GEMINI_MODEL", ""),
+			Timeout:          getEnvAsDuration("AI_TIMEOUT", 20*time.Second),
+			MaxRetries:       int(getEnvAsInt64("AI_MAX_RETRIES", 2)),
+			TastingGraphPath: getEnv("TASTING_GRAPH_PATH", ""),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production"),
-			Expiry: getEnv("JWT_EXPIRY", "24h"),
+			Secret:        getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production"),
+			Expiry:        getEnv("JWT_EXPIRY", "15m"),
+			RefreshExpiry: getEnv("JWT_REFRESH_EXPIRY", "720h"),
+		},
+		Password: PasswordConfig{
+			Algorithm:         strings.ToLower(getEnv("PASSWORD_HASH_ALGORITHM", "argon2id")),
+			Argon2Memory:      uint32(getEnvAsInt64("PASSWORD_ARGON2_MEMORY", 64*1024)),
+			Argon2Iterations:  uint32(getEnvAsInt64("PASSWORD_ARGON2_ITERATIONS", 3)),
+			Argon2Parallelism: uint8(getEnvAsInt64("PASSWORD_ARGON2_PARALLELISM", 2)),
+			BcryptCost:        int(getEnvAsInt64("PASSWORD_BCRYPT_COST", 12)),
 		},
+		Mail: MailConfig{
+			Driver:       strings.ToLower(getEnv("MAIL_DRIVER", "log")),
+			From:         getEnv("MAIL_FROM", "Coffee Companion <no-reply@localhost>"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			FilePath:     getEnv("MAIL_FILE_PATH", "./data/mail.log"),
+			AppURL:       strings.TrimRight(getEnv("APP_URL", "http://localhost:3000"), "/"),
+		},
+		Login: LoginConfig{
+			MaxFailures:       int(getEnvAsInt64("LOGIN_MAX_FAILURES", 10)),
+			MaxFailuresPerIP:  int(getEnvAsInt64("LOGIN_MAX_FAILURES_PER_IP", 50)),
+			BackoffBase:       getEnvAsDuration("LOGIN_BACKOFF_BASE", time.Second),
+			Lockout:           getEnvAsDuration("LOGIN_LOCKOUT", 15*time.Minute),
+			TrustProxyHeaders: getEnv("TRUST_PROXY_HEADERS", "false") == "true",
+		},
+	}
+
+	// Impersonation skips authentication, so it must not reach a real deployment
+	if config.Server.DevImpersonation && !config.IsDevelopment() {
+		return nil, fmt.Errorf("DEV_IMPERSONATION is only allowed with ENV=development, got ENV=%q", config.Server.Environment)
+	}
+
+	switch config.AI.Provider {
+	case "", "openai", "gemini", "rules":
+	default:
+		return nil, fmt.Errorf("AI_PROVIDER must be openai, gemini or rules, got %q", config.AI.Provider)
+	}
+
+	switch config.Password.Algorithm {
+	case "argon2id", "bcrypt":
+	default:
+		return nil, fmt.Errorf("PASSWORD_HASH_ALGORITHM must be argon2id or bcrypt, got %q", config.Password.Algorithm)
+	}
+
+	switch config.Mail.Driver {
+	case "log", "file":
+		// Mailed tokens reset passwords, so they must not end up in logs or files of a real deployment
+		if !config.IsDevelopment() {
+			return nil, fmt.Errorf("MAIL_DRIVER=%s is only allowed with ENV=development, use MAIL_DRIVER=smtp with ENV=%q", config.Mail.Driver, config.Server.Environment)
+		}
+	case "smtp":
+		if config.Mail.SMTPHost == "" {
+			return nil, fmt.Errorf("MAIL_DRIVER=smtp needs SMTP_HOST")
+		}
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER must be smtp, file or log, got %q", config.Mail.Driver)
 	}
 
 	return config, nil
@@ -102,6 +238,15 @@ func getEnvAsInt64(key string, defaultValue int64) int64 {
 	return defaultValue
 }
 
+func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
 func getEnvSlice(key string, defaultValue []string) []string {
 	if value := os.Getenv(key); value != "" {
 		// Simple comma-separated values
@@ -0,0 +1,53 @@
+-- name: GetAccount :one
+SELECT id, email, username, email_verified_at
+FROM users
+WHERE id = ?;
+
+-- name: GetAccountByEmail :one
+SELECT id, email, username, email_verified_at
+FROM users
+WHERE email = ?;
+
+-- name: CreateUserToken :exec
+INSERT INTO user_tokens (user_id, purpose, email, token_hash, expires_at)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: GetUserToken :one
+SELECT *
+FROM user_tokens
+WHERE token_hash = ? AND purpose = ?;
+
+-- name: UseUserToken :execrows
+-- Affects no row when the token was already used.
+UPDATE user_tokens
+SET used_at = ?
+WHERE id = ? AND used_at IS NULL;
+
+-- name: UseUserTokensForPurpose :exec
+-- Retires a user's other outstanding tokens once one of them was used.
+UPDATE user_tokens
+SET used_at = ?
+WHERE user_id = ? AND purpose = ? AND used_at IS NULL;
+
+-- name: MarkEmailVerified :execrows
+-- Affects no row when the user's e-mail is no longer the verified address.
+UPDATE users
+SET email_verified_at = ?
+WHERE id = ? AND email = ?;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET password_hash = ?, password_salt = ''
+WHERE id = ?;
+
+-- name: GetPasswordResetThrottle :one
+SELECT *
+FROM password_reset_throttles
+WHERE kind = ? AND subject = ?;
+
+-- name: UpsertPasswordResetThrottle :exec
+INSERT INTO password_reset_throttles (kind, subject, requests, window_started_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (kind, subject) DO UPDATE SET
+    requests = excluded.requests,
+    window_started_at = excluded.window_started_at;
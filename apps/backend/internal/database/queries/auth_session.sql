@@ -0,0 +1,42 @@
+-- name: CreateAuthSession :exec
+INSERT INTO auth_sessions (id, user_id)
+VALUES (?, ?);
+
+-- name: GetAuthSession :one
+SELECT *
+FROM auth_sessions
+WHERE id = ?;
+
+-- name: RevokeAuthSession :exec
+UPDATE auth_sessions
+SET revoked_at = ?, revoked_reason = ?
+WHERE id = ? AND revoked_at IS NULL;
+
+-- name: CreateRefreshToken :exec
+INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+VALUES (?, ?, ?);
+
+-- name: GetRefreshTokenByHash :one
+SELECT *
+FROM refresh_tokens
+WHERE token_hash = ?;
+
+-- name: UseRefreshToken :execrows
+-- Affects no row when the token was already used, as by a concurrent refresh.
+UPDATE refresh_tokens
+SET used_at = ?
+WHERE id = ? AND used_at IS NULL;
+
+-- name: DeleteExpiredRefreshTokens :exec
+DELETE FROM refresh_tokens
+WHERE expires_at < ?;
+
+-- name: GetAuthUser :one
+SELECT id, email, username
+FROM users
+WHERE id = ?;
+
+-- name: RevokeAuthSessionsForUser :exec
+UPDATE auth_sessions
+SET revoked_at = ?, revoked_reason = ?
+WHERE user_id = ? AND revoked_at IS NULL;
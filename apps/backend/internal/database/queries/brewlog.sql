@@ -0,0 +1,37 @@
+-- name: GetCoffeeOwnerID :one
+SELECT user_id
+FROM coffees
+WHERE id = ?;
+
+-- name: CreateBrewLog :one
+INSERT INTO brew_logs (user_id, coffee_id, brew_method, coffee_weight, water_weight, grind_size, water_temperature, brew_time, tasting_notes, rating, visibility, tds, beverage_weight, grinder_id, brewer_id, grind_setting, yield_weight, pressure_profile, pre_infusion_time, basket_size, parent_brew_log_id)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetBrewLogByID :one
+SELECT *
+FROM brew_logs
+WHERE id = ?;
+
+-- name: ListRatedBrewLogsForCoffee :many
+-- Newest first; the recommender reads the most recent attempts at a coffee.
+SELECT *
+FROM brew_logs
+WHERE user_id = ? AND coffee_id = ? AND rating IS NOT NULL
+ORDER BY created_at DESC, id DESC
+LIMIT ?;
+
+-- name: UpdateBrewLog :exec
+UPDATE brew_logs
+SET coffee_id = ?, brew_method = ?, coffee_weight = ?, water_weight = ?, grind_size = ?, water_temperature = ?, brew_time = ?, tasting_notes = ?, rating = ?, visibility = ?, tds = ?, beverage_weight = ?, grinder_id = ?, brewer_id = ?, grind_setting = ?, yield_weight = ?, pressure_profile = ?, pre_infusion_time = ?, basket_size = ?
+WHERE id = ? AND user_id = ?;
+
+-- name: ReparentBrewLogs :exec
+-- Children of a deleted log move up to its parent so the lineage stays connected.
+UPDATE brew_logs
+SET parent_brew_log_id = sqlc.narg(new_parent_id)
+WHERE parent_brew_log_id = sqlc.arg(parent_brew_log_id) AND user_id = sqlc.arg(user_id);
+
+-- name: DeleteBrewLog :exec
+DELETE FROM brew_logs
+WHERE id = ? AND user_id = ?;
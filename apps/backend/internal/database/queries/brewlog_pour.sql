@@ -0,0 +1,19 @@
+-- name: CreateBrewLogPour :exec
+INSERT INTO brew_log_pours (brew_log_id, position, time_offset, water_amount, water_temperature)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: ListBrewLogPours :many
+SELECT *
+FROM brew_log_pours
+WHERE brew_log_id = ?
+ORDER BY position;
+
+-- name: ListBrewLogPoursForBrewLogs :many
+SELECT *
+FROM brew_log_pours
+WHERE brew_log_id IN (sqlc.slice(brew_log_ids))
+ORDER BY brew_log_id, position;
+
+-- name: DeleteBrewLogPours :exec
+DELETE FROM brew_log_pours
+WHERE brew_log_id = ?;
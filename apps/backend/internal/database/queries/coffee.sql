@@ -1,25 +1,32 @@
 -- name: ListCoffeesForUser :many
 SELECT 
-    id, name, origin, roaster, description, photo_path, created_at, updated_at 
+    id, user_id, name, origin, roaster, description, photo_path, created_at, updated_at, roast_date, purchase_date, opened_date, bag_size, price, remaining_weight, country, region, producer, process, varietals, altitude_min, altitude_max, roaster_id 
 FROM coffees 
 WHERE user_id = ? 
 ORDER BY created_at DESC;
 
 -- name: GetCoffeeByID :one
 SELECT 
-    id, user_id, name, origin, roaster, description, photo_path, created_at, updated_at 
+    id, user_id, name, origin, roaster, description, photo_path, created_at, updated_at, roast_date, purchase_date, opened_date, bag_size, price, remaining_weight, country, region, producer, process, varietals, altitude_min, altitude_max, roaster_id 
 FROM coffees 
 WHERE id = ? AND user_id = ?;
 
 -- name: FindCoffeeByUserAndDetails :one
+-- A coffee is the same coffee when all of its identifying text matches; NULL and '' are equal.
 SELECT id 
 FROM coffees 
-WHERE user_id = ? AND name = ? AND IFNULL(origin,'') = ? AND IFNULL(roaster,'') = ?;
+WHERE user_id = sqlc.arg(user_id) AND name = sqlc.arg(name)
+    AND IFNULL(origin, '') = IFNULL(sqlc.narg(origin), '')
+    AND IFNULL(roaster, '') = IFNULL(sqlc.narg(roaster), '')
+    AND IFNULL(country, '') = IFNULL(sqlc.narg(country), '')
+    AND IFNULL(region, '') = IFNULL(sqlc.narg(region), '')
+    AND IFNULL(producer, '') = IFNULL(sqlc.narg(producer), '')
+    AND IFNULL(process, '') = IFNULL(sqlc.narg(process), '');
 
 -- name: CreateCoffee :one
-INSERT INTO coffees (user_id, name, origin, roaster, description, photo_path) 
-VALUES (?, ?, ?, ?, ?, ?) 
-RETURNING id, user_id, name, origin, roaster, description, photo_path, created_at, updated_at;
+INSERT INTO coffees (user_id, name, origin, roaster, description, photo_path, roast_date, purchase_date, opened_date, bag_size, price, remaining_weight, country, region, producer, process, varietals, altitude_min, altitude_max, roaster_id) 
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) 
+RETURNING id, user_id, name, origin, roaster, description, photo_path, created_at, updated_at, roast_date, purchase_date, opened_date, bag_size, price, remaining_weight, country, region, producer, process, varietals, altitude_min, altitude_max, roaster_id;
 
 -- name: UpdateCoffeePhotoPath :exec
 UPDATE coffees 
@@ -31,3 +38,57 @@ SELECT
     origin, roaster, description, photo_path, created_at, updated_at 
 FROM coffees 
 WHERE id = ?;
+
+-- name: UpdateCoffee :exec
+UPDATE coffees
+SET name = ?, origin = ?, roaster = ?, description = ?, photo_path = ?, roast_date = ?, purchase_date = ?, opened_date = ?, bag_size = ?, price = ?, remaining_weight = ?, country = ?, region = ?, producer = ?, process = ?, varietals = ?, altitude_min = ?, altitude_max = ?, roaster_id = ?
+WHERE id = ? AND user_id = ?;
+
+-- name: CountBrewLogsForCoffee :one
+SELECT COUNT(*)
+FROM brew_logs
+WHERE coffee_id = ?;
+
+-- name: CountUserCoffeesWithPhoto :one
+SELECT COUNT(*)
+FROM coffees
+WHERE user_id = ? AND photo_path = ?;
+
+-- name: DeleteBrewLogPoursForCoffee :exec
+DELETE FROM brew_log_pours
+WHERE brew_log_id IN (SELECT id FROM brew_logs WHERE coffee_id = ?);
+
+-- name: ClearBrewLogParentsForCoffee :exec
+-- Repeats moved to another coffee lose their link to the logs being removed.
+UPDATE brew_logs
+SET parent_brew_log_id = NULL
+WHERE parent_brew_log_id IN (SELECT id FROM brew_logs WHERE coffee_id = ?1) AND coffee_id != ?1;
+
+-- name: DeleteBrewLogsForCoffee :exec
+DELETE FROM brew_logs
+WHERE coffee_id = ?;
+
+-- name: DeleteCoffee :exec
+DELETE FROM coffees
+WHERE id = ? AND user_id = ?;
+
+-- name: ListCoffeePhotoPaths :many
+SELECT DISTINCT photo_path
+FROM coffees
+WHERE photo_path IS NOT NULL;
+
+-- name: AdjustCoffeeRemainingWeight :exec
+-- Brew logs consume (negative delta) or give back grams; untracked bags are left alone.
+UPDATE coffees
+SET remaining_weight = MIN(MAX(remaining_weight + sqlc.arg(delta), 0), COALESCE(bag_size, remaining_weight + sqlc.arg(delta)))
+WHERE id = sqlc.arg(id) AND remaining_weight IS NOT NULL;
+
+-- name: GetCoffeeAverageDose :one
+-- Average coffee_weight of the coffee's last 10 weighed brews.
+SELECT CAST(AVG(coffee_weight) AS REAL) AS average_dose, CAST(COUNT(*) AS INTEGER) AS brew_count
+FROM (
+    SELECT coffee_weight FROM brew_logs
+    WHERE coffee_id = ? AND coffee_weight IS NOT NULL
+    ORDER BY created_at DESC, id DESC
+    LIMIT 10
+);
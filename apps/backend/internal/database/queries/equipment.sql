@@ -0,0 +1,35 @@
+-- name: CreateEquipment :one
+INSERT INTO equipment (user_id, type, name, brand, model, notes, grind_scale_min, grind_scale_max, grind_scale_step)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetEquipmentByID :one
+SELECT *
+FROM equipment
+WHERE id = ? AND user_id = ?;
+
+-- name: ListEquipmentForUser :many
+SELECT *
+FROM equipment
+WHERE user_id = sqlc.arg(user_id) AND (sqlc.narg(type) IS NULL OR type = sqlc.narg(type))
+ORDER BY type, name, id;
+
+-- name: UpdateEquipment :exec
+UPDATE equipment
+SET name = ?, brand = ?, model = ?, notes = ?, grind_scale_min = ?, grind_scale_max = ?, grind_scale_step = ?
+WHERE id = ? AND user_id = ?;
+
+-- name: DeleteEquipment :exec
+DELETE FROM equipment
+WHERE id = ? AND user_id = ?;
+
+-- name: ClearBrewLogGrinder :exec
+-- The setting is a position on the deleted grinder's scale, so it goes too.
+UPDATE brew_logs
+SET grinder_id = NULL, grind_setting = NULL
+WHERE grinder_id = ? AND user_id = ?;
+
+-- name: ClearBrewLogBrewer :exec
+UPDATE brew_logs
+SET brewer_id = NULL
+WHERE brewer_id = ? AND user_id = ?;
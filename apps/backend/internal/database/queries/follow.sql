@@ -0,0 +1,54 @@
+-- name: GetUserBrewLogVisibility :one
+SELECT brew_log_visibility
+FROM users
+WHERE id = ?;
+
+-- name: CreateFollow :exec
+INSERT OR IGNORE INTO follows (follower_id, followee_id)
+VALUES (?, ?);
+
+-- name: DeleteFollow :exec
+DELETE FROM follows
+WHERE follower_id = ? AND followee_id = ?;
+
+-- name: ApproveFollow :execrows
+UPDATE follows
+SET approved_at = COALESCE(approved_at, CURRENT_TIMESTAMP)
+WHERE follower_id = ? AND followee_id = ?;
+
+-- name: ListFollowers :many
+-- Pending requests first, then approved followers, newest first.
+SELECT f.follower_id, u.username, f.created_at, f.approved_at
+FROM follows f
+JOIN users u ON u.id = f.follower_id
+WHERE f.followee_id = ?
+ORDER BY f.approved_at IS NOT NULL, f.created_at DESC, f.follower_id DESC;
+
+-- name: ListVisibleBrewLogsForUser :many
+SELECT
+    bl.id, bl.user_id, bl.coffee_id, bl.brew_method, bl.coffee_weight, bl.water_weight, bl.grind_size,
+    bl.water_temperature, bl.brew_time, bl.tasting_notes, bl.rating, bl.tds, bl.beverage_weight,
+    bl.yield_weight, bl.pressure_profile, bl.pre_infusion_time, bl.basket_size, bl.created_at,
+    u.username,
+    c.name AS coffee_name,
+    c.roaster AS coffee_roaster,
+    CAST(COALESCE(bl.visibility, u.brew_log_visibility) AS TEXT) AS effective_visibility,
+    CAST(bl.created_at AS TEXT) AS created_at_key
+FROM brew_logs bl
+JOIN users u ON u.id = bl.user_id
+JOIN coffees c ON c.id = bl.coffee_id AND c.user_id = bl.user_id
+WHERE bl.user_id = sqlc.arg(owner_id)
+  AND (
+    bl.user_id = sqlc.arg(viewer_id)
+    OR COALESCE(bl.visibility, u.brew_log_visibility) = 'public'
+    OR (
+      COALESCE(bl.visibility, u.brew_log_visibility) = 'followers'
+      AND EXISTS (
+        SELECT 1 FROM follows f
+        WHERE f.follower_id = sqlc.arg(viewer_id) AND f.followee_id = bl.user_id AND f.approved_at IS NOT NULL
+      )
+    )
+  )
+  AND (sqlc.arg(after_id) = 0 OR (bl.created_at, bl.id) < (sqlc.arg(after_created_at), sqlc.arg(after_id)))
+ORDER BY bl.created_at DESC, bl.id DESC
+LIMIT sqlc.arg(limit);
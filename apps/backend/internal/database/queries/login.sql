@@ -0,0 +1,20 @@
+-- name: CreateLoginAttempt :exec
+INSERT INTO login_attempts (email, ip_address, user_agent, user_id, outcome)
+VALUES (?, ?, ?, ?, ?);
+
+-- name: GetLoginThrottle :one
+SELECT *
+FROM login_throttles
+WHERE kind = ? AND subject = ?;
+
+-- name: UpsertLoginThrottle :exec
+INSERT INTO login_throttles (kind, subject, failures, last_failure_at, blocked_until)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (kind, subject) DO UPDATE SET
+    failures = excluded.failures,
+    last_failure_at = excluded.last_failure_at,
+    blocked_until = excluded.blocked_until;
+
+-- name: DeleteLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE kind = ? AND subject = ?;
@@ -0,0 +1,42 @@
+-- name: CreateRoaster :one
+-- Returns no row when a roaster with the same name (ignoring case) exists.
+INSERT INTO roasters (name, website, location, created_by, match_key)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetRoasterByID :one
+SELECT *
+FROM roasters
+WHERE id = ?;
+
+-- name: GetRoasterByName :one
+SELECT *
+FROM roasters
+WHERE name = ? COLLATE NOCASE;
+
+-- name: ListRoasterMatchCandidates :many
+-- Roasters whose match keys are between the given lengths, in name order.
+SELECT *
+FROM roasters
+WHERE length(match_key) BETWEEN sqlc.arg(min_length) AND sqlc.arg(max_length)
+ORDER BY name COLLATE NOCASE, id;
+
+-- name: ListRoasters :many
+SELECT *
+FROM roasters
+ORDER BY name COLLATE NOCASE, id;
+
+-- name: FillRoasterDetails :exec
+-- Adds a website or location the directory does not have yet; known values
+-- are kept. Only the user who added the roaster may fill them in.
+UPDATE roasters
+SET website = COALESCE(website, sqlc.narg(website)), location = COALESCE(location, sqlc.narg(location))
+WHERE id = sqlc.arg(id) AND created_by = sqlc.arg(created_by);
+
+-- name: GetRoasterRatingForUser :one
+-- Average rating and count of the user's brew logs of coffees from the roaster.
+SELECT CAST(AVG(b.rating) AS REAL) AS average_rating, CAST(COUNT(b.id) AS INTEGER) AS brew_count
+FROM brew_logs b
+JOIN coffees c ON c.id = b.coffee_id
+WHERE c.roaster_id = ? AND c.user_id = ?;
@@ -0,0 +1,45 @@
+-- name: CreateTastingSession :one
+INSERT INTO tasting_sessions (user_id, brew_log_id, brew_method, current_question_id)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetTastingSession :one
+SELECT *
+FROM tasting_sessions
+WHERE id = ? AND user_id = ?;
+
+-- name: ListTastingSessionsForBrewLog :many
+SELECT *
+FROM tasting_sessions
+WHERE user_id = ? AND brew_log_id = ?
+ORDER BY created_at DESC, id DESC;
+
+-- name: UpdateTastingSessionProgress :execrows
+-- Only applies to a session still at the question and answers it was read
+-- with, so concurrent answers cannot overwrite each other.
+UPDATE tasting_sessions
+SET status = ?, current_question_id = ?, answers = ?, note = ?, completed_at = ?
+WHERE id = ? AND user_id = ?
+  AND current_question_id = sqlc.arg(read_question_id) AND answers = sqlc.arg(read_answers);
+
+-- name: AttachTastingSession :exec
+UPDATE tasting_sessions
+SET brew_log_id = ?
+WHERE id = ? AND user_id = ?;
+
+-- name: FillBrewLogTastingNotes :exec
+-- Notes the user already wrote are kept.
+UPDATE brew_logs
+SET tasting_notes = ?
+WHERE id = ? AND user_id = ? AND trim(IFNULL(tasting_notes, '')) = '';
+
+-- name: ClearTastingSessionBrewLog :exec
+-- Sessions outlive the brew log they were attached to.
+UPDATE tasting_sessions
+SET brew_log_id = NULL
+WHERE brew_log_id = ?;
+
+-- name: ClearTastingSessionsForCoffee :exec
+UPDATE tasting_sessions
+SET brew_log_id = NULL
+WHERE brew_log_id IN (SELECT id FROM brew_logs WHERE coffee_id = ?);
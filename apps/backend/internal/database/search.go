@@ -0,0 +1,53 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// coffeeSearchSchema indexes the searchable coffee columns with FTS5. The
+// index reads its text from coffees (external content) and triggers keep it
+// in step with every write.
+const coffeeSearchSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS coffees_fts USING fts5(
+    name, roaster, origin, description,
+    content='coffees', content_rowid='id',
+    tokenize='unicode61 remove_diacritics 2'
+);
+CREATE TRIGGER IF NOT EXISTS coffees_fts_ai AFTER INSERT ON coffees BEGIN
+    INSERT INTO coffees_fts(rowid, name, roaster, origin, description)
+    VALUES (NEW.id, NEW.name, NEW.roaster, NEW.origin, NEW.description);
+END;
+CREATE TRIGGER IF NOT EXISTS coffees_fts_ad AFTER DELETE ON coffees BEGIN
+    INSERT INTO coffees_fts(coffees_fts, rowid, name, roaster, origin, description)
+    VALUES ('delete', OLD.id, OLD.name, OLD.roaster, OLD.origin, OLD.description);
+END;
+CREATE TRIGGER IF NOT EXISTS coffees_fts_au AFTER UPDATE ON coffees BEGIN
+    INSERT INTO coffees_fts(coffees_fts, rowid, name, roaster, origin, description)
+    VALUES ('delete', OLD.id, OLD.name, OLD.roaster, OLD.origin, OLD.description);
+    INSERT INTO coffees_fts(rowid, name, roaster, origin, description)
+    VALUES (NEW.id, NEW.name, NEW.roaster, NEW.origin, NEW.description);
+END;
+INSERT INTO coffees_fts(coffees_fts) VALUES ('rebuild');
+`
+
+// EnableCoffeeSearch sets up the full-text index used by coffee search and
+// rebuilds it, so rows written while it was disabled are picked up. It is not
+// a migration because FTS5 is only compiled into go-sqlite3 with the
+// sqlite_fts5 build tag; without it this reports false, removes the triggers
+// a previous build may have left (they would make every coffee write fail)
+// and search falls back to substring matching.
+func EnableCoffeeSearch(conn *sql.DB) (bool, error) {
+	if _, err := conn.Exec(coffeeSearchSchema); err != nil {
+		if !strings.Contains(err.Error(), "no such module: fts5") {
+			return false, err
+		}
+		_, err := conn.Exec(`
+            DROP TRIGGER IF EXISTS coffees_fts_ai;
+            DROP TRIGGER IF EXISTS coffees_fts_ad;
+            DROP TRIGGER IF EXISTS coffees_fts_au;
+        `)
+		return false, err
+	}
+	return true, nil
+}
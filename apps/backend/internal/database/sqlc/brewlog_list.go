@@ -0,0 +1,206 @@
+package db
+
+// This file is maintained by hand: sqlc cannot generate optional filters or a
+// caller-chosen ORDER BY, so the brew log list query lives next to the
+// generated code and reuses its Queries/BrewLog types.
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Derived brew metrics as SQL. They must agree with the Go calculations in
+// services/brew_metrics.go: ratio is water (or an espresso's yield) per gram
+// of coffee, strength is the TDS reading, and extraction yield is TDS x
+// beverage weight / dose, with the beverage weight taken from the espresso
+// yield or estimated as water minus 2 g retained per gram of grounds when it
+// was not measured. A beverage weight of 0, measured or estimated, gives no
+// extraction yield rather than 0%.
+const (
+	brewRatioExpr       = "(COALESCE(yield_weight, water_weight) / NULLIF(coffee_weight, 0))"
+	extractionYieldExpr = "(tds * NULLIF(COALESCE(beverage_weight, yield_weight, MAX(water_weight - 2 * coffee_weight, 0)), 0) / NULLIF(coffee_weight, 0))"
+	strengthExpr        = "tds"
+)
+
+// BrewLogSortExprs maps the supported sort keys to SQL expressions.
+// NULL ratings/ratios sort as 0 so keyset pagination stays total.
+var BrewLogSortExprs = map[string]string{
+	"date":            "created_at",
+	"rating":          "COALESCE(rating, 0)",
+	"ratio":           "COALESCE(" + brewRatioExpr + ", 0)",
+	"extractionYield": "COALESCE(" + extractionYieldExpr + ", 0)",
+	"strength":        "COALESCE(" + strengthExpr + ", 0)",
+}
+
+type ListBrewLogsParams struct {
+	UserID        int64
+	CoffeeID      sql.NullInt64
+	BrewMethod    sql.NullString
+	GrinderID     sql.NullInt64
+	BrewerID      sql.NullInt64
+	MinRating     sql.NullInt64
+	MaxRating     sql.NullInt64
+	CreatedFrom   sql.NullTime // inclusive
+	CreatedBefore sql.NullTime // exclusive
+	// Inclusive ranges over the derived metrics; logs without a value are excluded.
+	MinRatio           sql.NullFloat64
+	MaxRatio           sql.NullFloat64
+	MinExtractionYield sql.NullFloat64
+	MaxExtractionYield sql.NullFloat64
+	MinStrength        sql.NullFloat64
+	MaxStrength        sql.NullFloat64
+	SortBy             string // key of BrewLogSortExprs
+	Descending         bool
+	// Keyset position: rows strictly after (AfterSortKey, AfterID) in sort order.
+	// Ignored when AfterID is 0.
+	AfterSortKey any
+	AfterID      int64
+	Limit        int64
+}
+
+type ListBrewLogsRow struct {
+	BrewLog
+	// SortKey is the raw value of the sort expression, used to build cursors.
+	SortKey any
+}
+
+// ListBrewLogs returns a page of a user's brew logs. Filters line up with
+// idx_brew_logs_user_id, _coffee_id, _brew_method and _created_at.
+func (q *Queries) ListBrewLogs(ctx context.Context, arg ListBrewLogsParams) ([]ListBrewLogsRow, error) {
+	sortExpr, ok := BrewLogSortExprs[arg.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported brew log sort %q", arg.SortBy)
+	}
+
+	where := []string{"user_id = ?"}
+	args := []any{arg.UserID}
+	if arg.CoffeeID.Valid {
+		where = append(where, "coffee_id = ?")
+		args = append(args, arg.CoffeeID.Int64)
+	}
+	if arg.BrewMethod.Valid {
+		where = append(where, "brew_method = ?")
+		args = append(args, arg.BrewMethod.String)
+	}
+	if arg.GrinderID.Valid {
+		where = append(where, "grinder_id = ?")
+		args = append(args, arg.GrinderID.Int64)
+	}
+	if arg.BrewerID.Valid {
+		where = append(where, "brewer_id = ?")
+		args = append(args, arg.BrewerID.Int64)
+	}
+	if arg.MinRating.Valid {
+		where = append(where, "rating >= ?")
+		args = append(args, arg.MinRating.Int64)
+	}
+	if arg.MaxRating.Valid {
+		where = append(where, "rating <= ?")
+		args = append(args, arg.MaxRating.Int64)
+	}
+	if arg.CreatedFrom.Valid {
+		where = append(where, "created_at >= ?")
+		args = append(args, sqliteTimestamp(arg.CreatedFrom.Time))
+	}
+	if arg.CreatedBefore.Valid {
+		where = append(where, "created_at < ?")
+		args = append(args, sqliteTimestamp(arg.CreatedBefore.Time))
+	}
+
+	for _, r := range []struct {
+		expr string
+		op   string
+		v    sql.NullFloat64
+	}{
+		{brewRatioExpr, ">=", arg.MinRatio},
+		{brewRatioExpr, "<=", arg.MaxRatio},
+		{extractionYieldExpr, ">=", arg.MinExtractionYield},
+		{extractionYieldExpr, "<=", arg.MaxExtractionYield},
+		{strengthExpr, ">=", arg.MinStrength},
+		{strengthExpr, "<=", arg.MaxStrength},
+	} {
+		if r.v.Valid {
+			where = append(where, r.expr+" "+r.op+" ?")
+			args = append(args, r.v.Float64)
+		}
+	}
+
+	cmp, dir := ">", "ASC"
+	if arg.Descending {
+		cmp, dir = "<", "DESC"
+	}
+	if arg.AfterID != 0 {
+		where = append(where, "("+sortExpr+", id) "+cmp+" (?, ?)")
+		args = append(args, arg.AfterSortKey, arg.AfterID)
+	}
+
+	// created_at is cast for the sort key so the driver does not turn it into
+	// a time.Time whose layout no longer compares equal to the stored text.
+	keyExpr := sortExpr
+	if arg.SortBy == "date" {
+		keyExpr = "CAST(created_at AS TEXT)"
+	}
+	query := "SELECT id, user_id, coffee_id, brew_method, coffee_weight, water_weight, grind_size, water_temperature, brew_time, tasting_notes, rating, created_at, updated_at, visibility, tds, beverage_weight, grinder_id, brewer_id, grind_setting, yield_weight, pressure_profile, pre_infusion_time, basket_size, parent_brew_log_id, " + keyExpr +
+		" FROM brew_logs WHERE " + strings.Join(where, " AND ") +
+		" ORDER BY " + sortExpr + " " + dir + ", id " + dir +
+		" LIMIT ?"
+	args = append(args, arg.Limit)
+
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListBrewLogsRow{}
+	for rows.Next() {
+		var i ListBrewLogsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.CoffeeID,
+			&i.BrewMethod,
+			&i.CoffeeWeight,
+			&i.WaterWeight,
+			&i.GrindSize,
+			&i.WaterTemperature,
+			&i.BrewTime,
+			&i.TastingNotes,
+			&i.Rating,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Visibility,
+			&i.Tds,
+			&i.BeverageWeight,
+			&i.GrinderID,
+			&i.BrewerID,
+			&i.GrindSetting,
+			&i.YieldWeight,
+			&i.PressureProfile,
+			&i.PreInfusionTime,
+			&i.BasketSize,
+			&i.ParentBrewLogID,
+			&i.SortKey,
+		); err != nil {
+			return nil, err
+		}
+		if b, isBytes := i.SortKey.([]byte); isBytes {
+			i.SortKey = string(b)
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// sqliteTimestamp matches the text layout SQLite's CURRENT_TIMESTAMP writes.
+func sqliteTimestamp(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
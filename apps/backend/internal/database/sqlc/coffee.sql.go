@@ -11,31 +11,119 @@ import (
 	"time"
 )
 
+const adjustCoffeeRemainingWeight = `-- name: AdjustCoffeeRemainingWeight :exec
+UPDATE coffees
+SET remaining_weight = MIN(MAX(remaining_weight + ?1, 0), COALESCE(bag_size, remaining_weight + ?1))
+WHERE id = ?2 AND remaining_weight IS NOT NULL
+`
+
+type AdjustCoffeeRemainingWeightParams struct {
+	Delta float64 `json:"delta"`
+	ID    int64   `json:"id"`
+}
+
+// Brew logs consume (negative delta) or give back grams; untracked bags are left alone.
+func (q *Queries) AdjustCoffeeRemainingWeight(ctx context.Context, arg AdjustCoffeeRemainingWeightParams) error {
+	_, err := q.db.ExecContext(ctx, adjustCoffeeRemainingWeight, arg.Delta, arg.ID)
+	return err
+}
+
+const clearBrewLogParentsForCoffee = `-- name: ClearBrewLogParentsForCoffee :exec
+UPDATE brew_logs
+SET parent_brew_log_id = NULL
+WHERE parent_brew_log_id IN (SELECT id FROM brew_logs WHERE coffee_id = ?1) AND coffee_id != ?1
+`
+
+// Repeats moved to another coffee lose their link to the logs being removed.
+func (q *Queries) ClearBrewLogParentsForCoffee(ctx context.Context, coffeeID int64) error {
+	_, err := q.db.ExecContext(ctx, clearBrewLogParentsForCoffee, coffeeID)
+	return err
+}
+
+const countBrewLogsForCoffee = `-- name: CountBrewLogsForCoffee :one
+SELECT COUNT(*)
+FROM brew_logs
+WHERE coffee_id = ?
+`
+
+func (q *Queries) CountBrewLogsForCoffee(ctx context.Context, coffeeID int64) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBrewLogsForCoffee, coffeeID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserCoffeesWithPhoto = `-- name: CountUserCoffeesWithPhoto :one
+SELECT COUNT(*)
+FROM coffees
+WHERE user_id = ? AND photo_path = ?
+`
+
+type CountUserCoffeesWithPhotoParams struct {
+	UserID    int64          `json:"user_id"`
+	PhotoPath sql.NullString `json:"photo_path"`
+}
+
+func (q *Queries) CountUserCoffeesWithPhoto(ctx context.Context, arg CountUserCoffeesWithPhotoParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUserCoffeesWithPhoto, arg.UserID, arg.PhotoPath)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createCoffee = `-- name: CreateCoffee :one
-INSERT INTO coffees (user_id, name, origin, roaster, description, photo_path) 
-VALUES (?, ?, ?, ?, ?, ?) 
-RETURNING id, user_id, name, origin, roaster, description, photo_path, created_at, updated_at
+INSERT INTO coffees (user_id, name, origin, roaster, description, photo_path, roast_date, purchase_date, opened_date, bag_size, price, remaining_weight, country, region, producer, process, varietals, altitude_min, altitude_max, roaster_id) 
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) 
+RETURNING id, user_id, name, origin, roaster, description, photo_path, created_at, updated_at, roast_date, purchase_date, opened_date, bag_size, price, remaining_weight, country, region, producer, process, varietals, altitude_min, altitude_max, roaster_id
 `
 
 type CreateCoffeeParams struct {
-	UserID      int64          `json:"user_id"`
-	Name        string         `json:"name"`
-	Origin      sql.NullString `json:"origin"`
-	Roaster     sql.NullString `json:"roaster"`
-	Description sql.NullString `json:"description"`
-	PhotoPath   sql.NullString `json:"photo_path"`
+	UserID          int64           `json:"user_id"`
+	Name            string          `json:"name"`
+	Origin          sql.NullString  `json:"origin"`
+	Roaster         sql.NullString  `json:"roaster"`
+	Description     sql.NullString  `json:"description"`
+	PhotoPath       sql.NullString  `json:"photo_path"`
+	RoastDate       sql.NullString  `json:"roast_date"`
+	PurchaseDate    sql.NullString  `json:"purchase_date"`
+	OpenedDate      sql.NullString  `json:"opened_date"`
+	BagSize         sql.NullFloat64 `json:"bag_size"`
+	Price           sql.NullFloat64 `json:"price"`
+	RemainingWeight sql.NullFloat64 `json:"remaining_weight"`
+	Country         sql.NullString  `json:"country"`
+	Region          sql.NullString  `json:"region"`
+	Producer        sql.NullString  `json:"producer"`
+	Process         sql.NullString  `json:"process"`
+	Varietals       sql.NullString  `json:"varietals"`
+	AltitudeMin     sql.NullInt64   `json:"altitude_min"`
+	AltitudeMax     sql.NullInt64   `json:"altitude_max"`
+	RoasterID       sql.NullInt64   `json:"roaster_id"`
 }
 
 type CreateCoffeeRow struct {
-	ID          int64          `json:"id"`
-	UserID      int64          `json:"user_id"`
-	Name        string         `json:"name"`
-	Origin      sql.NullString `json:"origin"`
-	Roaster     sql.NullString `json:"roaster"`
-	Description sql.NullString `json:"description"`
-	PhotoPath   sql.NullString `json:"photo_path"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
+	ID              int64           `json:"id"`
+	UserID          int64           `json:"user_id"`
+	Name            string          `json:"name"`
+	Origin          sql.NullString  `json:"origin"`
+	Roaster         sql.NullString  `json:"roaster"`
+	Description     sql.NullString  `json:"description"`
+	PhotoPath       sql.NullString  `json:"photo_path"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	RoastDate       sql.NullString  `json:"roast_date"`
+	PurchaseDate    sql.NullString  `json:"purchase_date"`
+	OpenedDate      sql.NullString  `json:"opened_date"`
+	BagSize         sql.NullFloat64 `json:"bag_size"`
+	Price           sql.NullFloat64 `json:"price"`
+	RemainingWeight sql.NullFloat64 `json:"remaining_weight"`
+	Country         sql.NullString  `json:"country"`
+	Region          sql.NullString  `json:"region"`
+	Producer        sql.NullString  `json:"producer"`
+	Process         sql.NullString  `json:"process"`
+	Varietals       sql.NullString  `json:"varietals"`
+	AltitudeMin     sql.NullInt64   `json:"altitude_min"`
+	AltitudeMax     sql.NullInt64   `json:"altitude_max"`
+	RoasterID       sql.NullInt64   `json:"roaster_id"`
 }
 
 func (q *Queries) CreateCoffee(ctx context.Context, arg CreateCoffeeParams) (CreateCoffeeRow, error) {
@@ -46,6 +134,20 @@ func (q *Queries) CreateCoffee(ctx context.Context, arg CreateCoffeeParams) (Cre
 		arg.Roaster,
 		arg.Description,
 		arg.PhotoPath,
+		arg.RoastDate,
+		arg.PurchaseDate,
+		arg.OpenedDate,
+		arg.BagSize,
+		arg.Price,
+		arg.RemainingWeight,
+		arg.Country,
+		arg.Region,
+		arg.Producer,
+		arg.Process,
+		arg.Varietals,
+		arg.AltitudeMin,
+		arg.AltitudeMax,
+		arg.RoasterID,
 	)
 	var i CreateCoffeeRow
 	err := row.Scan(
@@ -58,38 +160,125 @@ func (q *Queries) CreateCoffee(ctx context.Context, arg CreateCoffeeParams) (Cre
 		&i.PhotoPath,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RoastDate,
+		&i.PurchaseDate,
+		&i.OpenedDate,
+		&i.BagSize,
+		&i.Price,
+		&i.RemainingWeight,
+		&i.Country,
+		&i.Region,
+		&i.Producer,
+		&i.Process,
+		&i.Varietals,
+		&i.AltitudeMin,
+		&i.AltitudeMax,
+		&i.RoasterID,
 	)
 	return i, err
 }
 
+const deleteBrewLogPoursForCoffee = `-- name: DeleteBrewLogPoursForCoffee :exec
+DELETE FROM brew_log_pours
+WHERE brew_log_id IN (SELECT id FROM brew_logs WHERE coffee_id = ?)
+`
+
+func (q *Queries) DeleteBrewLogPoursForCoffee(ctx context.Context, coffeeID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteBrewLogPoursForCoffee, coffeeID)
+	return err
+}
+
+const deleteBrewLogsForCoffee = `-- name: DeleteBrewLogsForCoffee :exec
+DELETE FROM brew_logs
+WHERE coffee_id = ?
+`
+
+func (q *Queries) DeleteBrewLogsForCoffee(ctx context.Context, coffeeID int64) error {
+	_, err := q.db.ExecContext(ctx, deleteBrewLogsForCoffee, coffeeID)
+	return err
+}
+
+const deleteCoffee = `-- name: DeleteCoffee :exec
+DELETE FROM coffees
+WHERE id = ? AND user_id = ?
+`
+
+type DeleteCoffeeParams struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"user_id"`
+}
+
+func (q *Queries) DeleteCoffee(ctx context.Context, arg DeleteCoffeeParams) error {
+	_, err := q.db.ExecContext(ctx, deleteCoffee, arg.ID, arg.UserID)
+	return err
+}
+
 const findCoffeeByUserAndDetails = `-- name: FindCoffeeByUserAndDetails :one
 SELECT id 
 FROM coffees 
-WHERE user_id = ? AND name = ? AND IFNULL(origin,'') = ? AND IFNULL(roaster,'') = ?
+WHERE user_id = ?1 AND name = ?2
+    AND IFNULL(origin, '') = IFNULL(?3, '')
+    AND IFNULL(roaster, '') = IFNULL(?4, '')
+    AND IFNULL(country, '') = IFNULL(?5, '')
+    AND IFNULL(region, '') = IFNULL(?6, '')
+    AND IFNULL(producer, '') = IFNULL(?7, '')
+    AND IFNULL(process, '') = IFNULL(?8, '')
 `
 
 type FindCoffeeByUserAndDetailsParams struct {
-	UserID  int64          `json:"user_id"`
-	Name    string         `json:"name"`
-	Origin  sql.NullString `json:"origin"`
-	Roaster sql.NullString `json:"roaster"`
+	UserID   int64          `json:"user_id"`
+	Name     string         `json:"name"`
+	Origin   sql.NullString `json:"origin"`
+	Roaster  sql.NullString `json:"roaster"`
+	Country  sql.NullString `json:"country"`
+	Region   sql.NullString `json:"region"`
+	Producer sql.NullString `json:"producer"`
+	Process  sql.NullString `json:"process"`
 }
 
+// A coffee is the same coffee when all of its identifying text matches; NULL and ” are equal.
 func (q *Queries) FindCoffeeByUserAndDetails(ctx context.Context, arg FindCoffeeByUserAndDetailsParams) (int64, error) {
 	row := q.db.QueryRowContext(ctx, findCoffeeByUserAndDetails,
 		arg.UserID,
 		arg.Name,
 		arg.Origin,
 		arg.Roaster,
+		arg.Country,
+		arg.Region,
+		arg.Producer,
+		arg.Process,
 	)
 	var id int64
 	err := row.Scan(&id)
 	return id, err
 }
 
+const getCoffeeAverageDose = `-- name: GetCoffeeAverageDose :one
+SELECT CAST(AVG(coffee_weight) AS REAL) AS average_dose, CAST(COUNT(*) AS INTEGER) AS brew_count
+FROM (
+    SELECT coffee_weight FROM brew_logs
+    WHERE coffee_id = ? AND coffee_weight IS NOT NULL
+    ORDER BY created_at DESC, id DESC
+    LIMIT 10
+)
+`
+
+type GetCoffeeAverageDoseRow struct {
+	AverageDose sql.NullFloat64 `json:"average_dose"`
+	BrewCount   int64           `json:"brew_count"`
+}
+
+// Average coffee_weight of the coffee's last 10 weighed brews.
+func (q *Queries) GetCoffeeAverageDose(ctx context.Context, coffeeID int64) (GetCoffeeAverageDoseRow, error) {
+	row := q.db.QueryRowContext(ctx, getCoffeeAverageDose, coffeeID)
+	var i GetCoffeeAverageDoseRow
+	err := row.Scan(&i.AverageDose, &i.BrewCount)
+	return i, err
+}
+
 const getCoffeeByID = `-- name: GetCoffeeByID :one
 SELECT 
-    id, user_id, name, origin, roaster, description, photo_path, created_at, updated_at 
+    id, user_id, name, origin, roaster, description, photo_path, created_at, updated_at, roast_date, purchase_date, opened_date, bag_size, price, remaining_weight, country, region, producer, process, varietals, altitude_min, altitude_max, roaster_id 
 FROM coffees 
 WHERE id = ? AND user_id = ?
 `
@@ -100,15 +289,29 @@ type GetCoffeeByIDParams struct {
 }
 
 type GetCoffeeByIDRow struct {
-	ID          int64          `json:"id"`
-	UserID      int64          `json:"user_id"`
-	Name        string         `json:"name"`
-	Origin      sql.NullString `json:"origin"`
-	Roaster     sql.NullString `json:"roaster"`
-	Description sql.NullString `json:"description"`
-	PhotoPath   sql.NullString `json:"photo_path"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
+	ID              int64           `json:"id"`
+	UserID          int64           `json:"user_id"`
+	Name            string          `json:"name"`
+	Origin          sql.NullString  `json:"origin"`
+	Roaster         sql.NullString  `json:"roaster"`
+	Description     sql.NullString  `json:"description"`
+	PhotoPath       sql.NullString  `json:"photo_path"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	RoastDate       sql.NullString  `json:"roast_date"`
+	PurchaseDate    sql.NullString  `json:"purchase_date"`
+	OpenedDate      sql.NullString  `json:"opened_date"`
+	BagSize         sql.NullFloat64 `json:"bag_size"`
+	Price           sql.NullFloat64 `json:"price"`
+	RemainingWeight sql.NullFloat64 `json:"remaining_weight"`
+	Country         sql.NullString  `json:"country"`
+	Region          sql.NullString  `json:"region"`
+	Producer        sql.NullString  `json:"producer"`
+	Process         sql.NullString  `json:"process"`
+	Varietals       sql.NullString  `json:"varietals"`
+	AltitudeMin     sql.NullInt64   `json:"altitude_min"`
+	AltitudeMax     sql.NullInt64   `json:"altitude_max"`
+	RoasterID       sql.NullInt64   `json:"roaster_id"`
 }
 
 func (q *Queries) GetCoffeeByID(ctx context.Context, arg GetCoffeeByIDParams) (GetCoffeeByIDRow, error) {
@@ -124,6 +327,20 @@ func (q *Queries) GetCoffeeByID(ctx context.Context, arg GetCoffeeByIDParams) (G
 		&i.PhotoPath,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RoastDate,
+		&i.PurchaseDate,
+		&i.OpenedDate,
+		&i.BagSize,
+		&i.Price,
+		&i.RemainingWeight,
+		&i.Country,
+		&i.Region,
+		&i.Producer,
+		&i.Process,
+		&i.Varietals,
+		&i.AltitudeMin,
+		&i.AltitudeMax,
+		&i.RoasterID,
 	)
 	return i, err
 }
@@ -158,23 +375,67 @@ func (q *Queries) GetCoffeeByIDOnly(ctx context.Context, id int64) (GetCoffeeByI
 	return i, err
 }
 
+const listCoffeePhotoPaths = `-- name: ListCoffeePhotoPaths :many
+SELECT DISTINCT photo_path
+FROM coffees
+WHERE photo_path IS NOT NULL
+`
+
+func (q *Queries) ListCoffeePhotoPaths(ctx context.Context) ([]sql.NullString, error) {
+	rows, err := q.db.QueryContext(ctx, listCoffeePhotoPaths)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []sql.NullString{}
+	for rows.Next() {
+		var photo_path sql.NullString
+		if err := rows.Scan(&photo_path); err != nil {
+			return nil, err
+		}
+		items = append(items, photo_path)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listCoffeesForUser = `-- name: ListCoffeesForUser :many
 SELECT 
-    id, name, origin, roaster, description, photo_path, created_at, updated_at 
+    id, user_id, name, origin, roaster, description, photo_path, created_at, updated_at, roast_date, purchase_date, opened_date, bag_size, price, remaining_weight, country, region, producer, process, varietals, altitude_min, altitude_max, roaster_id 
 FROM coffees 
 WHERE user_id = ? 
 ORDER BY created_at DESC
 `
 
 type ListCoffeesForUserRow struct {
-	ID          int64          `json:"id"`
-	Name        string         `json:"name"`
-	Origin      sql.NullString `json:"origin"`
-	Roaster     sql.NullString `json:"roaster"`
-	Description sql.NullString `json:"description"`
-	PhotoPath   sql.NullString `json:"photo_path"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
+	ID              int64           `json:"id"`
+	UserID          int64           `json:"user_id"`
+	Name            string          `json:"name"`
+	Origin          sql.NullString  `json:"origin"`
+	Roaster         sql.NullString  `json:"roaster"`
+	Description     sql.NullString  `json:"description"`
+	PhotoPath       sql.NullString  `json:"photo_path"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	RoastDate       sql.NullString  `json:"roast_date"`
+	PurchaseDate    sql.NullString  `json:"purchase_date"`
+	OpenedDate      sql.NullString  `json:"opened_date"`
+	BagSize         sql.NullFloat64 `json:"bag_size"`
+	Price           sql.NullFloat64 `json:"price"`
+	RemainingWeight sql.NullFloat64 `json:"remaining_weight"`
+	Country         sql.NullString  `json:"country"`
+	Region          sql.NullString  `json:"region"`
+	Producer        sql.NullString  `json:"producer"`
+	Process         sql.NullString  `json:"process"`
+	Varietals       sql.NullString  `json:"varietals"`
+	AltitudeMin     sql.NullInt64   `json:"altitude_min"`
+	AltitudeMax     sql.NullInt64   `json:"altitude_max"`
+	RoasterID       sql.NullInt64   `json:"roaster_id"`
 }
 
 func (q *Queries) ListCoffeesForUser(ctx context.Context, userID int64) ([]ListCoffeesForUserRow, error) {
@@ -188,6 +449,7 @@ func (q *Queries) ListCoffeesForUser(ctx context.Context, userID int64) ([]ListC
 		var i ListCoffeesForUserRow
 		if err := rows.Scan(
 			&i.ID,
+			&i.UserID,
 			&i.Name,
 			&i.Origin,
 			&i.Roaster,
@@ -195,6 +457,20 @@ func (q *Queries) ListCoffeesForUser(ctx context.Context, userID int64) ([]ListC
 			&i.PhotoPath,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.RoastDate,
+			&i.PurchaseDate,
+			&i.OpenedDate,
+			&i.BagSize,
+			&i.Price,
+			&i.RemainingWeight,
+			&i.Country,
+			&i.Region,
+			&i.Producer,
+			&i.Process,
+			&i.Varietals,
+			&i.AltitudeMin,
+			&i.AltitudeMax,
+			&i.RoasterID,
 		); err != nil {
 			return nil, err
 		}
@@ -209,6 +485,63 @@ func (q *Queries) ListCoffeesForUser(ctx context.Context, userID int64) ([]ListC
 	return items, nil
 }
 
+const updateCoffee = `-- name: UpdateCoffee :exec
+UPDATE coffees
+SET name = ?, origin = ?, roaster = ?, description = ?, photo_path = ?, roast_date = ?, purchase_date = ?, opened_date = ?, bag_size = ?, price = ?, remaining_weight = ?, country = ?, region = ?, producer = ?, process = ?, varietals = ?, altitude_min = ?, altitude_max = ?, roaster_id = ?
+WHERE id = ? AND user_id = ?
+`
+
+type UpdateCoffeeParams struct {
+	Name            string          `json:"name"`
+	Origin          sql.NullString  `json:"origin"`
+	Roaster         sql.NullString  `json:"roaster"`
+	Description     sql.NullString  `json:"description"`
+	PhotoPath       sql.NullString  `json:"photo_path"`
+	RoastDate       sql.NullString  `json:"roast_date"`
+	PurchaseDate    sql.NullString  `json:"purchase_date"`
+	OpenedDate      sql.NullString  `json:"opened_date"`
+	BagSize         sql.NullFloat64 `json:"bag_size"`
+	Price           sql.NullFloat64 `json:"price"`
+	RemainingWeight sql.NullFloat64 `json:"remaining_weight"`
+	Country         sql.NullString  `json:"country"`
+	Region          sql.NullString  `json:"region"`
+	Producer        sql.NullString  `json:"producer"`
+	Process         sql.NullString  `json:"process"`
+	Varietals       sql.NullString  `json:"varietals"`
+	AltitudeMin     sql.NullInt64   `json:"altitude_min"`
+	AltitudeMax     sql.NullInt64   `json:"altitude_max"`
+	RoasterID       sql.NullInt64   `json:"roaster_id"`
+	ID              int64           `json:"id"`
+	UserID          int64           `json:"user_id"`
+}
+
+func (q *Queries) UpdateCoffee(ctx context.Context, arg UpdateCoffeeParams) error {
+	_, err := q.db.ExecContext(ctx, updateCoffee,
+		arg.Name,
+		arg.Origin,
+		arg.Roaster,
+		arg.Description,
+		arg.PhotoPath,
+		arg.RoastDate,
+		arg.PurchaseDate,
+		arg.OpenedDate,
+		arg.BagSize,
+		arg.Price,
+		arg.RemainingWeight,
+		arg.Country,
+		arg.Region,
+		arg.Producer,
+		arg.Process,
+		arg.Varietals,
+		arg.AltitudeMin,
+		arg.AltitudeMax,
+		arg.RoasterID,
+		arg.ID,
+		arg.UserID,
+	)
+	return err
+}
+
 const updateCoffeePhotoPath = `-- name: UpdateCoffeePhotoPath :exec
 UPDATE coffees 
 SET photo_path = ? 
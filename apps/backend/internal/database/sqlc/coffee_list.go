@@ -0,0 +1,261 @@
+package db
+
+// This file is maintained by hand: sqlc cannot generate optional filters, a
+// caller-chosen ORDER BY or a join that only exists when SQLite was built with
+// FTS5, so the coffee search queries live next to the generated code and
+// reuse its Queries type.
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// coffeeAverageRatingExpr is a coffee's mean brew log rating; NULL without rated logs.
+const coffeeAverageRatingExpr = "(SELECT AVG(rating) FROM brew_logs b WHERE b.coffee_id = c.id AND b.rating IS NOT NULL)"
+
+// CoffeeSortExprs maps the supported sort keys to SQL expressions over the
+// matched rows. Unrated coffees sort as 0 so keyset pagination stays total.
+var CoffeeSortExprs = map[string]string{
+	"created":   "created_at",
+	"name":      "name COLLATE NOCASE",
+	"rating":    "COALESCE(average_rating, 0)",
+	"relevance": "relevance",
+}
+
+type SearchCoffeesParams struct {
+	UserID int64
+	// Terms are matched as word prefixes; a coffee must match all of them in
+	// its name, roaster, origin or description.
+	Terms   []string
+	Roaster sql.NullString
+	Origin  sql.NullString
+	// RoasterID keeps only coffees linked to that directory roaster.
+	RoasterID sql.NullInt64
+	// FullText selects the coffees_fts index; otherwise terms are matched as
+	// substrings and every coffee is equally relevant.
+	FullText   bool
+	SortBy     string // key of CoffeeSortExprs
+	Descending bool
+	// Keyset position: rows strictly after (AfterSortKey, AfterID) in sort order.
+	// Ignored when AfterID is 0.
+	AfterSortKey any
+	AfterID      int64
+	Limit        int64
+}
+
+type SearchCoffeesRow struct {
+	GetCoffeeByIDRow
+	AverageRating sql.NullFloat64
+	BrewCount     int64
+	// SortKey is the raw value of the sort expression, used to build cursors.
+	SortKey any
+}
+
+// CoffeeFacet is the number of matching coffees sharing one roaster or origin.
+type CoffeeFacet struct {
+	Value string
+	Count int64
+}
+
+type CoffeeFacets struct {
+	Total    int64
+	Roasters []CoffeeFacet
+	Origins  []CoffeeFacet
+}
+
+// HasCoffeeSearchIndex reports whether database.EnableCoffeeSearch set up the
+// FTS5 index for this database. Its insert trigger only exists while it is.
+func (q *Queries) HasCoffeeSearchIndex(ctx context.Context) (bool, error) {
+	var n int64
+	err := q.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'trigger' AND name = 'coffees_fts_ai'`).Scan(&n)
+	return n > 0, err
+}
+
+// SearchCoffees returns a page of a user's coffees matching the search.
+func (q *Queries) SearchCoffees(ctx context.Context, arg SearchCoffeesParams) ([]SearchCoffeesRow, error) {
+	sortExpr, ok := CoffeeSortExprs[arg.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported coffee sort %q", arg.SortBy)
+	}
+
+	matched, args := coffeeMatchQuery(arg)
+	where := []string{"1 = 1"}
+	cmp, dir := ">", "ASC"
+	if arg.Descending {
+		cmp, dir = "<", "DESC"
+	}
+	if arg.AfterID != 0 {
+		where = append(where, "("+sortExpr+", id) "+cmp+" (?, ?)")
+		args = append(args, arg.AfterSortKey, arg.AfterID)
+	}
+
+	// created_at is cast for the sort key so the driver does not turn it into
+	// a time.Time whose layout no longer compares equal to the stored text.
+	keyExpr := sortExpr
+	switch arg.SortBy {
+	case "created":
+		keyExpr = "CAST(created_at AS TEXT)"
+	case "name":
+		keyExpr = "name"
+	}
+	query := "WITH matched AS (" + matched + ") " +
+		"SELECT id, user_id, name, origin, roaster, description, photo_path, created_at, updated_at, roast_date, purchase_date, opened_date, bag_size, price, remaining_weight, " +
+		"country, region, producer, process, varietals, altitude_min, altitude_max, roaster_id, average_rating, brew_count, " + keyExpr +
+		" FROM matched WHERE " + strings.Join(where, " AND ") +
+		" ORDER BY " + sortExpr + " " + dir + ", id " + dir +
+		" LIMIT ?"
+	args = append(args, arg.Limit)
+
+	rows, err := q.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchCoffeesRow
+	for rows.Next() {
+		var i SearchCoffeesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Name,
+			&i.Origin,
+			&i.Roaster,
+			&i.Description,
+			&i.PhotoPath,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.RoastDate,
+			&i.PurchaseDate,
+			&i.OpenedDate,
+			&i.BagSize,
+			&i.Price,
+			&i.RemainingWeight,
+			&i.Country,
+			&i.Region,
+			&i.Producer,
+			&i.Process,
+			&i.Varietals,
+			&i.AltitudeMin,
+			&i.AltitudeMax,
+			&i.RoasterID,
+			&i.AverageRating,
+			&i.BrewCount,
+			&i.SortKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CoffeeSearchFacets counts the coffees matching a search by roaster and by
+// origin. Each facet ignores its own filter, so picking a roaster still shows
+// how many coffees the other roasters have; Total applies every filter.
+// Coffees without a roaster or origin are not counted in that facet. The
+// grouping uses idx_coffees_roaster and idx_coffees_origin.
+func (q *Queries) CoffeeSearchFacets(ctx context.Context, arg SearchCoffeesParams) (CoffeeFacets, error) {
+	var facets CoffeeFacets
+	matched, args := coffeeMatchQuery(arg)
+	if err := q.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+matched+")", args...).Scan(&facets.Total); err != nil {
+		return facets, err
+	}
+
+	var err error
+	byRoaster, byOrigin := arg, arg
+	byRoaster.Roaster = sql.NullString{}
+	byOrigin.Origin = sql.NullString{}
+	if facets.Roasters, err = q.coffeeFacet(ctx, "roaster", byRoaster); err != nil {
+		return facets, err
+	}
+	if facets.Origins, err = q.coffeeFacet(ctx, "origin", byOrigin); err != nil {
+		return facets, err
+	}
+	return facets, nil
+}
+
+func (q *Queries) coffeeFacet(ctx context.Context, column string, arg SearchCoffeesParams) ([]CoffeeFacet, error) {
+	matched, args := coffeeMatchQuery(arg)
+	rows, err := q.db.QueryContext(ctx, "SELECT "+column+", COUNT(*) FROM ("+matched+") WHERE "+column+" IS NOT NULL"+
+		" GROUP BY "+column+" ORDER BY COUNT(*) DESC, "+column+" COLLATE NOCASE", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	facets := []CoffeeFacet{}
+	for rows.Next() {
+		var f CoffeeFacet
+		if err := rows.Scan(&f.Value, &f.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, f)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return facets, rows.Err()
+}
+
+// coffeeMatchQuery selects the user's coffees matching the terms and filters,
+// with their average rating, brew count and search relevance (higher is better).
+func coffeeMatchQuery(arg SearchCoffeesParams) (string, []any) {
+	from := "coffees c"
+	relevance := "0.0"
+	where := []string{"c.user_id = ?"}
+	args := []any{arg.UserID}
+	terms := arg.Terms
+	switch {
+	case len(terms) > 0 && arg.FullText:
+		// bm25 is lower for better matches; a name hit counts most
+		from = "coffees c JOIN coffees_fts ON coffees_fts.rowid = c.id"
+		relevance = "-bm25(coffees_fts, 10.0, 5.0, 5.0, 1.0)"
+		where = append(where, "coffees_fts MATCH ?")
+		args = append(args, ftsQuery(terms))
+	case len(terms) > 0:
+		for _, t := range terms {
+			like := "%" + likeEscaper.Replace(t) + "%"
+			where = append(where, `(c.name LIKE ? ESCAPE '\' OR c.roaster LIKE ? ESCAPE '\' OR c.origin LIKE ? ESCAPE '\' OR c.description LIKE ? ESCAPE '\')`)
+			args = append(args, like, like, like, like)
+		}
+	}
+	if arg.Roaster.Valid {
+		where = append(where, "c.roaster = ?")
+		args = append(args, arg.Roaster.String)
+	}
+	if arg.Origin.Valid {
+		where = append(where, "c.origin = ?")
+		args = append(args, arg.Origin.String)
+	}
+	if arg.RoasterID.Valid {
+		where = append(where, "c.roaster_id = ?")
+		args = append(args, arg.RoasterID.Int64)
+	}
+
+	return "SELECT c.id, c.user_id, c.name, c.origin, c.roaster, c.description, c.photo_path, c.created_at, c.updated_at, " +
+		"c.roast_date, c.purchase_date, c.opened_date, c.bag_size, c.price, c.remaining_weight, " +
+		"c.country, c.region, c.producer, c.process, c.varietals, c.altitude_min, c.altitude_max, c.roaster_id, " +
+		coffeeAverageRatingExpr + " AS average_rating, " +
+		"(SELECT COUNT(*) FROM brew_logs b WHERE b.coffee_id = c.id) AS brew_count, " +
+		relevance + " AS relevance" +
+		" FROM " + from + " WHERE " + strings.Join(where, " AND "), args
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ftsQuery turns search terms into an FTS5 query that matches every term as
+// a word prefix. Terms are quoted so FTS5 operators in user input are plain text.
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"*`
+	}
+	return strings.Join(quoted, " ")
+}
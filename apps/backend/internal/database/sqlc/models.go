@@ -9,6 +9,14 @@ import (
 	"time"
 )
 
+type AuthSession struct {
+	ID            string         `json:"id"`
+	UserID        int64          `json:"user_id"`
+	CreatedAt     time.Time      `json:"created_at"`
+	RevokedAt     sql.NullTime   `json:"revoked_at"`
+	RevokedReason sql.NullString `json:"revoked_reason"`
+}
+
 type BrewLog struct {
 	ID               int64           `json:"id"`
 	UserID           int64           `json:"user_id"`
@@ -22,26 +30,155 @@ type BrewLog struct {
 	TastingNotes     sql.NullString  `json:"tasting_notes"`
 	Rating           sql.NullInt64   `json:"rating"`
 	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        sql.NullTime    `json:"updated_at"`
+	Visibility       sql.NullString  `json:"visibility"`
+	Tds              sql.NullFloat64 `json:"tds"`
+	BeverageWeight   sql.NullFloat64 `json:"beverage_weight"`
+	GrinderID        sql.NullInt64   `json:"grinder_id"`
+	BrewerID         sql.NullInt64   `json:"brewer_id"`
+	GrindSetting     sql.NullFloat64 `json:"grind_setting"`
+	YieldWeight      sql.NullFloat64 `json:"yield_weight"`
+	PressureProfile  sql.NullString  `json:"pressure_profile"`
+	PreInfusionTime  sql.NullInt64   `json:"pre_infusion_time"`
+	BasketSize       sql.NullFloat64 `json:"basket_size"`
+	ParentBrewLogID  sql.NullInt64   `json:"parent_brew_log_id"`
+}
+
+type BrewLogPour struct {
+	ID               int64           `json:"id"`
+	BrewLogID        int64           `json:"brew_log_id"`
+	Position         int64           `json:"position"`
+	TimeOffset       int64           `json:"time_offset"`
+	WaterAmount      float64         `json:"water_amount"`
+	WaterTemperature sql.NullFloat64 `json:"water_temperature"`
 }
 
 type Coffee struct {
-	UserID      int64          `json:"user_id"`
-	ID          int64          `json:"id"`
-	Name        string         `json:"name"`
-	Origin      sql.NullString `json:"origin"`
-	Roaster     sql.NullString `json:"roaster"`
-	Description sql.NullString `json:"description"`
-	PhotoPath   sql.NullString `json:"photo_path"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
+	UserID          int64           `json:"user_id"`
+	ID              int64           `json:"id"`
+	Name            string          `json:"name"`
+	Origin          sql.NullString  `json:"origin"`
+	Roaster         sql.NullString  `json:"roaster"`
+	Description     sql.NullString  `json:"description"`
+	PhotoPath       sql.NullString  `json:"photo_path"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	RoastDate       sql.NullString  `json:"roast_date"`
+	PurchaseDate    sql.NullString  `json:"purchase_date"`
+	OpenedDate      sql.NullString  `json:"opened_date"`
+	BagSize         sql.NullFloat64 `json:"bag_size"`
+	Price           sql.NullFloat64 `json:"price"`
+	RemainingWeight sql.NullFloat64 `json:"remaining_weight"`
+	Country         sql.NullString  `json:"country"`
+	Region          sql.NullString  `json:"region"`
+	Producer        sql.NullString  `json:"producer"`
+	Process         sql.NullString  `json:"process"`
+	Varietals       sql.NullString  `json:"varietals"`
+	AltitudeMin     sql.NullInt64   `json:"altitude_min"`
+	AltitudeMax     sql.NullInt64   `json:"altitude_max"`
+	RoasterID       sql.NullInt64   `json:"roaster_id"`
+}
+
+type Equipment struct {
+	ID             int64           `json:"id"`
+	UserID         int64           `json:"user_id"`
+	Type           string          `json:"type"`
+	Name           string          `json:"name"`
+	Brand          sql.NullString  `json:"brand"`
+	Model          sql.NullString  `json:"model"`
+	Notes          sql.NullString  `json:"notes"`
+	GrindScaleMin  sql.NullFloat64 `json:"grind_scale_min"`
+	GrindScaleMax  sql.NullFloat64 `json:"grind_scale_max"`
+	GrindScaleStep sql.NullFloat64 `json:"grind_scale_step"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+type Follow struct {
+	FollowerID int64        `json:"follower_id"`
+	FolloweeID int64        `json:"followee_id"`
+	CreatedAt  time.Time    `json:"created_at"`
+	ApprovedAt sql.NullTime `json:"approved_at"`
+}
+
+type LoginAttempt struct {
+	ID        int64         `json:"id"`
+	Email     string        `json:"email"`
+	IpAddress string        `json:"ip_address"`
+	UserAgent string        `json:"user_agent"`
+	UserID    sql.NullInt64 `json:"user_id"`
+	Outcome   string        `json:"outcome"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	Kind          string       `json:"kind"`
+	Subject       string       `json:"subject"`
+	Failures      int64        `json:"failures"`
+	LastFailureAt time.Time    `json:"last_failure_at"`
+	BlockedUntil  sql.NullTime `json:"blocked_until"`
+}
+
+type PasswordResetThrottle struct {
+	Kind            string    `json:"kind"`
+	Subject         string    `json:"subject"`
+	Requests        int64     `json:"requests"`
+	WindowStartedAt time.Time `json:"window_started_at"`
+}
+
+type RefreshToken struct {
+	ID        int64        `json:"id"`
+	SessionID string       `json:"session_id"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type Roaster struct {
+	ID        int64          `json:"id"`
+	Name      string         `json:"name"`
+	Website   sql.NullString `json:"website"`
+	Location  sql.NullString `json:"location"`
+	CreatedBy sql.NullInt64  `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	MatchKey  string         `json:"match_key"`
+}
+
+type TastingSession struct {
+	ID                int64          `json:"id"`
+	UserID            int64          `json:"user_id"`
+	BrewLogID         sql.NullInt64  `json:"brew_log_id"`
+	BrewMethod        sql.NullString `json:"brew_method"`
+	Status            string         `json:"status"`
+	CurrentQuestionID sql.NullString `json:"current_question_id"`
+	Answers           string         `json:"answers"`
+	Note              sql.NullString `json:"note"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	CompletedAt       sql.NullTime   `json:"completed_at"`
 }
 
 type User struct {
-	ID           int64     `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"password_hash"`
-	PasswordSalt string    `json:"password_salt"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID                int64        `json:"id"`
+	Username          string       `json:"username"`
+	Email             string       `json:"email"`
+	PasswordHash      string       `json:"password_hash"`
+	PasswordSalt      string       `json:"password_salt"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+	BrewLogVisibility string       `json:"brew_log_visibility"`
+	EmailVerifiedAt   sql.NullTime `json:"email_verified_at"`
+}
+
+type UserToken struct {
+	ID        int64        `json:"id"`
+	UserID    int64        `json:"user_id"`
+	Purpose   string       `json:"purpose"`
+	Email     string       `json:"email"`
+	TokenHash string       `json:"token_hash"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
 }
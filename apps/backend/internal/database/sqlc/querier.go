@@ -6,15 +6,110 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 )
 
 type Querier interface {
+	// Brew logs consume (negative delta) or give back grams; untracked bags are left alone.
+	AdjustCoffeeRemainingWeight(ctx context.Context, arg AdjustCoffeeRemainingWeightParams) error
+	ApproveFollow(ctx context.Context, arg ApproveFollowParams) (int64, error)
+	AttachTastingSession(ctx context.Context, arg AttachTastingSessionParams) error
+	ClearBrewLogBrewer(ctx context.Context, arg ClearBrewLogBrewerParams) error
+	// The setting is a position on the deleted grinder's scale, so it goes too.
+	ClearBrewLogGrinder(ctx context.Context, arg ClearBrewLogGrinderParams) error
+	// Repeats moved to another coffee lose their link to the logs being removed.
+	ClearBrewLogParentsForCoffee(ctx context.Context, coffeeID int64) error
+	// Sessions outlive the brew log they were attached to.
+	ClearTastingSessionBrewLog(ctx context.Context, brewLogID sql.NullInt64) error
+	ClearTastingSessionsForCoffee(ctx context.Context, coffeeID int64) error
+	CountBrewLogsForCoffee(ctx context.Context, coffeeID int64) (int64, error)
+	CountUserCoffeesWithPhoto(ctx context.Context, arg CountUserCoffeesWithPhotoParams) (int64, error)
+	CreateAuthSession(ctx context.Context, arg CreateAuthSessionParams) error
+	CreateBrewLog(ctx context.Context, arg CreateBrewLogParams) (BrewLog, error)
+	CreateBrewLogPour(ctx context.Context, arg CreateBrewLogPourParams) error
 	CreateCoffee(ctx context.Context, arg CreateCoffeeParams) (CreateCoffeeRow, error)
+	CreateEquipment(ctx context.Context, arg CreateEquipmentParams) (Equipment, error)
+	CreateFollow(ctx context.Context, arg CreateFollowParams) error
+	CreateLoginAttempt(ctx context.Context, arg CreateLoginAttemptParams) error
+	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) error
+	// Returns no row when a roaster with the same name (ignoring case) exists.
+	CreateRoaster(ctx context.Context, arg CreateRoasterParams) (Roaster, error)
+	CreateTastingSession(ctx context.Context, arg CreateTastingSessionParams) (TastingSession, error)
+	CreateUserToken(ctx context.Context, arg CreateUserTokenParams) error
+	DeleteBrewLog(ctx context.Context, arg DeleteBrewLogParams) error
+	DeleteBrewLogPours(ctx context.Context, brewLogID int64) error
+	DeleteBrewLogPoursForCoffee(ctx context.Context, coffeeID int64) error
+	DeleteBrewLogsForCoffee(ctx context.Context, coffeeID int64) error
+	DeleteCoffee(ctx context.Context, arg DeleteCoffeeParams) error
+	DeleteEquipment(ctx context.Context, arg DeleteEquipmentParams) error
+	DeleteExpiredRefreshTokens(ctx context.Context, expiresAt time.Time) error
+	DeleteFollow(ctx context.Context, arg DeleteFollowParams) error
+	DeleteLoginThrottle(ctx context.Context, arg DeleteLoginThrottleParams) error
+	// Notes the user already wrote are kept.
+	FillBrewLogTastingNotes(ctx context.Context, arg FillBrewLogTastingNotesParams) error
+	// Adds a website or location the directory does not have yet; known values
+	// are kept. Only the user who added the roaster may fill them in.
+	FillRoasterDetails(ctx context.Context, arg FillRoasterDetailsParams) error
+	// A coffee is the same coffee when all of its identifying text matches; NULL and '' are equal.
 	FindCoffeeByUserAndDetails(ctx context.Context, arg FindCoffeeByUserAndDetailsParams) (int64, error)
+	GetAccount(ctx context.Context, id int64) (GetAccountRow, error)
+	GetAccountByEmail(ctx context.Context, email string) (GetAccountByEmailRow, error)
+	GetAuthSession(ctx context.Context, id string) (AuthSession, error)
+	GetAuthUser(ctx context.Context, id int64) (GetAuthUserRow, error)
+	GetBrewLogByID(ctx context.Context, id int64) (BrewLog, error)
+	// Average coffee_weight of the coffee's last 10 weighed brews.
+	GetCoffeeAverageDose(ctx context.Context, coffeeID int64) (GetCoffeeAverageDoseRow, error)
 	GetCoffeeByID(ctx context.Context, arg GetCoffeeByIDParams) (GetCoffeeByIDRow, error)
 	GetCoffeeByIDOnly(ctx context.Context, id int64) (GetCoffeeByIDOnlyRow, error)
+	GetCoffeeOwnerID(ctx context.Context, id int64) (int64, error)
+	GetEquipmentByID(ctx context.Context, arg GetEquipmentByIDParams) (Equipment, error)
+	GetLoginThrottle(ctx context.Context, arg GetLoginThrottleParams) (LoginThrottle, error)
+	GetPasswordResetThrottle(ctx context.Context, arg GetPasswordResetThrottleParams) (PasswordResetThrottle, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error)
+	GetRoasterByID(ctx context.Context, id int64) (Roaster, error)
+	GetRoasterByName(ctx context.Context, name string) (Roaster, error)
+	// Average rating and count of the user's brew logs of coffees from the roaster.
+	GetRoasterRatingForUser(ctx context.Context, arg GetRoasterRatingForUserParams) (GetRoasterRatingForUserRow, error)
+	GetTastingSession(ctx context.Context, arg GetTastingSessionParams) (TastingSession, error)
+	GetUserBrewLogVisibility(ctx context.Context, id int64) (string, error)
+	GetUserToken(ctx context.Context, arg GetUserTokenParams) (UserToken, error)
+	ListBrewLogPours(ctx context.Context, brewLogID int64) ([]BrewLogPour, error)
+	ListBrewLogPoursForBrewLogs(ctx context.Context, brewLogIds []int64) ([]BrewLogPour, error)
+	ListCoffeePhotoPaths(ctx context.Context) ([]sql.NullString, error)
 	ListCoffeesForUser(ctx context.Context, userID int64) ([]ListCoffeesForUserRow, error)
+	ListEquipmentForUser(ctx context.Context, arg ListEquipmentForUserParams) ([]Equipment, error)
+	// Pending requests first, then approved followers, newest first.
+	ListFollowers(ctx context.Context, followeeID int64) ([]ListFollowersRow, error)
+	// Newest first; the recommender reads the most recent attempts at a coffee.
+	ListRatedBrewLogsForCoffee(ctx context.Context, arg ListRatedBrewLogsForCoffeeParams) ([]BrewLog, error)
+	// Roasters whose match keys are between the given lengths, in name order.
+	ListRoasterMatchCandidates(ctx context.Context, arg ListRoasterMatchCandidatesParams) ([]Roaster, error)
+	ListRoasters(ctx context.Context) ([]Roaster, error)
+	ListTastingSessionsForBrewLog(ctx context.Context, arg ListTastingSessionsForBrewLogParams) ([]TastingSession, error)
+	ListVisibleBrewLogsForUser(ctx context.Context, arg ListVisibleBrewLogsForUserParams) ([]ListVisibleBrewLogsForUserRow, error)
+	// Affects no row when the user's e-mail is no longer the verified address.
+	MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) (int64, error)
+	// Children of a deleted log move up to its parent so the lineage stays connected.
+	ReparentBrewLogs(ctx context.Context, arg ReparentBrewLogsParams) error
+	RevokeAuthSession(ctx context.Context, arg RevokeAuthSessionParams) error
+	RevokeAuthSessionsForUser(ctx context.Context, arg RevokeAuthSessionsForUserParams) error
+	UpdateBrewLog(ctx context.Context, arg UpdateBrewLogParams) error
+	UpdateCoffee(ctx context.Context, arg UpdateCoffeeParams) error
 	UpdateCoffeePhotoPath(ctx context.Context, arg UpdateCoffeePhotoPathParams) error
+	UpdateEquipment(ctx context.Context, arg UpdateEquipmentParams) error
+	// Only applies to a session still at the question and answers it was read
+	// with, so concurrent answers cannot overwrite each other.
+	UpdateTastingSessionProgress(ctx context.Context, arg UpdateTastingSessionProgressParams) (int64, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	// Affects no row when the token was already used, as by a concurrent refresh.
+	UpsertLoginThrottle(ctx context.Context, arg UpsertLoginThrottleParams) error
+	UpsertPasswordResetThrottle(ctx context.Context, arg UpsertPasswordResetThrottleParams) error
+	UseRefreshToken(ctx context.Context, arg UseRefreshTokenParams) (int64, error)
+	// Affects no row when the token was already used.
+	UseUserToken(ctx context.Context, arg UseUserTokenParams) (int64, error)
+	// Retires a user's other outstanding tokens once one of them was used.
+	UseUserTokensForPurpose(ctx context.Context, arg UseUserTokensForPurposeParams) error
 }
 
 var _ Querier = (*Queries)(nil)
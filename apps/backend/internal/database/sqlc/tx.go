@@ -0,0 +1,28 @@
+package db
+
+// This file is maintained by hand: sqlc only generates WithTx, so the helper
+// that opens, commits and rolls back a transaction lives here.
+
+import (
+	"context"
+	"database/sql"
+)
+
+// InTx runs fn with a Queries bound to a single transaction, committing if fn
+// returns nil and rolling back otherwise. If q is already bound to a
+// transaction, fn joins it.
+func (q *Queries) InTx(ctx context.Context, fn func(*Queries) error) error {
+	conn, ok := q.db.(*sql.DB)
+	if !ok {
+		return fn(q)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(q.WithTx(tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
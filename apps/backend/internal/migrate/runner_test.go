@@ -86,3 +86,173 @@ func TestSchemaMigrationsTracking(t *testing.T) {
 		t.Fatalf("expected schema_migrations table to exist")
 	}
 }
+
+func TestCoffeeOriginBackfill(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "origin.db"))
+	if err != nil {
+		t.Fatalf("failed to init db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	migrationsDir := filepath.Clean(filepath.Join("..", "..", "migrations"))
+
+	if err := ApplyToVersion(db, migrationsDir, 11); err != nil {
+		t.Fatalf("migrate to 11 failed: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (id, email, username, password_hash, password_salt) VALUES (1, 'a@example.com', 'a', 'h', 's');
+		INSERT INTO coffees (user_id, name, origin) VALUES
+		(1, 'a', 'Ethiopia, Guji - Natural'), (1, 'b', 'Huila, Red Honey, Pitalito, Colombia'), (1, 'c', 'Sumatra Wet-Hulled'),
+		(1, 'd', 'Ethiopia & Brazil'), (1, 'e', 'Kenya 1800m'), (1, 'f', 'House blend'), (1, 'g', NULL)`); err != nil {
+		t.Fatalf("seed failed: %v", err)
+	}
+	if err := ApplyUpToLatest(db, migrationsDir); err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+
+	want := map[string][3]string{
+		"a": {"ET", "Guji", "natural"},
+		"b": {"CO", "Huila, Pitalito", "honey"},
+		"c": {"ID", "Sumatra", "wet-hulled"},
+		"d": {"", "", ""},
+		"e": {"KE", "", ""},
+		"f": {"", "", ""},
+		"g": {"", "", ""},
+	}
+	rows, err := db.Query(`SELECT name, IFNULL(country, ''), IFNULL(region, ''), IFNULL(process, '') FROM coffees`)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var got [3]string
+		if err := rows.Scan(&name, &got[0], &got[1], &got[2]); err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		if got != want[name] {
+			t.Errorf("coffee %s: expected %v, got %v", name, want[name], got)
+		}
+	}
+}
+
+func TestRoasterBackfill(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "roasters.db"))
+	if err != nil {
+		t.Fatalf("failed to init db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	migrationsDir := filepath.Clean(filepath.Join("..", "..", "migrations"))
+
+	if err := ApplyToVersion(db, migrationsDir, 12); err != nil {
+		t.Fatalf("migrate to 12 failed: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (id, email, username, password_hash, password_salt) VALUES (1, 'a@example.com', 'a', 'h', 's');
+		INSERT INTO coffees (user_id, name, roaster) VALUES
+		(1, 'a', 'Sey'), (1, 'b', ' sey '), (1, 'c', 'Onyx'), (1, 'd', NULL), (1, 'e', '')`); err != nil {
+		t.Fatalf("seed failed: %v", err)
+	}
+	if err := ApplyUpToLatest(db, migrationsDir); err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+
+	var roasters int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM roasters`).Scan(&roasters); err != nil || roasters != 2 {
+		t.Fatalf("expected 2 roasters, got %d (%v)", roasters, err)
+	}
+	var linked, sameSey int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM coffees WHERE roaster_id IS NOT NULL`).Scan(&linked)
+	_ = db.QueryRow(`SELECT COUNT(DISTINCT roaster_id) FROM coffees WHERE name IN ('a', 'b')`).Scan(&sameSey)
+	if linked != 3 || sameSey != 1 {
+		t.Fatalf("expected 3 linked coffees sharing one Sey, got %d linked and %d Sey ids", linked, sameSey)
+	}
+}
+
+func TestBrewMethodRefold(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "methods.db"))
+	if err != nil {
+		t.Fatalf("failed to init db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	migrationsDir := filepath.Clean(filepath.Join("..", "..", "migrations"))
+
+	if err := ApplyToVersion(db, migrationsDir, 7); err != nil {
+		t.Fatalf("migrate to 7 failed: %v", err)
+	}
+	want := map[string]string{
+		"Hario V60":            "v60",
+		"V60 (02)":             "v60",
+		"Kalita Wave #185":     "kalita-wave",
+		"AeroPress, Go!":       "aeropress",
+		"CAFETIÈRE":            "french-press",
+		"Cafetière":            "french-press",
+		"Moka pot\t(Bialetti)": "Moka pot\t(Bialetti)",
+		"Turkish":              "Turkish",
+	}
+	for method := range want {
+		if _, err := db.Exec(`INSERT INTO brew_logs (user_id, coffee_id, brew_method, tasting_notes) VALUES (1, 1, ?, ?)`, method, method); err != nil {
+			t.Fatalf("seed failed: %v", err)
+		}
+	}
+	if err := ApplyUpToLatest(db, migrationsDir); err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+
+	rows, err := db.Query(`SELECT tasting_notes, brew_method FROM brew_logs`)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var original, method string
+		if err := rows.Scan(&original, &method); err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		if method != want[original] {
+			t.Errorf("brew method %q: expected %q, got %q", original, want[original], method)
+		}
+	}
+}
+
+func TestRoasterMatchKeyBackfill(t *testing.T) {
+	db, err := database.Connect(filepath.Join(t.TempDir(), "roaster-keys.db"))
+	if err != nil {
+		t.Fatalf("failed to init db: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	migrationsDir := filepath.Clean(filepath.Join("..", "..", "migrations"))
+
+	if err := ApplyToVersion(db, migrationsDir, 19); err != nil {
+		t.Fatalf("migrate to 19 failed: %v", err)
+	}
+	// The same names and keys are checked against services.roasterMatchKey
+	want := map[string]string{
+		"The Onyx Coffee Lab":         "onyxlab",
+		"Square Mile Coffee Roasters": "squaremile",
+		"Red Rooster & Co.":           "redrooster",
+		"Café Grumpy":                 "cafégrumpy",
+		"The Coffee Company":          "thecoffeecompany",
+		"Sey":                         "sey",
+	}
+	for name := range want {
+		if _, err := db.Exec(`INSERT INTO roasters (name) VALUES (?)`, name); err != nil {
+			t.Fatalf("seed failed: %v", err)
+		}
+	}
+	if err := ApplyUpToLatest(db, migrationsDir); err != nil {
+		t.Fatalf("migrate up failed: %v", err)
+	}
+
+	rows, err := db.Query(`SELECT name, match_key FROM roasters`)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, key string
+		if err := rows.Scan(&name, &key); err != nil {
+			t.Fatalf("scan failed: %v", err)
+		}
+		if key != want[name] {
+			t.Errorf("roaster %q: expected key %q, got %q", name, want[name], key)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"coffeeee/backend/internal/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Purposes of the tokens mailed to users.
+const (
+	TokenPurposeVerifyEmail   = "verify-email"
+	TokenPurposeResetPassword = "reset-password"
+)
+
+// SessionRevokedPasswordReset means the user's password was reset, which
+// signs out every session.
+const SessionRevokedPasswordReset = "password-reset"
+
+const (
+	emailVerificationTTL = 48 * time.Hour
+	passwordResetTTL     = time.Hour
+	// At most passwordResetsPerEmail reset requests for one address, and
+	// passwordResetsPerIP from one client IP, are sent per
+	// passwordResetWindow; further requests send nothing.
+	passwordResetsPerEmail = 3
+	passwordResetsPerIP    = 20
+	passwordResetWindow    = time.Hour
+	// maxPendingPasswordResets bounds the reset e-mails being sent in the
+	// background; requests beyond it are dropped. The per-address and per-IP
+	// limits keep one sender from filling it.
+	maxPendingPasswordResets = 16
+)
+
+// AccountService verifies e-mail addresses and resets forgotten passwords
+// with tokens it mails to the user. A token is a random part signed with the
+// server secret for its purpose; only a hash of the random part is stored,
+// and each token works once and expires.
+type AccountService struct {
+	queries   *db.Queries
+	mailer    Mailer
+	passwords *utils.Passwords
+	secret    []byte
+	appURL    string
+	// resetSlots holds one entry per reset e-mail being sent.
+	resetSlots chan struct{}
+	// resetMu serializes counting reset requests, so two concurrent requests
+	// cannot both count from the same old number.
+	resetMu sync.Mutex
+}
+
+// NewAccountService signs tokens with secret; links in the e-mails point to
+// the frontend at appURL.
+func NewAccountService(queries *db.Queries, mailer Mailer, passwords *utils.Passwords, secret, appURL string) *AccountService {
+	return &AccountService{
+		queries:    queries,
+		mailer:     mailer,
+		passwords:  passwords,
+		secret:     []byte(secret),
+		appURL:     strings.TrimRight(appURL, "/"),
+		resetSlots: make(chan struct{}, maxPendingPasswordResets),
+	}
+}
+
+// SendVerification mails the user a link to verify their e-mail address.
+func (s *AccountService) SendVerification(ctx context.Context, userID int64) error {
+	account, err := s.queries.GetAccount(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &NotFoundError{Message: "user not found"}
+	}
+	if err != nil {
+		return err
+	}
+	if account.EmailVerifiedAt.Valid {
+		return &ValidationError{Message: "e-mail address is already verified"}
+	}
+	token, err := s.issue(ctx, account.ID, account.Email, TokenPurposeVerifyEmail, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, Email{
+		To:      account.Email,
+		Subject: "Verify your e-mail address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your e-mail address by opening this link:\n\n%s\n\n"+
+			"The link works once and expires in %d hours. If you did not sign up, ignore this e-mail.\n",
+			account.Username, s.link("/verify-email", token), int(emailVerificationTTL.Hours())),
+	})
+}
+
+// VerifyEmail marks the address a verification token was sent to as
+// verified, if it is still the user's address.
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	return s.queries.InTx(ctx, func(q *db.Queries) error {
+		t, err := s.use(ctx, q, token, TokenPurposeVerifyEmail)
+		if err != nil {
+			return err
+		}
+		n, err := q.MarkEmailVerified(ctx, db.MarkEmailVerifiedParams{
+			EmailVerifiedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			ID:              t.UserID,
+			Email:           t.Email,
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &ValidationError{Message: "the e-mail address has changed since this link was sent"}
+		}
+		return nil
+	})
+}
+
+// RequestPasswordReset mails a password reset link to email. An address
+// without an account gets nothing, and the caller is not told either way:
+// the account is looked up and the e-mail sent after it returns, so neither
+// the answer nor its timing reveals who has an account. Requests past the
+// limit for the address or for the client IP send nothing. Failures are only
+// logged.
+func (s *AccountService) RequestPasswordReset(ctx context.Context, email, ip string) {
+	allowed, err := s.countPasswordReset(ctx, email, ip)
+	if err != nil {
+		log.Printf("password reset request: %v", err)
+		return
+	}
+	if !allowed {
+		log.Printf("password reset request throttled: too many for the address or IP")
+		return
+	}
+	select {
+	case s.resetSlots <- struct{}{}:
+	default:
+		log.Printf("password reset request dropped: %d already being sent", maxPendingPasswordResets)
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		defer func() { <-s.resetSlots }()
+		if err := s.sendPasswordReset(ctx, email); err != nil {
+			log.Printf("password reset request: %v", err)
+		}
+	}()
+}
+
+// countPasswordReset counts a reset request against its e-mail address and
+// client IP, whether or not the address has an account, and reports whether
+// both are still within their limits.
+func (s *AccountService) countPasswordReset(ctx context.Context, email, ip string) (bool, error) {
+	s.resetMu.Lock()
+	defer s.resetMu.Unlock()
+	allowed := true
+	err := s.queries.InTx(ctx, func(q *db.Queries) error {
+		now := time.Now().UTC()
+		keys := []db.GetPasswordResetThrottleParams{{Kind: loginThrottleEmail, Subject: email}}
+		if ip != "" {
+			keys = append(keys, db.GetPasswordResetThrottleParams{Kind: loginThrottleIP, Subject: ip})
+		}
+		for _, key := range keys {
+			t, err := q.GetPasswordResetThrottle(ctx, key)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return err
+			}
+			if now.Sub(t.WindowStartedAt) >= passwordResetWindow {
+				t.Requests = 0
+				t.WindowStartedAt = now
+			}
+			t.Requests++
+			limit := int64(passwordResetsPerEmail)
+			if key.Kind == loginThrottleIP {
+				limit = passwordResetsPerIP
+			}
+			if t.Requests > limit {
+				allowed = false
+			}
+			err = q.UpsertPasswordResetThrottle(ctx, db.UpsertPasswordResetThrottleParams{
+				Kind:            key.Kind,
+				Subject:         key.Subject,
+				Requests:        t.Requests,
+				WindowStartedAt: t.WindowStartedAt,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
+
+func (s *AccountService) sendPasswordReset(ctx context.Context, email string) error {
+	account, err := s.queries.GetAccountByEmail(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Printf("password reset requested for unknown e-mail")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token, err := s.issue(ctx, account.ID, account.Email, TokenPurposeResetPassword, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, Email{
+		To:      account.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account. To choose a new password, open this link:\n\n%s\n\n"+
+			"The link works once and expires in %d minutes. If you did not ask for it, ignore this e-mail; your password stays the same.\n",
+			account.Username, s.link("/reset-password", token), int(passwordResetTTL.Minutes())),
+	})
+}
+
+// ResetPassword sets a new password with a reset token. Other reset tokens
+// of the user stop working and every login session is signed out. Having
+// received the mail, the user's address counts as verified.
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	if password == "" {
+		return &ValidationError{Message: "password is required"}
+	}
+	// Hashing is slow on purpose; only spend it on a token that can be used
+	if _, err := s.check(ctx, s.queries, token, TokenPurposeResetPassword); err != nil {
+		return err
+	}
+	hash, err := s.passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+	return s.queries.InTx(ctx, func(q *db.Queries) error {
+		t, err := s.use(ctx, q, token, TokenPurposeResetPassword)
+		if err != nil {
+			return err
+		}
+		now := sql.NullTime{Time: time.Now().UTC(), Valid: true}
+		if err := q.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{PasswordHash: hash, ID: t.UserID}); err != nil {
+			return err
+		}
+		err = q.UseUserTokensForPurpose(ctx, db.UseUserTokensForPurposeParams{UsedAt: now, UserID: t.UserID, Purpose: TokenPurposeResetPassword})
+		if err != nil {
+			return err
+		}
+		err = q.RevokeAuthSessionsForUser(ctx, db.RevokeAuthSessionsForUserParams{
+			RevokedAt:     now,
+			RevokedReason: sql.NullString{String: SessionRevokedPasswordReset, Valid: true},
+			UserID:        t.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = q.MarkEmailVerified(ctx, db.MarkEmailVerifiedParams{EmailVerifiedAt: now, ID: t.UserID, Email: t.Email})
+		return err
+	})
+}
+
+// issue stores a new token and returns it as it is mailed.
+func (s *AccountService) issue(ctx context.Context, userID int64, email, purpose string, ttl time.Duration) (string, error) {
+	random, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	err = s.queries.CreateUserToken(ctx, db.CreateUserTokenParams{
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		TokenHash: hashRefreshToken(random),
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+	return random + "." + s.sign(purpose, random), nil
+}
+
+// invalidToken is the error for any mailed token that cannot be used,
+// without saying why.
+func invalidToken() error {
+	return &ValidationError{Message: "the link is invalid or has expired"}
+}
+
+// check returns the stored token behind a mailed one if it is unused and
+// has not expired.
+func (s *AccountService) check(ctx context.Context, q *db.Queries, token, purpose string) (db.UserToken, error) {
+	random, sig, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(s.sign(purpose, random))) {
+		return db.UserToken{}, invalidToken()
+	}
+	t, err := q.GetUserToken(ctx, db.GetUserTokenParams{TokenHash: hashRefreshToken(random), Purpose: purpose})
+	if errors.Is(err, sql.ErrNoRows) {
+		return t, invalidToken()
+	}
+	if err != nil {
+		return t, err
+	}
+	if t.UsedAt.Valid || !time.Now().Before(t.ExpiresAt) {
+		return t, invalidToken()
+	}
+	return t, nil
+}
+
+// use checks a mailed token and marks it used. Of concurrent uses of one
+// token only the first succeeds.
+func (s *AccountService) use(ctx context.Context, q *db.Queries, token, purpose string) (db.UserToken, error) {
+	t, err := s.check(ctx, q, token, purpose)
+	if err != nil {
+		return t, err
+	}
+	n, err := q.UseUserToken(ctx, db.UseUserTokenParams{UsedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true}, ID: t.ID})
+	if err != nil {
+		return t, err
+	}
+	if n == 0 {
+		return t, invalidToken()
+	}
+	return t, nil
+}
+
+// sign binds a token to its purpose, so a verification token cannot be
+// replayed as a reset token.
+func (s *AccountService) sign(purpose, random string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(purpose + "." + random))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *AccountService) link(path, token string) string {
+	return s.appURL + path + "?token=" + url.QueryEscape(token)
+}
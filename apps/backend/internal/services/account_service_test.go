@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"coffeeee/backend/internal/database"
+	"coffeeee/backend/internal/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// countingHasher is a cheap bcrypt hasher that counts the hashes it makes.
+type countingHasher struct {
+	utils.BcryptHasher
+	hashes *int
+}
+
+func (h countingHasher) Hash(password string) (string, error) {
+	*h.hashes++
+	return h.BcryptHasher.Hash(password)
+}
+
+func TestAccountService_ResetPasswordHashesOnlyForUsableTokens(t *testing.T) {
+	conn := newTestDB(t)
+	if _, err := conn.Exec(`INSERT INTO users (id, email, username, password_hash, password_salt) VALUES (1, 'a@example.com', 'a', 'h', '')`); err != nil {
+		t.Fatalf("seed user: %v", err)
+	}
+	hashes := 0
+	passwords := utils.NewPasswords(countingHasher{BcryptHasher: utils.BcryptHasher{Cost: bcrypt.MinCost}, hashes: &hashes})
+	s := NewAccountService(database.NewQueries(conn), &LogMailer{}, passwords, "test-secret", "http://app.example.com")
+	ctx := context.Background()
+
+	token, err := s.issue(ctx, 1, "a@example.com", TokenPurposeResetPassword, time.Hour)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	expired, err := s.issue(ctx, 1, "a@example.com", TokenPurposeResetPassword, -time.Minute)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+	verification, err := s.issue(ctx, 1, "a@example.com", TokenPurposeVerifyEmail, time.Hour)
+	if err != nil {
+		t.Fatalf("issue: %v", err)
+	}
+
+	var invalid *ValidationError
+	for _, bad := range []string{"", "garbage", token + "x", expired, verification} {
+		if err := s.ResetPassword(ctx, bad, "newsecret"); !errors.As(err, &invalid) {
+			t.Fatalf("%q: expected ValidationError, got %v", bad, err)
+		}
+	}
+	if hashes != 0 {
+		t.Fatalf("expected no hashing for unusable tokens, got %d hashes", hashes)
+	}
+
+	if err := s.ResetPassword(ctx, token, "newsecret"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if err := s.ResetPassword(ctx, token, "again"); !errors.As(err, &invalid) {
+		t.Fatalf("expected ValidationError reusing the token, got %v", err)
+	}
+	if hashes != 1 {
+		t.Fatalf("expected one hash for one reset, got %d", hashes)
+	}
+}
+
+func TestAccountService_CountPasswordResetLimitsAddressAndIP(t *testing.T) {
+	conn := newTestDB(t)
+	s := NewAccountService(database.NewQueries(conn), &LogMailer{}, nil, "test-secret", "http://app.example.com")
+	ctx := context.Background()
+	count := func(email, ip string) bool {
+		t.Helper()
+		allowed, err := s.countPasswordReset(ctx, email, ip)
+		if err != nil {
+			t.Fatalf("count: %v", err)
+		}
+		return allowed
+	}
+
+	for i := 0; i < passwordResetsPerEmail; i++ {
+		if !count("a@example.com", "10.0.0.1") {
+			t.Fatalf("request %d for the address refused", i+1)
+		}
+	}
+	if count("a@example.com", "10.0.0.2") {
+		t.Fatalf("expected the address refused past its limit, from any IP")
+	}
+
+	// Other addresses use up the rest of the IP's requests
+	for i := passwordResetsPerEmail; i < passwordResetsPerIP; i++ {
+		if !count(fmt.Sprintf("u%d@example.com", i), "10.0.0.1") {
+			t.Fatalf("request %d from the IP refused", i+1)
+		}
+	}
+	if count("fresh@example.com", "10.0.0.1") {
+		t.Fatalf("expected the IP refused past its limit")
+	}
+	if !count("fresh@example.com", "10.0.0.3") {
+		t.Fatalf("expected another IP allowed")
+	}
+
+	// A window that has passed starts over
+	if _, err := conn.Exec(`UPDATE password_reset_throttles SET window_started_at = ?`, time.Now().UTC().Add(-passwordResetWindow)); err != nil {
+		t.Fatalf("age throttles: %v", err)
+	}
+	if !count("a@example.com", "10.0.0.1") {
+		t.Fatalf("expected the address and IP allowed again in a new window")
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GeminiService talks to the Google Gemini generateContent API.
+type GeminiService struct {
+	client aiClient
+}
+
+func NewGeminiService(opts AIClientOptions) *GeminiService {
+	if opts.BaseURL == "" {
+		opts.BaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	}
+	if opts.Model == "" {
+		opts.Model = "gemini-1.5-flash"
+	}
+	return &GeminiService{client: newAIClient("gemini", opts)}
+}
+
+func (s *GeminiService) Name() string {
+	return "gemini"
+}
+
+func (s *GeminiService) ExtractCoffee(ctx context.Context, label CoffeeLabel) (*CoffeeExtraction, error) {
+	parts := []map[string]any{{"text": extractCoffeeMessage(label)}}
+	if len(label.Image) > 0 {
+		parts = append(parts, map[string]any{
+			"inlineData": map[string]string{"mimeType": label.ImageType, "data": base64.StdEncoding.EncodeToString(label.Image)},
+		})
+	}
+	reply, err := s.generate(ctx, extractCoffeePrompt, parts)
+	if err != nil {
+		return nil, err
+	}
+	return parseExtraction(reply)
+}
+
+func (s *GeminiService) RecommendBrew(ctx context.Context, input BrewRecommendationInput) (*BrewRecommendation, error) {
+	message, err := recommendBrewMessage(input)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := s.generate(ctx, recommendBrewPrompt, []map[string]any{{"text": message}})
+	if err != nil {
+		return nil, err
+	}
+	return parseRecommendation(reply, s.Name())
+}
+
+// generate sends the system instruction and the user's parts and returns the
+// text of the first candidate, asking for JSON.
+func (s *GeminiService) generate(ctx context.Context, system string, parts []map[string]any) (string, error) {
+	body := map[string]any{
+		"systemInstruction": map[string]any{"parts": []map[string]any{{"text": system}}},
+		"contents":          []map[string]any{{"role": "user", "parts": parts}},
+		"generationConfig":  map[string]any{"responseMimeType": "application/json", "temperature": 0},
+	}
+	// The key goes in a header rather than the query so it stays out of logs
+	header := http.Header{}
+	header.Set("x-goog-api-key", s.client.opts.APIKey)
+	var resp struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+		} `json:"candidates"`
+	}
+	endpoint := s.client.opts.BaseURL + "/models/" + url.PathEscape(s.client.opts.Model) + ":generateContent"
+	if err := s.client.postJSON(ctx, endpoint, header, body, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Candidates) == 0 {
+		return "", errors.New("gemini: response has no candidates")
+	}
+	var reply strings.Builder
+	for _, p := range resp.Candidates[0].Content.Parts {
+		reply.WriteString(p.Text)
+	}
+	return reply.String(), nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAITimeout      = 20 * time.Second
+	defaultAIRetryBackoff = 500 * time.Millisecond
+	maxAIErrorBody        = 512
+)
+
+// AIClientOptions configure a hosted AI provider.
+type AIClientOptions struct {
+	APIKey  string
+	BaseURL string // API root, e.g. https://api.openai.com/v1; no trailing slash needed
+	Model   string
+	// Timeout bounds a whole call, retries included. 0 means 20s.
+	Timeout time.Duration
+	// MaxRetries is how often a failed request is repeated after network
+	// errors, 429s and 5xx responses.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry; it doubles each time.
+	// 0 means 500ms.
+	RetryBackoff time.Duration
+	// HTTPClient sends the requests; nil means a new http.Client.
+	HTTPClient *http.Client
+}
+
+// aiClient posts JSON to a provider with a deadline and retries.
+type aiClient struct {
+	provider string
+	opts     AIClientOptions
+}
+
+func newAIClient(provider string, opts AIClientOptions) aiClient {
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultAITimeout
+	}
+	if opts.RetryBackoff <= 0 {
+		opts.RetryBackoff = defaultAIRetryBackoff
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{}
+	}
+	return aiClient{provider: provider, opts: opts}
+}
+
+// postJSON sends body to url and decodes the JSON response into out.
+func (c aiClient) postJSON(ctx context.Context, url string, header http.Header, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.opts.Timeout)
+	defer cancel()
+
+	backoff := c.opts.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.try(ctx, url, header, payload, out)
+		if err == nil || retryAfter < 0 || attempt >= c.opts.MaxRetries {
+			return err
+		}
+		wait := max(backoff, retryAfter)
+		backoff *= 2
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s: %w (last error: %v)", c.provider, ctx.Err(), err)
+		case <-time.After(wait):
+		}
+	}
+}
+
+// try makes one request. A failure that is worth retrying comes with the
+// wait the provider asked for (0 if it did not say); others with -1.
+func (c aiClient) try(ctx context.Context, url string, header http.Header, payload []byte, out any) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return -1, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.opts.HTTPClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, fmt.Errorf("%s: %w", c.provider, ctx.Err())
+		}
+		return 0, fmt.Errorf("%s: %w", c.provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, maxAIErrorBody))
+		err := fmt.Errorf("%s: status %d: %s", c.provider, resp.StatusCode, strings.TrimSpace(string(msg)))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return retryAfterOf(resp), err
+		}
+		return -1, err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return -1, fmt.Errorf("%s: invalid response: %w", c.provider, err)
+	}
+	return 0, nil
+}
+
+// retryAfterOf reads a Retry-After header given in seconds.
+func retryAfterOf(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs < 0 {
+		return 0
+	}
+	return time.Duration(secs) * time.Second
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+)
+
+// OpenAIService talks to the OpenAI chat completions API or any server
+// that implements it (Azure OpenAI, OpenRouter, Ollama, vLLM...).
+type OpenAIService struct {
+	client aiClient
+}
+
+func NewOpenAIService(opts AIClientOptions) *OpenAIService {
+	if opts.BaseURL == "" {
+		opts.BaseURL = "https://api.openai.com/v1"
+	}
+	if opts.Model == "" {
+		opts.Model = "gpt-4o-mini"
+	}
+	return &OpenAIService{client: newAIClient("openai", opts)}
+}
+
+func (s *OpenAIService) Name() string {
+	return "openai"
+}
+
+func (s *OpenAIService) ExtractCoffee(ctx context.Context, label CoffeeLabel) (*CoffeeExtraction, error) {
+	content := []map[string]any{{"type": "text", "text": extractCoffeeMessage(label)}}
+	if len(label.Image) > 0 {
+		content = append(content, map[string]any{
+			"type":      "image_url",
+			"image_url": map[string]string{"url": "data:" + label.ImageType + ";base64," + base64.StdEncoding.EncodeToString(label.Image)},
+		})
+	}
+	reply, err := s.complete(ctx, extractCoffeePrompt, content)
+	if err != nil {
+		return nil, err
+	}
+	return parseExtraction(reply)
+}
+
+func (s *OpenAIService) RecommendBrew(ctx context.Context, input BrewRecommendationInput) (*BrewRecommendation, error) {
+	message, err := recommendBrewMessage(input)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := s.complete(ctx, recommendBrewPrompt, message)
+	if err != nil {
+		return nil, err
+	}
+	return parseRecommendation(reply, s.Name())
+}
+
+// complete sends one system and one user message and returns the reply,
+// asking for a JSON object.
+func (s *OpenAIService) complete(ctx context.Context, system string, user any) (string, error) {
+	body := map[string]any{
+		"model": s.client.opts.Model,
+		"messages": []map[string]any{
+			{"role": "system", "content": system},
+			{"role": "user", "content": user},
+		},
+		"response_format": map[string]string{"type": "json_object"},
+		"temperature":     0,
+	}
+	header := http.Header{}
+	if s.client.opts.APIKey != "" {
+		header.Set("Authorization", "Bearer "+s.client.opts.APIKey)
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := s.client.postJSON(ctx, s.client.opts.BaseURL+"/chat/completions", header, body, &resp); err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("openai: response has no choices")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
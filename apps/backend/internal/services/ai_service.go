@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// AIService is a provider of the AI features: reading coffee labels and
+// suggesting brew changes. The rules provider answers offline; the others
+// call a hosted model.
+type AIService interface {
+	CoffeeExtractor
+	RecommendBrew(ctx context.Context, input BrewRecommendationInput) (*BrewRecommendation, error)
+}
+
+// BrewSnapshot is the brew a recommendation starts from. Any field may be
+// missing.
+type BrewSnapshot struct {
+	BrewMethod       *string  `json:"brewMethod,omitempty"`
+	CoffeeWeight     *float64 `json:"coffeeWeight,omitempty"`
+	WaterWeight      *float64 `json:"waterWeight,omitempty"`
+	GrindSize        *string  `json:"grindSize,omitempty"`
+	GrindSetting     *float64 `json:"grindSetting,omitempty"`
+	WaterTemperature *float64 `json:"waterTemperature,omitempty"`
+	BrewTime         *int     `json:"brewTime,omitempty"`
+	TastingNotes     *string  `json:"tastingNotes,omitempty"`
+	Rating           *int     `json:"rating,omitempty"`
+}
+
+type BrewRecommendationInput struct {
+	// UserID and CoffeeID select the brew history to learn from; without
+	// them only BrewLog and Goal are used.
+	UserID   int64
+	CoffeeID *int64
+	BrewLog  BrewSnapshot
+	Goal     string
+}
+
+// BrewChange is a single-variable change to try on the next brew.
+type BrewChange struct {
+	Variable string `json:"variable"`
+	Delta    string `json:"delta"`
+	// Target is the value to brew with next, when the current one is known.
+	Target *float64 `json:"target,omitempty"`
+}
+
+type BrewRecommendation struct {
+	Change      BrewChange `json:"change"`
+	Explanation string     `json:"explanation"`
+	Provider    string     `json:"provider"`
+	// Evidence is set when the recommendation comes from the user's history.
+	Evidence *RecommendationEvidence `json:"evidence,omitempty"`
+}
+
+// brewVariables are the variables a recommendation may change.
+var brewVariables = map[string]bool{
+	"grind": true, "coffeeWeight": true, "waterWeight": true, "waterTemperature": true, "brewTime": true,
+}
+
+// RulesAIService is the offline provider: label text is read by
+// LabelTextExtractor and recommendations follow fixed rules of thumb.
+type RulesAIService struct {
+	LabelTextExtractor
+}
+
+func NewRulesAIService() *RulesAIService {
+	return &RulesAIService{}
+}
+
+func (s *RulesAIService) RecommendBrew(ctx context.Context, input BrewRecommendationInput) (*BrewRecommendation, error) {
+	goal := strings.ToLower(strings.TrimSpace(input.Goal))
+	out := &BrewRecommendation{Provider: s.Name()}
+	switch {
+	case strings.Contains(goal, "sweet"):
+		out.Change = BrewChange{Variable: "grind", Delta: "2 clicks finer"}
+		out.Explanation = "A slightly finer grind increases extraction and can enhance perceived sweetness."
+	case strings.Contains(goal, "bitter"):
+		out.Change = BrewChange{Variable: "waterTemperature", Delta: "-2°C"}
+		out.Explanation = "Lowering water temperature slightly can reduce over-extraction bitterness."
+	case strings.Contains(goal, "strong") || strings.Contains(goal, "strength"):
+		out.Change = BrewChange{Variable: "coffeeWeight", Delta: "+1g per 15g"}
+		out.Explanation = "Increasing dose relative to water raises strength without extending brew time."
+	default:
+		out.Change = BrewChange{Variable: "grind", Delta: "1 click coarser"}
+		out.Explanation = "A small grind adjustment is a safe single-variable test to move flavor balance."
+	}
+	return out, nil
+}
+
+// FallbackAIService asks a hosted provider first and the offline rules when
+// it fails, so an outage or a bad answer degrades the feature instead of
+// breaking it.
+type FallbackAIService struct {
+	primary  AIService
+	fallback AIService
+}
+
+func NewFallbackAIService(primary, fallback AIService) *FallbackAIService {
+	return &FallbackAIService{
+		primary:  primary,
+		fallback: fallback,
+	}
+}
+
+func (s *FallbackAIService) Name() string {
+	return s.primary.Name()
+}
+
+// ExtractCoffee falls back only when the fallback can read the label; a
+// photo it cannot read reports the primary provider's error.
+func (s *FallbackAIService) ExtractCoffee(ctx context.Context, label CoffeeLabel) (*CoffeeExtraction, error) {
+	out, err := s.primary.ExtractCoffee(ctx, label)
+	if !s.shouldFallBack(ctx, err) {
+		return out, err
+	}
+	out, fallbackErr := s.fallback.ExtractCoffee(ctx, label)
+	if fallbackErr != nil {
+		return nil, err
+	}
+	out.Provider = s.fallback.Name()
+	return out, nil
+}
+
+func (s *FallbackAIService) RecommendBrew(ctx context.Context, input BrewRecommendationInput) (*BrewRecommendation, error) {
+	out, err := s.primary.RecommendBrew(ctx, input)
+	if !s.shouldFallBack(ctx, err) {
+		return out, err
+	}
+	return s.fallback.RecommendBrew(ctx, input)
+}
+
+func (s *FallbackAIService) shouldFallBack(ctx context.Context, err error) bool {
+	var validationErr *ValidationError
+	if err == nil || errors.As(err, &validationErr) || ctx.Err() != nil {
+		return false
+	}
+	log.Printf("AI provider %s failed, using %s: %v", s.primary.Name(), s.fallback.Name(), err)
+	return true
+}
+
+const extractCoffeePrompt = `You read coffee bag labels. Reply with a JSON object only, with these keys:
+"name", "roaster", "origin", "process", "tastingNotes", "roastDate".
+Each value is {"value": string, "confidence": number from 0 to 1}; use {"value": "", "confidence": 0} for anything not on the label.
+origin is the country and region as printed, process is e.g. washed, natural, honey or anaerobic,
+tastingNotes are the flavour notes as printed and roastDate is YYYY-MM-DD.`
+
+const recommendBrewPrompt = `You are a coffee brewing coach. Given a brew and the user's goal, suggest exactly one
+change to a single variable for the next brew. Reply with a JSON object only:
+{"variable": one of "grind", "coffeeWeight", "waterWeight", "waterTemperature", "brewTime",
+"delta": a short change such as "2 clicks finer" or "-2°C", "explanation": one or two sentences}.`
+
+// recommendBrewMessage is the user message for a recommendation request.
+func recommendBrewMessage(input BrewRecommendationInput) (string, error) {
+	brew, err := json.Marshal(input.BrewLog)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Brew: %s\nGoal: %s", brew, input.Goal), nil
+}
+
+// extractCoffeeMessage is the user message for a label; a photo is sent
+// alongside it.
+func extractCoffeeMessage(label CoffeeLabel) string {
+	if label.Text == "" {
+		return "Read the coffee bag in the photo."
+	}
+	return "Label text:\n" + label.Text
+}
+
+// parseModelJSON decodes the JSON object in a model's reply. Models asked for
+// JSON still sometimes wrap it in a Markdown code fence or a sentence.
+func parseModelJSON(reply string, out any) error {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("reply has no JSON object: %.200q", reply)
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), out); err != nil {
+		return fmt.Errorf("reply is not the expected JSON: %w", err)
+	}
+	return nil
+}
+
+// parseExtraction decodes an extraction reply.
+func parseExtraction(reply string) (*CoffeeExtraction, error) {
+	var out CoffeeExtraction
+	if err := parseModelJSON(reply, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// parseRecommendation decodes a recommendation reply and checks it names
+// one known variable.
+func parseRecommendation(reply, provider string) (*BrewRecommendation, error) {
+	var body struct {
+		BrewChange
+		Explanation string `json:"explanation"`
+	}
+	if err := parseModelJSON(reply, &body); err != nil {
+		return nil, err
+	}
+	if !brewVariables[body.Variable] || strings.TrimSpace(body.Delta) == "" || strings.TrimSpace(body.Explanation) == "" {
+		return nil, fmt.Errorf("reply is not a single-variable change: %.200q", reply)
+	}
+	return &BrewRecommendation{Change: body.BrewChange, Explanation: strings.TrimSpace(body.Explanation), Provider: provider}, nil
+}
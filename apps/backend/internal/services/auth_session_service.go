@@ -0,0 +1,229 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"time"
+)
+
+// Reasons a login session was revoked.
+const (
+	SessionRevokedLogout = "logout"
+	// SessionRevokedReuse means a refresh token was presented after it had
+	// been rotated, so it may have been stolen.
+	SessionRevokedReuse = "reuse"
+)
+
+// AuthSessionService keeps login sessions and their rotating refresh tokens.
+// Access tokens carry the session ID, so revoking a session rejects them
+// before they expire.
+type AuthSessionService struct {
+	queries    *db.Queries
+	refreshTTL time.Duration
+}
+
+func NewAuthSessionService(queries *db.Queries, refreshTTL time.Duration) *AuthSessionService {
+	return &AuthSessionService{
+		queries:    queries,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// IssuedSession is a session with a fresh refresh token, and the user the
+// access token for it is issued to.
+type IssuedSession struct {
+	SessionID        string
+	UserID           int64
+	Email            string
+	Username         string
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+// Start opens a session for a user who just signed in.
+func (s *AuthSessionService) Start(ctx context.Context, userID int64) (*IssuedSession, error) {
+	sessionID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	// Rotated tokens are kept until they expire to detect reuse; sign-ins
+	// sweep the expired ones
+	if err := s.queries.DeleteExpiredRefreshTokens(ctx, time.Now().UTC()); err != nil {
+		log.Printf("delete expired refresh tokens: %v", err)
+	}
+
+	var issued *IssuedSession
+	err = s.queries.InTx(ctx, func(q *db.Queries) error {
+		if err := q.CreateAuthSession(ctx, db.CreateAuthSessionParams{ID: sessionID, UserID: userID}); err != nil {
+			return err
+		}
+		issued, err = s.issue(ctx, q, sessionID, userID)
+		return err
+	})
+	return issued, err
+}
+
+// Refresh exchanges a refresh token for a new one in the same session. Each
+// token works once: presenting a used token again revokes the session, and
+// with it every token issued for it.
+func (s *AuthSessionService) Refresh(ctx context.Context, refreshToken string) (*IssuedSession, error) {
+	token, session, err := s.lookup(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if session.RevokedAt.Valid {
+		return nil, &UnauthorizedError{Message: "session has been revoked"}
+	}
+	if token.UsedAt.Valid {
+		return nil, s.revokeReused(ctx, session.ID)
+	}
+	if !time.Now().Before(token.ExpiresAt) {
+		return nil, &UnauthorizedError{Message: "refresh token has expired"}
+	}
+
+	var issued *IssuedSession
+	reused := false
+	err = s.queries.InTx(ctx, func(q *db.Queries) error {
+		n, err := q.UseRefreshToken(ctx, db.UseRefreshTokenParams{
+			UsedAt: sql.NullTime{Time: time.Now().UTC(), Valid: true},
+			ID:     token.ID,
+		})
+		if err != nil {
+			return err
+		}
+		// Another request rotated the token since it was read
+		if n == 0 {
+			reused = true
+			return nil
+		}
+		issued, err = s.issue(ctx, q, session.ID, session.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, s.revokeReused(ctx, session.ID)
+	}
+	return issued, nil
+}
+
+// Logout revokes the session of refreshToken, or sessionID when no token is
+// given. Unknown tokens and sessions are ignored, so logging out twice is
+// not an error.
+func (s *AuthSessionService) Logout(ctx context.Context, refreshToken, sessionID string) error {
+	if refreshToken != "" {
+		_, session, err := s.lookup(ctx, refreshToken)
+		var unauthorized *UnauthorizedError
+		if errors.As(err, &unauthorized) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sessionID = session.ID
+	}
+	if sessionID == "" {
+		return &UnauthorizedError{Message: "a refresh token or access token is required"}
+	}
+	return s.revoke(ctx, s.queries, sessionID, SessionRevokedLogout)
+}
+
+// SessionActive reports whether access tokens of the session are still
+// accepted.
+func (s *AuthSessionService) SessionActive(ctx context.Context, sessionID string) (bool, error) {
+	session, err := s.queries.GetAuthSession(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !session.RevokedAt.Valid, nil
+}
+
+// issue stores a new refresh token for the session.
+func (s *AuthSessionService) issue(ctx context.Context, q *db.Queries, sessionID string, userID int64) (*IssuedSession, error) {
+	user, err := q.GetAuthUser(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &UnauthorizedError{Message: "user no longer exists"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().UTC().Add(s.refreshTTL)
+	err = q.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
+		SessionID: sessionID,
+		TokenHash: hashRefreshToken(refreshToken),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &IssuedSession{
+		SessionID:        sessionID,
+		UserID:           user.ID,
+		Email:            user.Email,
+		Username:         user.Username,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *AuthSessionService) lookup(ctx context.Context, refreshToken string) (db.RefreshToken, db.AuthSession, error) {
+	token, err := s.queries.GetRefreshTokenByHash(ctx, hashRefreshToken(refreshToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		return token, db.AuthSession{}, &UnauthorizedError{Message: "invalid refresh token"}
+	}
+	if err != nil {
+		return token, db.AuthSession{}, err
+	}
+	session, err := s.queries.GetAuthSession(ctx, token.SessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return token, session, &UnauthorizedError{Message: "invalid refresh token"}
+	}
+	return token, session, err
+}
+
+func (s *AuthSessionService) revokeReused(ctx context.Context, sessionID string) error {
+	if err := s.revoke(ctx, s.queries, sessionID, SessionRevokedReuse); err != nil {
+		return err
+	}
+	log.Printf("refresh token reused; revoked session %s", sessionID)
+	return &UnauthorizedError{Message: "refresh token was already used; please sign in again"}
+}
+
+func (s *AuthSessionService) revoke(ctx context.Context, q *db.Queries, sessionID, reason string) error {
+	return q.RevokeAuthSession(ctx, db.RevokeAuthSessionParams{
+		RevokedAt:     sql.NullTime{Time: time.Now().UTC(), Valid: true},
+		RevokedReason: sql.NullString{String: reason, Valid: true},
+		ID:            sessionID,
+	})
+}
+
+// hashRefreshToken is what is stored of a refresh token. The tokens are
+// random, so a plain SHA-256 is enough to make a leaked table useless.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomToken returns n random bytes, URL-safe base64 encoded.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
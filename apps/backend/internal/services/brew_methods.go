@@ -0,0 +1,225 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Brew method categories group methods that share an extraction style.
+const (
+	BrewCategoryPourOver  = "pour-over"
+	BrewCategoryImmersion = "immersion"
+	BrewCategoryPressure  = "pressure"
+	BrewCategoryCold      = "cold"
+	BrewCategoryOther     = "other"
+)
+
+// BrewMethodOther is the catch-all method. It keeps the generic ranges brew
+// logs were validated against before the catalog existed.
+const BrewMethodOther = "other"
+
+// ParamRange is an inclusive range a brew parameter must fall in.
+type ParamRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// BrewMethod is one entry of the brew method catalog. Brew logs store the
+// canonical ID; aliases are accepted on input and resolved to it.
+type BrewMethod struct {
+	ID               string     `json:"id"`
+	Name             string     `json:"name"`
+	Category         string     `json:"category"`
+	Aliases          []string   `json:"aliases"`
+	CoffeeWeight     ParamRange `json:"coffeeWeight"`     // grams
+	WaterWeight      ParamRange `json:"waterWeight"`      // grams
+	WaterTemperature ParamRange `json:"waterTemperature"` // Celsius
+	BrewTime         ParamRange `json:"brewTime"`         // seconds
+	// Espresso is set for methods that take the espresso fields.
+	Espresso *EspressoRanges `json:"espresso,omitempty"`
+}
+
+// EspressoRanges bound the espresso-only fields of a brew log.
+type EspressoRanges struct {
+	YieldWeight     ParamRange `json:"yieldWeight"`     // grams out
+	Ratio           ParamRange `json:"ratio"`           // yield per gram of dose
+	PreInfusionTime ParamRange `json:"preInfusionTime"` // seconds
+	BasketSize      ParamRange `json:"basketSize"`      // grams
+	Pressure        ParamRange `json:"pressure"`        // bar
+}
+
+// brewMethodCatalog lists every supported method. Migration
+// 008_brew_method_catalog maps existing free-text values onto these IDs, so
+// aliases added here should be added there too.
+var brewMethodCatalog = []BrewMethod{
+	{
+		ID: "v60", Name: "Hario V60", Category: BrewCategoryPourOver,
+		Aliases:      []string{"Hario V60", "V-60", "V60 01", "V60 02", "V60 03"},
+		CoffeeWeight: ParamRange{5, 60}, WaterWeight: ParamRange{50, 1000},
+		WaterTemperature: ParamRange{80, 100}, BrewTime: ParamRange{60, 600},
+	},
+	{
+		ID: "kalita-wave", Name: "Kalita Wave", Category: BrewCategoryPourOver,
+		Aliases:      []string{"Kalita", "Wave", "Kalita Wave 155", "Kalita Wave 185"},
+		CoffeeWeight: ParamRange{5, 60}, WaterWeight: ParamRange{50, 1000},
+		WaterTemperature: ParamRange{80, 100}, BrewTime: ParamRange{60, 600},
+	},
+	{
+		ID: "chemex", Name: "Chemex", Category: BrewCategoryPourOver,
+		Aliases:      []string{"Chemex Classic"},
+		CoffeeWeight: ParamRange{10, 100}, WaterWeight: ParamRange{150, 1500},
+		WaterTemperature: ParamRange{80, 100}, BrewTime: ParamRange{120, 900},
+	},
+	{
+		ID: "origami", Name: "Origami Dripper", Category: BrewCategoryPourOver,
+		Aliases:      []string{"Origami"},
+		CoffeeWeight: ParamRange{5, 60}, WaterWeight: ParamRange{50, 1000},
+		WaterTemperature: ParamRange{80, 100}, BrewTime: ParamRange{60, 600},
+	},
+	{
+		ID: "clever", Name: "Clever Dripper", Category: BrewCategoryImmersion,
+		Aliases:      []string{"Clever"},
+		CoffeeWeight: ParamRange{5, 60}, WaterWeight: ParamRange{50, 1000},
+		WaterTemperature: ParamRange{80, 100}, BrewTime: ParamRange{60, 900},
+	},
+	{
+		ID: "aeropress", Name: "AeroPress", Category: BrewCategoryImmersion,
+		Aliases:      []string{"Aero Press", "AeroPress Go", "AeroPress XL"},
+		CoffeeWeight: ParamRange{5, 40}, WaterWeight: ParamRange{50, 500},
+		WaterTemperature: ParamRange{70, 100}, BrewTime: ParamRange{30, 600},
+	},
+	{
+		ID: "french-press", Name: "French Press", Category: BrewCategoryImmersion,
+		Aliases:      []string{"Cafetiere", "Cafetière", "Press Pot", "Plunger"},
+		CoffeeWeight: ParamRange{10, 120}, WaterWeight: ParamRange{150, 2000},
+		WaterTemperature: ParamRange{85, 100}, BrewTime: ParamRange{120, 1800},
+	},
+	{
+		ID: "siphon", Name: "Siphon", Category: BrewCategoryImmersion,
+		Aliases:      []string{"Syphon", "Vacuum Pot", "Vac Pot"},
+		CoffeeWeight: ParamRange{10, 60}, WaterWeight: ParamRange{150, 1000},
+		WaterTemperature: ParamRange{85, 100}, BrewTime: ParamRange{60, 600},
+	},
+	{
+		ID: "moka-pot", Name: "Moka Pot", Category: BrewCategoryPressure,
+		Aliases:      []string{"Moka", "Stovetop", "Bialetti"},
+		CoffeeWeight: ParamRange{5, 40}, WaterWeight: ParamRange{50, 500},
+		WaterTemperature: ParamRange{0, 100}, BrewTime: ParamRange{60, 900},
+	},
+	{
+		ID: "espresso", Name: "Espresso", Category: BrewCategoryPressure,
+		Aliases:      []string{"Shot", "Ristretto", "Lungo"},
+		CoffeeWeight: ParamRange{5, 30}, WaterWeight: ParamRange{10, 150},
+		WaterTemperature: ParamRange{85, 100}, BrewTime: ParamRange{10, 90},
+		Espresso: &EspressoRanges{
+			YieldWeight: ParamRange{5, 150}, Ratio: ParamRange{0.5, 5},
+			PreInfusionTime: ParamRange{0, 30}, BasketSize: ParamRange{5, 30},
+			Pressure: ParamRange{0, 15},
+		},
+	},
+	{
+		ID: "cold-brew", Name: "Cold Brew", Category: BrewCategoryCold,
+		Aliases:      []string{"Toddy", "Cold Steep"},
+		CoffeeWeight: ParamRange{10, 500}, WaterWeight: ParamRange{100, 3000},
+		WaterTemperature: ParamRange{0, 30}, BrewTime: ParamRange{3600, 172800},
+	},
+	{
+		ID: BrewMethodOther, Name: "Other", Category: BrewCategoryOther,
+		Aliases:      []string{},
+		CoffeeWeight: ParamRange{0, 200}, WaterWeight: ParamRange{0, 3000},
+		WaterTemperature: ParamRange{0, 100}, BrewTime: ParamRange{0, 3600},
+	},
+}
+
+// brewMethodIndex maps the lookup key of every ID, name and alias to its method.
+var brewMethodIndex = func() map[string]*BrewMethod {
+	index := make(map[string]*BrewMethod)
+	for i := range brewMethodCatalog {
+		m := &brewMethodCatalog[i]
+		for _, name := range append([]string{m.ID, m.Name}, m.Aliases...) {
+			index[brewMethodKey(name)] = m
+		}
+	}
+	return index
+}()
+
+// BrewMethods returns the catalog in display order.
+func BrewMethods() []BrewMethod {
+	methods := make([]BrewMethod, len(brewMethodCatalog))
+	copy(methods, brewMethodCatalog)
+	for i := range methods {
+		if methods[i].Espresso != nil {
+			ranges := *methods[i].Espresso
+			methods[i].Espresso = &ranges
+		}
+	}
+	return methods
+}
+
+// LookupBrewMethod resolves an ID, name or alias, ignoring case, spacing and
+// punctuation, so "V60", "v-60" and "Hario V60" are the same method.
+func LookupBrewMethod(s string) (BrewMethod, bool) {
+	m, ok := brewMethodIndex[brewMethodKey(s)]
+	if !ok {
+		return BrewMethod{}, false
+	}
+	return *m, true
+}
+
+// resolveBrewMethod is LookupBrewMethod for stored values: rows written before
+// the catalog existed may hold anything, and are treated as BrewMethodOther.
+func resolveBrewMethod(s string) BrewMethod {
+	if m, ok := LookupBrewMethod(s); ok {
+		return m
+	}
+	m, _ := LookupBrewMethod(BrewMethodOther)
+	return m
+}
+
+// normalizeBrewMethod turns user input into a canonical method ID.
+func normalizeBrewMethod(s string) (BrewMethod, error) {
+	if strings.TrimSpace(s) == "" {
+		return BrewMethod{}, &ValidationError{Message: "brewMethod is required"}
+	}
+	m, ok := LookupBrewMethod(s)
+	if !ok {
+		return BrewMethod{}, &ValidationError{Message: fmt.Sprintf("unknown brewMethod %q; use an id from GET /api/v1/brew-methods or %q", strings.TrimSpace(s), BrewMethodOther)}
+	}
+	return m, nil
+}
+
+// brewMethodKey keeps only lower-cased letters and digits. Migration 019
+// applies the same folding in SQL, as far as SQLite can for non-ASCII text;
+// stored values it could not map are still resolved by resolveBrewMethod.
+func brewMethodKey(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// checkRanges validates the parameters that are present against the
+// method's ranges.
+func (m BrewMethod) checkRanges(coffeeWeight, waterWeight, waterTemperature sql.NullFloat64, brewTime sql.NullInt64) error {
+	for _, p := range []struct {
+		name  string
+		value sql.NullFloat64
+		r     ParamRange
+		unit  string
+	}{
+		{"coffeeWeight", coffeeWeight, m.CoffeeWeight, ""},
+		{"waterWeight", waterWeight, m.WaterWeight, ""},
+		{"waterTemperature", waterTemperature, m.WaterTemperature, ""},
+		{"brewTime", sql.NullFloat64{Float64: float64(brewTime.Int64), Valid: brewTime.Valid}, m.BrewTime, " seconds"},
+	} {
+		if p.value.Valid && (p.value.Float64 < p.r.Min || p.value.Float64 > p.r.Max) {
+			return &ValidationError{Message: fmt.Sprintf("%s must be between %g and %g%s for %s", p.name, p.r.Min, p.r.Max, p.unit, m.Name)}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"database/sql"
+	"math"
+)
+
+// groundsRetention is the water (g) held back per gram of spent coffee. It is
+// used to estimate beverage weight when the user did not weigh the cup.
+const groundsRetention = 2.0
+
+// BrewMetrics are values derived from a brew log's measurements. The list
+// query filters and sorts on the same formulas (see db.BrewLogSortExprs).
+type BrewMetrics struct {
+	// Ratio is grams of water (or espresso yield) per gram of coffee, i.e. the x in 1:x.
+	Ratio *float64 `json:"ratio,omitempty"`
+	// ExtractionYield is the percentage of the dose that ended up in the cup.
+	ExtractionYield *float64 `json:"extractionYield,omitempty"`
+	// Strength is the beverage concentration in percent, which is the TDS reading.
+	Strength *float64 `json:"strength,omitempty"`
+}
+
+// computeBrewMetrics derives ratio from the dose and water, and extraction
+// yield and strength when a TDS reading is present. An espresso's yield
+// weight stands in for the water in the ratio (18 g in, 36 g out is 1:2) and
+// for an unweighed beverage.
+func computeBrewMetrics(coffeeWeight, waterWeight, yieldWeight, tds, beverageWeight sql.NullFloat64) BrewMetrics {
+	var m BrewMetrics
+	dose := coffeeWeight.Float64
+	hasDose := coffeeWeight.Valid && dose > 0
+	if yieldWeight.Valid {
+		waterWeight = yieldWeight
+		if !beverageWeight.Valid {
+			beverageWeight = yieldWeight
+		}
+	}
+
+	if hasDose && waterWeight.Valid {
+		m.Ratio = roundedMetric(waterWeight.Float64 / dose)
+	}
+	if !tds.Valid {
+		return m
+	}
+	m.Strength = roundedMetric(tds.Float64)
+
+	beverage := beverageWeight.Float64
+	if !beverageWeight.Valid {
+		if !hasDose || !waterWeight.Valid {
+			return m
+		}
+		beverage = waterWeight.Float64 - groundsRetention*dose
+	}
+	if hasDose && beverage > 0 {
+		m.ExtractionYield = roundedMetric(tds.Float64 * beverage / dose)
+	}
+	return m
+}
+
+func roundedMetric(v float64) *float64 {
+	r := math.Round(v*100) / 100
+	return &r
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	// recommendationHistorySize is how many of the latest rated brews of a
+	// coffee the recommender reads.
+	recommendationHistorySize = 50
+	// minRecommendationEvidence is how many changes to a variable the history
+	// needs before the recommender trusts what it saw.
+	minRecommendationEvidence = 2
+	// changeTolerance ignores differences below measurement noise.
+	changeTolerance = 1e-6
+)
+
+// RecommendationDataPoint is one pair of successive brews in which the
+// recommended variable changed.
+type RecommendationDataPoint struct {
+	FromBrewLogID int64   `json:"fromBrewLogId"`
+	ToBrewLogID   int64   `json:"toBrewLogId"`
+	From          float64 `json:"from"`
+	To            float64 `json:"to"`
+	RatingChange  int64   `json:"ratingChange"`
+	// OtherChanges counts the other variables that changed at the same time,
+	// which make the point weaker evidence.
+	OtherChanges int `json:"otherChanges"`
+}
+
+// RecommendationEvidence is what a recommendation from the user's history
+// rests on.
+type RecommendationEvidence struct {
+	// BrewLogs is how many rated brews of the coffee were compared.
+	BrewLogs int `json:"brewLogs"`
+	// Effect is the weighted average rating change when the variable moved
+	// in the recommended direction.
+	Effect float64 `json:"effect"`
+	// Agreement is the share of data points that moved the rating the same
+	// way as Effect, from 0 to 1.
+	Agreement  float64                   `json:"agreement"`
+	DataPoints []RecommendationDataPoint `json:"dataPoints"`
+}
+
+// brewVariable reads one recommendable variable from a brew log.
+type brewVariable struct {
+	name  string
+	label string
+	unit  string
+	value func(l db.BrewLog) sql.NullFloat64
+	// comparable reports whether the variable can be compared across two
+	// brews; grind settings only mean something on the same grinder.
+	comparable func(a, b db.BrewLog) bool
+	snapshot   func(s BrewSnapshot) *float64
+}
+
+var recommendableVariables = []brewVariable{
+	{
+		name: "grind", label: "grind setting", unit: " on the grind setting",
+		value: func(l db.BrewLog) sql.NullFloat64 { return l.GrindSetting },
+		comparable: func(a, b db.BrewLog) bool {
+			return a.GrinderID.Valid && a.GrinderID == b.GrinderID
+		},
+		snapshot: func(s BrewSnapshot) *float64 { return s.GrindSetting },
+	},
+	{
+		name: "coffeeWeight", label: "dose", unit: "g",
+		value:    func(l db.BrewLog) sql.NullFloat64 { return l.CoffeeWeight },
+		snapshot: func(s BrewSnapshot) *float64 { return s.CoffeeWeight },
+	},
+	{
+		name: "waterWeight", label: "water", unit: "g",
+		value:    func(l db.BrewLog) sql.NullFloat64 { return l.WaterWeight },
+		snapshot: func(s BrewSnapshot) *float64 { return s.WaterWeight },
+	},
+	{
+		name: "waterTemperature", label: "water temperature", unit: "°C",
+		value:    func(l db.BrewLog) sql.NullFloat64 { return l.WaterTemperature },
+		snapshot: func(s BrewSnapshot) *float64 { return s.WaterTemperature },
+	},
+	{
+		name: "brewTime", label: "brew time", unit: "s",
+		value: func(l db.BrewLog) sql.NullFloat64 {
+			return sql.NullFloat64{Float64: float64(l.BrewTime.Int64), Valid: l.BrewTime.Valid}
+		},
+		snapshot: func(s BrewSnapshot) *float64 {
+			if s.BrewTime == nil {
+				return nil
+			}
+			v := float64(*s.BrewTime)
+			return &v
+		},
+	},
+}
+
+// goalDirections is which way each variable has to move to get closer to
+// goal: 1 for up, -1 for down. Goals are read like RulesAIService reads
+// them; a goal it does not recognise, such as "better", gives nil and leaves
+// the choice to the ratings alone. Grind settings are left out because
+// grinders do not agree on which way is finer.
+func goalDirections(goal string) map[string]float64 {
+	goal = strings.ToLower(strings.TrimSpace(goal))
+	switch {
+	case strings.Contains(goal, "sweet"):
+		// more extraction
+		return map[string]float64{"waterTemperature": 1, "brewTime": 1}
+	case strings.Contains(goal, "bitter"):
+		// less extraction
+		return map[string]float64{"waterTemperature": -1, "brewTime": -1}
+	case strings.Contains(goal, "strong") || strings.Contains(goal, "strength"):
+		return map[string]float64{"coffeeWeight": 1, "waterWeight": -1}
+	}
+	return nil
+}
+
+// BrewRecommender suggests the next single-variable change for a brew. It
+// first looks for a change toward the goal that went with better ratings in
+// the user's own brews of the same coffee and method, and asks the AI
+// provider only when the history is too thin to say or points away from the
+// goal.
+type BrewRecommender struct {
+	queries *db.Queries
+	ai      AIService
+}
+
+func NewBrewRecommender(queries *db.Queries, ai AIService) *BrewRecommender {
+	return &BrewRecommender{
+		queries: queries,
+		ai:      ai,
+	}
+}
+
+// Recommend returns a change learned from history when input names a user
+// and a coffee with enough rated brews, and the AI provider's otherwise.
+func (r *BrewRecommender) Recommend(ctx context.Context, input BrewRecommendationInput) (*BrewRecommendation, error) {
+	if r.queries != nil && input.UserID != 0 && input.CoffeeID != nil {
+		logs, err := r.queries.ListRatedBrewLogsForCoffee(ctx, db.ListRatedBrewLogsForCoffeeParams{
+			UserID:   input.UserID,
+			CoffeeID: *input.CoffeeID,
+			Limit:    recommendationHistorySize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		method := ""
+		if input.BrewLog.BrewMethod != nil {
+			method = resolveBrewMethod(*input.BrewLog.BrewMethod).ID
+		}
+		if out := recommendFromHistory(logs, method, input.BrewLog, input.Goal); out != nil {
+			return out, nil
+		}
+	}
+	out, err := r.ai.RecommendBrew(ctx, input)
+	if err != nil {
+		return nil, &UnavailableError{Message: "recommendation failed", Err: err}
+	}
+	return out, nil
+}
+
+// recommendFromHistory compares each rated brew with the one before it
+// (newest first in logs) for the brew method, or the method of the latest
+// brew when method is empty. Every variable that changed between the two is
+// credited with the rating change, shared among the variables that changed
+// together. The variable whose changes went with the largest average rating
+// gain in one direction is recommended, stepping by the typical size of the
+// changes the user made. When the goal names a direction only changes that
+// move toward it are considered. It returns nil when no such variable has
+// enough evidence.
+func recommendFromHistory(logs []db.BrewLog, method string, base BrewSnapshot, goal string) *BrewRecommendation {
+	if method == "" && len(logs) > 0 {
+		method = resolveBrewMethod(logs[0].BrewMethod).ID
+	}
+	var brews []db.BrewLog
+	for i := len(logs) - 1; i >= 0; i-- {
+		if resolveBrewMethod(logs[i].BrewMethod).ID == method {
+			brews = append(brews, logs[i])
+		}
+	}
+	if len(brews) < 2 {
+		return nil
+	}
+
+	points := make(map[string][]RecommendationDataPoint)
+	for i := 1; i < len(brews); i++ {
+		prev, next := brews[i-1], brews[i]
+		var changed []RecommendationDataPoint
+		var names []string
+		for _, v := range recommendableVariables {
+			from, to := v.value(prev), v.value(next)
+			if !from.Valid || !to.Valid || math.Abs(to.Float64-from.Float64) < changeTolerance {
+				continue
+			}
+			if v.comparable != nil && !v.comparable(prev, next) {
+				continue
+			}
+			changed = append(changed, RecommendationDataPoint{
+				FromBrewLogID: prev.ID,
+				ToBrewLogID:   next.ID,
+				From:          from.Float64,
+				To:            to.Float64,
+				RatingChange:  next.Rating.Int64 - prev.Rating.Int64,
+			})
+			names = append(names, v.name)
+		}
+		for j, p := range changed {
+			p.OtherChanges = len(changed) - 1
+			points[names[j]] = append(points[names[j]], p)
+		}
+	}
+
+	directions := goalDirections(goal)
+	var best *BrewRecommendation
+	var bestVar brewVariable
+	for _, v := range recommendableVariables {
+		evidence := weighEvidence(points[v.name])
+		if evidence == nil {
+			continue
+		}
+		if directions != nil && directions[v.name] != math.Copysign(1, evidence.Effect) {
+			continue
+		}
+		if best == nil || math.Abs(evidence.Effect) > math.Abs(best.Evidence.Effect) ||
+			(math.Abs(evidence.Effect) == math.Abs(best.Evidence.Effect) && len(evidence.DataPoints) > len(best.Evidence.DataPoints)) {
+			best = &BrewRecommendation{Evidence: evidence}
+			bestVar = v
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	best.Evidence.BrewLogs = len(brews)
+
+	step := typicalStep(best.Evidence.DataPoints)
+	if best.Evidence.Effect < 0 {
+		step = -step
+	}
+	best.Evidence.Effect = math.Abs(best.Evidence.Effect)
+	best.Provider = "history"
+	best.Change = BrewChange{Variable: bestVar.name, Delta: fmt.Sprintf("%+g%s", step, bestVar.unit)}
+
+	current := bestVar.snapshot(base)
+	if current == nil {
+		if v := bestVar.value(brews[len(brews)-1]); v.Valid {
+			current = &v.Float64
+		}
+	}
+	if current != nil {
+		best.Change.Target = roundedMetric(*current + step)
+	}
+
+	direction := "Raising"
+	if step < 0 {
+		direction = "Lowering"
+	}
+	best.Explanation = fmt.Sprintf("%s the %s went with a rating %.1f points higher on average across %d of your brews of this coffee.",
+		direction, bestVar.label, best.Evidence.Effect, len(best.Evidence.DataPoints))
+	return best
+}
+
+// weighEvidence averages the rating change per data point with the sign of
+// the variable's change, so a positive effect favours raising it. Points
+// where several variables changed at once count for less. It returns nil
+// without enough points or a clear direction.
+func weighEvidence(points []RecommendationDataPoint) *RecommendationEvidence {
+	if len(points) < minRecommendationEvidence {
+		return nil
+	}
+	var sum, weights float64
+	for _, p := range points {
+		w := 1 / float64(1+p.OtherChanges)
+		sum += w * math.Copysign(1, p.To-p.From) * float64(p.RatingChange)
+		weights += w
+	}
+	effect := math.Round(sum/weights*100) / 100
+	if effect == 0 {
+		return nil
+	}
+	agreeing := 0
+	for _, p := range points {
+		if math.Copysign(1, p.To-p.From)*float64(p.RatingChange)*effect > 0 {
+			agreeing++
+		}
+	}
+	return &RecommendationEvidence{
+		Effect:     effect,
+		Agreement:  math.Round(float64(agreeing)/float64(len(points))*100) / 100,
+		DataPoints: points,
+	}
+}
+
+// typicalStep is the median size of the changes the user made, so the
+// suggestion is a step they are used to taking.
+func typicalStep(points []RecommendationDataPoint) float64 {
+	steps := make([]float64, len(points))
+	for i, p := range points {
+		steps[i] = math.Abs(p.To - p.From)
+	}
+	sort.Float64s(steps)
+	mid := len(steps) / 2
+	if len(steps)%2 == 0 {
+		return *roundedMetric((steps[mid-1] + steps[mid]) / 2)
+	}
+	return *roundedMetric(steps[mid])
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// grindStepTolerance absorbs float error when checking a setting sits on a scale step.
+const grindStepTolerance = 1e-6
+
+// checkEquipment validates the grinder, brewer and grind setting a brew log
+// will end up with. Equipment must belong to userID and be of the right type,
+// and a grind setting must be a position on the grinder's scale.
+func (s *BrewLogService) checkEquipment(ctx context.Context, userID int64, grinderID, brewerID sql.NullInt64, grindSetting sql.NullFloat64) error {
+	if brewerID.Valid {
+		if _, err := s.loadEquipment(ctx, userID, brewerID.Int64, EquipmentBrewer); err != nil {
+			return err
+		}
+	}
+	if !grinderID.Valid {
+		if grindSetting.Valid {
+			return &ValidationError{Message: "grindSetting requires a grinderId"}
+		}
+		return nil
+	}
+	grinder, err := s.loadEquipment(ctx, userID, grinderID.Int64, EquipmentGrinder)
+	if err != nil {
+		return err
+	}
+	if !grindSetting.Valid || !grinder.GrindScaleMin.Valid || !grinder.GrindScaleMax.Valid {
+		return nil
+	}
+	min, max := grinder.GrindScaleMin.Float64, grinder.GrindScaleMax.Float64
+	if grindSetting.Float64 < min || grindSetting.Float64 > max {
+		return &ValidationError{Message: fmt.Sprintf("grindSetting must be between %g and %g on this grinder", min, max)}
+	}
+	if grinder.GrindScaleStep.Valid {
+		steps := (grindSetting.Float64 - min) / grinder.GrindScaleStep.Float64
+		if math.Abs(steps-math.Round(steps)) > grindStepTolerance {
+			return &ValidationError{Message: fmt.Sprintf("grindSetting must be in steps of %g on this grinder", grinder.GrindScaleStep.Float64)}
+		}
+	}
+	return nil
+}
+
+// loadEquipment fetches the caller's equipment and checks it is of wantType.
+func (s *BrewLogService) loadEquipment(ctx context.Context, userID, id int64, wantType string) (db.Equipment, error) {
+	e, err := s.queries.GetEquipmentByID(ctx, db.GetEquipmentByIDParams{ID: id, UserID: userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return e, &NotFoundError{Message: wantType + " not found"}
+	} else if err != nil {
+		return e, err
+	}
+	if e.Type != wantType {
+		return e, &ValidationError{Message: fmt.Sprintf("equipment %d is a %s, not a %s", id, e.Type, wantType)}
+	}
+	return e, nil
+}
+
+// nullEquipmentID maps nil and 0 (the "unlink" value) to NULL.
+func nullEquipmentID(p *int64) sql.NullInt64 {
+	if p == nil || *p == 0 {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *p, Valid: true}
+}
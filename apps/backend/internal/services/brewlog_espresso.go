@@ -0,0 +1,165 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+const maxPressurePoints = 100
+
+// EspressoFields are the brew log fields that only apply to methods with
+// EspressoRanges in the catalog.
+type EspressoFields struct {
+	YieldWeight     *float64 `json:"yieldWeight,omitempty"`     // grams in the cup
+	PreInfusionTime *int64   `json:"preInfusionTime,omitempty"` // seconds, part of brewTime
+	BasketSize      *float64 `json:"basketSize,omitempty"`      // nominal basket dose, grams
+}
+
+// PressurePoint is one sample of a shot's pressure profile.
+type PressurePoint struct {
+	TimeOffset int64   `json:"timeOffset"` // seconds from the start of the shot
+	Pressure   float64 `json:"pressure"`   // bar
+}
+
+// espressoValues is the merged espresso state of a brew log as stored.
+type espressoValues struct {
+	YieldWeight     sql.NullFloat64
+	PressureProfile sql.NullString
+	PreInfusionTime sql.NullInt64
+	BasketSize      sql.NullFloat64
+}
+
+func (v espressoValues) isZero() bool {
+	return v == espressoValues{}
+}
+
+// checkEspresso validates the espresso fields against the method. Methods
+// without espresso ranges accept none of them.
+func (m BrewMethod) checkEspresso(v espressoValues, coffeeWeight sql.NullFloat64, brewTime sql.NullInt64, profile []PressurePoint) error {
+	if m.Espresso == nil {
+		if !v.isZero() {
+			return &ValidationError{Message: fmt.Sprintf("yieldWeight, preInfusionTime, basketSize and pressureProfile only apply to espresso, not %s", m.Name)}
+		}
+		return nil
+	}
+	r := m.Espresso
+	for _, p := range []struct {
+		name  string
+		value sql.NullFloat64
+		r     ParamRange
+		unit  string
+	}{
+		{"yieldWeight", v.YieldWeight, r.YieldWeight, ""},
+		{"preInfusionTime", sql.NullFloat64{Float64: float64(v.PreInfusionTime.Int64), Valid: v.PreInfusionTime.Valid}, r.PreInfusionTime, " seconds"},
+		{"basketSize", v.BasketSize, r.BasketSize, ""},
+	} {
+		if p.value.Valid && (p.value.Float64 < p.r.Min || p.value.Float64 > p.r.Max) {
+			return &ValidationError{Message: fmt.Sprintf("%s must be between %g and %g%s for %s", p.name, p.r.Min, p.r.Max, p.unit, m.Name)}
+		}
+	}
+	if v.YieldWeight.Valid && coffeeWeight.Valid && coffeeWeight.Float64 > 0 {
+		ratio := v.YieldWeight.Float64 / coffeeWeight.Float64
+		if ratio < r.Ratio.Min || ratio > r.Ratio.Max {
+			return &ValidationError{Message: fmt.Sprintf("yieldWeight must be between %g and %g times coffeeWeight for %s", r.Ratio.Min, r.Ratio.Max, m.Name)}
+		}
+	}
+	if v.PreInfusionTime.Valid && brewTime.Valid && v.PreInfusionTime.Int64 >= brewTime.Int64 {
+		return &ValidationError{Message: "preInfusionTime must be shorter than brewTime"}
+	}
+	for i, p := range profile {
+		if p.TimeOffset < 0 || (brewTime.Valid && p.TimeOffset > brewTime.Int64) {
+			return &ValidationError{Message: fmt.Sprintf("pressureProfile[%d].timeOffset must be between 0 and brewTime", i)}
+		}
+		if i > 0 && p.TimeOffset <= profile[i-1].TimeOffset {
+			return &ValidationError{Message: "pressureProfile must be in ascending timeOffset order"}
+		}
+		if p.Pressure < r.Pressure.Min || p.Pressure > r.Pressure.Max {
+			return &ValidationError{Message: fmt.Sprintf("pressureProfile[%d].pressure must be between %g and %g bar", i, r.Pressure.Min, r.Pressure.Max)}
+		}
+	}
+	return nil
+}
+
+// mergeEspresso applies the espresso fields of a partial update to params,
+// which already carry the merged method, dose and brew time. Espresso values
+// do not carry over when a log moves to a method without them.
+func mergeEspresso(params *db.UpdateBrewLogParams, input UpdateBrewLogInput) error {
+	method := resolveBrewMethod(params.BrewMethod)
+	given := input.EspressoFields != (EspressoFields{}) || input.PressureProfile != nil
+	if method.Espresso == nil && !given {
+		params.YieldWeight = sql.NullFloat64{}
+		params.PressureProfile = sql.NullString{}
+		params.PreInfusionTime = sql.NullInt64{}
+		params.BasketSize = sql.NullFloat64{}
+		return nil
+	}
+
+	if input.YieldWeight != nil {
+		params.YieldWeight = nullFloat64(input.YieldWeight)
+	}
+	if input.PreInfusionTime != nil {
+		params.PreInfusionTime = nullInt64(input.PreInfusionTime)
+	}
+	if input.BasketSize != nil {
+		params.BasketSize = nullFloat64(input.BasketSize)
+	}
+	profile := decodePressureProfile(params.PressureProfile)
+	if input.PressureProfile != nil {
+		profile = *input.PressureProfile
+		encoded, err := encodePressureProfile(profile)
+		if err != nil {
+			return err
+		}
+		params.PressureProfile = encoded
+	}
+	return method.checkEspresso(espressoValues{
+		YieldWeight:     params.YieldWeight,
+		PressureProfile: params.PressureProfile,
+		PreInfusionTime: params.PreInfusionTime,
+		BasketSize:      params.BasketSize,
+	}, params.CoffeeWeight, params.BrewTime, profile)
+}
+
+func toEspressoFields(yieldWeight sql.NullFloat64, preInfusionTime sql.NullInt64, basketSize sql.NullFloat64) EspressoFields {
+	var f EspressoFields
+	if yieldWeight.Valid {
+		f.YieldWeight = &yieldWeight.Float64
+	}
+	if preInfusionTime.Valid {
+		f.PreInfusionTime = &preInfusionTime.Int64
+	}
+	if basketSize.Valid {
+		f.BasketSize = &basketSize.Float64
+	}
+	return f
+}
+
+// encodePressureProfile stores a profile as JSON; an empty profile is NULL.
+func encodePressureProfile(profile []PressurePoint) (sql.NullString, error) {
+	if len(profile) > maxPressurePoints {
+		return sql.NullString{}, &ValidationError{Message: fmt.Sprintf("at most %d pressureProfile points are allowed", maxPressurePoints)}
+	}
+	if len(profile) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(profile)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+// decodePressureProfile reads a stored profile. Unreadable values are
+// dropped rather than failing the whole brew log.
+func decodePressureProfile(s sql.NullString) []PressurePoint {
+	if !s.Valid {
+		return nil
+	}
+	var profile []PressurePoint
+	if err := json.Unmarshal([]byte(s.String), &profile); err != nil {
+		return nil
+	}
+	return profile
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"fmt"
+	"math"
+)
+
+const (
+	maxBrewLogPours = 50
+	// pourTotalTolerance absorbs scale rounding when comparing pours to waterWeight.
+	pourTotalTolerance = 0.5
+)
+
+// BrewLogPour is one stage of a multi-stage pour (bloom, pulses). Stages are
+// kept in the order given, which must also be ascending by TimeOffset.
+type BrewLogPour struct {
+	TimeOffset       int64    `json:"timeOffset"` // seconds from the start of the brew
+	WaterAmount      float64  `json:"waterAmount"`
+	WaterTemperature *float64 `json:"waterTemperature,omitempty"`
+}
+
+// validatePours checks each stage and the ordering, and returns the total water poured.
+func validatePours(pours []BrewLogPour) (float64, error) {
+	if len(pours) > maxBrewLogPours {
+		return 0, &ValidationError{Message: fmt.Sprintf("at most %d pours are allowed", maxBrewLogPours)}
+	}
+	var total float64
+	for i, p := range pours {
+		if p.TimeOffset < 0 || p.TimeOffset > 3600 {
+			return 0, &ValidationError{Message: fmt.Sprintf("pours[%d].timeOffset must be between 0 and 3600 seconds", i)}
+		}
+		if i > 0 && p.TimeOffset <= pours[i-1].TimeOffset {
+			return 0, &ValidationError{Message: "pours must be in ascending timeOffset order"}
+		}
+		if p.WaterAmount <= 0 || p.WaterAmount > 3000 {
+			return 0, &ValidationError{Message: fmt.Sprintf("pours[%d].waterAmount must be greater than 0 and at most 3000", i)}
+		}
+		if p.WaterTemperature != nil && (*p.WaterTemperature < 0 || *p.WaterTemperature > 100) {
+			return 0, &ValidationError{Message: fmt.Sprintf("pours[%d].waterTemperature must be between 0 and 100", i)}
+		}
+		total += p.WaterAmount
+	}
+	if total > 3000 {
+		return 0, &ValidationError{Message: "pours must not add up to more than 3000"}
+	}
+	return total, nil
+}
+
+// checkPourTotal requires the stages to add up to the brew's water weight.
+func checkPourTotal(total float64, waterWeight *float64) error {
+	if waterWeight == nil {
+		return &ValidationError{Message: "waterWeight is required when pours are given"}
+	}
+	if math.Abs(total-*waterWeight) > pourTotalTolerance {
+		return &ValidationError{Message: fmt.Sprintf("pours add up to %gg but waterWeight is %gg", total, *waterWeight)}
+	}
+	return nil
+}
+
+// replacePours swaps the stored stages of a brew log for pours.
+func replacePours(ctx context.Context, q *db.Queries, brewLogID int64, pours []BrewLogPour) error {
+	if err := q.DeleteBrewLogPours(ctx, brewLogID); err != nil {
+		return err
+	}
+	for i, p := range pours {
+		if err := q.CreateBrewLogPour(ctx, db.CreateBrewLogPourParams{
+			BrewLogID:        brewLogID,
+			Position:         int64(i + 1),
+			TimeOffset:       p.TimeOffset,
+			WaterAmount:      p.WaterAmount,
+			WaterTemperature: nullFloat64(p.WaterTemperature),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toBrewLogPours(rows []db.BrewLogPour) []BrewLogPour {
+	pours := make([]BrewLogPour, 0, len(rows))
+	for _, r := range rows {
+		p := BrewLogPour{TimeOffset: r.TimeOffset, WaterAmount: r.WaterAmount}
+		if r.WaterTemperature.Valid {
+			p.WaterTemperature = &r.WaterTemperature.Float64
+		}
+		pours = append(pours, p)
+	}
+	return pours
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+)
+
+// RepeatBrewLogInput brews an existing log again. Overrides follow the update
+// rules: nil fields keep the copied values.
+type RepeatBrewLogInput struct {
+	ID         int64   `json:"id"`
+	UserID     int64   `json:"user_id"`
+	CoffeeID   *int64  `json:"coffeeId,omitempty"`
+	BrewMethod *string `json:"brewMethod,omitempty"`
+	BrewLogFields
+	// Pours replaces the copied stages when non-nil; an empty list drops them.
+	Pours *[]BrewLogPour `json:"pours,omitempty"`
+	// PressureProfile replaces the copied profile when non-nil.
+	PressureProfile *[]PressurePoint `json:"pressureProfile,omitempty"`
+}
+
+// Repeat creates a new brew log pre-filled from one of the caller's logs and
+// records it as the parent. Only the recipe is copied: the rating, tasting
+// notes, TDS and beverage weight describe how that brew turned out and start
+// empty unless given as overrides.
+func (s *BrewLogService) Repeat(ctx context.Context, input RepeatBrewLogInput) (*BrewLogOutput, error) {
+	source, err := s.getOwned(ctx, input.UserID, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	pours, err := s.queries.ListBrewLogPours(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	recipe := toBrewLogOutput(source, pours)
+
+	create := CreateBrewLogInput{
+		UserID:   input.UserID,
+		CoffeeID: source.CoffeeID,
+		// Legacy free-text methods are repeated as BrewMethodOther
+		BrewMethod: resolveBrewMethod(source.BrewMethod).ID,
+		BrewLogFields: BrewLogFields{
+			CoffeeWeight:     recipe.CoffeeWeight,
+			WaterWeight:      recipe.WaterWeight,
+			GrindSize:        recipe.GrindSize,
+			WaterTemperature: recipe.WaterTemperature,
+			BrewTime:         recipe.BrewTime,
+			GrinderID:        recipe.GrinderID,
+			BrewerID:         recipe.BrewerID,
+			GrindSetting:     recipe.GrindSetting,
+			EspressoFields:   recipe.EspressoFields,
+			Visibility:       recipe.Visibility,
+		},
+		Pours:           recipe.Pours,
+		PressureProfile: recipe.PressureProfile,
+	}
+	if err := input.applyTo(&create); err != nil {
+		return nil, err
+	}
+	return s.create(ctx, create, sql.NullInt64{Int64: source.ID, Valid: true})
+}
+
+// applyTo overlays the overrides on the copied recipe. Changing the grinder
+// without a new setting, or switching to a method without espresso fields,
+// drops the copied values that no longer apply.
+func (in RepeatBrewLogInput) applyTo(c *CreateBrewLogInput) error {
+	if in.CoffeeID != nil {
+		if *in.CoffeeID <= 0 {
+			return &ValidationError{Message: "coffeeId must be a positive integer"}
+		}
+		c.CoffeeID = *in.CoffeeID
+	}
+	if in.BrewMethod != nil {
+		method, err := normalizeBrewMethod(*in.BrewMethod)
+		if err != nil {
+			return err
+		}
+		c.BrewMethod = method.ID
+		if method.Espresso == nil && in.EspressoFields == (EspressoFields{}) && in.PressureProfile == nil {
+			c.EspressoFields = EspressoFields{}
+			c.PressureProfile = nil
+		}
+	}
+
+	f := in.BrewLogFields
+	if f.CoffeeWeight != nil {
+		c.CoffeeWeight = f.CoffeeWeight
+	}
+	if f.WaterWeight != nil {
+		c.WaterWeight = f.WaterWeight
+	}
+	if f.GrindSize != nil {
+		c.GrindSize = f.GrindSize
+	}
+	if f.WaterTemperature != nil {
+		c.WaterTemperature = f.WaterTemperature
+	}
+	if f.BrewTime != nil {
+		c.BrewTime = f.BrewTime
+	}
+	if f.TastingNotes != nil {
+		c.TastingNotes = f.TastingNotes
+	}
+	if f.Rating != nil {
+		c.Rating = f.Rating
+	}
+	if f.Tds != nil {
+		c.Tds = f.Tds
+	}
+	if f.BeverageWeight != nil {
+		c.BeverageWeight = f.BeverageWeight
+	}
+	if f.GrinderID != nil {
+		if c.GrinderID == nil || *c.GrinderID != *f.GrinderID {
+			c.GrindSetting = nil
+		}
+		c.GrinderID = f.GrinderID
+	}
+	if f.BrewerID != nil {
+		c.BrewerID = f.BrewerID
+	}
+	if f.GrindSetting != nil {
+		c.GrindSetting = f.GrindSetting
+	}
+	if f.YieldWeight != nil {
+		c.YieldWeight = f.YieldWeight
+	}
+	if f.PreInfusionTime != nil {
+		c.PreInfusionTime = f.PreInfusionTime
+	}
+	if f.BasketSize != nil {
+		c.BasketSize = f.BasketSize
+	}
+	if f.Visibility != nil {
+		c.Visibility = f.Visibility
+	}
+	if in.Pours != nil {
+		c.Pours = *in.Pours
+	}
+	if in.PressureProfile != nil {
+		c.PressureProfile = *in.PressureProfile
+	}
+	return nil
+}
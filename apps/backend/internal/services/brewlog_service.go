@@ -0,0 +1,943 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultBrewLogPageSize = 20
+	MaxBrewLogPageSize     = 100
+)
+
+// Brew log visibility levels. A user's setting applies to all of their brew
+// logs unless a log carries its own override.
+const (
+	VisibilityPrivate   = "private"
+	VisibilityFollowers = "followers"
+	VisibilityPublic    = "public"
+)
+
+// brewLogTimeLayout keeps the millisecond UTC format brew log responses have always used.
+const brewLogTimeLayout = "2006-01-02T15:04:05.000Z"
+
+type BrewLogService struct {
+	queries *db.Queries
+}
+
+func NewBrewLogService(queries *db.Queries) *BrewLogService {
+	return &BrewLogService{
+		queries: queries,
+	}
+}
+
+type BrewLogOutput struct {
+	ID               int64    `json:"id"`
+	UserID           int64    `json:"userId"`
+	CoffeeID         int64    `json:"coffeeId"`
+	BrewMethod       string   `json:"brewMethod"`
+	CoffeeWeight     *float64 `json:"coffeeWeight,omitempty"`
+	WaterWeight      *float64 `json:"waterWeight,omitempty"`
+	GrindSize        *string  `json:"grindSize,omitempty"`
+	WaterTemperature *float64 `json:"waterTemperature,omitempty"`
+	BrewTime         *int64   `json:"brewTime,omitempty"`
+	TastingNotes     *string  `json:"tastingNotes,omitempty"`
+	Rating           *int64   `json:"rating,omitempty"`
+	Tds              *float64 `json:"tds,omitempty"`
+	BeverageWeight   *float64 `json:"beverageWeight,omitempty"`
+	GrinderID        *int64   `json:"grinderId,omitempty"`
+	BrewerID         *int64   `json:"brewerId,omitempty"`
+	GrindSetting     *float64 `json:"grindSetting,omitempty"`
+	EspressoFields
+	PressureProfile []PressurePoint `json:"pressureProfile,omitempty"`
+	BrewMetrics
+	Pours      []BrewLogPour `json:"pours"`
+	Visibility *string       `json:"visibility,omitempty"` // override; omitted when the owner's setting applies
+	// ParentBrewLogID is the log this one was repeated from.
+	ParentBrewLogID *int64 `json:"parentBrewLogId,omitempty"`
+	CreatedAt       string `json:"createdAt"`
+	UpdatedAt       string `json:"updatedAt"`
+}
+
+// BrewLogFields are the optional measurements shared by create and update inputs.
+type BrewLogFields struct {
+	CoffeeWeight     *float64 `json:"coffeeWeight,omitempty"`
+	WaterWeight      *float64 `json:"waterWeight,omitempty"`
+	GrindSize        *string  `json:"grindSize,omitempty"`
+	WaterTemperature *float64 `json:"waterTemperature,omitempty"`
+	BrewTime         *int64   `json:"brewTime,omitempty"` // seconds
+	TastingNotes     *string  `json:"tastingNotes,omitempty"`
+	Rating           *int64   `json:"rating,omitempty"`
+	Tds              *float64 `json:"tds,omitempty"`            // total dissolved solids, percent
+	BeverageWeight   *float64 `json:"beverageWeight,omitempty"` // grams in the cup
+	// GrinderID and BrewerID reference the caller's equipment; 0 unlinks it.
+	GrinderID    *int64   `json:"grinderId,omitempty"`
+	BrewerID     *int64   `json:"brewerId,omitempty"`
+	GrindSetting *float64 `json:"grindSetting,omitempty"` // position on the grinder's scale
+	EspressoFields
+	// Visibility overrides the owner's setting for this log; "" falls back to it.
+	Visibility *string `json:"visibility,omitempty"`
+}
+
+type CreateBrewLogInput struct {
+	UserID     int64  `json:"user_id"`
+	CoffeeID   int64  `json:"coffeeId"`
+	BrewMethod string `json:"brewMethod"`
+	BrewLogFields
+	// Pours must add up to WaterWeight; when WaterWeight is omitted it is taken from them.
+	Pours []BrewLogPour `json:"pours,omitempty"`
+	// PressureProfile is only accepted for espresso.
+	PressureProfile []PressurePoint `json:"pressureProfile,omitempty"`
+}
+
+// UpdateBrewLogInput is a partial update: nil fields keep their stored values.
+type UpdateBrewLogInput struct {
+	ID         int64   `json:"id"`
+	UserID     int64   `json:"user_id"`
+	CoffeeID   *int64  `json:"coffeeId,omitempty"`
+	BrewMethod *string `json:"brewMethod,omitempty"`
+	BrewLogFields
+	// Pours replaces all stages when non-nil; an empty list removes them.
+	Pours *[]BrewLogPour `json:"pours,omitempty"`
+	// PressureProfile replaces the stored profile when non-nil.
+	PressureProfile *[]PressurePoint `json:"pressureProfile,omitempty"`
+}
+
+type ListBrewLogsInput struct {
+	UserID        int64
+	CoffeeID      *int64
+	BrewMethod    *string
+	GrinderID     *int64
+	BrewerID      *int64
+	MinRating     *int64
+	MaxRating     *int64
+	CreatedFrom   *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+	// Inclusive ranges over the derived BrewMetrics
+	MinRatio           *float64
+	MaxRatio           *float64
+	MinExtractionYield *float64
+	MaxExtractionYield *float64
+	MinStrength        *float64
+	MaxStrength        *float64
+	Sort               string // date (default), rating, ratio, extractionYield or strength
+	Order              string // desc (default) or asc
+	Limit              int    // 0 means DefaultBrewLogPageSize
+	Cursor             string // NextCursor from the previous page
+}
+
+type BrewLogPage struct {
+	BrewLogs   []BrewLogOutput `json:"brewLogs"`
+	NextCursor *string         `json:"nextCursor"`
+}
+
+type ListUserBrewLogsInput struct {
+	OwnerID  int64
+	ViewerID int64  // 0 for anonymous callers
+	Limit    int    // 0 means DefaultBrewLogPageSize
+	Cursor   string // NextCursor from the previous page
+}
+
+// PublicBrewLogOutput is a brew log as shown on someone's journal feed. It
+// carries the owner's username but never their e-mail address.
+type PublicBrewLogOutput struct {
+	ID               int64    `json:"id"`
+	UserID           int64    `json:"userId"`
+	Username         string   `json:"username"`
+	CoffeeID         int64    `json:"coffeeId"`
+	CoffeeName       string   `json:"coffeeName"`
+	CoffeeRoaster    *string  `json:"coffeeRoaster,omitempty"`
+	BrewMethod       string   `json:"brewMethod"`
+	CoffeeWeight     *float64 `json:"coffeeWeight,omitempty"`
+	WaterWeight      *float64 `json:"waterWeight,omitempty"`
+	GrindSize        *string  `json:"grindSize,omitempty"`
+	WaterTemperature *float64 `json:"waterTemperature,omitempty"`
+	BrewTime         *int64   `json:"brewTime,omitempty"`
+	TastingNotes     *string  `json:"tastingNotes,omitempty"`
+	Rating           *int64   `json:"rating,omitempty"`
+	Tds              *float64 `json:"tds,omitempty"`
+	BeverageWeight   *float64 `json:"beverageWeight,omitempty"`
+	EspressoFields
+	PressureProfile []PressurePoint `json:"pressureProfile,omitempty"`
+	BrewMetrics
+	Visibility string `json:"visibility"` // effective level after applying any override
+	CreatedAt  string `json:"createdAt"`
+}
+
+type PublicBrewLogPage struct {
+	BrewLogs   []PublicBrewLogOutput `json:"brewLogs"`
+	NextCursor *string               `json:"nextCursor"`
+}
+
+// brewLogCursor is the opaque keyset position handed back as NextCursor.
+type brewLogCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value any    `json:"v"`
+	ID    int64  `json:"id"`
+}
+
+func (s *BrewLogService) Get(ctx context.Context, userID, id int64) (*BrewLogOutput, error) {
+	brewLog, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	pours, err := s.queries.ListBrewLogPours(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toBrewLogOutput(brewLog, pours), nil
+}
+
+func (s *BrewLogService) Create(ctx context.Context, input CreateBrewLogInput) (*BrewLogOutput, error) {
+	return s.create(ctx, input, sql.NullInt64{})
+}
+
+// create validates and stores a new brew log. parentID links a repeated brew
+// to the log it was copied from.
+func (s *BrewLogService) create(ctx context.Context, input CreateBrewLogInput, parentID sql.NullInt64) (*BrewLogOutput, error) {
+	// Validate input
+	if input.CoffeeID <= 0 {
+		return nil, &ValidationError{Message: "coffeeId is required"}
+	}
+	method, err := normalizeBrewMethod(input.BrewMethod)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.Pours) > 0 {
+		total, err := validatePours(input.Pours)
+		if err != nil {
+			return nil, err
+		}
+		if input.WaterWeight == nil {
+			input.WaterWeight = &total
+		} else if err := checkPourTotal(total, input.WaterWeight); err != nil {
+			return nil, err
+		}
+	}
+	if err := input.BrewLogFields.validate(); err != nil {
+		return nil, err
+	}
+	if err := method.checkRanges(nullFloat64(input.CoffeeWeight), nullFloat64(input.WaterWeight), nullFloat64(input.WaterTemperature), nullInt64(input.BrewTime)); err != nil {
+		return nil, err
+	}
+	pressureProfile, err := encodePressureProfile(input.PressureProfile)
+	if err != nil {
+		return nil, err
+	}
+	espresso := espressoValues{
+		YieldWeight:     nullFloat64(input.YieldWeight),
+		PressureProfile: pressureProfile,
+		PreInfusionTime: nullInt64(input.PreInfusionTime),
+		BasketSize:      nullFloat64(input.BasketSize),
+	}
+	if err := method.checkEspresso(espresso, nullFloat64(input.CoffeeWeight), nullInt64(input.BrewTime), input.PressureProfile); err != nil {
+		return nil, err
+	}
+	if err := checkBeverageWeight(nullFloat64(input.WaterWeight), nullFloat64(input.BeverageWeight)); err != nil {
+		return nil, err
+	}
+
+	// Ensure coffee exists and is owned by user
+	if err := s.checkCoffeeOwner(ctx, input.CoffeeID, input.UserID); err != nil {
+		return nil, err
+	}
+	grinderID, brewerID := nullEquipmentID(input.GrinderID), nullEquipmentID(input.BrewerID)
+	if err := s.checkEquipment(ctx, input.UserID, grinderID, brewerID, nullFloat64(input.GrindSetting)); err != nil {
+		return nil, err
+	}
+
+	var brewLog db.BrewLog
+	err = s.queries.InTx(ctx, func(q *db.Queries) error {
+		var err error
+		brewLog, err = q.CreateBrewLog(ctx, db.CreateBrewLogParams{
+			UserID:           input.UserID,
+			CoffeeID:         input.CoffeeID,
+			BrewMethod:       method.ID,
+			CoffeeWeight:     nullFloat64(input.CoffeeWeight),
+			WaterWeight:      nullFloat64(input.WaterWeight),
+			GrindSize:        nullTrimmedString(input.GrindSize),
+			WaterTemperature: nullFloat64(input.WaterTemperature),
+			BrewTime:         nullInt64(input.BrewTime),
+			TastingNotes:     nullTrimmedString(input.TastingNotes),
+			Rating:           nullInt64(input.Rating),
+			Visibility:       nullTrimmedString(input.Visibility),
+			Tds:              nullFloat64(input.Tds),
+			BeverageWeight:   nullFloat64(input.BeverageWeight),
+			GrinderID:        grinderID,
+			BrewerID:         brewerID,
+			GrindSetting:     nullFloat64(input.GrindSetting),
+			YieldWeight:      espresso.YieldWeight,
+			PressureProfile:  espresso.PressureProfile,
+			PreInfusionTime:  espresso.PreInfusionTime,
+			BasketSize:       espresso.BasketSize,
+			ParentBrewLogID:  parentID,
+		})
+		if err != nil {
+			return err
+		}
+		if err := consumeCoffee(ctx, q, brewLog.CoffeeID, brewLog.CoffeeWeight, -1); err != nil {
+			return err
+		}
+		return replacePours(ctx, q, brewLog.ID, input.Pours)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, input.UserID, brewLog.ID)
+}
+
+func (s *BrewLogService) Update(ctx context.Context, input UpdateBrewLogInput) (*BrewLogOutput, error) {
+	// Validate input before touching the database
+	if input.CoffeeID != nil && *input.CoffeeID <= 0 {
+		return nil, &ValidationError{Message: "coffeeId must be a positive integer"}
+	}
+	var method BrewMethod
+	if input.BrewMethod != nil {
+		if strings.TrimSpace(*input.BrewMethod) == "" {
+			return nil, &ValidationError{Message: "brewMethod cannot be empty"}
+		}
+		var err error
+		if method, err = normalizeBrewMethod(*input.BrewMethod); err != nil {
+			return nil, err
+		}
+	}
+	if err := input.BrewLogFields.validate(); err != nil {
+		return nil, err
+	}
+	var pourTotal float64
+	if input.Pours != nil {
+		total, err := validatePours(*input.Pours)
+		if err != nil {
+			return nil, err
+		}
+		pourTotal = total
+	}
+	if input.CoffeeID == nil && input.BrewMethod == nil && input.BrewLogFields == (BrewLogFields{}) && input.Pours == nil && input.PressureProfile == nil {
+		return nil, &ValidationError{Message: "At least one field must be provided"}
+	}
+
+	existing, err := s.getOwned(ctx, input.UserID, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	// Moving a log to another coffee requires owning that coffee too
+	if input.CoffeeID != nil {
+		if err := s.checkCoffeeOwner(ctx, *input.CoffeeID, input.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	params := db.UpdateBrewLogParams{
+		CoffeeID:         existing.CoffeeID,
+		BrewMethod:       existing.BrewMethod,
+		CoffeeWeight:     existing.CoffeeWeight,
+		WaterWeight:      existing.WaterWeight,
+		GrindSize:        existing.GrindSize,
+		WaterTemperature: existing.WaterTemperature,
+		BrewTime:         existing.BrewTime,
+		TastingNotes:     existing.TastingNotes,
+		Rating:           existing.Rating,
+		Visibility:       existing.Visibility,
+		Tds:              existing.Tds,
+		BeverageWeight:   existing.BeverageWeight,
+		GrinderID:        existing.GrinderID,
+		BrewerID:         existing.BrewerID,
+		GrindSetting:     existing.GrindSetting,
+		YieldWeight:      existing.YieldWeight,
+		PressureProfile:  existing.PressureProfile,
+		PreInfusionTime:  existing.PreInfusionTime,
+		BasketSize:       existing.BasketSize,
+		ID:               existing.ID,
+		UserID:           input.UserID,
+	}
+	if input.CoffeeID != nil {
+		params.CoffeeID = *input.CoffeeID
+	}
+	if input.BrewMethod != nil {
+		params.BrewMethod = method.ID
+	}
+	if input.CoffeeWeight != nil {
+		params.CoffeeWeight = nullFloat64(input.CoffeeWeight)
+	}
+	if input.WaterWeight != nil {
+		params.WaterWeight = nullFloat64(input.WaterWeight)
+	}
+	if input.GrindSize != nil {
+		params.GrindSize = nullTrimmedString(input.GrindSize)
+	}
+	if input.WaterTemperature != nil {
+		params.WaterTemperature = nullFloat64(input.WaterTemperature)
+	}
+	if input.BrewTime != nil {
+		params.BrewTime = nullInt64(input.BrewTime)
+	}
+	if input.TastingNotes != nil {
+		params.TastingNotes = nullTrimmedString(input.TastingNotes)
+	}
+	if input.Rating != nil {
+		params.Rating = nullInt64(input.Rating)
+	}
+	if input.Visibility != nil {
+		params.Visibility = nullTrimmedString(input.Visibility)
+	}
+	if input.Tds != nil {
+		params.Tds = nullFloat64(input.Tds)
+	}
+	if input.BeverageWeight != nil {
+		params.BeverageWeight = nullFloat64(input.BeverageWeight)
+	}
+	if input.GrinderID != nil {
+		params.GrinderID = nullEquipmentID(input.GrinderID)
+		// A setting only means something on the grinder it was taken on
+		if params.GrinderID != existing.GrinderID && input.GrindSetting == nil {
+			params.GrindSetting = sql.NullFloat64{}
+		}
+	}
+	if input.BrewerID != nil {
+		params.BrewerID = nullEquipmentID(input.BrewerID)
+	}
+	if input.GrindSetting != nil {
+		params.GrindSetting = nullFloat64(input.GrindSetting)
+	}
+	if input.GrinderID != nil || input.BrewerID != nil || input.GrindSetting != nil {
+		if err := s.checkEquipment(ctx, input.UserID, params.GrinderID, params.BrewerID, params.GrindSetting); err != nil {
+			return nil, err
+		}
+	}
+
+	// Pours and water weight must agree after merging. New pours without a
+	// waterWeight set it; otherwise stored pours are checked against any new waterWeight.
+	hasPours := input.Pours != nil && len(*input.Pours) > 0
+	if hasPours && input.WaterWeight == nil {
+		params.WaterWeight = nullFloat64(&pourTotal)
+	} else if input.Pours == nil {
+		stored, err := s.queries.ListBrewLogPours(ctx, existing.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range stored {
+			pourTotal += p.WaterAmount
+		}
+		hasPours = len(stored) > 0
+	}
+	if hasPours {
+		var waterWeight *float64
+		if params.WaterWeight.Valid {
+			waterWeight = &params.WaterWeight.Float64
+		}
+		if err := checkPourTotal(pourTotal, waterWeight); err != nil {
+			return nil, err
+		}
+	}
+
+	// A new method re-checks every stored parameter against its ranges;
+	// otherwise only the values being changed are checked
+	if input.BrewMethod != nil && method.ID != resolveBrewMethod(existing.BrewMethod).ID {
+		if err := method.checkRanges(params.CoffeeWeight, params.WaterWeight, params.WaterTemperature, params.BrewTime); err != nil {
+			return nil, err
+		}
+	} else {
+		waterWeight := nullFloat64(input.WaterWeight)
+		if input.WaterWeight == nil && params.WaterWeight != existing.WaterWeight {
+			waterWeight = params.WaterWeight // taken from new pours
+		}
+		if err := resolveBrewMethod(params.BrewMethod).checkRanges(nullFloat64(input.CoffeeWeight), waterWeight, nullFloat64(input.WaterTemperature), nullInt64(input.BrewTime)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mergeEspresso(&params, input); err != nil {
+		return nil, err
+	}
+
+	// Re-check against the merged row so a partial update cannot leave more
+	// beverage than water behind
+	if err := checkBeverageWeight(params.WaterWeight, params.BeverageWeight); err != nil {
+		return nil, err
+	}
+	err = s.queries.InTx(ctx, func(q *db.Queries) error {
+		if err := q.UpdateBrewLog(ctx, params); err != nil {
+			return err
+		}
+		// Give the old dose back before taking the new one, which also
+		// covers moving the log to another coffee
+		if err := consumeCoffee(ctx, q, existing.CoffeeID, existing.CoffeeWeight, 1); err != nil {
+			return err
+		}
+		if err := consumeCoffee(ctx, q, params.CoffeeID, params.CoffeeWeight, -1); err != nil {
+			return err
+		}
+		if input.Pours == nil {
+			return nil
+		}
+		return replacePours(ctx, q, existing.ID, *input.Pours)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, input.UserID, input.ID)
+}
+
+func (s *BrewLogService) Delete(ctx context.Context, userID, id int64) error {
+	brewLog, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	// Foreign keys are not enforced on our connections, so pours are removed
+	// and repeats and tasting sessions re-linked explicitly
+	return s.queries.InTx(ctx, func(q *db.Queries) error {
+		if err := q.DeleteBrewLogPours(ctx, id); err != nil {
+			return err
+		}
+		if err := q.ClearTastingSessionBrewLog(ctx, sql.NullInt64{Int64: id, Valid: true}); err != nil {
+			return err
+		}
+		if err := q.ReparentBrewLogs(ctx, db.ReparentBrewLogsParams{
+			NewParentID:     brewLog.ParentBrewLogID,
+			ParentBrewLogID: sql.NullInt64{Int64: id, Valid: true},
+			UserID:          userID,
+		}); err != nil {
+			return err
+		}
+		if err := consumeCoffee(ctx, q, brewLog.CoffeeID, brewLog.CoffeeWeight, 1); err != nil {
+			return err
+		}
+		return q.DeleteBrewLog(ctx, db.DeleteBrewLogParams{ID: id, UserID: userID})
+	})
+}
+
+// consumeCoffee takes a brew's dose out of (sign -1) or back into (sign 1)
+// the remaining weight of the coffee's bag. Logs without a coffee weight and
+// coffees whose bag is not tracked are left alone.
+func consumeCoffee(ctx context.Context, q *db.Queries, coffeeID int64, coffeeWeight sql.NullFloat64, sign float64) error {
+	if !coffeeWeight.Valid || coffeeWeight.Float64 == 0 {
+		return nil
+	}
+	return q.AdjustCoffeeRemainingWeight(ctx, db.AdjustCoffeeRemainingWeightParams{
+		Delta: sign * coffeeWeight.Float64,
+		ID:    coffeeID,
+	})
+}
+
+// List returns one page of the user's brew logs, newest first unless Sort/Order say otherwise.
+func (s *BrewLogService) List(ctx context.Context, input ListBrewLogsInput) (*BrewLogPage, error) {
+	params := db.ListBrewLogsParams{
+		UserID:     input.UserID,
+		SortBy:     "date",
+		Descending: true,
+	}
+
+	if input.CoffeeID != nil {
+		if *input.CoffeeID <= 0 {
+			return nil, &ValidationError{Message: "coffeeId must be a positive integer"}
+		}
+		params.CoffeeID = sql.NullInt64{Int64: *input.CoffeeID, Valid: true}
+	}
+	if input.BrewMethod != nil {
+		// Filter by canonical ID when the input names a catalog method
+		if m, ok := LookupBrewMethod(*input.BrewMethod); ok {
+			params.BrewMethod = sql.NullString{String: m.ID, Valid: true}
+		} else {
+			params.BrewMethod = nullTrimmedString(input.BrewMethod)
+		}
+	}
+	if (input.GrinderID != nil && *input.GrinderID <= 0) || (input.BrewerID != nil && *input.BrewerID <= 0) {
+		return nil, &ValidationError{Message: "grinderId and brewerId must be positive integers"}
+	}
+	params.GrinderID = nullInt64(input.GrinderID)
+	params.BrewerID = nullInt64(input.BrewerID)
+	if input.MinRating != nil && (*input.MinRating < 1 || *input.MinRating > 5) {
+		return nil, &ValidationError{Message: "minRating must be between 1 and 5"}
+	}
+	if input.MaxRating != nil && (*input.MaxRating < 1 || *input.MaxRating > 5) {
+		return nil, &ValidationError{Message: "maxRating must be between 1 and 5"}
+	}
+	if input.MinRating != nil && input.MaxRating != nil && *input.MinRating > *input.MaxRating {
+		return nil, &ValidationError{Message: "minRating must be <= maxRating"}
+	}
+	params.MinRating = nullInt64(input.MinRating)
+	params.MaxRating = nullInt64(input.MaxRating)
+	if input.CreatedFrom != nil {
+		params.CreatedFrom = sql.NullTime{Time: *input.CreatedFrom, Valid: true}
+	}
+	if input.CreatedBefore != nil {
+		params.CreatedBefore = sql.NullTime{Time: *input.CreatedBefore, Valid: true}
+	}
+
+	for _, r := range []struct {
+		name     string
+		min, max *float64
+		dstMin   *sql.NullFloat64
+		dstMax   *sql.NullFloat64
+	}{
+		{"Ratio", input.MinRatio, input.MaxRatio, &params.MinRatio, &params.MaxRatio},
+		{"ExtractionYield", input.MinExtractionYield, input.MaxExtractionYield, &params.MinExtractionYield, &params.MaxExtractionYield},
+		{"Strength", input.MinStrength, input.MaxStrength, &params.MinStrength, &params.MaxStrength},
+	} {
+		if (r.min != nil && *r.min < 0) || (r.max != nil && *r.max < 0) {
+			return nil, &ValidationError{Message: "min" + r.name + " and max" + r.name + " must not be negative"}
+		}
+		if r.min != nil && r.max != nil && *r.min > *r.max {
+			return nil, &ValidationError{Message: "min" + r.name + " must be <= max" + r.name}
+		}
+		*r.dstMin = nullFloat64(r.min)
+		*r.dstMax = nullFloat64(r.max)
+	}
+
+	if input.Sort != "" {
+		if _, ok := db.BrewLogSortExprs[input.Sort]; !ok {
+			return nil, &ValidationError{Message: "sort must be one of date, rating, ratio, extractionYield, strength"}
+		}
+		params.SortBy = input.Sort
+	}
+	order := "desc"
+	if input.Order != "" {
+		order = strings.ToLower(input.Order)
+		if order != "asc" && order != "desc" {
+			return nil, &ValidationError{Message: "order must be asc or desc"}
+		}
+		params.Descending = order == "desc"
+	}
+
+	limit := input.Limit
+	if limit == 0 {
+		limit = DefaultBrewLogPageSize
+	}
+	if limit < 1 || limit > MaxBrewLogPageSize {
+		return nil, &ValidationError{Message: "limit must be between 1 and 100"}
+	}
+	// Fetch one extra row; its presence means there is another page
+	params.Limit = int64(limit) + 1
+
+	if input.Cursor != "" {
+		c, err := decodeBrewLogCursor(input.Cursor)
+		if err != nil || c.Sort != params.SortBy || c.Order != order {
+			return nil, &ValidationError{Message: "cursor is invalid for this query"}
+		}
+		params.AfterSortKey = c.Value
+		params.AfterID = c.ID
+	}
+
+	rows, err := s.queries.ListBrewLogs(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &BrewLogPage{BrewLogs: make([]BrewLogOutput, 0, len(rows))}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[limit-1]
+		c := encodeBrewLogCursor(brewLogCursor{Sort: params.SortBy, Order: order, Value: last.SortKey, ID: last.ID})
+		page.NextCursor = &c
+	}
+	ids := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	pours, err := s.queries.ListBrewLogPoursForBrewLogs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	poursByLog := make(map[int64][]db.BrewLogPour, len(rows))
+	for _, p := range pours {
+		poursByLog[p.BrewLogID] = append(poursByLog[p.BrewLogID], p)
+	}
+	for _, row := range rows {
+		page.BrewLogs = append(page.BrewLogs, *toBrewLogOutput(row.BrewLog, poursByLog[row.ID]))
+	}
+	return page, nil
+}
+
+// ListByUser returns one page of ownerID's journal as seen by viewerID, newest
+// first. Logs the viewer may not see are left out rather than reported, so a
+// private journal reads as an empty one.
+func (s *BrewLogService) ListByUser(ctx context.Context, input ListUserBrewLogsInput) (*PublicBrewLogPage, error) {
+	if _, err := s.queries.GetUserBrewLogVisibility(ctx, input.OwnerID); errors.Is(err, sql.ErrNoRows) {
+		return nil, &NotFoundError{Message: "user not found"}
+	} else if err != nil {
+		return nil, err
+	}
+
+	limit := input.Limit
+	if limit == 0 {
+		limit = DefaultBrewLogPageSize
+	}
+	if limit < 1 || limit > MaxBrewLogPageSize {
+		return nil, &ValidationError{Message: "limit must be between 1 and 100"}
+	}
+	params := db.ListVisibleBrewLogsForUserParams{
+		OwnerID:  input.OwnerID,
+		ViewerID: input.ViewerID,
+		Limit:    int64(limit) + 1,
+	}
+	if input.Cursor != "" {
+		c, err := decodeBrewLogCursor(input.Cursor)
+		createdAt, isString := c.Value.(string)
+		if err != nil || c.Sort != "date" || c.Order != "desc" || !isString {
+			return nil, &ValidationError{Message: "cursor is invalid for this query"}
+		}
+		params.AfterCreatedAt = createdAt
+		params.AfterID = c.ID
+	}
+
+	rows, err := s.queries.ListVisibleBrewLogsForUser(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &PublicBrewLogPage{BrewLogs: make([]PublicBrewLogOutput, 0, len(rows))}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[limit-1]
+		c := encodeBrewLogCursor(brewLogCursor{Sort: "date", Order: "desc", Value: last.CreatedAtKey, ID: last.ID})
+		page.NextCursor = &c
+	}
+	for _, row := range rows {
+		page.BrewLogs = append(page.BrewLogs, toPublicBrewLogOutput(row))
+	}
+	return page, nil
+}
+
+// getOwned loads a brew log and applies the ownership rule used by Create:
+// the log and its coffee must both belong to userID.
+func (s *BrewLogService) getOwned(ctx context.Context, userID, id int64) (db.BrewLog, error) {
+	brewLog, err := s.queries.GetBrewLogByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return brewLog, &NotFoundError{Message: "brew log not found"}
+	} else if err != nil {
+		return brewLog, err
+	}
+	coffeeOwnerID, err := s.queries.GetCoffeeOwnerID(ctx, brewLog.CoffeeID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return brewLog, err
+	}
+	if brewLog.UserID != userID || coffeeOwnerID != userID {
+		return brewLog, &ForbiddenError{Message: "brew log not owned by user"}
+	}
+	return brewLog, nil
+}
+
+func (s *BrewLogService) checkCoffeeOwner(ctx context.Context, coffeeID, userID int64) error {
+	ownerID, err := s.queries.GetCoffeeOwnerID(ctx, coffeeID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &NotFoundError{Message: "coffee not found"}
+	} else if err != nil {
+		return err
+	}
+	if ownerID != userID {
+		return &ForbiddenError{Message: "coffee not owned by user"}
+	}
+	return nil
+}
+
+// validate checks the range rules for any fields that are present. Weights,
+// temperature and brew time depend on the method; see BrewMethod.checkRanges.
+func (f BrewLogFields) validate() error {
+	if f.Rating != nil && (*f.Rating < 1 || *f.Rating > 5) {
+		return &ValidationError{Message: "rating must be between 1 and 5"}
+	}
+	if f.Tds != nil && (*f.Tds < 0 || *f.Tds > 25) {
+		return &ValidationError{Message: "tds must be between 0 and 25"}
+	}
+	if f.BeverageWeight != nil && (*f.BeverageWeight < 0 || *f.BeverageWeight > 3000) {
+		return &ValidationError{Message: "beverageWeight must be between 0 and 3000"}
+	}
+	if (f.GrinderID != nil && *f.GrinderID < 0) || (f.BrewerID != nil && *f.BrewerID < 0) {
+		return &ValidationError{Message: "grinderId and brewerId must not be negative"}
+	}
+	if f.GrindSetting != nil && (*f.GrindSetting < 0 || *f.GrindSetting > 10000) {
+		return &ValidationError{Message: "grindSetting must be between 0 and 10000"}
+	}
+	if f.Visibility != nil {
+		if v := strings.TrimSpace(*f.Visibility); v != "" && !IsValidVisibility(v) {
+			return &ValidationError{Message: "visibility must be one of private, followers, public"}
+		}
+	}
+	return nil
+}
+
+// checkBeverageWeight rejects a beverage heavier than the water it was brewed with.
+func checkBeverageWeight(waterWeight, beverageWeight sql.NullFloat64) error {
+	if waterWeight.Valid && beverageWeight.Valid && beverageWeight.Float64 > waterWeight.Float64 {
+		return &ValidationError{Message: "beverageWeight cannot exceed waterWeight"}
+	}
+	return nil
+}
+
+// IsValidVisibility reports whether v is one of the supported visibility levels.
+func IsValidVisibility(v string) bool {
+	switch v {
+	case VisibilityPrivate, VisibilityFollowers, VisibilityPublic:
+		return true
+	}
+	return false
+}
+
+func toBrewLogOutput(b db.BrewLog, pours []db.BrewLogPour) *BrewLogOutput {
+	output := &BrewLogOutput{
+		ID:         b.ID,
+		UserID:     b.UserID,
+		CoffeeID:   b.CoffeeID,
+		BrewMethod: b.BrewMethod,
+		Pours:      toBrewLogPours(pours),
+		CreatedAt:  b.CreatedAt.UTC().Format(brewLogTimeLayout),
+		UpdatedAt:  b.CreatedAt.UTC().Format(brewLogTimeLayout),
+	}
+	if b.UpdatedAt.Valid {
+		output.UpdatedAt = b.UpdatedAt.Time.UTC().Format(brewLogTimeLayout)
+	}
+
+	if b.CoffeeWeight.Valid {
+		output.CoffeeWeight = &b.CoffeeWeight.Float64
+	}
+	if b.WaterWeight.Valid {
+		output.WaterWeight = &b.WaterWeight.Float64
+	}
+	if b.GrindSize.Valid {
+		output.GrindSize = &b.GrindSize.String
+	}
+	if b.WaterTemperature.Valid {
+		output.WaterTemperature = &b.WaterTemperature.Float64
+	}
+	if b.BrewTime.Valid {
+		output.BrewTime = &b.BrewTime.Int64
+	}
+	if b.TastingNotes.Valid {
+		output.TastingNotes = &b.TastingNotes.String
+	}
+	if b.Rating.Valid {
+		output.Rating = &b.Rating.Int64
+	}
+	if b.Tds.Valid {
+		output.Tds = &b.Tds.Float64
+	}
+	if b.BeverageWeight.Valid {
+		output.BeverageWeight = &b.BeverageWeight.Float64
+	}
+	if b.GrinderID.Valid {
+		output.GrinderID = &b.GrinderID.Int64
+	}
+	if b.BrewerID.Valid {
+		output.BrewerID = &b.BrewerID.Int64
+	}
+	if b.GrindSetting.Valid {
+		output.GrindSetting = &b.GrindSetting.Float64
+	}
+	output.EspressoFields = toEspressoFields(b.YieldWeight, b.PreInfusionTime, b.BasketSize)
+	output.PressureProfile = decodePressureProfile(b.PressureProfile)
+	output.BrewMetrics = computeBrewMetrics(b.CoffeeWeight, b.WaterWeight, b.YieldWeight, b.Tds, b.BeverageWeight)
+	if b.Visibility.Valid {
+		output.Visibility = &b.Visibility.String
+	}
+	if b.ParentBrewLogID.Valid {
+		output.ParentBrewLogID = &b.ParentBrewLogID.Int64
+	}
+
+	return output
+}
+
+func toPublicBrewLogOutput(r db.ListVisibleBrewLogsForUserRow) PublicBrewLogOutput {
+	output := PublicBrewLogOutput{
+		ID:         r.ID,
+		UserID:     r.UserID,
+		Username:   r.Username,
+		CoffeeID:   r.CoffeeID,
+		CoffeeName: r.CoffeeName,
+		BrewMethod: r.BrewMethod,
+		Visibility: r.EffectiveVisibility,
+		CreatedAt:  r.CreatedAt.UTC().Format(brewLogTimeLayout),
+	}
+	if r.CoffeeRoaster.Valid {
+		output.CoffeeRoaster = &r.CoffeeRoaster.String
+	}
+	if r.CoffeeWeight.Valid {
+		output.CoffeeWeight = &r.CoffeeWeight.Float64
+	}
+	if r.WaterWeight.Valid {
+		output.WaterWeight = &r.WaterWeight.Float64
+	}
+	if r.GrindSize.Valid {
+		output.GrindSize = &r.GrindSize.String
+	}
+	if r.WaterTemperature.Valid {
+		output.WaterTemperature = &r.WaterTemperature.Float64
+	}
+	if r.BrewTime.Valid {
+		output.BrewTime = &r.BrewTime.Int64
+	}
+	if r.TastingNotes.Valid {
+		output.TastingNotes = &r.TastingNotes.String
+	}
+	if r.Rating.Valid {
+		output.Rating = &r.Rating.Int64
+	}
+	if r.Tds.Valid {
+		output.Tds = &r.Tds.Float64
+	}
+	if r.BeverageWeight.Valid {
+		output.BeverageWeight = &r.BeverageWeight.Float64
+	}
+	output.EspressoFields = toEspressoFields(r.YieldWeight, r.PreInfusionTime, r.BasketSize)
+	output.PressureProfile = decodePressureProfile(r.PressureProfile)
+	output.BrewMetrics = computeBrewMetrics(r.CoffeeWeight, r.WaterWeight, r.YieldWeight, r.Tds, r.BeverageWeight)
+	return output
+}
+
+func encodeBrewLogCursor(c brewLogCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeBrewLogCursor(s string) (brewLogCursor, error) {
+	var c brewLogCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, err
+	}
+	switch c.Value.(type) {
+	case string, float64:
+	default:
+		return c, errors.New("invalid cursor value")
+	}
+	if c.ID <= 0 {
+		return c, errors.New("invalid cursor id")
+	}
+	return c, nil
+}
+
+func nullFloat64(p *float64) sql.NullFloat64 {
+	if p == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *p, Valid: true}
+}
+
+func nullInt64(p *int64) sql.NullInt64 {
+	if p == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: *p, Valid: true}
+}
+
+// nullTrimmedString maps nil and blank strings to NULL.
+func nullTrimmedString(p *string) sql.NullString {
+	if p == nil {
+		return sql.NullString{}
+	}
+	s := strings.TrimSpace(*p)
+	if s == "" {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: s, Valid: true}
+}
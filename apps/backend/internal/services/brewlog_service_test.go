@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"coffeeee/backend/internal/database"
+	"coffeeee/backend/internal/migrate"
+)
+
+// newTestDB opens a fresh database migrated to the latest version.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := database.Connect(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	if err := migrate.ApplyUpToLatest(conn, filepath.Join("..", "..", "migrations")); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return conn
+}
+
+// newBrewLogTestService seeds a fresh database with two coffees of user 1,
+// one with a tracked 250 g bag and one untracked, and one coffee of user 2.
+func newBrewLogTestService(t *testing.T) (*sql.DB, *BrewLogService) {
+	t.Helper()
+	conn := newTestDB(t)
+	_, err := conn.Exec(`INSERT INTO coffees(id, user_id, name, bag_size, remaining_weight) VALUES
+		(1, 1, 'Tracked', 250, 250),
+		(2, 1, 'Untracked', NULL, NULL),
+		(3, 2, 'Other', 250, 250)`)
+	if err != nil {
+		t.Fatalf("seed coffees: %v", err)
+	}
+	return conn, NewBrewLogService(database.NewQueries(conn))
+}
+
+func remainingWeight(t *testing.T, conn *sql.DB, coffeeID int64) sql.NullFloat64 {
+	t.Helper()
+	var w sql.NullFloat64
+	if err := conn.QueryRow(`SELECT remaining_weight FROM coffees WHERE id = ?`, coffeeID).Scan(&w); err != nil {
+		t.Fatalf("remaining weight: %v", err)
+	}
+	return w
+}
+
+func wantRemaining(t *testing.T, conn *sql.DB, coffeeID int64, want float64) {
+	t.Helper()
+	if got := remainingWeight(t, conn, coffeeID); !got.Valid || got.Float64 != want {
+		t.Fatalf("coffee %d: expected %v g remaining, got %+v", coffeeID, want, got)
+	}
+}
+
+func float64Ptr(v float64) *float64 { return &v }
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestBrewLogService_Create(t *testing.T) {
+	conn, s := newBrewLogTestService(t)
+	ctx := context.Background()
+
+	out, err := s.Create(ctx, CreateBrewLogInput{
+		UserID:        1,
+		CoffeeID:      1,
+		BrewMethod:    "V60",
+		BrewLogFields: BrewLogFields{CoffeeWeight: float64Ptr(18), WaterWeight: float64Ptr(300)},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if out.ID == 0 || out.UserID != 1 || out.BrewMethod != "v60" {
+		t.Fatalf("unexpected brew log %+v", out)
+	}
+	wantRemaining(t, conn, 1, 232)
+
+	// Untracked bags stay untracked
+	if _, err := s.Create(ctx, CreateBrewLogInput{UserID: 1, CoffeeID: 2, BrewMethod: "v60", BrewLogFields: BrewLogFields{CoffeeWeight: float64Ptr(18)}}); err != nil {
+		t.Fatalf("create on untracked coffee: %v", err)
+	}
+	if got := remainingWeight(t, conn, 2); got.Valid {
+		t.Fatalf("expected the untracked bag left alone, got %+v", got)
+	}
+
+	var forbidden *ForbiddenError
+	if _, err := s.Create(ctx, CreateBrewLogInput{UserID: 1, CoffeeID: 3, BrewMethod: "v60", BrewLogFields: BrewLogFields{CoffeeWeight: float64Ptr(18)}}); !errors.As(err, &forbidden) {
+		t.Fatalf("expected ForbiddenError for another user's coffee, got %v", err)
+	}
+	wantRemaining(t, conn, 3, 250)
+	var notFound *NotFoundError
+	if _, err := s.Create(ctx, CreateBrewLogInput{UserID: 1, CoffeeID: 99, BrewMethod: "v60"}); !errors.As(err, &notFound) {
+		t.Fatalf("expected NotFoundError for a missing coffee, got %v", err)
+	}
+	var invalid *ValidationError
+	if _, err := s.Create(ctx, CreateBrewLogInput{UserID: 1, CoffeeID: 1, BrewMethod: "v60", BrewLogFields: BrewLogFields{CoffeeWeight: float64Ptr(18), Rating: int64Ptr(6)}}); !errors.As(err, &invalid) {
+		t.Fatalf("expected ValidationError for a rating of 6, got %v", err)
+	}
+	wantRemaining(t, conn, 1, 232)
+}
+
+func TestBrewLogService_Update(t *testing.T) {
+	conn, s := newBrewLogTestService(t)
+	ctx := context.Background()
+	created, err := s.Create(ctx, CreateBrewLogInput{UserID: 1, CoffeeID: 1, BrewMethod: "v60", BrewLogFields: BrewLogFields{CoffeeWeight: float64Ptr(18)}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// A new dose gives the old one back before taking the new one
+	out, err := s.Update(ctx, UpdateBrewLogInput{ID: created.ID, UserID: 1, BrewLogFields: BrewLogFields{CoffeeWeight: float64Ptr(20)}})
+	if err != nil {
+		t.Fatalf("update dose: %v", err)
+	}
+	if out.CoffeeWeight == nil || *out.CoffeeWeight != 20 {
+		t.Fatalf("expected a 20 g dose, got %+v", out.CoffeeWeight)
+	}
+	wantRemaining(t, conn, 1, 230)
+
+	// Fields left out keep their stored values
+	if out, err = s.Update(ctx, UpdateBrewLogInput{ID: created.ID, UserID: 1, BrewLogFields: BrewLogFields{Rating: int64Ptr(4)}}); err != nil {
+		t.Fatalf("update rating: %v", err)
+	}
+	if out.CoffeeWeight == nil || *out.CoffeeWeight != 20 || out.Rating == nil || *out.Rating != 4 {
+		t.Fatalf("expected the dose kept and the rating set, got %+v", out)
+	}
+	wantRemaining(t, conn, 1, 230)
+
+	// Moving the log to another coffee moves its dose too
+	coffeeID := int64(2)
+	if _, err := s.Update(ctx, UpdateBrewLogInput{ID: created.ID, UserID: 1, CoffeeID: &coffeeID}); err != nil {
+		t.Fatalf("move to coffee 2: %v", err)
+	}
+	wantRemaining(t, conn, 1, 250)
+
+	var forbidden *ForbiddenError
+	coffeeID = 3
+	if _, err := s.Update(ctx, UpdateBrewLogInput{ID: created.ID, UserID: 1, CoffeeID: &coffeeID}); !errors.As(err, &forbidden) {
+		t.Fatalf("expected ForbiddenError moving to another user's coffee, got %v", err)
+	}
+	if _, err := s.Update(ctx, UpdateBrewLogInput{ID: created.ID, UserID: 2, BrewLogFields: BrewLogFields{Rating: int64Ptr(1)}}); !errors.As(err, &forbidden) {
+		t.Fatalf("expected ForbiddenError updating another user's log, got %v", err)
+	}
+	var notFound *NotFoundError
+	if _, err := s.Update(ctx, UpdateBrewLogInput{ID: 99, UserID: 1, BrewLogFields: BrewLogFields{Rating: int64Ptr(1)}}); !errors.As(err, &notFound) {
+		t.Fatalf("expected NotFoundError for a missing log, got %v", err)
+	}
+	var invalid *ValidationError
+	if _, err := s.Update(ctx, UpdateBrewLogInput{ID: created.ID, UserID: 1}); !errors.As(err, &invalid) {
+		t.Fatalf("expected ValidationError for an empty update, got %v", err)
+	}
+	wantRemaining(t, conn, 3, 250)
+}
+
+func TestBrewLogService_Delete(t *testing.T) {
+	conn, s := newBrewLogTestService(t)
+	ctx := context.Background()
+	created, err := s.Create(ctx, CreateBrewLogInput{UserID: 1, CoffeeID: 1, BrewMethod: "v60", BrewLogFields: BrewLogFields{CoffeeWeight: float64Ptr(18), WaterWeight: float64Ptr(300)}, Pours: []BrewLogPour{{WaterAmount: 100}, {TimeOffset: 45, WaterAmount: 200}}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	wantRemaining(t, conn, 1, 232)
+
+	var forbidden *ForbiddenError
+	if err := s.Delete(ctx, 2, created.ID); !errors.As(err, &forbidden) {
+		t.Fatalf("expected ForbiddenError deleting another user's log, got %v", err)
+	}
+	if err := s.Delete(ctx, 1, created.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	// The dose goes back into the bag and the pours go with the log
+	wantRemaining(t, conn, 1, 250)
+	var pours int
+	_ = conn.QueryRow(`SELECT COUNT(*) FROM brew_log_pours WHERE brew_log_id = ?`, created.ID).Scan(&pours)
+	if pours != 0 {
+		t.Fatalf("expected the pours deleted, got %d", pours)
+	}
+	var notFound *NotFoundError
+	if _, err := s.Get(ctx, 1, created.ID); !errors.As(err, &notFound) {
+		t.Fatalf("expected NotFoundError after delete, got %v", err)
+	}
+	if err := s.Delete(ctx, 1, created.ID); !errors.As(err, &notFound) {
+		t.Fatalf("expected NotFoundError deleting twice, got %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	bagDateLayout = "2006-01-02"
+	// reorderBrewsLeft is the point at which a bag is flagged for reordering.
+	reorderBrewsLeft = 3
+)
+
+// CoffeeBag describes the bag of a coffee the user currently has open. Dates
+// are YYYY-MM-DD, weights grams and the price is in the user's currency.
+// RemainingWeight goes down by each brew log's coffeeWeight.
+type CoffeeBag struct {
+	RoastDate       *string  `json:"roastDate,omitempty"`
+	PurchaseDate    *string  `json:"purchaseDate,omitempty"`
+	OpenedDate      *string  `json:"openedDate,omitempty"`
+	BagSize         *float64 `json:"bagSize,omitempty"`
+	Price           *float64 `json:"price,omitempty"`
+	RemainingWeight *float64 `json:"remainingWeight,omitempty"`
+}
+
+// CoffeeBagReport tells how fresh a bag is, how long it will last and what it
+// costs per cup. Values that cannot be worked out from what was recorded are
+// omitted.
+type CoffeeBagReport struct {
+	CoffeeID int64   `json:"coffeeId"`
+	Name     string  `json:"name"`
+	Roaster  *string `json:"roaster,omitempty"`
+	CoffeeBag
+	DaysOffRoast    *int `json:"daysOffRoast,omitempty"`
+	DaysSinceOpened *int `json:"daysSinceOpened,omitempty"`
+	// AverageDose is the mean coffeeWeight of the last 10 weighed brews.
+	AverageDose        *float64 `json:"averageDose,omitempty"`
+	EstimatedBrewsLeft *int64   `json:"estimatedBrewsLeft,omitempty"`
+	CostPerGram        *float64 `json:"costPerGram,omitempty"`
+	CostPerCup         *float64 `json:"costPerCup,omitempty"`
+	NeedsReorder       bool     `json:"needsReorder"`
+}
+
+// coffeeBagColumns are the bag columns of a coffee row.
+type coffeeBagColumns struct {
+	RoastDate       sql.NullString
+	PurchaseDate    sql.NullString
+	OpenedDate      sql.NullString
+	BagSize         sql.NullFloat64
+	Price           sql.NullFloat64
+	RemainingWeight sql.NullFloat64
+}
+
+func bagColumnsOf(c db.GetCoffeeByIDRow) coffeeBagColumns {
+	return coffeeBagColumns{
+		RoastDate:       c.RoastDate,
+		PurchaseDate:    c.PurchaseDate,
+		OpenedDate:      c.OpenedDate,
+		BagSize:         c.BagSize,
+		Price:           c.Price,
+		RemainingWeight: c.RemainingWeight,
+	}
+}
+
+// mergeInto applies the bag fields to cur the way updates do: nil keeps the
+// stored value, "" clears a date and a bagSize of 0 stops tracking the bag.
+// Without an explicit remainingWeight a new bag starts full and a resized bag
+// keeps the grams already used.
+func (b CoffeeBag) mergeInto(cur *coffeeBagColumns, now time.Time) error {
+	for _, f := range []struct {
+		name  string
+		value *string
+		out   *sql.NullString
+	}{
+		{"roastDate", b.RoastDate, &cur.RoastDate},
+		{"purchaseDate", b.PurchaseDate, &cur.PurchaseDate},
+		{"openedDate", b.OpenedDate, &cur.OpenedDate},
+	} {
+		if f.value == nil {
+			continue
+		}
+		if *f.value == "" {
+			*f.out = sql.NullString{}
+			continue
+		}
+		d, err := time.Parse(bagDateLayout, *f.value)
+		if err != nil {
+			return &ValidationError{Message: f.name + " must be a date in YYYY-MM-DD format"}
+		}
+		// A day of slack for users ahead of UTC
+		if d.After(now.AddDate(0, 0, 1)) {
+			return &ValidationError{Message: f.name + " cannot be in the future"}
+		}
+		*f.out = sql.NullString{String: d.Format(bagDateLayout), Valid: true}
+	}
+	// Dates are zero-padded, so they compare as strings
+	if cur.RoastDate.Valid {
+		if cur.PurchaseDate.Valid && cur.PurchaseDate.String < cur.RoastDate.String {
+			return &ValidationError{Message: "purchaseDate cannot be before roastDate"}
+		}
+		if cur.OpenedDate.Valid && cur.OpenedDate.String < cur.RoastDate.String {
+			return &ValidationError{Message: "openedDate cannot be before roastDate"}
+		}
+	}
+
+	if b.Price != nil {
+		if *b.Price < 0 {
+			return &ValidationError{Message: "price must be >= 0"}
+		}
+		cur.Price = sql.NullFloat64{Float64: *b.Price, Valid: true}
+	}
+
+	if b.BagSize != nil {
+		switch size := *b.BagSize; {
+		case size < 0:
+			return &ValidationError{Message: "bagSize must be >= 0"}
+		case size == 0:
+			cur.BagSize, cur.RemainingWeight = sql.NullFloat64{}, sql.NullFloat64{}
+		case cur.BagSize.Valid && cur.RemainingWeight.Valid:
+			used := cur.BagSize.Float64 - cur.RemainingWeight.Float64
+			cur.BagSize = sql.NullFloat64{Float64: size, Valid: true}
+			cur.RemainingWeight = sql.NullFloat64{Float64: math.Max(size-used, 0), Valid: true}
+		default:
+			cur.BagSize = sql.NullFloat64{Float64: size, Valid: true}
+			cur.RemainingWeight = cur.BagSize
+		}
+	}
+	if b.RemainingWeight != nil {
+		if *b.RemainingWeight < 0 {
+			return &ValidationError{Message: "remainingWeight must be >= 0"}
+		}
+		if !cur.BagSize.Valid {
+			return &ValidationError{Message: "remainingWeight requires a bagSize"}
+		}
+		if *b.RemainingWeight > cur.BagSize.Float64 {
+			return &ValidationError{Message: "remainingWeight cannot exceed bagSize"}
+		}
+		cur.RemainingWeight = sql.NullFloat64{Float64: *b.RemainingWeight, Valid: true}
+	}
+	return nil
+}
+
+// setBagParams stores bag columns in a coffee update.
+func setBagParams(p *db.UpdateCoffeeParams, c coffeeBagColumns) {
+	p.RoastDate, p.PurchaseDate, p.OpenedDate = c.RoastDate, c.PurchaseDate, c.OpenedDate
+	p.BagSize, p.Price, p.RemainingWeight = c.BagSize, c.Price, c.RemainingWeight
+}
+
+func (o *CoffeeOutput) setBag(c coffeeBagColumns) {
+	if c.RoastDate.Valid {
+		o.RoastDate = &c.RoastDate.String
+	}
+	if c.PurchaseDate.Valid {
+		o.PurchaseDate = &c.PurchaseDate.String
+	}
+	if c.OpenedDate.Valid {
+		o.OpenedDate = &c.OpenedDate.String
+	}
+	if c.BagSize.Valid {
+		o.BagSize = &c.BagSize.Float64
+	}
+	if c.Price.Valid {
+		o.Price = &c.Price.Float64
+	}
+	if c.RemainingWeight.Valid {
+		o.RemainingWeight = &c.RemainingWeight.Float64
+	}
+}
+
+// BagReport describes the bag of one of the user's coffees.
+func (s *CoffeeService) BagReport(ctx context.Context, userID, id int64) (*CoffeeBagReport, error) {
+	coffee, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.bagReport(ctx, coffee, time.Now())
+}
+
+// Inventory reports on every coffee whose bag is tracked, the ones that will
+// run out first leading. Bags without enough brews to estimate from come last.
+func (s *CoffeeService) Inventory(ctx context.Context, userID int64) ([]CoffeeBagReport, error) {
+	coffees, err := s.queries.ListCoffeesForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	reports := []CoffeeBagReport{}
+	for _, c := range coffees {
+		if !c.BagSize.Valid {
+			continue
+		}
+		report, err := s.bagReport(ctx, db.GetCoffeeByIDRow(c), now)
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, *report)
+	}
+	sort.SliceStable(reports, func(i, j int) bool {
+		a, b := reports[i].EstimatedBrewsLeft, reports[j].EstimatedBrewsLeft
+		if a == nil || b == nil {
+			return a != nil && b == nil
+		}
+		return *a < *b
+	})
+	return reports, nil
+}
+
+func (s *CoffeeService) bagReport(ctx context.Context, coffee db.GetCoffeeByIDRow, now time.Time) (*CoffeeBagReport, error) {
+	output := toCoffeeOutput(coffee)
+	report := &CoffeeBagReport{
+		CoffeeID:  coffee.ID,
+		Name:      coffee.Name,
+		Roaster:   output.Roaster,
+		CoffeeBag: output.CoffeeBag,
+	}
+	report.DaysOffRoast = daysSince(coffee.RoastDate, now)
+	report.DaysSinceOpened = daysSince(coffee.OpenedDate, now)
+
+	dose, err := s.queries.GetCoffeeAverageDose(ctx, coffee.ID)
+	if err != nil {
+		return nil, err
+	}
+	if dose.AverageDose.Valid && dose.AverageDose.Float64 > 0 {
+		average := roundTo(dose.AverageDose.Float64, 1)
+		report.AverageDose = &average
+		if coffee.RemainingWeight.Valid {
+			left := int64(coffee.RemainingWeight.Float64 / dose.AverageDose.Float64)
+			report.EstimatedBrewsLeft = &left
+			report.NeedsReorder = left <= reorderBrewsLeft
+		}
+	}
+	if coffee.RemainingWeight.Valid && coffee.RemainingWeight.Float64 == 0 {
+		report.NeedsReorder = true
+	}
+	if coffee.Price.Valid && coffee.BagSize.Valid {
+		perGram := coffee.Price.Float64 / coffee.BagSize.Float64
+		rounded := roundTo(perGram, 4)
+		report.CostPerGram = &rounded
+		if dose.AverageDose.Valid {
+			perCup := roundTo(perGram*dose.AverageDose.Float64, 2)
+			report.CostPerCup = &perCup
+		}
+	}
+	return report, nil
+}
+
+// daysSince counts whole calendar days from a stored YYYY-MM-DD date to now.
+func daysSince(date sql.NullString, now time.Time) *int {
+	if !date.Valid {
+		return nil
+	}
+	d, err := time.Parse(bagDateLayout, date.String)
+	if err != nil {
+		return nil
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(today.Sub(d).Hours() / 24)
+	return &days
+}
+
+func roundTo(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
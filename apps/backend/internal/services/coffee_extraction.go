@@ -0,0 +1,209 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const maxLabelTextLength = 10000
+
+// ErrImageNotSupported is returned by a CoffeeExtractor that can only read
+// label text when it is given a photo.
+var ErrImageNotSupported = errors.New("coffee extractor cannot read images")
+
+// CoffeeLabel is what a coffee is read from: a photo of the bag, the label
+// text pasted by the user, or both.
+type CoffeeLabel struct {
+	Text      string
+	Image     []byte
+	ImageType string // sniffed content type of Image, e.g. "image/jpeg"
+}
+
+// ExtractedValue is one field read from a label with how sure the extractor
+// is of it, from 0 to 1. A zero value means the field was not found.
+type ExtractedValue struct {
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+}
+
+// CoffeeExtraction is an extractor's raw reading of a label. Values are as
+// printed; CoffeeExtractionService cleans them up.
+type CoffeeExtraction struct {
+	Name         ExtractedValue `json:"name"`
+	Roaster      ExtractedValue `json:"roaster"`
+	Origin       ExtractedValue `json:"origin"`
+	Process      ExtractedValue `json:"process"`
+	TastingNotes ExtractedValue `json:"tastingNotes"`
+	RoastDate    ExtractedValue `json:"roastDate"`
+	// Provider names the extractor that answered when it is not the one
+	// asked, as when a provider falls back to the rules.
+	Provider string `json:"-"`
+}
+
+// CoffeeExtractor reads coffee details from a label. Implementations wrap an
+// AI provider or local rules; they need not validate what they return.
+type CoffeeExtractor interface {
+	// Name identifies the extractor in responses.
+	Name() string
+	ExtractCoffee(ctx context.Context, label CoffeeLabel) (*CoffeeExtraction, error)
+}
+
+// CoffeeDraft is a coffee read from a label in the shape POST /coffees
+// accepts, so a client can show it for review and send it back as is.
+// Tasting notes go in Description.
+type CoffeeDraft struct {
+	Name        string  `json:"name"`
+	Origin      *string `json:"origin,omitempty"`
+	Roaster     *string `json:"roaster,omitempty"`
+	RoasterID   *int64  `json:"roasterId,omitempty"`
+	Description *string `json:"description,omitempty"`
+	CoffeeOrigin
+	CoffeeBag
+}
+
+// ExtractedCoffee is a draft coffee with the confidence of each field that
+// was found, keyed by the draft's JSON field names.
+type ExtractedCoffee struct {
+	Coffee     CoffeeDraft        `json:"coffee"`
+	Confidence map[string]float64 `json:"confidence"`
+	Provider   string             `json:"provider"`
+}
+
+// CoffeeExtractionService turns bag photos and label text into draft coffees.
+type CoffeeExtractionService struct {
+	queries   *db.Queries
+	extractor CoffeeExtractor
+}
+
+func NewCoffeeExtractionService(queries *db.Queries, extractor CoffeeExtractor) *CoffeeExtractionService {
+	return &CoffeeExtractionService{
+		queries:   queries,
+		extractor: extractor,
+	}
+}
+
+// Extract reads a draft coffee from the label. Fields the extractor got
+// wrong (an unknown process, a date in the future, text over the column
+// limits) are left out rather than failing the request, and the roaster is
+// matched against the roaster directory without adding to it.
+func (s *CoffeeExtractionService) Extract(ctx context.Context, label CoffeeLabel) (*ExtractedCoffee, error) {
+	label.Text = strings.TrimSpace(label.Text)
+	if label.Text == "" && len(label.Image) == 0 {
+		return nil, &ValidationError{Message: "a photo or label text is required"}
+	}
+	if len(label.Text) > maxLabelTextLength {
+		return nil, &ValidationError{Message: fmt.Sprintf("text must be <= %d characters", maxLabelTextLength)}
+	}
+	if len(label.Image) > 0 {
+		label.ImageType = http.DetectContentType(label.Image)
+		if _, ok := photoExtensions[label.ImageType]; !ok {
+			return nil, &ValidationError{Message: "photo must be a JPEG, PNG or GIF image"}
+		}
+	}
+
+	raw, err := s.extractor.ExtractCoffee(ctx, label)
+	if errors.Is(err, ErrImageNotSupported) {
+		if label.Text == "" {
+			return nil, &ValidationError{Message: "this server cannot read photos; paste the label text instead"}
+		}
+		label.Image, label.ImageType = nil, ""
+		raw, err = s.extractor.ExtractCoffee(ctx, label)
+	}
+	if err != nil {
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			return nil, err
+		}
+		return nil, &UnavailableError{Message: "coffee extraction failed", Err: err}
+	}
+
+	out := &ExtractedCoffee{Confidence: map[string]float64{}, Provider: raw.Provider}
+	if out.Provider == "" {
+		out.Provider = s.extractor.Name()
+	}
+	if err := s.draftFrom(ctx, raw, out, time.Now()); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// draftFrom copies the usable fields of raw into out.
+func (s *CoffeeExtractionService) draftFrom(ctx context.Context, raw *CoffeeExtraction, out *ExtractedCoffee, now time.Time) error {
+	text := func(key string, v ExtractedValue, max int) *string {
+		value := strings.Join(strings.Fields(v.Value), " ")
+		if value == "" || (max > 0 && len(value) > max) {
+			return nil
+		}
+		out.Confidence[key] = confidenceOf(v)
+		return &value
+	}
+
+	if name := text("name", raw.Name, 255); name != nil {
+		out.Coffee.Name = *name
+	}
+	out.Coffee.Origin = text("origin", raw.Origin, maxOriginTextLength)
+	out.Coffee.Description = text("description", raw.TastingNotes, 0)
+	if roaster := text("roaster", raw.Roaster, 255); roaster != nil {
+		out.Coffee.Roaster = roaster
+		match, ok, err := findRoaster(ctx, s.queries, *roaster)
+		if err != nil {
+			return err
+		}
+		if ok {
+			out.Coffee.Roaster, out.Coffee.RoasterID = &match.Name, &match.ID
+		}
+	}
+	if p, err := normalizeProcess(raw.Process.Value); err == nil {
+		out.Coffee.Process = &p
+		out.Confidence["process"] = confidenceOf(raw.Process)
+	}
+	if d, ok := parseLabelDate(raw.RoastDate.Value, now); ok {
+		out.Coffee.RoastDate = &d
+		out.Confidence["roastDate"] = confidenceOf(raw.RoastDate)
+	}
+	return nil
+}
+
+// confidenceOf clamps a reported confidence to 0-1 with two decimals.
+func confidenceOf(v ExtractedValue) float64 {
+	if math.IsNaN(v.Confidence) {
+		return 0
+	}
+	return roundTo(math.Max(0, math.Min(1, v.Confidence)), 2)
+}
+
+// labelDateLayouts are the ways roast dates are printed on bags. Slashed
+// dates are left out: 03/04 is a different day in Europe and the US.
+var labelDateLayouts = []string{
+	bagDateLayout,
+	"2006/01/02",
+	"2.1.2006",
+	"2 Jan 2006",
+	"2 January 2006",
+	"Jan 2 2006",
+	"January 2 2006",
+	time.RFC3339,
+}
+
+// parseLabelDate reads a roast date as YYYY-MM-DD. Dates more than a day in
+// the future are misreadings.
+func parseLabelDate(s string, now time.Time) (string, bool) {
+	s = strings.Join(strings.Fields(strings.ReplaceAll(s, ",", " ")), " ")
+	for _, layout := range labelDateLayouts {
+		d, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if d.After(now.AddDate(0, 0, 1)) {
+			return "", false
+		}
+		return d.Format(bagDateLayout), true
+	}
+	return "", false
+}
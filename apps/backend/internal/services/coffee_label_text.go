@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// Confidence the label rules give a value depending on how it was found.
+const (
+	labelledConfidence = 0.9 // after a "Roaster:" style label
+	inferredConfidence = 0.6 // recognised by its wording
+	guessedConfidence  = 0.3 // the first line nothing else claimed
+)
+
+// labelFields maps the labels printed on bags to CoffeeExtraction fields.
+var labelFields = map[string]string{
+	"coffee":            "name",
+	"coffee name":       "name",
+	"name":              "name",
+	"lot":               "name",
+	"roaster":           "roaster",
+	"roastery":          "roaster",
+	"roasted by":        "roaster",
+	"origin":            "origin",
+	"country":           "origin",
+	"country of origin": "origin",
+	"region":            "origin",
+	"process":           "process",
+	"processing":        "process",
+	"notes":             "tastingNotes",
+	"tasting notes":     "tastingNotes",
+	"flavour notes":     "tastingNotes",
+	"flavor notes":      "tastingNotes",
+	"cupping notes":     "tastingNotes",
+	"we taste":          "tastingNotes",
+	"roast date":        "roastDate",
+	"roasted":           "roastDate",
+	"roasted on":        "roastDate",
+}
+
+var (
+	labelLinePattern   = regexp.MustCompile(`^([A-Za-z][A-Za-z ]{1,20}?)\s*:\s*(.+)$`)
+	roastedByPattern   = regexp.MustCompile(`(?i)^roasted\s+by\s+(.+)$`)
+	roasterLinePattern = regexp.MustCompile(`(?i)\b(roasters|roastery|roasting co\.?|roastworks)$`)
+	labelDatePattern   = regexp.MustCompile(`(?i)\b(\d{4}-\d{2}-\d{2}|\d{4}/\d{2}/\d{2}|\d{1,2}\.\d{1,2}\.\d{4}|\d{1,2} [a-z]{3,9},? \d{4}|[a-z]{3,9} \d{1,2},? \d{4})\b`)
+)
+
+// LabelTextExtractor reads coffee details from pasted label text with fixed
+// rules. It works offline and cannot read photos.
+type LabelTextExtractor struct{}
+
+func NewLabelTextExtractor() *LabelTextExtractor {
+	return &LabelTextExtractor{}
+}
+
+func (e *LabelTextExtractor) Name() string {
+	return "rules"
+}
+
+// ExtractCoffee takes "Label: value" lines at their word, then recognises
+// roaster names, origins, processes and dates by their wording. The first
+// line left over is taken to be the coffee's name.
+func (e *LabelTextExtractor) ExtractCoffee(ctx context.Context, label CoffeeLabel) (*CoffeeExtraction, error) {
+	if strings.TrimSpace(label.Text) == "" {
+		return nil, ErrImageNotSupported
+	}
+	var out CoffeeExtraction
+	fields := map[string]*ExtractedValue{
+		"name":         &out.Name,
+		"roaster":      &out.Roaster,
+		"origin":       &out.Origin,
+		"process":      &out.Process,
+		"tastingNotes": &out.TastingNotes,
+		"roastDate":    &out.RoastDate,
+	}
+	// A value found more surely is kept
+	set := func(field, value string, confidence float64) {
+		if v := fields[field]; value != "" && v.Confidence < confidence {
+			*v = ExtractedValue{Value: value, Confidence: confidence}
+		}
+	}
+
+	var rest []string
+	for _, line := range strings.Split(label.Text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		if m := labelLinePattern.FindStringSubmatch(line); m != nil {
+			if field, ok := labelFields[strings.ToLower(strings.TrimSpace(m[1]))]; ok {
+				set(field, strings.TrimSpace(m[2]), labelledConfidence)
+				continue
+			}
+		}
+		if m := roastedByPattern.FindStringSubmatch(line); m != nil {
+			set("roaster", m[1], labelledConfidence)
+			continue
+		}
+		rest = append(rest, line)
+	}
+
+	var unclaimed []string
+	for _, line := range rest {
+		claimed := false
+		lower := lowerASCII(line)
+		if roasterLinePattern.MatchString(line) {
+			set("roaster", line, inferredConfidence)
+			claimed = true
+		}
+		if m := labelDatePattern.FindString(line); m != "" {
+			set("roastDate", m, inferredConfidence)
+			claimed = true
+		}
+		if parseLegacyOrigin(line).Country.Valid {
+			set("origin", line, inferredConfidence)
+			claimed = true
+		}
+		for _, phrase := range processPhrases {
+			if indexWord(lower, phrase) >= 0 {
+				set("process", phrase, inferredConfidence)
+				claimed = true
+				break
+			}
+		}
+		if !claimed {
+			unclaimed = append(unclaimed, line)
+		}
+	}
+	if len(unclaimed) > 0 && len(unclaimed[0]) <= 100 {
+		set("name", unclaimed[0], guessedConfidence)
+	}
+	return &out, nil
+}
@@ -0,0 +1,560 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxOriginTextLength = 255
+	maxVarietals        = 20
+	maxVarietalLength   = 100
+	// maxAltitude (metres) is well above the highest coffee farms.
+	maxAltitude = 5000
+)
+
+// CoffeeOrigin is where and how a coffee was grown and processed. Country is
+// an ISO 3166-1 alpha-2 code and altitudes are metres above sea level.
+type CoffeeOrigin struct {
+	Country     *string   `json:"country,omitempty"`
+	Region      *string   `json:"region,omitempty"`
+	Producer    *string   `json:"producer,omitempty"` // farm, producer, cooperative or washing station
+	Process     *string   `json:"process,omitempty"`
+	Varietals   *[]string `json:"varietals,omitempty"`
+	AltitudeMin *int64    `json:"altitudeMin,omitempty"`
+	AltitudeMax *int64    `json:"altitudeMax,omitempty"`
+}
+
+func (o CoffeeOrigin) isZero() bool {
+	return o.Country == nil && o.Region == nil && o.Producer == nil && o.Process == nil &&
+		o.Varietals == nil && o.AltitudeMin == nil && o.AltitudeMax == nil
+}
+
+// coffeeOriginColumns are the structured origin columns of a coffee row.
+type coffeeOriginColumns struct {
+	Country     sql.NullString
+	Region      sql.NullString
+	Producer    sql.NullString
+	Process     sql.NullString
+	Varietals   sql.NullString // JSON array of names
+	AltitudeMin sql.NullInt64
+	AltitudeMax sql.NullInt64
+}
+
+// Coffee processing methods. Anything else is recorded as ProcessOther.
+const (
+	ProcessWashed    = "washed"
+	ProcessNatural   = "natural"
+	ProcessHoney     = "honey"
+	ProcessAnaerobic = "anaerobic"
+	ProcessWetHulled = "wet-hulled"
+	ProcessOther     = "other"
+)
+
+// processAliases maps the ways roasters write a process to its canonical name.
+// Longer phrases are tried first when reading a legacy origin.
+var processAliases = map[string]string{
+	"washed":           ProcessWashed,
+	"fully washed":     ProcessWashed,
+	"wet processed":    ProcessWashed,
+	"semi-washed":      ProcessWetHulled,
+	"natural":          ProcessNatural,
+	"dry processed":    ProcessNatural,
+	"sun-dried":        ProcessNatural,
+	"honey":            ProcessHoney,
+	"pulped natural":   ProcessHoney,
+	"red honey":        ProcessHoney,
+	"yellow honey":     ProcessHoney,
+	"black honey":      ProcessHoney,
+	"anaerobic":        ProcessAnaerobic,
+	"carbonic":         ProcessAnaerobic,
+	"wet-hulled":       ProcessWetHulled,
+	"wet hulled":       ProcessWetHulled,
+	"giling basah":     ProcessWetHulled,
+	"other":            ProcessOther,
+	"experimental":     ProcessOther,
+	"double fermented": ProcessOther,
+}
+
+// coffeeCountries are the coffee-growing countries recognised in free-text
+// origins, by the names they are written as and by growing regions well known
+// enough to stand in for the country ("Yirgacheffe"). A matched country name
+// is dropped from the text while a matched region is kept as the region.
+// migrations/012_coffee_origin.up.sql backfills existing rows from the same
+// table; keep them in step.
+var coffeeCountries = []struct {
+	Code    string
+	Names   []string
+	Regions []string
+}{
+	{"AO", []string{"Angola"}, nil},
+	{"AU", []string{"Australia"}, nil},
+	{"BI", []string{"Burundi"}, nil},
+	{"BO", []string{"Bolivia"}, nil},
+	{"BR", []string{"Brazil", "Brasil"}, []string{"Minas Gerais", "Cerrado Mineiro", "Mogiana"}},
+	{"CD", []string{"DR Congo", "DRC", "Democratic Republic of the Congo", "Congo"}, []string{"Kivu"}},
+	{"CI", []string{"Ivory Coast", "Côte d'Ivoire", "Cote d'Ivoire"}, nil},
+	{"CM", []string{"Cameroon"}, nil},
+	{"CN", []string{"China"}, []string{"Yunnan"}},
+	{"CO", []string{"Colombia"}, []string{"Huila", "Nariño", "Narino", "Cauca", "Tolima"}},
+	{"CR", []string{"Costa Rica"}, []string{"Tarrazu", "Tarrazú", "West Valley"}},
+	{"CU", []string{"Cuba"}, nil},
+	{"DO", []string{"Dominican Republic"}, nil},
+	{"EC", []string{"Ecuador"}, []string{"Galapagos", "Galápagos"}},
+	{"ET", []string{"Ethiopia"}, []string{"Yirgacheffe", "Yirgachefe", "Sidamo", "Sidama", "Guji", "Harrar", "Harar", "Limu", "Jimma"}},
+	{"GT", []string{"Guatemala"}, []string{"Huehuetenango", "Antigua", "Atitlan", "Atitlán"}},
+	{"HN", []string{"Honduras"}, []string{"Marcala"}},
+	{"HT", []string{"Haiti"}, nil},
+	{"ID", []string{"Indonesia"}, []string{"Sumatra", "Java", "Sulawesi", "Toraja", "Flores", "Bali", "Aceh", "Gayo"}},
+	{"IN", []string{"India"}, []string{"Karnataka", "Chikmagalur", "Monsooned Malabar"}},
+	{"JM", []string{"Jamaica"}, []string{"Blue Mountain"}},
+	{"KE", []string{"Kenya"}, []string{"Nyeri", "Kirinyaga", "Kiambu", "Embu", "Murang'a"}},
+	{"LA", []string{"Laos"}, nil},
+	{"MG", []string{"Madagascar"}, nil},
+	{"MM", []string{"Myanmar", "Burma"}, nil},
+	{"MW", []string{"Malawi"}, nil},
+	{"MX", []string{"Mexico", "México"}, []string{"Chiapas", "Oaxaca", "Veracruz"}},
+	{"NI", []string{"Nicaragua"}, []string{"Jinotega", "Nueva Segovia"}},
+	{"NP", []string{"Nepal"}, nil},
+	{"PA", []string{"Panama", "Panamá"}, []string{"Boquete", "Volcan", "Volcán"}},
+	{"PE", []string{"Peru", "Perú"}, []string{"Cajamarca"}},
+	{"PG", []string{"Papua New Guinea", "PNG"}, nil},
+	{"PH", []string{"Philippines"}, nil},
+	{"PR", []string{"Puerto Rico"}, nil},
+	{"PY", []string{"Paraguay"}, nil},
+	{"RW", []string{"Rwanda"}, nil},
+	{"SV", []string{"El Salvador"}, nil},
+	{"TH", []string{"Thailand"}, nil},
+	{"TL", []string{"Timor-Leste", "East Timor", "Timor"}, nil},
+	{"TW", []string{"Taiwan"}, nil},
+	{"TZ", []string{"Tanzania"}, []string{"Kilimanjaro"}},
+	{"UG", []string{"Uganda"}, []string{"Mount Elgon", "Rwenzori"}},
+	{"US", []string{"USA"}, []string{"Hawaii", "Kona", "Ka'u"}},
+	{"VE", []string{"Venezuela"}, nil},
+	{"VN", []string{"Vietnam", "Viet Nam"}, nil},
+	{"YE", []string{"Yemen"}, nil},
+	{"ZM", []string{"Zambia"}, nil},
+	{"ZW", []string{"Zimbabwe"}, nil},
+}
+
+// isoCountryCodes lists every ISO 3166-1 alpha-2 code.
+var isoCountryCodes = strings.Fields(`
+AD AE AF AG AI AL AM AO AQ AR AS AT AU AW AX AZ BA BB BD BE BF BG BH BI BJ BL BM BN BO BQ BR BS BT BV BW BY BZ
+CA CC CD CF CG CH CI CK CL CM CN CO CR CU CV CW CX CY CZ DE DJ DK DM DO DZ EC EE EG EH ER ES ET FI FJ FK FM FO
+FR GA GB GD GE GF GG GH GI GL GM GN GP GQ GR GS GT GU GW GY HK HM HN HR HT HU ID IE IL IM IN IO IQ IR IS IT JE
+JM JO JP KE KG KH KI KM KN KP KR KW KY KZ LA LB LC LI LK LR LS LT LU LV LY MA MC MD ME MF MG MH MK ML MM MN MO
+MP MQ MR MS MT MU MV MW MX MY MZ NA NC NE NF NG NI NL NO NP NR NU NZ OM PA PE PF PG PH PK PL PM PN PR PS PT PW
+PY QA RE RO RS RU RW SA SB SC SD SE SG SH SI SJ SK SL SM SN SO SR SS ST SV SX SY SZ TC TD TF TG TH TJ TK TL TM
+TN TO TR TT TV TW TZ UA UG UM US UY UZ VA VC VE VG VI VN VU WF WS YE YT ZA ZM ZW`)
+
+var (
+	isoCountrySet = func() map[string]bool {
+		set := make(map[string]bool, len(isoCountryCodes))
+		for _, c := range isoCountryCodes {
+			set[c] = true
+		}
+		return set
+	}()
+
+	// countryNames holds every recognised name in match order: country names
+	// before regions, longest first, so "DR Congo" wins over "Congo".
+	countryNames = func() []countryName {
+		var names []countryName
+		for _, c := range coffeeCountries {
+			for _, n := range c.Names {
+				names = append(names, countryName{lowerASCII(n), c.Code, false})
+			}
+			for _, n := range c.Regions {
+				names = append(names, countryName{lowerASCII(n), c.Code, true})
+			}
+		}
+		sort.SliceStable(names, func(i, j int) bool {
+			if names[i].isRegion != names[j].isRegion {
+				return !names[i].isRegion
+			}
+			return len(names[i].name) > len(names[j].name)
+		})
+		return names
+	}()
+
+	processPhrases = func() []string {
+		phrases := make([]string, 0, len(processAliases))
+		for p := range processAliases {
+			// "other" in free text rarely names a process
+			if p != ProcessOther {
+				phrases = append(phrases, p)
+			}
+		}
+		sort.Slice(phrases, func(i, j int) bool {
+			if len(phrases[i]) != len(phrases[j]) {
+				return len(phrases[i]) > len(phrases[j])
+			}
+			return phrases[i] < phrases[j]
+		})
+		return phrases
+	}()
+
+	altitudePattern  = regexp.MustCompile(`(?i)(\d[\d,.]{2,5})\s*(?:(?:-|–|to)\s*(\d[\d,.]{2,5})\s*)?(?:m\.?a\.?s\.?l\.?|masl|metres|meters|m)\b`)
+	originSeparators = regexp.MustCompile(`\s*[,/|;]\s*|\s+-\s+`)
+)
+
+type countryName struct {
+	name     string
+	code     string
+	isRegion bool
+}
+
+// countryDisplayName is the first listed name of a coffee country, or the
+// code itself for countries not in coffeeCountries.
+func countryDisplayName(code string) string {
+	for _, c := range coffeeCountries {
+		if c.Code == code {
+			return c.Names[0]
+		}
+	}
+	return code
+}
+
+// parseLegacyOrigin reads what it can out of a free-text origin such as
+// "Ethiopia, Guji - Natural, 1900-2100 masl": the country, a region (the
+// rest of the text once country, process and altitude are removed), the
+// process and the altitude range. Text naming no country or several
+// countries gets no country or region. Producers and varietals cannot be told
+// apart from regions and are left alone.
+func parseLegacyOrigin(origin string) coffeeOriginColumns {
+	var cols coffeeOriginColumns
+	rest := strings.TrimSpace(origin)
+	if rest == "" {
+		return cols
+	}
+
+	if m := altitudePattern.FindStringSubmatchIndex(rest); m != nil {
+		low, okLow := parseAltitude(rest[m[2]:m[3]])
+		high, okHigh := low, okLow
+		if m[4] >= 0 {
+			high, okHigh = parseAltitude(rest[m[4]:m[5]])
+		}
+		if okLow && okHigh && low <= high {
+			cols.AltitudeMin = sql.NullInt64{Int64: low, Valid: true}
+			cols.AltitudeMax = sql.NullInt64{Int64: high, Valid: true}
+			rest = rest[:m[0]] + " " + rest[m[1]:]
+		}
+	}
+
+	lower := lowerASCII(rest)
+	for _, phrase := range processPhrases {
+		if i := indexWord(lower, phrase); i >= 0 {
+			cols.Process = sql.NullString{String: processAliases[phrase], Valid: true}
+			rest, lower = rest[:i]+" "+rest[i+len(phrase):], lower[:i]+" "+lower[i+len(phrase):]
+			break
+		}
+	}
+
+	// Without exactly one recognised country the text is a blend or a name,
+	// not a region
+	match, at := -1, -1
+	for k, n := range countryNames {
+		i := indexWord(lower, n.name)
+		if i < 0 {
+			continue
+		}
+		if match < 0 {
+			match, at = k, i
+		} else if n.code != countryNames[match].code {
+			return cols
+		}
+	}
+	if match < 0 {
+		return cols
+	}
+	n := countryNames[match]
+	cols.Country = sql.NullString{String: n.code, Valid: true}
+	if !n.isRegion {
+		rest = rest[:at] + " " + rest[at+len(n.name):]
+	}
+
+	var parts []string
+	for _, p := range originSeparators.Split(rest, -1) {
+		p = strings.Trim(strings.Join(strings.Fields(p), " "), " -,.()")
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	if region := strings.Join(parts, ", "); region != "" && len(region) <= maxOriginTextLength {
+		cols.Region = sql.NullString{String: region, Valid: true}
+	}
+	return cols
+}
+
+// lowerASCII lowercases only ASCII letters, like SQLite's lower(), so byte
+// offsets in the result are offsets in s.
+func lowerASCII(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return string(b)
+}
+
+// indexWord finds word in s where it is not part of a longer word.
+func indexWord(s, word string) int {
+	for from := 0; from < len(s); {
+		i := strings.Index(s[from:], word)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		end := i + len(word)
+		if (i == 0 || !isWordByte(s[i-1])) && (end == len(s) || !isWordByte(s[end])) {
+			return i
+		}
+		from = i + 1
+	}
+	return -1
+}
+
+func isWordByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b >= 0x80
+}
+
+func parseAltitude(s string) (int64, bool) {
+	n, err := strconv.ParseInt(strings.NewReplacer(",", "", ".", "").Replace(s), 10, 64)
+	return n, err == nil && n > 0 && n <= maxAltitude
+}
+
+// normalizeProcess maps a process to its canonical name.
+func normalizeProcess(s string) (string, error) {
+	p, ok := processAliases[strings.ToLower(strings.Join(strings.Fields(s), " "))]
+	if !ok {
+		return "", &ValidationError{Message: "process must be one of washed, natural, honey, anaerobic, wet-hulled, other"}
+	}
+	return p, nil
+}
+
+// mergeInto applies the origin fields to cur: nil keeps the stored value, ""
+// (or an empty varietals list) clears it.
+func (o CoffeeOrigin) mergeInto(cur *coffeeOriginColumns) error {
+	if o.Country != nil {
+		code := strings.ToUpper(strings.TrimSpace(*o.Country))
+		if code != "" && !isoCountrySet[code] {
+			return &ValidationError{Message: "country must be an ISO 3166-1 alpha-2 code such as ET or CO"}
+		}
+		cur.Country = sql.NullString{String: code, Valid: code != ""}
+	}
+	for _, f := range []struct {
+		name  string
+		value *string
+		out   *sql.NullString
+	}{
+		{"region", o.Region, &cur.Region},
+		{"producer", o.Producer, &cur.Producer},
+	} {
+		if f.value == nil {
+			continue
+		}
+		v := strings.TrimSpace(*f.value)
+		if len(v) > maxOriginTextLength {
+			return &ValidationError{Message: fmt.Sprintf("%s must be <= %d characters", f.name, maxOriginTextLength)}
+		}
+		*f.out = sql.NullString{String: v, Valid: v != ""}
+	}
+	if o.Process != nil {
+		cur.Process = sql.NullString{}
+		if strings.TrimSpace(*o.Process) != "" {
+			p, err := normalizeProcess(*o.Process)
+			if err != nil {
+				return err
+			}
+			cur.Process = sql.NullString{String: p, Valid: true}
+		}
+	}
+	if o.Varietals != nil {
+		varietals, err := encodeVarietals(*o.Varietals)
+		if err != nil {
+			return err
+		}
+		cur.Varietals = varietals
+	}
+
+	if o.AltitudeMin != nil {
+		cur.AltitudeMin = sql.NullInt64{Int64: *o.AltitudeMin, Valid: *o.AltitudeMin != 0}
+	}
+	if o.AltitudeMax != nil {
+		cur.AltitudeMax = sql.NullInt64{Int64: *o.AltitudeMax, Valid: *o.AltitudeMax != 0}
+	}
+	// A single altitude is a range of one
+	if cur.AltitudeMin.Valid != cur.AltitudeMax.Valid {
+		if cur.AltitudeMin.Valid {
+			cur.AltitudeMax = cur.AltitudeMin
+		} else {
+			cur.AltitudeMin = cur.AltitudeMax
+		}
+	}
+	if cur.AltitudeMin.Valid {
+		if cur.AltitudeMin.Int64 < 1 || cur.AltitudeMax.Int64 > maxAltitude {
+			return &ValidationError{Message: fmt.Sprintf("altitude must be between 1 and %d metres", maxAltitude)}
+		}
+		if cur.AltitudeMin.Int64 > cur.AltitudeMax.Int64 {
+			return &ValidationError{Message: "altitudeMin must be <= altitudeMax"}
+		}
+	}
+	return nil
+}
+
+func originColumnsOf(c db.GetCoffeeByIDRow) coffeeOriginColumns {
+	return coffeeOriginColumns{
+		Country:     c.Country,
+		Region:      c.Region,
+		Producer:    c.Producer,
+		Process:     c.Process,
+		Varietals:   c.Varietals,
+		AltitudeMin: c.AltitudeMin,
+		AltitudeMax: c.AltitudeMax,
+	}
+}
+
+func setOriginParams(p *db.UpdateCoffeeParams, c coffeeOriginColumns) {
+	p.Country, p.Region, p.Producer, p.Process = c.Country, c.Region, c.Producer, c.Process
+	p.Varietals, p.AltitudeMin, p.AltitudeMax = c.Varietals, c.AltitudeMin, c.AltitudeMax
+}
+
+// reconcile keeps the legacy origin text and the structured fields in step
+// for a new coffee: fields the client left out are read from the text, and
+// a coffee sent with only structured fields gets a text built from them.
+func (cur *coffeeOriginColumns) reconcile(legacy *sql.NullString) {
+	if legacy.Valid {
+		cur.fillFrom(parseLegacyOrigin(legacy.String))
+		return
+	}
+	*legacy = cur.legacyOrigin()
+}
+
+// update applies a partial update of the origin to the stored columns.
+// Fields that were read from the old origin text follow the text when it
+// changes, and a text that was built from the fields is rebuilt when they
+// change.
+func (cur *coffeeOriginColumns) update(oldOrigin sql.NullString, originChanged bool, in CoffeeOrigin, legacy *sql.NullString) error {
+	if originChanged {
+		cur.dropParsed(parseLegacyOrigin(oldOrigin.String))
+	} else if oldOrigin.Valid && oldOrigin == cur.legacyOrigin() {
+		*legacy = sql.NullString{}
+	}
+	if err := in.mergeInto(cur); err != nil {
+		return err
+	}
+	cur.reconcile(legacy)
+	return nil
+}
+
+// dropParsed clears the fields that still hold what was read from an origin text.
+func (cur *coffeeOriginColumns) dropParsed(parsed coffeeOriginColumns) {
+	for _, f := range []struct{ cur, parsed *sql.NullString }{
+		{&cur.Country, &parsed.Country},
+		{&cur.Region, &parsed.Region},
+		{&cur.Process, &parsed.Process},
+	} {
+		if f.parsed.Valid && *f.cur == *f.parsed {
+			*f.cur = sql.NullString{}
+		}
+	}
+	if parsed.AltitudeMin.Valid && cur.AltitudeMin == parsed.AltitudeMin && cur.AltitudeMax == parsed.AltitudeMax {
+		cur.AltitudeMin, cur.AltitudeMax = sql.NullInt64{}, sql.NullInt64{}
+	}
+}
+
+// fillFrom copies the fields of parsed that cur does not have yet.
+func (cur *coffeeOriginColumns) fillFrom(parsed coffeeOriginColumns) {
+	if !cur.Country.Valid {
+		cur.Country = parsed.Country
+	}
+	if !cur.Region.Valid {
+		cur.Region = parsed.Region
+	}
+	if !cur.Process.Valid {
+		cur.Process = parsed.Process
+	}
+	if !cur.AltitudeMin.Valid && !cur.AltitudeMax.Valid {
+		cur.AltitudeMin, cur.AltitudeMax = parsed.AltitudeMin, parsed.AltitudeMax
+	}
+}
+
+// legacyOrigin builds the free-text origin older clients read from the
+// structured fields, e.g. "Guji, Ethiopia".
+func (cur coffeeOriginColumns) legacyOrigin() sql.NullString {
+	var parts []string
+	if cur.Region.Valid {
+		parts = append(parts, cur.Region.String)
+	}
+	if cur.Country.Valid {
+		parts = append(parts, countryDisplayName(cur.Country.String))
+	}
+	s := strings.Join(parts, ", ")
+	if len(s) > 100 {
+		s = cur.Region.String
+		if len(s) > 100 {
+			s = countryDisplayName(cur.Country.String)
+		}
+	}
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func encodeVarietals(varietals []string) (sql.NullString, error) {
+	if len(varietals) > maxVarietals {
+		return sql.NullString{}, &ValidationError{Message: fmt.Sprintf("varietals must have at most %d entries", maxVarietals)}
+	}
+	clean := make([]string, 0, len(varietals))
+	seen := map[string]bool{}
+	for _, v := range varietals {
+		v = strings.Join(strings.Fields(v), " ")
+		if v == "" || len(v) > maxVarietalLength {
+			return sql.NullString{}, &ValidationError{Message: fmt.Sprintf("each varietal must be 1-%d characters", maxVarietalLength)}
+		}
+		if key := strings.ToLower(v); !seen[key] {
+			seen[key] = true
+			clean = append(clean, v)
+		}
+	}
+	if len(clean) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(clean)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func (o *CoffeeOutput) setOrigin(c coffeeOriginColumns) {
+	if c.Country.Valid {
+		o.Country = &c.Country.String
+	}
+	if c.Region.Valid {
+		o.Region = &c.Region.String
+	}
+	if c.Producer.Valid {
+		o.Producer = &c.Producer.String
+	}
+	if c.Process.Valid {
+		o.Process = &c.Process.String
+	}
+	if c.Varietals.Valid {
+		var varietals []string
+		if err := json.Unmarshal([]byte(c.Varietals.String), &varietals); err == nil && len(varietals) > 0 {
+			o.Varietals = &varietals
+		}
+	}
+	if c.AltitudeMin.Valid {
+		o.AltitudeMin = &c.AltitudeMin.Int64
+	}
+	if c.AltitudeMax.Valid {
+		o.AltitudeMax = &c.AltitudeMax.Int64
+	}
+}
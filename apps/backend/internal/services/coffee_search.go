@@ -0,0 +1,169 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"database/sql"
+	"strings"
+)
+
+const (
+	DefaultCoffeePageSize = 50
+	MaxCoffeePageSize     = 100
+	maxCoffeeQueryLength  = 200
+	maxCoffeeQueryTerms   = 10
+)
+
+type ListCoffeesInput struct {
+	UserID int64
+	// Query is free text matched against name, roaster, origin and description.
+	Query string
+	// Roaster and Origin keep only coffees with exactly that value.
+	Roaster *string
+	Origin  *string
+	// RoasterID keeps only coffees linked to that directory roaster.
+	RoasterID *int64
+	Sort      string // created|name|rating|relevance; relevance (with Query) or created by default
+	Order     string // asc|desc; asc for name, desc otherwise by default
+	Limit     int    // 0 means DefaultCoffeePageSize
+	Cursor    string // NextCursor from the previous page
+}
+
+// CoffeeListItem is a coffee in search results, with how it has brewed so far.
+type CoffeeListItem struct {
+	CoffeeOutput
+	AverageRating *float64 `json:"averageRating,omitempty"`
+	BrewCount     int64    `json:"brewCount"`
+}
+
+type CoffeeFacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type CoffeeFacetsOutput struct {
+	Roasters []CoffeeFacetCount `json:"roasters"`
+	Origins  []CoffeeFacetCount `json:"origins"`
+}
+
+type CoffeePage struct {
+	Coffees    []CoffeeListItem `json:"coffees"`
+	NextCursor *string          `json:"nextCursor"`
+	// Total counts every coffee matching the search, not just this page.
+	Total  int64              `json:"total"`
+	Facets CoffeeFacetsOutput `json:"facets"`
+}
+
+// List searches the user's coffees and returns one page of them with facet
+// counts by roaster and origin. Search uses the FTS5 index when the server
+// has one and substring matching otherwise.
+func (s *CoffeeService) List(ctx context.Context, input ListCoffeesInput) (*CoffeePage, error) {
+	params := db.SearchCoffeesParams{UserID: input.UserID}
+
+	query := strings.TrimSpace(input.Query)
+	if len(query) > maxCoffeeQueryLength {
+		return nil, &ValidationError{Message: "q must be <= 200 characters"}
+	}
+	params.Terms = strings.Fields(query)
+	if len(params.Terms) > maxCoffeeQueryTerms {
+		return nil, &ValidationError{Message: "q must have at most 10 words"}
+	}
+	if len(params.Terms) > 0 {
+		fullText, err := s.queries.HasCoffeeSearchIndex(ctx)
+		if err != nil {
+			return nil, err
+		}
+		params.FullText = fullText
+	}
+	params.Roaster = nullTrimmedString(input.Roaster)
+	params.Origin = nullTrimmedString(input.Origin)
+	if input.RoasterID != nil {
+		params.RoasterID = sql.NullInt64{Int64: *input.RoasterID, Valid: true}
+	}
+
+	params.SortBy = "created"
+	if len(params.Terms) > 0 {
+		params.SortBy = "relevance"
+	}
+	if input.Sort != "" {
+		if _, ok := db.CoffeeSortExprs[input.Sort]; !ok {
+			return nil, &ValidationError{Message: "sort must be one of created, name, rating, relevance"}
+		}
+		if input.Sort == "relevance" && len(params.Terms) == 0 {
+			return nil, &ValidationError{Message: "sort=relevance needs a search query"}
+		}
+		params.SortBy = input.Sort
+	}
+	order := "desc"
+	if params.SortBy == "name" {
+		order = "asc"
+	}
+	if input.Order != "" {
+		order = strings.ToLower(input.Order)
+		if order != "asc" && order != "desc" {
+			return nil, &ValidationError{Message: "order must be asc or desc"}
+		}
+	}
+	params.Descending = order == "desc"
+
+	limit := input.Limit
+	if limit == 0 {
+		limit = DefaultCoffeePageSize
+	}
+	if limit < 1 || limit > MaxCoffeePageSize {
+		return nil, &ValidationError{Message: "limit must be between 1 and 100"}
+	}
+	// Fetch one extra row; its presence means there is another page
+	params.Limit = int64(limit) + 1
+
+	if input.Cursor != "" {
+		// Coffee cursors share the brew log encoding: sort, order, key and id
+		c, err := decodeBrewLogCursor(input.Cursor)
+		if err != nil || c.Sort != params.SortBy || c.Order != order {
+			return nil, &ValidationError{Message: "cursor is invalid for this query"}
+		}
+		params.AfterSortKey = c.Value
+		params.AfterID = c.ID
+	}
+
+	rows, err := s.queries.SearchCoffees(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	facets, err := s.queries.CoffeeSearchFacets(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &CoffeePage{
+		Coffees: make([]CoffeeListItem, 0, len(rows)),
+		Total:   facets.Total,
+		Facets: CoffeeFacetsOutput{
+			Roasters: toCoffeeFacetCounts(facets.Roasters),
+			Origins:  toCoffeeFacetCounts(facets.Origins),
+		},
+	}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[limit-1]
+		c := encodeBrewLogCursor(brewLogCursor{Sort: params.SortBy, Order: order, Value: last.SortKey, ID: last.ID})
+		page.NextCursor = &c
+	}
+	for _, row := range rows {
+		item := CoffeeListItem{CoffeeOutput: *toCoffeeOutput(row.GetCoffeeByIDRow), BrewCount: row.BrewCount}
+		if row.AverageRating.Valid {
+			avg := roundTo(row.AverageRating.Float64, 2)
+			item.AverageRating = &avg
+		}
+		page.Coffees = append(page.Coffees, item)
+	}
+	return page, nil
+}
+
+func toCoffeeFacetCounts(facets []db.CoffeeFacet) []CoffeeFacetCount {
+	out := make([]CoffeeFacetCount, len(facets))
+	for i, f := range facets {
+		out[i] = CoffeeFacetCount{Value: f.Value, Count: f.Count}
+	}
+	return out
+}
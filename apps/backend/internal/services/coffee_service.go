@@ -4,6 +4,8 @@ import (
 	db "coffeeee/backend/internal/database/sqlc"
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -19,115 +21,109 @@ func NewCoffeeService(queries *db.Queries) *CoffeeService {
 }
 
 type CoffeeOutput struct {
-	ID          int64   `json:"id"`
-	Name        string  `json:"name"`
-	Origin      *string `json:"origin,omitempty"`
-	Roaster     *string `json:"roaster,omitempty"`
+	ID      int64   `json:"id"`
+	Name    string  `json:"name"`
+	Origin  *string `json:"origin,omitempty"`
+	Roaster *string `json:"roaster,omitempty"`
+	// RoasterID links the coffee to the shared roaster directory.
+	RoasterID   *int64  `json:"roasterId,omitempty"`
 	Description *string `json:"description,omitempty"`
 	PhotoPath   *string `json:"photoPath,omitempty"`
-	CreatedAt   string  `json:"createdAt"`
-	UpdatedAt   string  `json:"updatedAt"`
+	// PhotoURL and ThumbnailURL are set for photos uploaded through
+	// POST /coffees/{id}/photo; they need the same authentication as the API.
+	PhotoURL     *string `json:"photoUrl,omitempty"`
+	ThumbnailURL *string `json:"thumbnailUrl,omitempty"`
+	CoffeeOrigin
+	CoffeeBag
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
 }
 
 type CreateCoffeeInput struct {
+	UserID  int64   `json:"user_id"`
+	Name    string  `json:"name"`
+	Origin  *string `json:"origin,omitempty"`
+	Roaster *string `json:"roaster,omitempty"`
+	// RoasterID picks a directory roaster and wins over Roaster. Without it
+	// Roaster is linked to the closest directory entry, or added as a new one.
+	RoasterID   *int64  `json:"roasterId,omitempty"`
+	Description *string `json:"description,omitempty"`
+	PhotoPath   *string `json:"photoPath,omitempty"`
+	CoffeeOrigin
+	CoffeeBag
+}
+
+// UpdateCoffeeInput is a partial update: nil fields keep their stored values
+// and "" clears an optional field. RoasterID 0 unlinks the roaster.
+type UpdateCoffeeInput struct {
+	ID          int64   `json:"id"`
 	UserID      int64   `json:"user_id"`
-	Name        string  `json:"name"`
+	Name        *string `json:"name,omitempty"`
 	Origin      *string `json:"origin,omitempty"`
 	Roaster     *string `json:"roaster,omitempty"`
+	RoasterID   *int64  `json:"roasterId,omitempty"`
 	Description *string `json:"description,omitempty"`
 	PhotoPath   *string `json:"photoPath,omitempty"`
+	CoffeeOrigin
+	CoffeeBag
 }
 
-func (s *CoffeeService) ListForUser(ctx context.Context, userID int64) ([]CoffeeOutput, error) {
-	coffees, err := s.queries.ListCoffeesForUser(ctx, userID)
-	if err != nil {
-		return nil, err
-	}
-
-	var result []CoffeeOutput
-	for _, coffee := range coffees {
-		output := CoffeeOutput{
-			ID:        coffee.ID,
-			Name:      coffee.Name,
-			CreatedAt: coffee.CreatedAt.Format(time.RFC3339),
-			UpdatedAt: coffee.UpdatedAt.Format(time.RFC3339),
-		}
-
-		if coffee.Origin.Valid {
-			output.Origin = &coffee.Origin.String
-		}
-		if coffee.Roaster.Valid {
-			output.Roaster = &coffee.Roaster.String
-		}
-		if coffee.Description.Valid {
-			output.Description = &coffee.Description.String
-		}
-		if coffee.PhotoPath.Valid {
-			output.PhotoPath = &coffee.PhotoPath.String
-		}
+// CoffeeHasBrewLogsError is returned by Delete when removing the coffee would
+// also remove brew logs and the caller has not confirmed it.
+type CoffeeHasBrewLogsError struct {
+	BrewLogCount int64
+}
 
-		result = append(result, output)
-	}
+func (e *CoffeeHasBrewLogsError) Error() string {
+	return fmt.Sprintf("coffee has %d brew logs that would be deleted with it", e.BrewLogCount)
+}
 
-	return result, nil
+// coffeeDetails are the optional coffee columns, trimmed and validated.
+type coffeeDetails struct {
+	Origin      sql.NullString
+	Roaster     sql.NullString
+	Description sql.NullString
+	PhotoPath   sql.NullString
 }
 
 func (s *CoffeeService) CreateForUser(ctx context.Context, input CreateCoffeeInput) (*CoffeeOutput, error) {
 	// Validate input
-	name := strings.TrimSpace(input.Name)
-	if name == "" || len(name) > 255 {
-		return nil, &ValidationError{Message: "name is required and must be <= 255 characters"}
+	name, err := coffeeName(input.Name)
+	if err != nil {
+		return nil, err
 	}
-
-	var origin, roaster, description, photoPath sql.NullString
-
-	if input.Origin != nil {
-		originStr := strings.TrimSpace(*input.Origin)
-		if len(originStr) > 100 {
-			return nil, &ValidationError{Message: "origin must be <= 100 characters"}
-		}
-		if originStr != "" {
-			origin.String = originStr
-			origin.Valid = true
-		}
+	details, err := coffeeDetailsFrom(input.Origin, input.Roaster, input.Description, input.PhotoPath)
+	if err != nil {
+		return nil, err
 	}
-
-	if input.Roaster != nil {
-		roasterStr := strings.TrimSpace(*input.Roaster)
-		if len(roasterStr) > 255 {
-			return nil, &ValidationError{Message: "roaster must be <= 255 characters"}
-		}
-		if roasterStr != "" {
-			roaster.String = roasterStr
-			roaster.Valid = true
-		}
+	if err := s.checkPhotoPath(ctx, input.UserID, details.PhotoPath); err != nil {
+		return nil, err
 	}
-
-	if input.Description != nil {
-		descStr := strings.TrimSpace(*input.Description)
-		if descStr != "" {
-			description.String = descStr
-			description.Valid = true
-		}
+	var structured coffeeOriginColumns
+	if err := input.CoffeeOrigin.mergeInto(&structured); err != nil {
+		return nil, err
 	}
-
-	if input.PhotoPath != nil {
-		photoStr := strings.TrimSpace(*input.PhotoPath)
-		if len(photoStr) > 500 {
-			return nil, &ValidationError{Message: "photoPath must be <= 500 characters"}
-		}
-		if photoStr != "" {
-			photoPath.String = photoStr
-			photoPath.Valid = true
-		}
+	structured.reconcile(&details.Origin)
+	roasterID, err := s.linkRoaster(ctx, input.UserID, input.RoasterID, &details.Roaster)
+	if err != nil {
+		return nil, err
+	}
+	origin, roaster, description, photoPath := details.Origin, details.Roaster, details.Description, details.PhotoPath
+	var bag coffeeBagColumns
+	if err := input.CoffeeBag.mergeInto(&bag, time.Now()); err != nil {
+		return nil, err
 	}
 
 	// Check if coffee already exists
 	params := db.FindCoffeeByUserAndDetailsParams{
-		UserID:  input.UserID,
-		Name:    name,
-		Origin:  origin,
-		Roaster: roaster,
+		UserID:   input.UserID,
+		Name:     name,
+		Origin:   origin,
+		Roaster:  roaster,
+		Country:  structured.Country,
+		Region:   structured.Region,
+		Producer: structured.Producer,
+		Process:  structured.Process,
 	}
 
 	existingID, err := s.queries.FindCoffeeByUserAndDetails(ctx, params)
@@ -144,68 +140,329 @@ func (s *CoffeeService) CreateForUser(ctx context.Context, input CreateCoffeeInp
 			}
 		}
 
-		// Get the updated coffee
-		coffee, err := s.queries.GetCoffeeByIDOnly(ctx, existingID)
-		if err != nil {
-			return nil, err
+		// Bag details describe a new bag of the same coffee and replace the old one
+		if input.CoffeeBag != (CoffeeBag{}) {
+			coffee, err := s.getOwned(ctx, input.UserID, existingID)
+			if err != nil {
+				return nil, err
+			}
+			params := updateCoffeeParamsFrom(coffee)
+			setBagParams(&params, bag)
+			if err := s.queries.UpdateCoffee(ctx, params); err != nil {
+				return nil, err
+			}
 		}
 
-		return s.convertToOutput(existingID, name, coffee), nil
+		return s.Get(ctx, input.UserID, existingID)
 	}
 
 	// Create new coffee
 	createParams := db.CreateCoffeeParams{
-		UserID:      input.UserID,
-		Name:        name,
-		Origin:      origin,
-		Roaster:     roaster,
-		Description: description,
-		PhotoPath:   photoPath,
+		UserID:          input.UserID,
+		Name:            name,
+		Origin:          origin,
+		Roaster:         roaster,
+		Description:     description,
+		PhotoPath:       photoPath,
+		RoastDate:       bag.RoastDate,
+		PurchaseDate:    bag.PurchaseDate,
+		OpenedDate:      bag.OpenedDate,
+		BagSize:         bag.BagSize,
+		Price:           bag.Price,
+		RemainingWeight: bag.RemainingWeight,
+		Country:         structured.Country,
+		Region:          structured.Region,
+		Producer:        structured.Producer,
+		Process:         structured.Process,
+		Varietals:       structured.Varietals,
+		AltitudeMin:     structured.AltitudeMin,
+		AltitudeMax:     structured.AltitudeMax,
+		RoasterID:       roasterID,
 	}
 
 	coffee, err := s.queries.CreateCoffee(ctx, createParams)
 	if err != nil {
 		return nil, err
 	}
+	return toCoffeeOutput(db.GetCoffeeByIDRow(coffee)), nil
+}
 
-	return s.convertToOutput(coffee.ID, coffee.Name, db.GetCoffeeByIDOnlyRow{
-		Origin:      coffee.Origin,
-		Roaster:     coffee.Roaster,
-		Description: coffee.Description,
-		PhotoPath:   coffee.PhotoPath,
-		CreatedAt:   coffee.CreatedAt,
-		UpdatedAt:   coffee.UpdatedAt,
-	}), nil
+func (s *CoffeeService) Get(ctx context.Context, userID, id int64) (*CoffeeOutput, error) {
+	coffee, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toCoffeeOutput(coffee), nil
 }
 
-func (s *CoffeeService) convertToOutput(id int64, name string, coffee db.GetCoffeeByIDOnlyRow) *CoffeeOutput {
-	output := &CoffeeOutput{
-		ID:        id,
-		Name:      name,
-		CreatedAt: coffee.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: coffee.UpdatedAt.Format(time.RFC3339),
+func (s *CoffeeService) Update(ctx context.Context, input UpdateCoffeeInput) (*CoffeeOutput, error) {
+	if input.Name == nil && input.Origin == nil && input.Roaster == nil && input.RoasterID == nil && input.Description == nil && input.PhotoPath == nil &&
+		input.CoffeeOrigin.isZero() && input.CoffeeBag == (CoffeeBag{}) {
+		return nil, &ValidationError{Message: "At least one field must be provided"}
+	}
+	var name string
+	if input.Name != nil {
+		var err error
+		if name, err = coffeeName(*input.Name); err != nil {
+			return nil, err
+		}
+	}
+	details, err := coffeeDetailsFrom(input.Origin, input.Roaster, input.Description, input.PhotoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.getOwned(ctx, input.UserID, input.ID)
+	if err != nil {
+		return nil, err
 	}
+	params := updateCoffeeParamsFrom(existing)
+	bag := bagColumnsOf(existing)
+	if err := input.CoffeeBag.mergeInto(&bag, time.Now()); err != nil {
+		return nil, err
+	}
+	setBagParams(&params, bag)
+	if input.Name != nil {
+		params.Name = name
+	}
+	if input.Origin != nil {
+		params.Origin = details.Origin
+	}
+	switch {
+	case input.RoasterID != nil && *input.RoasterID == 0:
+		params.Roaster, params.RoasterID = sql.NullString{}, sql.NullInt64{}
+	case input.RoasterID != nil || input.Roaster != nil:
+		if params.RoasterID, err = s.linkRoaster(ctx, input.UserID, input.RoasterID, &details.Roaster); err != nil {
+			return nil, err
+		}
+		params.Roaster = details.Roaster
+	}
+	if input.Description != nil {
+		params.Description = details.Description
+	}
+	if input.PhotoPath != nil {
+		if err := s.checkPhotoPath(ctx, input.UserID, details.PhotoPath); err != nil {
+			return nil, err
+		}
+		params.PhotoPath = details.PhotoPath
+	}
+	structured := originColumnsOf(existing)
+	if err := structured.update(existing.Origin, input.Origin != nil, input.CoffeeOrigin, &params.Origin); err != nil {
+		return nil, err
+	}
+	setOriginParams(&params, structured)
+
+	// Keep find-or-create meaningful: an edit may not turn this coffee into
+	// a duplicate of another one
+	otherID, err := s.queries.FindCoffeeByUserAndDetails(ctx, db.FindCoffeeByUserAndDetailsParams{
+		UserID:   input.UserID,
+		Name:     params.Name,
+		Origin:   params.Origin,
+		Roaster:  params.Roaster,
+		Country:  params.Country,
+		Region:   params.Region,
+		Producer: params.Producer,
+		Process:  params.Process,
+	})
+	switch {
+	case err == nil && otherID != existing.ID:
+		return nil, &ConflictError{Message: "another coffee with this name, origin and roaster already exists"}
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	if err := s.queries.UpdateCoffee(ctx, params); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, input.UserID, input.ID)
+}
+
+// Delete removes the coffee together with its brew logs. When there are brew
+// logs and force is false nothing is deleted and a *CoffeeHasBrewLogsError
+// reports how many would go.
+func (s *CoffeeService) Delete(ctx context.Context, userID, id int64, force bool) error {
+	if _, err := s.getOwned(ctx, userID, id); err != nil {
+		return err
+	}
+
+	// Counting in the transaction keeps a brew log added meanwhile from being
+	// deleted unconfirmed. Foreign keys are not enforced on our connections,
+	// so the cascade is done here.
+	return s.queries.InTx(ctx, func(q *db.Queries) error {
+		count, err := q.CountBrewLogsForCoffee(ctx, id)
+		if err != nil {
+			return err
+		}
+		if count > 0 && !force {
+			return &CoffeeHasBrewLogsError{BrewLogCount: count}
+		}
+		if err := q.DeleteBrewLogPoursForCoffee(ctx, id); err != nil {
+			return err
+		}
+		if err := q.ClearBrewLogParentsForCoffee(ctx, id); err != nil {
+			return err
+		}
+		if err := q.ClearTastingSessionsForCoffee(ctx, id); err != nil {
+			return err
+		}
+		if err := q.DeleteBrewLogsForCoffee(ctx, id); err != nil {
+			return err
+		}
+		return q.DeleteCoffee(ctx, db.DeleteCoffeeParams{ID: id, UserID: userID})
+	})
+}
 
-	if coffee.Origin.Valid {
-		output.Origin = &coffee.Origin.String
+// checkPhotoPath only lets a client keep, reuse or clear a photo already on
+// one of its own coffees; new photos come in through PhotoService.Upload.
+// Otherwise a coffee could point at another user's photo and keep it from
+// being collected.
+func (s *CoffeeService) checkPhotoPath(ctx context.Context, userID int64, photoPath sql.NullString) error {
+	if !photoPath.Valid {
+		return nil
 	}
-	if coffee.Roaster.Valid {
-		output.Roaster = &coffee.Roaster.String
+	n, err := s.queries.CountUserCoffeesWithPhoto(ctx, db.CountUserCoffeesWithPhotoParams{UserID: userID, PhotoPath: photoPath})
+	if err != nil {
+		return err
 	}
-	if coffee.Description.Valid {
-		output.Description = &coffee.Description.String
+	if n == 0 {
+		return &ValidationError{Message: "photoPath must be a photo uploaded to one of your coffees"}
 	}
-	if coffee.PhotoPath.Valid {
-		output.PhotoPath = &coffee.PhotoPath.String
+	return nil
+}
+
+// linkRoaster finds the directory roaster for a coffee. An explicit
+// roasterID must exist and replaces the roaster text with its name; otherwise
+// the text is matched against the directory, and added to it when nothing is
+// close, but kept as the user typed it.
+func (s *CoffeeService) linkRoaster(ctx context.Context, userID int64, roasterID *int64, roaster *sql.NullString) (sql.NullInt64, error) {
+	var r db.Roaster
+	var err error
+	switch {
+	case roasterID != nil:
+		if *roasterID <= 0 {
+			return sql.NullInt64{}, roasterIDError(*roasterID)
+		}
+		r, err = s.queries.GetRoasterByID(ctx, *roasterID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.NullInt64{}, roasterIDError(*roasterID)
+		}
+	case roaster.Valid:
+		r, err = resolveRoaster(ctx, s.queries, userID, roaster.String, sql.NullString{}, sql.NullString{})
+	default:
+		return sql.NullInt64{}, nil
 	}
+	if err != nil {
+		return sql.NullInt64{}, err
+	}
+	if roasterID != nil {
+		*roaster = sql.NullString{String: r.Name, Valid: true}
+	}
+	return sql.NullInt64{Int64: r.ID, Valid: true}, nil
+}
 
-	return output
+// updateCoffeeParamsFrom starts an update that writes the row back unchanged.
+func updateCoffeeParamsFrom(c db.GetCoffeeByIDRow) db.UpdateCoffeeParams {
+	params := db.UpdateCoffeeParams{
+		Name:        c.Name,
+		Origin:      c.Origin,
+		Roaster:     c.Roaster,
+		Description: c.Description,
+		PhotoPath:   c.PhotoPath,
+		RoasterID:   c.RoasterID,
+		ID:          c.ID,
+		UserID:      c.UserID,
+	}
+	setOriginParams(&params, originColumnsOf(c))
+	setBagParams(&params, bagColumnsOf(c))
+	return params
+}
+
+// getOwned loads a coffee owned by userID. Other users' coffees are reported
+// as not found.
+func (s *CoffeeService) getOwned(ctx context.Context, userID, id int64) (db.GetCoffeeByIDRow, error) {
+	coffee, err := s.queries.GetCoffeeByID(ctx, db.GetCoffeeByIDParams{ID: id, UserID: userID})
+	return coffee, coffeeLookupError(err)
+}
+
+// coffeeLookupError turns a missing row from an owner-scoped coffee query
+// into a NotFoundError.
+func coffeeLookupError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return &NotFoundError{Message: "coffee not found"}
+	}
+	return err
+}
+
+func coffeeName(s string) (string, error) {
+	name := strings.TrimSpace(s)
+	if name == "" || len(name) > 255 {
+		return "", &ValidationError{Message: "name is required and must be <= 255 characters"}
+	}
+	return name, nil
 }
 
-type ValidationError struct {
-	Message string
+// coffeeDetailsFrom trims and length-checks the optional coffee fields. Blank
+// values become NULL.
+func coffeeDetailsFrom(origin, roaster, description, photoPath *string) (coffeeDetails, error) {
+	var d coffeeDetails
+	for _, f := range []struct {
+		name  string
+		value *string
+		max   int
+		out   *sql.NullString
+	}{
+		{"origin", origin, 100, &d.Origin},
+		{"roaster", roaster, 255, &d.Roaster},
+		{"description", description, 0, &d.Description},
+		{"photoPath", photoPath, 500, &d.PhotoPath},
+	} {
+		if f.value == nil {
+			continue
+		}
+		v := strings.TrimSpace(*f.value)
+		if f.max > 0 && len(v) > f.max {
+			return d, &ValidationError{Message: fmt.Sprintf("%s must be <= %d characters", f.name, f.max)}
+		}
+		*f.out = sql.NullString{String: v, Valid: v != ""}
+	}
+	return d, nil
 }
 
-func (e *ValidationError) Error() string {
-	return e.Message
+func toCoffeeOutput(c db.GetCoffeeByIDRow) *CoffeeOutput {
+	output := &CoffeeOutput{
+		ID:        c.ID,
+		Name:      c.Name,
+		CreatedAt: c.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: c.UpdatedAt.Format(time.RFC3339),
+	}
+	if c.Origin.Valid {
+		output.Origin = &c.Origin.String
+	}
+	if c.Roaster.Valid {
+		output.Roaster = &c.Roaster.String
+	}
+	if c.RoasterID.Valid {
+		output.RoasterID = &c.RoasterID.Int64
+	}
+	if c.Description.Valid {
+		output.Description = &c.Description.String
+	}
+	output.setPhoto(c.PhotoPath)
+	output.setOrigin(originColumnsOf(c))
+	output.setBag(bagColumnsOf(c))
+	return output
+}
+
+// setPhoto fills PhotoPath, and the download URLs when the photo was uploaded
+// rather than supplied as a string by the client. o.ID must be set.
+func (o *CoffeeOutput) setPhoto(photoPath sql.NullString) {
+	if !photoPath.Valid {
+		return
+	}
+	o.PhotoPath = &photoPath.String
+	if photoNamePattern.MatchString(photoPath.String) {
+		photoURL := fmt.Sprintf("/api/v1/coffees/%d/photo", o.ID)
+		thumbnailURL := photoURL + "/thumbnail"
+		o.PhotoURL, o.ThumbnailURL = &photoURL, &thumbnailURL
+	}
 }
@@ -0,0 +1,40 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DevImpersonator lets requests in development act as any user, such as the
+// seeded ones, named by ID or e-mail address instead of signing in. It is
+// only wired up when the server runs in development with DEV_IMPERSONATION
+// set.
+type DevImpersonator struct {
+	queries *db.Queries
+}
+
+func NewDevImpersonator(queries *db.Queries) *DevImpersonator {
+	return &DevImpersonator{queries: queries}
+}
+
+// Impersonate returns the ID of the user with the given ID or e-mail address.
+func (d *DevImpersonator) Impersonate(ctx context.Context, user string) (int64, error) {
+	user = strings.TrimSpace(user)
+	if id, err := strconv.ParseInt(user, 10, 64); err == nil {
+		u, err := d.queries.GetAuthUser(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, &NotFoundError{Message: fmt.Sprintf("no user with ID %d", id)}
+		}
+		return u.ID, err
+	}
+	account, err := d.queries.GetAccountByEmail(ctx, strings.ToLower(user))
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, &NotFoundError{Message: fmt.Sprintf("no user with e-mail %q", user)}
+	}
+	return account.ID, err
+}
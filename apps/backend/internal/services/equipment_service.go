@@ -0,0 +1,305 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Equipment types. Only grinders carry a grind scale.
+const (
+	EquipmentGrinder = "grinder"
+	EquipmentBrewer  = "brewer"
+	EquipmentKettle  = "kettle"
+	EquipmentFilter  = "filter"
+)
+
+// EquipmentService manages the grinders, brewers, kettles and filters a user owns.
+type EquipmentService struct {
+	queries *db.Queries
+}
+
+func NewEquipmentService(queries *db.Queries) *EquipmentService {
+	return &EquipmentService{
+		queries: queries,
+	}
+}
+
+type EquipmentOutput struct {
+	ID    int64   `json:"id"`
+	Type  string  `json:"type"`
+	Name  string  `json:"name"`
+	Brand *string `json:"brand,omitempty"`
+	Model *string `json:"model,omitempty"`
+	Notes *string `json:"notes,omitempty"`
+	GrindScale
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// GrindScale describes a grinder's adjustment range, e.g. 0-40 clicks in steps
+// of 1. Brew log grind settings are positions on this scale.
+type GrindScale struct {
+	GrindScaleMin  *float64 `json:"grindScaleMin,omitempty"`
+	GrindScaleMax  *float64 `json:"grindScaleMax,omitempty"`
+	GrindScaleStep *float64 `json:"grindScaleStep,omitempty"`
+}
+
+type CreateEquipmentInput struct {
+	UserID int64   `json:"user_id"`
+	Type   string  `json:"type"`
+	Name   string  `json:"name"`
+	Brand  *string `json:"brand,omitempty"`
+	Model  *string `json:"model,omitempty"`
+	Notes  *string `json:"notes,omitempty"`
+	GrindScale
+}
+
+// UpdateEquipmentInput is a partial update: nil fields keep their stored
+// values. The type of a piece of equipment cannot change.
+type UpdateEquipmentInput struct {
+	ID     int64   `json:"id"`
+	UserID int64   `json:"user_id"`
+	Name   *string `json:"name,omitempty"`
+	Brand  *string `json:"brand,omitempty"`
+	Model  *string `json:"model,omitempty"`
+	Notes  *string `json:"notes,omitempty"`
+	GrindScale
+}
+
+// List returns the user's equipment, optionally limited to one type.
+func (s *EquipmentService) List(ctx context.Context, userID int64, equipmentType string) ([]EquipmentOutput, error) {
+	params := db.ListEquipmentForUserParams{UserID: userID}
+	if equipmentType != "" {
+		if !IsValidEquipmentType(equipmentType) {
+			return nil, &ValidationError{Message: "type must be one of grinder, brewer, kettle, filter"}
+		}
+		params.Type = sql.NullString{String: equipmentType, Valid: true}
+	}
+
+	rows, err := s.queries.ListEquipmentForUser(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]EquipmentOutput, 0, len(rows))
+	for _, e := range rows {
+		result = append(result, *toEquipmentOutput(e))
+	}
+	return result, nil
+}
+
+func (s *EquipmentService) Get(ctx context.Context, userID, id int64) (*EquipmentOutput, error) {
+	e, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toEquipmentOutput(e), nil
+}
+
+func (s *EquipmentService) Create(ctx context.Context, input CreateEquipmentInput) (*EquipmentOutput, error) {
+	equipmentType := strings.TrimSpace(input.Type)
+	if !IsValidEquipmentType(equipmentType) {
+		return nil, &ValidationError{Message: "type must be one of grinder, brewer, kettle, filter"}
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" || len(name) > 255 {
+		return nil, &ValidationError{Message: "name is required and must be <= 255 characters"}
+	}
+	if err := validateEquipmentDetails(input.Brand, input.Model); err != nil {
+		return nil, err
+	}
+	params := db.CreateEquipmentParams{
+		UserID:         input.UserID,
+		Type:           equipmentType,
+		Name:           name,
+		Brand:          nullTrimmedString(input.Brand),
+		Model:          nullTrimmedString(input.Model),
+		Notes:          nullTrimmedString(input.Notes),
+		GrindScaleMin:  nullFloat64(input.GrindScaleMin),
+		GrindScaleMax:  nullFloat64(input.GrindScaleMax),
+		GrindScaleStep: nullFloat64(input.GrindScaleStep),
+	}
+	if err := validateGrindScale(equipmentType, params.GrindScaleMin, params.GrindScaleMax, params.GrindScaleStep); err != nil {
+		return nil, err
+	}
+
+	e, err := s.queries.CreateEquipment(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return toEquipmentOutput(e), nil
+}
+
+func (s *EquipmentService) Update(ctx context.Context, input UpdateEquipmentInput) (*EquipmentOutput, error) {
+	var name string
+	if input.Name != nil {
+		name = strings.TrimSpace(*input.Name)
+		if name == "" || len(name) > 255 {
+			return nil, &ValidationError{Message: "name is required and must be <= 255 characters"}
+		}
+	}
+	if err := validateEquipmentDetails(input.Brand, input.Model); err != nil {
+		return nil, err
+	}
+	if input.Name == nil && input.Brand == nil && input.Model == nil && input.Notes == nil && input.GrindScale == (GrindScale{}) {
+		return nil, &ValidationError{Message: "At least one field must be provided"}
+	}
+
+	existing, err := s.getOwned(ctx, input.UserID, input.ID)
+	if err != nil {
+		return nil, err
+	}
+	params := db.UpdateEquipmentParams{
+		Name:           existing.Name,
+		Brand:          existing.Brand,
+		Model:          existing.Model,
+		Notes:          existing.Notes,
+		GrindScaleMin:  existing.GrindScaleMin,
+		GrindScaleMax:  existing.GrindScaleMax,
+		GrindScaleStep: existing.GrindScaleStep,
+		ID:             existing.ID,
+		UserID:         input.UserID,
+	}
+	if input.Name != nil {
+		params.Name = name
+	}
+	if input.Brand != nil {
+		params.Brand = nullTrimmedString(input.Brand)
+	}
+	if input.Model != nil {
+		params.Model = nullTrimmedString(input.Model)
+	}
+	if input.Notes != nil {
+		params.Notes = nullTrimmedString(input.Notes)
+	}
+	if input.GrindScaleMin != nil {
+		params.GrindScaleMin = nullFloat64(input.GrindScaleMin)
+	}
+	if input.GrindScaleMax != nil {
+		params.GrindScaleMax = nullFloat64(input.GrindScaleMax)
+	}
+	if input.GrindScaleStep != nil {
+		params.GrindScaleStep = nullFloat64(input.GrindScaleStep)
+	}
+	// Check the merged scale so a partial update cannot leave min above max
+	if err := validateGrindScale(existing.Type, params.GrindScaleMin, params.GrindScaleMax, params.GrindScaleStep); err != nil {
+		return nil, err
+	}
+
+	if err := s.queries.UpdateEquipment(ctx, params); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, input.UserID, input.ID)
+}
+
+// Delete removes the equipment and unlinks it from the user's brew logs. The
+// logs themselves are kept.
+func (s *EquipmentService) Delete(ctx context.Context, userID, id int64) error {
+	e, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	// Foreign keys are not enforced on our connections, so ON DELETE SET NULL is done here
+	ref := sql.NullInt64{Int64: id, Valid: true}
+	return s.queries.InTx(ctx, func(q *db.Queries) error {
+		switch e.Type {
+		case EquipmentGrinder:
+			if err := q.ClearBrewLogGrinder(ctx, db.ClearBrewLogGrinderParams{GrinderID: ref, UserID: userID}); err != nil {
+				return err
+			}
+		case EquipmentBrewer:
+			if err := q.ClearBrewLogBrewer(ctx, db.ClearBrewLogBrewerParams{BrewerID: ref, UserID: userID}); err != nil {
+				return err
+			}
+		}
+		return q.DeleteEquipment(ctx, db.DeleteEquipmentParams{ID: id, UserID: userID})
+	})
+}
+
+// getOwned loads equipment owned by userID. Other users' equipment is
+// reported as not found.
+func (s *EquipmentService) getOwned(ctx context.Context, userID, id int64) (db.Equipment, error) {
+	e, err := s.queries.GetEquipmentByID(ctx, db.GetEquipmentByIDParams{ID: id, UserID: userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return e, &NotFoundError{Message: "equipment not found"}
+	}
+	return e, err
+}
+
+// IsValidEquipmentType reports whether t is one of the supported equipment types.
+func IsValidEquipmentType(t string) bool {
+	switch t {
+	case EquipmentGrinder, EquipmentBrewer, EquipmentKettle, EquipmentFilter:
+		return true
+	}
+	return false
+}
+
+func validateEquipmentDetails(brand, model *string) error {
+	if brand != nil && len(strings.TrimSpace(*brand)) > 255 {
+		return &ValidationError{Message: "brand must be <= 255 characters"}
+	}
+	if model != nil && len(strings.TrimSpace(*model)) > 255 {
+		return &ValidationError{Message: "model must be <= 255 characters"}
+	}
+	return nil
+}
+
+// validateGrindScale checks a grinder's scale. Min and max come as a pair; the
+// step is optional but needs a range to step through.
+func validateGrindScale(equipmentType string, min, max, step sql.NullFloat64) error {
+	if !min.Valid && !max.Valid && !step.Valid {
+		return nil
+	}
+	if equipmentType != EquipmentGrinder {
+		return &ValidationError{Message: "grind scale fields only apply to grinders"}
+	}
+	if min.Valid != max.Valid {
+		return &ValidationError{Message: "grindScaleMin and grindScaleMax must be given together"}
+	}
+	if !min.Valid {
+		return &ValidationError{Message: "grindScaleStep requires grindScaleMin and grindScaleMax"}
+	}
+	if min.Float64 < 0 || max.Float64 > 10000 {
+		return &ValidationError{Message: "grind scale must be within 0 and 10000"}
+	}
+	if min.Float64 >= max.Float64 {
+		return &ValidationError{Message: "grindScaleMin must be < grindScaleMax"}
+	}
+	if step.Valid && (step.Float64 <= 0 || step.Float64 > max.Float64-min.Float64) {
+		return &ValidationError{Message: "grindScaleStep must be greater than 0 and at most the scale range"}
+	}
+	return nil
+}
+
+func toEquipmentOutput(e db.Equipment) *EquipmentOutput {
+	output := &EquipmentOutput{
+		ID:        e.ID,
+		Type:      e.Type,
+		Name:      e.Name,
+		CreatedAt: e.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt: e.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if e.Brand.Valid {
+		output.Brand = &e.Brand.String
+	}
+	if e.Model.Valid {
+		output.Model = &e.Model.String
+	}
+	if e.Notes.Valid {
+		output.Notes = &e.Notes.String
+	}
+	if e.GrindScaleMin.Valid {
+		output.GrindScaleMin = &e.GrindScaleMin.Float64
+	}
+	if e.GrindScaleMax.Valid {
+		output.GrindScaleMax = &e.GrindScaleMax.Float64
+	}
+	if e.GrindScaleStep.Valid {
+		output.GrindScaleStep = &e.GrindScaleStep.Float64
+	}
+	return output
+}
@@ -0,0 +1,65 @@
+package services
+
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// NotFoundError reports that a referenced resource does not exist.
+type NotFoundError struct {
+	Message string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Message
+}
+
+// UnauthorizedError reports credentials, such as a refresh token, that are
+// missing, invalid, expired or revoked.
+type UnauthorizedError struct {
+	Message string
+}
+
+func (e *UnauthorizedError) Error() string {
+	return e.Message
+}
+
+// ForbiddenError reports that a resource exists but is not owned by the caller.
+type ForbiddenError struct {
+	Message string
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+// ConflictError reports a request that is valid but clashes with the current
+// state, such as a duplicate or a change made by someone else meanwhile.
+type ConflictError struct {
+	Message string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Message
+}
+
+// UnavailableError reports that a service the request depends on, such as an
+// AI provider, failed or could not be reached. Err is the underlying cause.
+type UnavailableError struct {
+	Message string
+	Err     error
+}
+
+func (e *UnavailableError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Follow states. A follow is a request until the followee approves it.
+const (
+	FollowPending  = "pending"
+	FollowApproved = "approved"
+)
+
+// FollowService manages the follow graph behind the "followers" visibility level.
+type FollowService struct {
+	queries *db.Queries
+}
+
+func NewFollowService(queries *db.Queries) *FollowService {
+	return &FollowService{
+		queries: queries,
+	}
+}
+
+// Follower is someone following, or asking to follow, a user.
+type Follower struct {
+	UserID      int64   `json:"userId"`
+	Username    string  `json:"username"`
+	Status      string  `json:"status"` // pending or approved
+	RequestedAt string  `json:"requestedAt"`
+	ApprovedAt  *string `json:"approvedAt,omitempty"`
+}
+
+// Follow asks for followerID to follow followeeID. The follow only unlocks
+// followeeID's "followers" brew logs once they approve it. Following twice is
+// a no-op.
+func (s *FollowService) Follow(ctx context.Context, followerID, followeeID int64) error {
+	if err := s.checkFollowee(ctx, followerID, followeeID); err != nil {
+		return err
+	}
+	return s.queries.CreateFollow(ctx, db.CreateFollowParams{FollowerID: followerID, FolloweeID: followeeID})
+}
+
+// Unfollow removes the follow if present.
+func (s *FollowService) Unfollow(ctx context.Context, followerID, followeeID int64) error {
+	if err := s.checkFollowee(ctx, followerID, followeeID); err != nil {
+		return err
+	}
+	return s.queries.DeleteFollow(ctx, db.DeleteFollowParams{FollowerID: followerID, FolloweeID: followeeID})
+}
+
+// ListFollowers returns everyone following, or asking to follow, userID.
+func (s *FollowService) ListFollowers(ctx context.Context, userID int64) ([]Follower, error) {
+	rows, err := s.queries.ListFollowers(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	followers := make([]Follower, 0, len(rows))
+	for _, r := range rows {
+		f := Follower{
+			UserID:      r.FollowerID,
+			Username:    r.Username,
+			Status:      FollowPending,
+			RequestedAt: r.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		if r.ApprovedAt.Valid {
+			approvedAt := r.ApprovedAt.Time.UTC().Format(time.RFC3339)
+			f.Status, f.ApprovedAt = FollowApproved, &approvedAt
+		}
+		followers = append(followers, f)
+	}
+	return followers, nil
+}
+
+// ApproveFollower lets followerID see userID's "followers" brew logs.
+// Approving twice is a no-op.
+func (s *FollowService) ApproveFollower(ctx context.Context, userID, followerID int64) error {
+	n, err := s.queries.ApproveFollow(ctx, db.ApproveFollowParams{FollowerID: followerID, FolloweeID: userID})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return &NotFoundError{Message: "follow request not found"}
+	}
+	return nil
+}
+
+// RemoveFollower declines a follow request or removes an approved follower.
+// They can ask again, but need approving again.
+func (s *FollowService) RemoveFollower(ctx context.Context, userID, followerID int64) error {
+	return s.queries.DeleteFollow(ctx, db.DeleteFollowParams{FollowerID: followerID, FolloweeID: userID})
+}
+
+func (s *FollowService) checkFollowee(ctx context.Context, followerID, followeeID int64) error {
+	if followerID == followeeID {
+		return &ValidationError{Message: "users cannot follow themselves"}
+	}
+	if _, err := s.queries.GetUserBrewLogVisibility(ctx, followeeID); errors.Is(err, sql.ErrNoRows) {
+		return &NotFoundError{Message: "user not found"}
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
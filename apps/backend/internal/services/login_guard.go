@@ -0,0 +1,255 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of a sign-in attempt in the audit trail.
+const (
+	LoginSucceeded = "success"
+	LoginFailed    = "failure"
+	// LoginBlocked is an attempt refused, without checking the password,
+	// while its e-mail address or IP was throttled.
+	LoginBlocked = "blocked"
+)
+
+const (
+	loginThrottleEmail = "email"
+	loginThrottleIP    = "ip"
+	// loginFreeFailures failed attempts in a row cost no wait, so a typo
+	// does not slow the user down.
+	loginFreeFailures = 2
+)
+
+// LoginPolicy is when failed sign-ins start to be refused. Zero fields take
+// the defaults.
+type LoginPolicy struct {
+	// MaxFailures failures for one e-mail address lock it for Lockout;
+	// before that each failure past the free ones doubles the wait,
+	// starting at BackoffBase.
+	MaxFailures int
+	// MaxFailuresPerIP failures from one IP lock the IP for Lockout. There is
+	// no backoff per IP, since many users may share one.
+	MaxFailuresPerIP int
+	BackoffBase      time.Duration
+	// Lockout is also how long a failure counts: failures are forgotten
+	// once the last one is this old.
+	Lockout time.Duration
+}
+
+func (p LoginPolicy) withDefaults() LoginPolicy {
+	if p.MaxFailures <= 0 {
+		p.MaxFailures = 10
+	}
+	if p.MaxFailuresPerIP <= 0 {
+		p.MaxFailuresPerIP = 50
+	}
+	if p.BackoffBase <= 0 {
+		p.BackoffBase = time.Second
+	}
+	if p.Lockout <= 0 {
+		p.Lockout = 15 * time.Minute
+	}
+	return p
+}
+
+// LoginGuard throttles password guessing. It counts recent failed sign-ins
+// per e-mail address and per client IP, whether or not the address has an
+// account, and keeps an audit trail of every attempt.
+type LoginGuard struct {
+	queries *db.Queries
+	policy  LoginPolicy
+	// mu serializes reading and writing the throttles, so two concurrent
+	// attempts cannot both count from the same old number of failures.
+	mu sync.Mutex
+}
+
+func NewLoginGuard(queries *db.Queries, policy LoginPolicy) *LoginGuard {
+	return &LoginGuard{
+		queries: queries,
+		policy:  policy.withDefaults(),
+	}
+}
+
+// LoginAttempt is one sign-in attempt. UserID is 0 when the e-mail address
+// has no account.
+type LoginAttempt struct {
+	Email     string
+	IPAddress string
+	UserAgent string
+	UserID    int64
+	Outcome   string
+}
+
+// Reserve counts the attempt as a failure of its e-mail address and IP
+// before the password is checked, so a burst of parallel guesses cannot all
+// pass the throttle before any of them is counted; Record gives the failure
+// back if the password turns out right. It reports false, and audits the
+// attempt as blocked, while sign-ins for the address or IP are refused.
+func (g *LoginGuard) Reserve(ctx context.Context, attempt LoginAttempt) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	allowed := true
+	err := g.queries.InTx(ctx, func(q *db.Queries) error {
+		now := time.Now().UTC()
+		keys := loginThrottleKeys(attempt.Email, attempt.IPAddress)
+		for _, key := range keys {
+			t, err := q.GetLoginThrottle(ctx, key)
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if t.BlockedUntil.Valid && now.Before(t.BlockedUntil.Time) {
+				allowed = false
+				attempt.Outcome = LoginBlocked
+				return audit(ctx, q, attempt)
+			}
+		}
+		for _, key := range keys {
+			if err := g.fail(ctx, q, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
+
+// Record adds a reserved attempt to the audit trail once its password has
+// been checked. A failure was already counted by Reserve; a success clears
+// the e-mail address and gives back the failure reserved for the IP, but
+// keeps earlier failures of the IP, so one account cannot be used to keep
+// guessing at others.
+func (g *LoginGuard) Record(ctx context.Context, attempt LoginAttempt) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.queries.InTx(ctx, func(q *db.Queries) error {
+		if err := audit(ctx, q, attempt); err != nil {
+			return err
+		}
+		if attempt.Outcome != LoginSucceeded {
+			return nil
+		}
+		if err := q.DeleteLoginThrottle(ctx, db.DeleteLoginThrottleParams{Kind: loginThrottleEmail, Subject: attempt.Email}); err != nil {
+			return err
+		}
+		if attempt.IPAddress == "" {
+			return nil
+		}
+		return g.release(ctx, q, db.GetLoginThrottleParams{Kind: loginThrottleIP, Subject: attempt.IPAddress})
+	})
+}
+
+func audit(ctx context.Context, q *db.Queries, attempt LoginAttempt) error {
+	userAgent := attempt.UserAgent
+	if len(userAgent) > 255 {
+		userAgent = strings.ToValidUTF8(userAgent[:255], "")
+	}
+	return q.CreateLoginAttempt(ctx, db.CreateLoginAttemptParams{
+		Email:     attempt.Email,
+		IpAddress: attempt.IPAddress,
+		UserAgent: userAgent,
+		UserID:    sql.NullInt64{Int64: attempt.UserID, Valid: attempt.UserID != 0},
+		Outcome:   attempt.Outcome,
+	})
+}
+
+// fail counts a failure for the key and blocks it for as long as the policy
+// says.
+func (g *LoginGuard) fail(ctx context.Context, q *db.Queries, key db.GetLoginThrottleParams) error {
+	now := time.Now().UTC()
+	t, err := q.GetLoginThrottle(ctx, key)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	failures := t.Failures
+	if now.Sub(t.LastFailureAt) >= g.policy.Lockout {
+		failures = 0
+	}
+	failures++
+
+	var blockedUntil sql.NullTime
+	if wait := g.wait(key.Kind, failures); wait > 0 {
+		blockedUntil = sql.NullTime{Time: now.Add(wait), Valid: true}
+	}
+	return q.UpsertLoginThrottle(ctx, db.UpsertLoginThrottleParams{
+		Kind:          key.Kind,
+		Subject:       key.Subject,
+		Failures:      failures,
+		LastFailureAt: now,
+		BlockedUntil:  blockedUntil,
+	})
+}
+
+// release takes back a failure Reserve counted for the key, with the block
+// of the failures left.
+func (g *LoginGuard) release(ctx context.Context, q *db.Queries, key db.GetLoginThrottleParams) error {
+	t, err := q.GetLoginThrottle(ctx, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if t.Failures <= 1 {
+		return q.DeleteLoginThrottle(ctx, db.DeleteLoginThrottleParams(key))
+	}
+	failures := t.Failures - 1
+	var blockedUntil sql.NullTime
+	if wait := g.wait(key.Kind, failures); wait > 0 {
+		blockedUntil = sql.NullTime{Time: t.LastFailureAt.Add(wait), Valid: true}
+	}
+	return q.UpsertLoginThrottle(ctx, db.UpsertLoginThrottleParams{
+		Kind:          key.Kind,
+		Subject:       key.Subject,
+		Failures:      failures,
+		LastFailureAt: t.LastFailureAt,
+		BlockedUntil:  blockedUntil,
+	})
+}
+
+// wait is how long sign-ins are refused after the given number of failures
+// in a row.
+func (g *LoginGuard) wait(kind string, failures int64) time.Duration {
+	if kind == loginThrottleIP {
+		if failures >= int64(g.policy.MaxFailuresPerIP) {
+			return g.policy.Lockout
+		}
+		return 0
+	}
+	if failures >= int64(g.policy.MaxFailures) {
+		return g.policy.Lockout
+	}
+	if failures <= loginFreeFailures {
+		return 0
+	}
+	// 1x, 2x, 4x... BackoffBase, never longer than a lockout
+	shift := failures - loginFreeFailures - 1
+	if shift > 30 {
+		return g.policy.Lockout
+	}
+	wait := g.policy.BackoffBase << shift
+	if wait > g.policy.Lockout {
+		return g.policy.Lockout
+	}
+	return wait
+}
+
+func loginThrottleKeys(email, ip string) []db.GetLoginThrottleParams {
+	keys := []db.GetLoginThrottleParams{{Kind: loginThrottleEmail, Subject: email}}
+	if ip != "" {
+		keys = append(keys, db.GetLoginThrottleParams{Kind: loginThrottleIP, Subject: ip})
+	}
+	return keys
+}
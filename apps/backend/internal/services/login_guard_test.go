@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"testing"
+	"time"
+
+	"coffeeee/backend/internal/database"
+	db "coffeeee/backend/internal/database/sqlc"
+)
+
+func TestLoginGuard_ParallelAttemptsCannotSkipThrottle(t *testing.T) {
+	conn := newTestDB(t)
+	g := NewLoginGuard(database.NewQueries(conn), LoginPolicy{MaxFailures: 5, BackoffBase: time.Hour})
+	ctx := context.Background()
+	attempt := LoginAttempt{Email: "test@example.com", IPAddress: "192.0.2.1"}
+
+	// Each attempt is counted before any password is checked, so of a burst
+	// only the free failures and the one that starts the backoff get through
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := g.Reserve(ctx, attempt)
+			if err != nil {
+				t.Errorf("reserve: %v", err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != loginFreeFailures+1 {
+		t.Fatalf("expected %d attempts let through, got %d", loginFreeFailures+1, allowed)
+	}
+	var blocked int
+	_ = conn.QueryRow(`SELECT COUNT(*) FROM login_attempts WHERE outcome = 'blocked'`).Scan(&blocked)
+	if blocked != 20-allowed {
+		t.Fatalf("expected %d blocked attempts audited, got %d", 20-allowed, blocked)
+	}
+}
+
+func TestLoginGuard_SuccessGivesBackReservation(t *testing.T) {
+	conn := newTestDB(t)
+	g := NewLoginGuard(database.NewQueries(conn), LoginPolicy{})
+	ctx := context.Background()
+	q := database.NewQueries(conn)
+
+	wrong := LoginAttempt{Email: "other@example.com", IPAddress: "192.0.2.1", Outcome: LoginFailed}
+	if ok, err := g.Reserve(ctx, wrong); !ok || err != nil {
+		t.Fatalf("reserve: %v %v", ok, err)
+	}
+	if err := g.Record(ctx, wrong); err != nil {
+		t.Fatalf("record failure: %v", err)
+	}
+	right := LoginAttempt{Email: "test@example.com", IPAddress: "192.0.2.1", Outcome: LoginSucceeded}
+	if ok, err := g.Reserve(ctx, right); !ok || err != nil {
+		t.Fatalf("reserve: %v %v", ok, err)
+	}
+	if err := g.Record(ctx, right); err != nil {
+		t.Fatalf("record success: %v", err)
+	}
+
+	// The success clears its address and leaves only the earlier failure on the IP
+	if _, err := q.GetLoginThrottle(ctx, db.GetLoginThrottleParams{Kind: loginThrottleEmail, Subject: right.Email}); err != sql.ErrNoRows {
+		t.Fatalf("expected the address cleared, got %v", err)
+	}
+	ip, err := q.GetLoginThrottle(ctx, db.GetLoginThrottleParams{Kind: loginThrottleIP, Subject: right.IPAddress})
+	if err != nil || ip.Failures != 1 {
+		t.Fatalf("expected one failure left on the IP, got %+v %v", ip, err)
+	}
+}
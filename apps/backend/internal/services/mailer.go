@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Email is a plain-text message to one recipient.
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends account e-mails. SMTPMailer delivers them; FileMailer and
+// LogMailer keep them local, so development and tests need no mail server.
+type Mailer interface {
+	Send(ctx context.Context, msg Email) error
+}
+
+// SMTPMailer sends through an SMTP server, with STARTTLS when the server
+// offers it and PLAIN auth when Username is set.
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Email) error {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %w", m.From, err)
+	}
+	data, err := formatEmail(m.From, msg)
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	return smtp.SendMail(net.JoinHostPort(m.Host, m.Port), auth, from.Address, []string{msg.To}, data)
+}
+
+// FileMailer appends each message to the file at Path, creating it and its
+// directory as needed.
+type FileMailer struct {
+	Path string
+	From string
+
+	mu sync.Mutex
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Email) error {
+	data, err := formatEmail(m.From, msg)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(m.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(m.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, "\r\n"...)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LogMailer notes each message in the server log by recipient and subject
+// only, since bodies hold single-use links.
+type LogMailer struct {
+	From string
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Email) error {
+	if _, err := formatEmail(m.From, msg); err != nil {
+		return err
+	}
+	log.Printf("mail not sent (MAIL_DRIVER=log): to %s, subject %q", msg.To, msg.Subject)
+	return nil
+}
+
+// formatEmail renders msg as an RFC 5322 message. Header values are checked
+// for line breaks so a recipient or subject cannot add headers.
+func formatEmail(from string, msg Email) ([]byte, error) {
+	for _, v := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(v, "\r\n") {
+			return nil, &ValidationError{Message: "e-mail headers cannot contain line breaks"}
+		}
+	}
+	if _, err := mail.ParseAddress(msg.To); err != nil {
+		return nil, &ValidationError{Message: fmt.Sprintf("invalid recipient %q", msg.To)}
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return b.Bytes(), nil
+}
@@ -0,0 +1,25 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLogMailer_LeavesBodyOut(t *testing.T) {
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	m := &LogMailer{From: "Coffeeee <noreply@example.com>"}
+	err := m.Send(context.Background(), Email{To: "test@example.com", Subject: "Reset your password", Body: "https://app.example.com/reset-password?token=secret-token"})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if out := logged.String(); strings.Contains(out, "secret-token") || !strings.Contains(out, "test@example.com") || !strings.Contains(out, "Reset your password") {
+		t.Fatalf("expected only the recipient and subject logged, got %q", out)
+	}
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"bytes"
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for image.Decode
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// maxPhotoPixels rejects images that are small on disk but would need
+	// gigabytes of memory to decode.
+	maxPhotoPixels = 50_000_000
+	// photoGCGrace keeps fresh files away from the garbage collector while the
+	// upload that wrote them is still recording the name on its coffee.
+	photoGCGrace = time.Minute
+	thumbnailDir = "thumbs"
+)
+
+// photoExtensions maps the accepted (sniffed) content types to file extensions.
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// photoNamePattern matches the content-addressed names PhotoService stores in
+// coffees.photo_path. Any other value is a legacy client-supplied string.
+var photoNamePattern = regexp.MustCompile(`^[0-9a-f]{64}\.(jpg|png|gif)$`)
+
+// PhotoService stores uploaded coffee photos under the upload directory.
+// Files are named by the SHA-256 of their content, so the same photo uploaded
+// twice is stored once and may be shared by several coffees; a file is only
+// removed once no coffee refers to it.
+type PhotoService struct {
+	queries     *db.Queries
+	root        string
+	maxFileSize int64
+}
+
+func NewPhotoService(queries *db.Queries, uploadPath string, maxFileSize int64) *PhotoService {
+	return &PhotoService{
+		queries:     queries,
+		root:        uploadPath,
+		maxFileSize: maxFileSize,
+	}
+}
+
+// MaxFileSize is the largest accepted upload in bytes.
+func (s *PhotoService) MaxFileSize() int64 {
+	return s.maxFileSize
+}
+
+// IsAcceptedPhotoType reports whether a declared content type may be uploaded.
+// The content itself is sniffed again before it is stored.
+func IsAcceptedPhotoType(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	_, ok := photoExtensions[strings.ToLower(strings.TrimSpace(mediaType))]
+	return ok
+}
+
+// Upload stores data as the photo of one of the user's coffees, writes its
+// thumbnail and points the coffee at it. The previous photo is collected if
+// nothing else uses it.
+func (s *PhotoService) Upload(ctx context.Context, userID, coffeeID int64, data []byte) (*CoffeeOutput, error) {
+	if int64(len(data)) > s.maxFileSize {
+		return nil, &ValidationError{Message: fmt.Sprintf("photo must be at most %d bytes", s.maxFileSize)}
+	}
+	ext, ok := photoExtensions[http.DetectContentType(data)]
+	if !ok {
+		return nil, &ValidationError{Message: "photo must be a JPEG, PNG or GIF image"}
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, &ValidationError{Message: "photo is not a readable image"}
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxPhotoPixels {
+		return nil, &ValidationError{Message: "photo dimensions are too large"}
+	}
+	if _, err := s.queries.GetCoffeeByID(ctx, db.GetCoffeeByIDParams{ID: coffeeID, UserID: userID}); err != nil {
+		return nil, coffeeLookupError(err)
+	}
+
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:]) + ext
+	if err := s.store(name, data); err != nil {
+		return nil, err
+	}
+
+	if err := s.queries.UpdateCoffeePhotoPath(ctx, db.UpdateCoffeePhotoPathParams{
+		PhotoPath: sql.NullString{String: name, Valid: true},
+		ID:        coffeeID,
+		UserID:    userID,
+	}); err != nil {
+		return nil, err
+	}
+	if _, err := s.CollectGarbage(ctx); err != nil {
+		return nil, err
+	}
+
+	coffee, err := s.queries.GetCoffeeByID(ctx, db.GetCoffeeByIDParams{ID: coffeeID, UserID: userID})
+	if err != nil {
+		return nil, err
+	}
+	return toCoffeeOutput(coffee), nil
+}
+
+// Path returns the file holding the photo (or its thumbnail) of one of the
+// user's coffees. Coffees without an uploaded photo report NotFoundError.
+func (s *PhotoService) Path(ctx context.Context, userID, coffeeID int64, thumbnail bool) (string, error) {
+	coffee, err := s.queries.GetCoffeeByID(ctx, db.GetCoffeeByIDParams{ID: coffeeID, UserID: userID})
+	if err != nil {
+		return "", coffeeLookupError(err)
+	}
+	if !coffee.PhotoPath.Valid || !photoNamePattern.MatchString(coffee.PhotoPath.String) {
+		return "", &NotFoundError{Message: "coffee has no uploaded photo"}
+	}
+	if thumbnail {
+		return s.thumbnailPath(coffee.PhotoPath.String), nil
+	}
+	return filepath.Join(s.root, coffee.PhotoPath.String), nil
+}
+
+// CollectGarbage removes stored photos and thumbnails that no coffee refers
+// to any more and returns how many photos went. Files younger than
+// photoGCGrace are left for the next run.
+func (s *PhotoService) CollectGarbage(ctx context.Context) (int, error) {
+	entries, err := os.ReadDir(s.root)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	paths, err := s.queries.ListCoffeePhotoPaths(ctx)
+	if err != nil {
+		return 0, err
+	}
+	referenced := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		referenced[p.String] = true
+	}
+
+	removed := 0
+	cutoff := time.Now().Add(-photoGCGrace)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !photoNamePattern.MatchString(name) || referenced[name] {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.root, name)); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		if err := os.Remove(s.thumbnailPath(name)); err != nil && !os.IsNotExist(err) {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// store writes the photo and its thumbnail unless they already exist. Files
+// are written to a temporary name and renamed so readers never see a partial
+// file.
+func (s *PhotoService) store(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Join(s.root, thumbnailDir), 0o755); err != nil {
+		return err
+	}
+	thumbPath := s.thumbnailPath(name)
+	if _, err := os.Stat(thumbPath); os.IsNotExist(err) {
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return &ValidationError{Message: "photo is not a readable image"}
+		}
+		thumb, err := makeThumbnail(img)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(thumbPath, thumb); err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(s.root, name)
+	if _, err := os.Stat(path); err == nil {
+		// Already stored; refresh the time so a concurrent collection skips it
+		now := time.Now()
+		return os.Chtimes(path, now, now)
+	}
+	return writeFileAtomic(path, data)
+}
+
+func (s *PhotoService) thumbnailPath(name string) string {
+	return filepath.Join(s.root, thumbnailDir, strings.TrimSuffix(name, filepath.Ext(name))+".jpg")
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
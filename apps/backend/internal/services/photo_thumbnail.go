@@ -0,0 +1,57 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+)
+
+const (
+	thumbnailMaxSide = 320
+	thumbnailQuality = 80
+)
+
+// makeThumbnail scales img down so its longer side is at most
+// thumbnailMaxSide and encodes it as JPEG. Each thumbnail pixel is the average
+// of the source pixels it covers, which is plenty for bag photos and needs
+// nothing beyond the standard library. Transparent areas come out white.
+func makeThumbnail(img image.Image) ([]byte, error) {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > thumbnailMaxSide || srcH > thumbnailMaxSide {
+		if srcW >= srcH {
+			dstW, dstH = thumbnailMaxSide, max(1, srcH*thumbnailMaxSide/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*thumbnailMaxSide/srcH), thumbnailMaxSide
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := b.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, b.Min.Y+(y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := b.Min.X + x*srcW/dstW
+			x1 := max(x0+1, b.Min.X+(x+1)*srcW/dstW)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			// Composite onto white: JPEG has no alpha, and the colours are premultiplied
+			bg := 0xffff - a/n
+			dst.Set(x, y, color.RGBA64{R: uint16(r/n + bg), G: uint16(g/n + bg), B: uint16(bl/n + bg), A: 0xffff})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// roasterMatchScore is how alike two roaster names must be for a coffee's
+	// roaster to be linked to an existing directory entry.
+	roasterMatchScore = 0.8
+	// roasterSuggestScore is the lowest score a search result may have.
+	roasterSuggestScore = 0.5
+	maxRoasterResults   = 20
+)
+
+// RoasterService manages the roaster directory shared by all users.
+type RoasterService struct {
+	queries *db.Queries
+}
+
+func NewRoasterService(queries *db.Queries) *RoasterService {
+	return &RoasterService{
+		queries: queries,
+	}
+}
+
+type RoasterOutput struct {
+	ID        int64   `json:"id"`
+	Name      string  `json:"name"`
+	Website   *string `json:"website,omitempty"`
+	Location  *string `json:"location,omitempty"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+type CreateRoasterInput struct {
+	UserID   int64   `json:"user_id"`
+	Name     string  `json:"name"`
+	Website  *string `json:"website,omitempty"`
+	Location *string `json:"location,omitempty"`
+}
+
+// List returns the directory in name order, or with a query the roasters
+// whose names are closest to it, best match first.
+func (s *RoasterService) List(ctx context.Context, query string) ([]RoasterOutput, error) {
+	query = strings.TrimSpace(query)
+	if len(query) > 255 {
+		return nil, &ValidationError{Message: "q must be <= 255 characters"}
+	}
+	roasters, err := s.queries.ListRoasters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if query != "" {
+		roasters = rankRoasters(roasters, query)
+		if len(roasters) > maxRoasterResults {
+			roasters = roasters[:maxRoasterResults]
+		}
+	}
+	out := make([]RoasterOutput, 0, len(roasters))
+	for _, r := range roasters {
+		out = append(out, *toRoasterOutput(r))
+	}
+	return out, nil
+}
+
+func (s *RoasterService) Get(ctx context.Context, id int64) (*RoasterOutput, error) {
+	r, err := s.queries.GetRoasterByID(ctx, id)
+	if err != nil {
+		return nil, roasterLookupError(err)
+	}
+	return toRoasterOutput(r), nil
+}
+
+// Create is find-or-create: a name close enough to an existing roaster
+// returns that roaster, with the website and location filled in if it had
+// none, instead of adding a second spelling.
+func (s *RoasterService) Create(ctx context.Context, input CreateRoasterInput) (*RoasterOutput, error) {
+	name, err := roasterName(input.Name)
+	if err != nil {
+		return nil, err
+	}
+	website := nullTrimmedString(input.Website)
+	if website.Valid {
+		u, err := url.Parse(website.String)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || len(website.String) > 500 {
+			return nil, &ValidationError{Message: "website must be an http(s) URL of at most 500 characters"}
+		}
+	}
+	location := nullTrimmedString(input.Location)
+	if len(location.String) > 255 {
+		return nil, &ValidationError{Message: "location must be <= 255 characters"}
+	}
+
+	r, err := resolveRoaster(ctx, s.queries, input.UserID, name, website, location)
+	if err != nil {
+		return nil, err
+	}
+	return toRoasterOutput(r), nil
+}
+
+// RoasterCoffeesPage is the caller's coffees from one roaster with how the
+// roaster's coffees have rated across all of the caller's brew logs.
+type RoasterCoffeesPage struct {
+	Roaster       RoasterOutput    `json:"roaster"`
+	AverageRating *float64         `json:"averageRating,omitempty"`
+	BrewCount     int64            `json:"brewCount"`
+	Coffees       []CoffeeListItem `json:"coffees"`
+	NextCursor    *string          `json:"nextCursor"`
+	Total         int64            `json:"total"`
+}
+
+// ListForRoaster pages through the user's coffees linked to the roaster,
+// best rated first unless input.Sort says otherwise.
+func (s *CoffeeService) ListForRoaster(ctx context.Context, roasterID int64, input ListCoffeesInput) (*RoasterCoffeesPage, error) {
+	r, err := s.queries.GetRoasterByID(ctx, roasterID)
+	if err != nil {
+		return nil, roasterLookupError(err)
+	}
+	if input.Sort == "" && strings.TrimSpace(input.Query) == "" {
+		input.Sort = "rating"
+	}
+	input.RoasterID = &roasterID
+	page, err := s.List(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	rating, err := s.queries.GetRoasterRatingForUser(ctx, db.GetRoasterRatingForUserParams{
+		RoasterID: sql.NullInt64{Int64: roasterID, Valid: true},
+		UserID:    input.UserID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out := &RoasterCoffeesPage{
+		Roaster:    *toRoasterOutput(r),
+		BrewCount:  rating.BrewCount,
+		Coffees:    page.Coffees,
+		NextCursor: page.NextCursor,
+		Total:      page.Total,
+	}
+	if rating.AverageRating.Valid {
+		avg := roundTo(rating.AverageRating.Float64, 2)
+		out.AverageRating = &avg
+	}
+	return out, nil
+}
+
+// resolveRoaster finds the directory entry for a roaster name, creating it
+// when no existing name is close enough. Missing website and location
+// details of an existing entry are filled in when userID added it.
+func resolveRoaster(ctx context.Context, q *db.Queries, userID int64, name string, website, location sql.NullString) (db.Roaster, error) {
+	createdBy := sql.NullInt64{Int64: userID, Valid: userID != 0}
+	match, ok, err := findRoaster(ctx, q, name)
+	if err != nil {
+		return db.Roaster{}, err
+	}
+	if ok {
+		if createdBy.Valid && match.CreatedBy == createdBy &&
+			((website.Valid && !match.Website.Valid) || (location.Valid && !match.Location.Valid)) {
+			err := q.FillRoasterDetails(ctx, db.FillRoasterDetailsParams{Website: website, Location: location, ID: match.ID, CreatedBy: createdBy})
+			if err != nil {
+				return db.Roaster{}, err
+			}
+			return q.GetRoasterByID(ctx, match.ID)
+		}
+		return match, nil
+	}
+
+	r, err := q.CreateRoaster(ctx, db.CreateRoasterParams{
+		Name:      name,
+		Website:   website,
+		Location:  location,
+		CreatedBy: createdBy,
+		MatchKey:  roasterMatchKey(name),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Someone added the same name since the directory was read
+		return q.GetRoasterByName(ctx, name)
+	}
+	return r, err
+}
+
+// findRoaster returns the directory roaster that is the same as name, if
+// any. Only roasters whose match keys are about as long as name's are read,
+// since keys further apart in length cannot reach roasterMatchScore.
+func findRoaster(ctx context.Context, q *db.Queries, name string) (db.Roaster, bool, error) {
+	n := float64(len([]rune(roasterMatchKey(name))))
+	candidates, err := q.ListRoasterMatchCandidates(ctx, db.ListRoasterMatchCandidatesParams{
+		MinLength: int64(math.Ceil(n*roasterMatchScore - 1e-9)),
+		MaxLength: int64(math.Floor(n/roasterMatchScore + 1e-9)),
+	})
+	if err != nil {
+		return db.Roaster{}, false, err
+	}
+	match, ok := matchRoaster(candidates, name)
+	return match, ok, nil
+}
+
+// matchRoaster returns the roaster whose name is most like name, if any is
+// alike enough to be the same roaster.
+func matchRoaster(roasters []db.Roaster, name string) (db.Roaster, bool) {
+	var best db.Roaster
+	bestScore := 0.0
+	for _, r := range roasters {
+		if score := roasterScore(r.Name, name); score > bestScore {
+			best, bestScore = r, score
+		}
+	}
+	return best, bestScore >= roasterMatchScore
+}
+
+// rankRoasters orders the roasters alike enough to the query by how alike
+// they are, then by name, for autocomplete. Roasters below
+// roasterSuggestScore are dropped.
+func rankRoasters(roasters []db.Roaster, query string) []db.Roaster {
+	type scored struct {
+		roaster db.Roaster
+		score   float64
+	}
+	var matches []scored
+	for _, r := range roasters {
+		if score := roasterSuggestionScore(r.Name, query); score >= roasterSuggestScore {
+			matches = append(matches, scored{r, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	out := make([]db.Roaster, len(matches))
+	for i, m := range matches {
+		out[i] = m.roaster
+	}
+	return out
+}
+
+// roasterNoise are words roasters add to or drop from their names at will.
+var roasterNoise = map[string]bool{
+	"the": true, "and": true, "coffee": true, "coffees": true, "cafe": true, "roasters": true, "roaster": true,
+	"roastery": true, "roasting": true, "roastworks": true, "co": true, "company": true, "ltd": true, "inc": true, "llc": true,
+	"gmbh": true,
+}
+
+// roasterScore is how alike two roaster names are, from 0 to 1: one minus
+// the edit distance between their match keys relative to the longer key.
+// Keys leave out the generic words, so "The Onyx Coffee Lab" and "onyx lab"
+// are equal, while "The Barn" and "Barn Owl Roasters" are far apart.
+func roasterScore(a, b string) float64 {
+	ka, kb := roasterMatchKey(a), roasterMatchKey(b)
+	if ka == "" || kb == "" {
+		return 0
+	}
+	if ka == kb {
+		return 1
+	}
+	longest := max(len([]rune(ka)), len([]rune(kb)))
+	return 1 - float64(editDistance(ka, kb))/float64(longest)
+}
+
+// roasterSuggestionScore is roasterScore, except that a query whose words
+// start the name ("Onyx" for "Onyx Lab") scores 0.9, since autocomplete is
+// asked for names before they are typed out.
+func roasterSuggestionScore(name, query string) float64 {
+	score := roasterScore(name, query)
+	nameWords, queryWords := roasterWords(name), roasterWords(query)
+	if isWordPrefix(queryWords, nameWords) || isWordPrefix(withoutNoise(queryWords), withoutNoise(nameWords)) {
+		score = max(score, 0.9)
+	}
+	return score
+}
+
+// roasterMatchKey folds a roaster name for matching: its words without the
+// generic ones, run together.
+func roasterMatchKey(name string) string {
+	return strings.Join(withoutNoise(roasterWords(name)), "")
+}
+
+// roasterWords splits a roaster name into lowercase words.
+func roasterWords(name string) []string {
+	return strings.FieldsFunc(lowerASCII(name), func(r rune) bool {
+		return r < 0x80 && !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+}
+
+// withoutNoise drops the generic words. A name made only of generic words
+// keeps them.
+func withoutNoise(words []string) []string {
+	tokens := make([]string, 0, len(words))
+	for _, w := range words {
+		if !roasterNoise[w] {
+			tokens = append(tokens, w)
+		}
+	}
+	if len(tokens) == 0 {
+		return words
+	}
+	return tokens
+}
+
+func isWordPrefix(short, long []string) bool {
+	if len(short) == 0 || len(short) > len(long) {
+		return false
+	}
+	for i := range short {
+		if short[i] != long[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and swaps of neighbours cost 1.
+func editDistance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+func roasterName(s string) (string, error) {
+	name := strings.Join(strings.Fields(s), " ")
+	if name == "" || len(name) > 255 {
+		return "", &ValidationError{Message: "roaster name is required and must be <= 255 characters"}
+	}
+	return name, nil
+}
+
+// roasterLookupError turns a missing roaster into a NotFoundError.
+func roasterLookupError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return &NotFoundError{Message: "roaster not found"}
+	}
+	return err
+}
+
+func toRoasterOutput(r db.Roaster) *RoasterOutput {
+	out := &RoasterOutput{
+		ID:        r.ID,
+		Name:      r.Name,
+		CreatedAt: r.CreatedAt.Format(time.RFC3339),
+	}
+	if r.Website.Valid {
+		out.Website = &r.Website.String
+	}
+	if r.Location.Valid {
+		out.Location = &r.Location.String
+	}
+	return out
+}
+
+// roasterIDError reports a roasterId that is not in the directory.
+func roasterIDError(id int64) error {
+	return &ValidationError{Message: fmt.Sprintf("roasterId %d does not exist", id)}
+}
@@ -0,0 +1,45 @@
+package services
+
+import "testing"
+
+func TestRoasterScore(t *testing.T) {
+	for _, tc := range []struct {
+		a, b  string
+		match bool
+	}{
+		{"Onyx Coffee Lab", "onyx coffee lab", true},
+		{"Onyx Coffee Lab", "The Onyx Lab", true},
+		{"Onyx Coffee Lab", "Onyx Coffee Labs", true},
+		{"Square Mile Coffee Roasters", "squaremile", true},
+		// Names that only start alike are different roasters
+		{"The Barn", "Barn Owl Roasters", false},
+		{"Red", "Red Rooster", false},
+		{"Onyx Coffee Lab", "Onyx", false},
+		{"Heart Roasters", "Onyx Coffee Lab", false},
+	} {
+		if got := roasterScore(tc.a, tc.b) >= roasterMatchScore; got != tc.match {
+			t.Errorf("%q vs %q: expected match %v, got score %.2f", tc.a, tc.b, tc.match, roasterScore(tc.a, tc.b))
+		}
+	}
+
+	// Autocomplete still suggests names from their first words
+	if score := roasterSuggestionScore("Onyx Coffee Lab", "Onyx"); score < roasterSuggestScore {
+		t.Errorf("expected Onyx suggested for Onyx Coffee Lab, got %.2f", score)
+	}
+}
+
+func TestRoasterMatchKey(t *testing.T) {
+	// Migration 020 folds the stored keys the same way; see TestRoasterMatchKeyBackfill
+	for name, want := range map[string]string{
+		"The Onyx Coffee Lab":         "onyxlab",
+		"Square Mile Coffee Roasters": "squaremile",
+		"Red Rooster & Co.":           "redrooster",
+		"Café Grumpy":                 "cafégrumpy",
+		"The Coffee Company":          "thecoffeecompany",
+		"Sey":                         "sey",
+	} {
+		if got := roasterMatchKey(name); got != want {
+			t.Errorf("%q: expected key %q, got %q", name, want, got)
+		}
+	}
+}
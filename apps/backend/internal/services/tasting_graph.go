@@ -0,0 +1,304 @@
+package services
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Tasting note fields a question can fill. Aroma and flavors collect a list
+// of descriptors; the others take one answer.
+const (
+	TastingFieldAroma     = "aroma"
+	TastingFieldFlavors   = "flavors"
+	TastingFieldAcidity   = "acidity"
+	TastingFieldSweetness = "sweetness"
+	TastingFieldBody      = "body"
+	TastingFieldFinish    = "finish"
+)
+
+var tastingListFields = map[string]bool{TastingFieldAroma: true, TastingFieldFlavors: true}
+
+var tastingValueFields = map[string]bool{
+	TastingFieldAcidity: true, TastingFieldSweetness: true, TastingFieldBody: true, TastingFieldFinish: true,
+}
+
+//go:embed tasting_questions.json
+var defaultTastingGraph []byte
+
+// TastingOption is one answer to a tasting question. Next, when set, is the
+// question it leads to instead of the question's own Next.
+type TastingOption struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+	Next  string `json:"next,omitempty"`
+}
+
+// TastingQuestion is a node of the tasting question graph. Its answer fills
+// Field of the tasting note, if any; questions without a field only steer
+// the session. An empty Next ends the session.
+type TastingQuestion struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	Hint string `json:"hint,omitempty"`
+	// MethodHints replace Hint for sessions on a brew method of the given
+	// category, e.g. "pressure".
+	MethodHints map[string]string `json:"methodHints,omitempty"`
+	Field       string            `json:"field,omitempty"`
+	Options     []TastingOption   `json:"options"`
+	Next        string            `json:"next,omitempty"`
+}
+
+// TastingGraph is the question graph the tasting assistant walks, such as a
+// flavor wheel: broad questions lead to narrower ones through the options'
+// Next, and an answer to a narrower question on the same field refines the
+// broader answer that led to it.
+type TastingGraph struct {
+	Name      string            `json:"name"`
+	Start     string            `json:"start"`
+	Questions []TastingQuestion `json:"questions"`
+
+	byID map[string]*TastingQuestion
+}
+
+// DefaultTastingGraph returns the built-in flavor wheel.
+func DefaultTastingGraph() *TastingGraph {
+	g, err := ParseTastingGraph(defaultTastingGraph)
+	if err != nil {
+		panic("services: invalid built-in tasting graph: " + err.Error())
+	}
+	return g
+}
+
+// LoadTastingGraph reads a question graph from a JSON file in the format of
+// tasting_questions.json.
+func LoadTastingGraph(path string) (*TastingGraph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	g, err := ParseTastingGraph(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return g, nil
+}
+
+// ParseTastingGraph decodes a question graph and checks that every question
+// it refers to exists and that a session started on it can finish.
+func ParseTastingGraph(data []byte) (*TastingGraph, error) {
+	var g TastingGraph
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, err
+	}
+	g.byID = make(map[string]*TastingQuestion, len(g.Questions))
+	for i := range g.Questions {
+		q := &g.Questions[i]
+		if q.ID == "" || strings.TrimSpace(q.Text) == "" {
+			return nil, fmt.Errorf("question %d needs an id and text", i)
+		}
+		if g.byID[q.ID] != nil {
+			return nil, fmt.Errorf("question %q is defined twice", q.ID)
+		}
+		if q.Field != "" && !tastingListFields[q.Field] && !tastingValueFields[q.Field] {
+			return nil, fmt.Errorf("question %q: unknown field %q", q.ID, q.Field)
+		}
+		if len(q.Options) == 0 {
+			return nil, fmt.Errorf("question %q has no options", q.ID)
+		}
+		values := make(map[string]bool, len(q.Options))
+		for _, o := range q.Options {
+			if o.Value == "" || strings.TrimSpace(o.Label) == "" || values[o.Value] {
+				return nil, fmt.Errorf("question %q: options need a label and a unique value", q.ID)
+			}
+			values[o.Value] = true
+		}
+		g.byID[q.ID] = q
+	}
+	if g.byID[g.Start] == nil {
+		return nil, fmt.Errorf("start question %q does not exist", g.Start)
+	}
+	for _, q := range g.Questions {
+		for _, next := range q.nexts() {
+			if next != "" && g.byID[next] == nil {
+				return nil, fmt.Errorf("question %q leads to unknown question %q", q.ID, next)
+			}
+		}
+	}
+	if !g.canFinish() {
+		return nil, fmt.Errorf("no path from %q ends the session", g.Start)
+	}
+	return &g, nil
+}
+
+// Question returns the question with the given ID, or nil.
+func (g *TastingGraph) Question(id string) *TastingQuestion {
+	return g.byID[id]
+}
+
+// nexts lists where each option of q leads; "" is the end.
+func (q *TastingQuestion) nexts() []string {
+	out := make([]string, len(q.Options))
+	for i, o := range q.Options {
+		out[i] = q.next(o)
+	}
+	return out
+}
+
+func (q *TastingQuestion) next(o TastingOption) string {
+	if o.Next != "" {
+		return o.Next
+	}
+	return q.Next
+}
+
+func (q *TastingQuestion) option(value string) (TastingOption, bool) {
+	for _, o := range q.Options {
+		if o.Value == value {
+			return o, true
+		}
+	}
+	return TastingOption{}, false
+}
+
+// canFinish reports whether some path from the start reaches the end.
+func (g *TastingGraph) canFinish() bool {
+	seen := map[string]bool{}
+	stack := []string{g.Start}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == "" {
+			return true
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		stack = append(stack, g.byID[id].nexts()...)
+	}
+	return false
+}
+
+// TastingAnswer is one answer given in a session.
+type TastingAnswer struct {
+	QuestionID string `json:"questionId"`
+	Value      string `json:"value"`
+}
+
+// TastingDescriptor is an answer as it appears in a tasting note. Path holds
+// the labels of the broader answers it refines, ending with Label.
+type TastingDescriptor struct {
+	Value string   `json:"value"`
+	Label string   `json:"label"`
+	Path  []string `json:"path,omitempty"`
+}
+
+// TastingNote is the structured result of a tasting session. Summary is the
+// same note as text, as it is written into a brew log's tasting notes.
+type TastingNote struct {
+	Aroma     []TastingDescriptor `json:"aroma"`
+	Flavors   []TastingDescriptor `json:"flavors"`
+	Acidity   *TastingDescriptor  `json:"acidity,omitempty"`
+	Sweetness *TastingDescriptor  `json:"sweetness,omitempty"`
+	Body      *TastingDescriptor  `json:"body,omitempty"`
+	Finish    *TastingDescriptor  `json:"finish,omitempty"`
+	Summary   string              `json:"summary"`
+}
+
+// replay walks answers from the start and returns the question they leave
+// the session on ("" once it has ended) and the note they make up. An
+// answer that is not to the question it was given at is an error.
+func (g *TastingGraph) replay(answers []TastingAnswer) (string, *TastingNote, error) {
+	note := &TastingNote{Aroma: []TastingDescriptor{}, Flavors: []TastingDescriptor{}}
+	current := g.Start
+	// refining is set when the last answer led on to a question narrowing it
+	var refining *TastingDescriptor
+	for _, a := range answers {
+		q := g.byID[current]
+		if q == nil || a.QuestionID != current {
+			return "", nil, fmt.Errorf("answer to %q does not follow the questions", a.QuestionID)
+		}
+		o, ok := q.option(a.Value)
+		if !ok {
+			return "", nil, fmt.Errorf("%q is not an answer to %q", a.Value, q.ID)
+		}
+		d := TastingDescriptor{Value: o.Value, Label: o.Label, Path: []string{o.Label}}
+		var slot *TastingDescriptor
+		switch {
+		case refining != nil:
+			d.Path = append(append([]string{}, refining.Path...), o.Label)
+			slot = refining
+		case tastingListFields[q.Field]:
+			list := note.list(q.Field)
+			*list = append(*list, d)
+			slot = &(*list)[len(*list)-1]
+		case tastingValueFields[q.Field]:
+			slot = new(TastingDescriptor)
+			*note.value(q.Field) = slot
+		}
+		if slot != nil {
+			*slot = d
+		}
+
+		current = q.next(o)
+		refining = nil
+		if next := g.byID[current]; slot != nil && o.Next != "" && next != nil && next.Field == q.Field {
+			refining = slot
+		}
+	}
+	if current == "" {
+		note.Summary = note.summarize()
+	}
+	return current, note, nil
+}
+
+func (n *TastingNote) list(field string) *[]TastingDescriptor {
+	if field == TastingFieldAroma {
+		return &n.Aroma
+	}
+	return &n.Flavors
+}
+
+func (n *TastingNote) value(field string) **TastingDescriptor {
+	switch field {
+	case TastingFieldAcidity:
+		return &n.Acidity
+	case TastingFieldSweetness:
+		return &n.Sweetness
+	case TastingFieldBody:
+		return &n.Body
+	default:
+		return &n.Finish
+	}
+}
+
+// summarize writes the note as text, e.g. "Aroma: floral. Flavors:
+// blueberry, dark chocolate. Acidity: high."
+func (n *TastingNote) summarize() string {
+	var parts []string
+	labels := func(ds []TastingDescriptor) string {
+		out := make([]string, len(ds))
+		for i, d := range ds {
+			out[i] = strings.ToLower(d.Label)
+		}
+		return strings.Join(out, ", ")
+	}
+	if len(n.Aroma) > 0 {
+		parts = append(parts, "Aroma: "+labels(n.Aroma)+".")
+	}
+	if len(n.Flavors) > 0 {
+		parts = append(parts, "Flavors: "+labels(n.Flavors)+".")
+	}
+	for _, f := range []struct {
+		name string
+		d    *TastingDescriptor
+	}{{"Acidity", n.Acidity}, {"Sweetness", n.Sweetness}, {"Body", n.Body}, {"Finish", n.Finish}} {
+		if f.d != nil {
+			parts = append(parts, f.name+": "+strings.ToLower(f.d.Label)+".")
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,349 @@
+package services
+
+import (
+	db "coffeeee/backend/internal/database/sqlc"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Tasting session statuses.
+const (
+	TastingSessionActive    = "active"
+	TastingSessionCompleted = "completed"
+)
+
+// maxTastingAnswers stops a graph with loops, such as "another flavor?",
+// from growing a session without end.
+const maxTastingAnswers = 50
+
+// TastingService runs tasting assistant sessions over a question graph.
+// Sessions are stored, so a client sends one answer at a time and can pick a
+// session up again later.
+type TastingService struct {
+	queries *db.Queries
+	graph   *TastingGraph
+}
+
+func NewTastingService(queries *db.Queries, graph *TastingGraph) *TastingService {
+	return &TastingService{
+		queries: queries,
+		graph:   graph,
+	}
+}
+
+// TastingQuestionOutput is a question as the client shows it.
+type TastingQuestionOutput struct {
+	QuestionID string                `json:"questionId"`
+	Text       string                `json:"text"`
+	Options    []TastingOptionOutput `json:"options"`
+	Hint       *string               `json:"hint,omitempty"`
+}
+
+type TastingOptionOutput struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+type TastingSessionOutput struct {
+	ID         int64           `json:"id"`
+	Status     string          `json:"status"`
+	BrewLogID  *int64          `json:"brewLogId,omitempty"`
+	BrewMethod *string         `json:"brewMethod,omitempty"`
+	Answers    []TastingAnswer `json:"answers"`
+	// Question is the question to answer next; nil once the session is completed.
+	Question *TastingQuestionOutput `json:"question,omitempty"`
+	// Note is set once the session is completed.
+	Note        *TastingNote `json:"note,omitempty"`
+	CreatedAt   string       `json:"createdAt"`
+	UpdatedAt   string       `json:"updatedAt"`
+	CompletedAt *string      `json:"completedAt,omitempty"`
+}
+
+type CreateTastingSessionInput struct {
+	UserID     int64   `json:"user_id"`
+	BrewLogID  *int64  `json:"brewLogId,omitempty"`
+	BrewMethod *string `json:"brewMethod,omitempty"`
+}
+
+// Create starts a session at the graph's first question. A brew log it is
+// started for must be the caller's and gives the brew method when none is
+// given.
+func (s *TastingService) Create(ctx context.Context, input CreateTastingSessionInput) (*TastingSessionOutput, error) {
+	params := db.CreateTastingSessionParams{
+		UserID:            input.UserID,
+		CurrentQuestionID: sql.NullString{String: s.graph.Start, Valid: true},
+	}
+	if input.BrewMethod != nil {
+		method, err := normalizeBrewMethod(*input.BrewMethod)
+		if err != nil {
+			return nil, err
+		}
+		params.BrewMethod = sql.NullString{String: method.ID, Valid: true}
+	}
+	if input.BrewLogID != nil {
+		brewLog, err := s.ownedBrewLog(ctx, input.UserID, *input.BrewLogID)
+		if err != nil {
+			return nil, err
+		}
+		params.BrewLogID = sql.NullInt64{Int64: brewLog.ID, Valid: true}
+		if !params.BrewMethod.Valid {
+			params.BrewMethod = sql.NullString{String: resolveBrewMethod(brewLog.BrewMethod).ID, Valid: true}
+		}
+	}
+
+	session, err := s.queries.CreateTastingSession(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return s.toOutput(session)
+}
+
+func (s *TastingService) Get(ctx context.Context, userID, id int64) (*TastingSessionOutput, error) {
+	session, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toOutput(session)
+}
+
+// ListForBrewLog returns the caller's sessions attached to a brew log, newest first.
+func (s *TastingService) ListForBrewLog(ctx context.Context, userID, brewLogID int64) ([]TastingSessionOutput, error) {
+	if _, err := s.ownedBrewLog(ctx, userID, brewLogID); err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListTastingSessionsForBrewLog(ctx, db.ListTastingSessionsForBrewLogParams{
+		UserID:    userID,
+		BrewLogID: sql.NullInt64{Int64: brewLogID, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]TastingSessionOutput, 0, len(rows))
+	for _, row := range rows {
+		out, err := s.toOutput(row)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *out)
+	}
+	return result, nil
+}
+
+// Answer records the answer to the session's current question and moves it
+// on. questionID must be the current question, so a repeated submit cannot
+// answer the next one by mistake. The answer that ends the session writes
+// the tasting note, and fills in the attached brew log's tasting notes when
+// they are empty.
+func (s *TastingService) Answer(ctx context.Context, userID, id int64, answer TastingAnswer) (*TastingSessionOutput, error) {
+	session, answers, err := s.getActive(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if answer.QuestionID != session.CurrentQuestionID.String {
+		return nil, &ValidationError{Message: fmt.Sprintf("questionId must be the current question %q", session.CurrentQuestionID.String)}
+	}
+	q := s.graph.Question(answer.QuestionID)
+	if q == nil {
+		return nil, staleTastingSession(fmt.Errorf("unknown question %q", answer.QuestionID))
+	}
+	if _, ok := q.option(answer.Value); !ok {
+		return nil, &ValidationError{Message: fmt.Sprintf("value must be one of the options of %q", answer.QuestionID)}
+	}
+	if len(answers) >= maxTastingAnswers {
+		return nil, &ValidationError{Message: fmt.Sprintf("a session takes at most %d answers", maxTastingAnswers)}
+	}
+	return s.save(ctx, session, append(answers, answer))
+}
+
+// Back removes the last answer of an active session, returning it to the
+// question before.
+func (s *TastingService) Back(ctx context.Context, userID, id int64) (*TastingSessionOutput, error) {
+	session, answers, err := s.getActive(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(answers) == 0 {
+		return nil, &ValidationError{Message: "the session has no answers to undo"}
+	}
+	return s.save(ctx, session, answers[:len(answers)-1])
+}
+
+// Attach links the session to one of the caller's brew logs. A completed
+// session fills in the brew log's tasting notes when they are empty.
+func (s *TastingService) Attach(ctx context.Context, userID, id, brewLogID int64) (*TastingSessionOutput, error) {
+	session, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.ownedBrewLog(ctx, userID, brewLogID); err != nil {
+		return nil, err
+	}
+	session.BrewLogID = sql.NullInt64{Int64: brewLogID, Valid: true}
+	err = s.queries.InTx(ctx, func(q *db.Queries) error {
+		if err := q.AttachTastingSession(ctx, db.AttachTastingSessionParams{BrewLogID: session.BrewLogID, ID: id, UserID: userID}); err != nil {
+			return err
+		}
+		return fillBrewLogTastingNotes(ctx, q, session)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID, id)
+}
+
+// save stores answers as the session's progress. It fails when the session
+// moved on since it was read, e.g. when the same question was answered twice
+// at once.
+func (s *TastingService) save(ctx context.Context, session db.TastingSession, answers []TastingAnswer) (*TastingSessionOutput, error) {
+	current, note, err := s.graph.replay(answers)
+	if err != nil {
+		return nil, staleTastingSession(err)
+	}
+	encoded, err := json.Marshal(answers)
+	if err != nil {
+		return nil, err
+	}
+	params := db.UpdateTastingSessionProgressParams{
+		Status:            TastingSessionActive,
+		CurrentQuestionID: sql.NullString{String: current, Valid: current != ""},
+		Answers:           string(encoded),
+		ID:                session.ID,
+		UserID:            session.UserID,
+		ReadQuestionID:    session.CurrentQuestionID,
+		ReadAnswers:       session.Answers,
+	}
+	if current == "" {
+		encodedNote, err := json.Marshal(note)
+		if err != nil {
+			return nil, err
+		}
+		params.Status = TastingSessionCompleted
+		params.Note = sql.NullString{String: string(encodedNote), Valid: true}
+		params.CompletedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+	}
+	session.Status, session.Note = params.Status, params.Note
+
+	err = s.queries.InTx(ctx, func(q *db.Queries) error {
+		n, err := q.UpdateTastingSessionProgress(ctx, params)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &ConflictError{Message: "the session changed while this answer was being saved; load it again"}
+		}
+		return fillBrewLogTastingNotes(ctx, q, session)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, session.UserID, session.ID)
+}
+
+// fillBrewLogTastingNotes writes the note of a completed session into its
+// brew log's tasting notes if the user has not written any.
+func fillBrewLogTastingNotes(ctx context.Context, q *db.Queries, session db.TastingSession) error {
+	if session.Status != TastingSessionCompleted || !session.BrewLogID.Valid || !session.Note.Valid {
+		return nil
+	}
+	var note TastingNote
+	if err := json.Unmarshal([]byte(session.Note.String), &note); err != nil {
+		return err
+	}
+	if note.Summary == "" {
+		return nil
+	}
+	return q.FillBrewLogTastingNotes(ctx, db.FillBrewLogTastingNotesParams{
+		TastingNotes: sql.NullString{String: note.Summary, Valid: true},
+		ID:           session.BrewLogID.Int64,
+		UserID:       session.UserID,
+	})
+}
+
+// getOwned loads a session owned by userID. Other users' sessions are
+// reported as not found.
+func (s *TastingService) getOwned(ctx context.Context, userID, id int64) (db.TastingSession, error) {
+	session, err := s.queries.GetTastingSession(ctx, db.GetTastingSessionParams{ID: id, UserID: userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, &NotFoundError{Message: "tasting session not found"}
+	}
+	return session, err
+}
+
+// getActive loads a session that can still take answers, with its answers.
+func (s *TastingService) getActive(ctx context.Context, userID, id int64) (db.TastingSession, []TastingAnswer, error) {
+	session, err := s.getOwned(ctx, userID, id)
+	if err != nil {
+		return session, nil, err
+	}
+	if session.Status != TastingSessionActive {
+		return session, nil, &ValidationError{Message: "the session is completed; start a new one to taste again"}
+	}
+	var answers []TastingAnswer
+	if err := json.Unmarshal([]byte(session.Answers), &answers); err != nil {
+		return session, nil, err
+	}
+	return session, answers, nil
+}
+
+func (s *TastingService) ownedBrewLog(ctx context.Context, userID, id int64) (db.BrewLog, error) {
+	brewLog, err := s.queries.GetBrewLogByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && brewLog.UserID != userID) {
+		return brewLog, &NotFoundError{Message: "brew log not found"}
+	}
+	return brewLog, err
+}
+
+// staleTastingSession reports answers the current question graph no longer
+// accepts, as after the graph file was changed.
+func staleTastingSession(err error) error {
+	return &ValidationError{Message: "the session no longer matches the tasting questions; start a new one (" + err.Error() + ")"}
+}
+
+func (s *TastingService) toOutput(session db.TastingSession) (*TastingSessionOutput, error) {
+	out := &TastingSessionOutput{
+		ID:        session.ID,
+		Status:    session.Status,
+		Answers:   []TastingAnswer{},
+		CreatedAt: session.CreatedAt.UTC().Format(time.RFC3339),
+		UpdatedAt: session.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+	if err := json.Unmarshal([]byte(session.Answers), &out.Answers); err != nil {
+		return nil, err
+	}
+	if session.BrewLogID.Valid {
+		out.BrewLogID = &session.BrewLogID.Int64
+	}
+	if session.BrewMethod.Valid {
+		out.BrewMethod = &session.BrewMethod.String
+	}
+	if session.CompletedAt.Valid {
+		completedAt := session.CompletedAt.Time.UTC().Format(time.RFC3339)
+		out.CompletedAt = &completedAt
+	}
+	if session.Note.Valid {
+		out.Note = &TastingNote{}
+		if err := json.Unmarshal([]byte(session.Note.String), out.Note); err != nil {
+			return nil, err
+		}
+	}
+	if q := s.graph.Question(session.CurrentQuestionID.String); session.Status == TastingSessionActive && q != nil {
+		out.Question = &TastingQuestionOutput{QuestionID: q.ID, Text: q.Text, Options: make([]TastingOptionOutput, len(q.Options))}
+		for i, o := range q.Options {
+			out.Question.Options[i] = TastingOptionOutput{Label: o.Label, Value: o.Value}
+		}
+		hint := q.Hint
+		if session.BrewMethod.Valid {
+			if h, ok := q.MethodHints[resolveBrewMethod(session.BrewMethod.String).Category]; ok {
+				hint = h
+			}
+		}
+		if hint != "" {
+			out.Question.Hint = &hint
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"coffeeee/backend/internal/database"
+)
+
+func TestTastingService_RejectsStaleProgress(t *testing.T) {
+	s := NewTastingService(database.NewQueries(newTestDB(t)), DefaultTastingGraph())
+	ctx := context.Background()
+	created, err := s.Create(ctx, CreateTastingSessionInput{UserID: 1})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// Two requests read the session at the same question; the first to save wins
+	stale, answers, err := s.getActive(ctx, 1, created.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if _, err := s.Answer(ctx, 1, created.ID, TastingAnswer{QuestionID: "aroma", Value: "floral"}); err != nil {
+		t.Fatalf("answer: %v", err)
+	}
+	var conflict *ConflictError
+	if _, err := s.save(ctx, stale, append(answers, TastingAnswer{QuestionID: "aroma", Value: "floral"})); !errors.As(err, &conflict) {
+		t.Fatalf("expected ConflictError saving from a stale read, got %v", err)
+	}
+
+	// Two undos from one read only remove one answer
+	stale, answers, _ = s.getActive(ctx, 1, created.ID)
+	if _, err := s.Back(ctx, 1, created.ID); err != nil {
+		t.Fatalf("back: %v", err)
+	}
+	if _, err := s.save(ctx, stale, answers[:len(answers)-1]); !errors.As(err, &conflict) {
+		t.Fatalf("expected ConflictError undoing twice from one read, got %v", err)
+	}
+
+	out, err := s.Get(ctx, 1, created.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if len(out.Answers) != 0 || out.Question == nil || out.Question.QuestionID != "aroma" {
+		t.Fatalf("expected the session back at the first question, got %+v", out)
+	}
+}
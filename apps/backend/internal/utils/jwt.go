@@ -1,6 +1,8 @@
 package utils
 
 import (
+    "crypto/rand"
+    "encoding/hex"
     "fmt"
     "strconv"
     "time"
@@ -12,16 +14,31 @@ type Claims struct {
 	UserID   int64  `json:"user_id"`
 	Email    string `json:"email"`
 	Username string `json:"username"`
+	// SessionID is the login session the token was issued for; revoking the
+	// session rejects the token.
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // GenerateToken creates a new JWT token for the given user
 func GenerateToken(userID int64, email, username, secret string, expiry time.Duration) (string, error) {
+	return GenerateAccessToken(userID, email, username, "", secret, expiry)
+}
+
+// GenerateAccessToken creates a JWT token for the given user and login
+// session, with a random jti
+func GenerateAccessToken(userID int64, email, username, sessionID, secret string, expiry time.Duration) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
 	claims := Claims{
-		UserID:   userID,
-		Email:    email,
-		Username: username,
+		UserID:    userID,
+		Email:     email,
+		Username:  username,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(jti),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -1,32 +1,209 @@
 package utils
 
 import (
-    "crypto/rand"
-    "crypto/sha256"
-    "encoding/hex"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
 )
 
-// GenerateSalt returns a securely generated random salt of n bytes, hex-encoded.
-func GenerateSalt(n int) (string, error) {
-    b := make([]byte, n)
-    if _, err := rand.Read(b); err != nil {
-        return "", err
-    }
-    return hex.EncodeToString(b), nil
+// ErrUnknownPasswordHash is returned for a stored hash no scheme recognizes.
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordHasher is one password hashing scheme. Hashes are self-describing
+// strings that carry the scheme and its parameters, so a hash can be checked
+// after the configured parameters change.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of password with a fresh salt.
+	Hash(password string) (string, error)
+	// Recognizes reports whether encoded is in this scheme's format.
+	Recognizes(encoded string) bool
+	// Verify checks password against encoded in constant time.
+	Verify(password, encoded string) (bool, error)
+	// Current reports whether encoded was made with this hasher's
+	// parameters, so that it needs no rehash.
+	Current(encoded string) bool
+}
+
+// Argon2idHasher hashes with Argon2id into the PHC string format,
+// e.g. $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>. Zero fields take the
+// defaults.
+type Argon2idHasher struct {
+	// Memory is in KiB.
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+const argon2idPrefix = "$argon2id$"
+
+func (h Argon2idHasher) withDefaults() Argon2idHasher {
+	if h.Memory == 0 {
+		h.Memory = 64 * 1024
+	}
+	if h.Iterations == 0 {
+		h.Iterations = 3
+	}
+	if h.Parallelism == 0 {
+		h.Parallelism = 2
+	}
+	if h.SaltLength == 0 {
+		h.SaltLength = 16
+	}
+	if h.KeyLength == 0 {
+		h.KeyLength = 32
+	}
+	return h
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	h = h.withDefaults()
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (h Argon2idHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+func (h Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	got := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+	return subtle.ConstantTimeCompare(got, key) == 1, nil
+}
+
+func (h Argon2idHasher) Current(encoded string) bool {
+	params, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false
+	}
+	want := h.withDefaults()
+	return params.Memory == want.Memory && params.Iterations == want.Iterations && params.Parallelism == want.Parallelism &&
+		uint32(len(salt)) == want.SaltLength && uint32(len(key)) == want.KeyLength
+}
+
+// decodeArgon2id splits a PHC string into its parameters, salt and key.
+func decodeArgon2id(encoded string) (Argon2idHasher, []byte, []byte, error) {
+	var params Argon2idHasher
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, ErrUnknownPasswordHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("invalid argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("invalid argon2 hash")
+	}
+	return params, salt, key, nil
 }
 
-// HashPassword computes a SHA-256 hash of password+salt and returns hex string.
-// Note: For production, consider a stronger KDF like scrypt/argon2.
-func HashPassword(password, salt string) string {
-    h := sha256.New()
-    h.Write([]byte(password))
-    h.Write([]byte(salt))
-    sum := h.Sum(nil)
-    return hex.EncodeToString(sum)
+// BcryptHasher hashes with bcrypt at Cost, or bcrypt.DefaultCost when zero.
+type BcryptHasher struct {
+	Cost int
 }
 
-// VerifyPassword checks whether the provided password matches the stored hash and salt.
-func VerifyPassword(password, salt, expectedHash string) bool {
-    return HashPassword(password, salt) == expectedHash
+func (h BcryptHasher) cost() int {
+	if h.Cost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return h.Cost
 }
 
+func (h BcryptHasher) Hash(password string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(password), h.cost())
+	return string(b), err
+}
+
+func (h BcryptHasher) Recognizes(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func (h BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h BcryptHasher) Current(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err == nil && cost == h.cost()
+}
+
+// Passwords hashes new passwords with one scheme and verifies stored hashes
+// of any known scheme, including the legacy salted SHA-256 hex digests.
+type Passwords struct {
+	hasher PasswordHasher
+	known  []PasswordHasher
+}
+
+// NewPasswords hashes with hasher. Argon2id and bcrypt hashes are verified
+// whichever scheme hasher is.
+func NewPasswords(hasher PasswordHasher) *Passwords {
+	return &Passwords{
+		hasher: hasher,
+		known:  []PasswordHasher{hasher, Argon2idHasher{}, BcryptHasher{}},
+	}
+}
+
+// Hash returns the encoded hash of password with the configured scheme.
+func (p *Passwords) Hash(password string) (string, error) {
+	return p.hasher.Hash(password)
+}
+
+// Verify checks password against a stored hash. legacySalt is only used for
+// hashes from before encoded hashes, which were SHA-256 of password+salt.
+// rehash reports that the password matched but the hash is not in the
+// configured scheme and parameters, and should be replaced by Hash(password).
+func (p *Passwords) Verify(password, encoded, legacySalt string) (ok, rehash bool, err error) {
+	for _, h := range p.known {
+		if h.Recognizes(encoded) {
+			ok, err = h.Verify(password, encoded)
+			return ok, ok && !p.hasher.Current(encoded), err
+		}
+	}
+	if isLegacySHA256(encoded) {
+		sum := sha256.Sum256([]byte(password + legacySalt))
+		got := hex.EncodeToString(sum[:])
+		ok = subtle.ConstantTimeCompare([]byte(got), []byte(strings.ToLower(encoded))) == 1
+		return ok, ok, nil
+	}
+	return false, false, ErrUnknownPasswordHash
+}
+
+func isLegacySHA256(encoded string) bool {
+	if len(encoded) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(encoded)
+	return err == nil
+}
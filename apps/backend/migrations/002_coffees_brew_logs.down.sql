@@ -0,0 +1,12 @@
+-- Coffees and brew logs (down)
+DROP TRIGGER IF EXISTS update_coffees_updated_at;
+DROP INDEX IF EXISTS idx_brew_logs_brew_method;
+DROP INDEX IF EXISTS idx_brew_logs_created_at;
+DROP INDEX IF EXISTS idx_brew_logs_coffee_id;
+DROP INDEX IF EXISTS idx_brew_logs_user_id;
+DROP INDEX IF EXISTS idx_coffees_user_name;
+DROP INDEX IF EXISTS idx_coffees_user_id;
+DROP INDEX IF EXISTS idx_coffees_origin;
+DROP INDEX IF EXISTS idx_coffees_roaster;
+DROP TABLE IF EXISTS brew_logs;
+DROP TABLE IF EXISTS coffees;
@@ -0,0 +1,49 @@
+-- Coffees and brew logs (up)
+-- Mirrors 001_initial_schema.sql so databases built with the migration runner
+-- match the legacy one-off setup.
+CREATE TABLE IF NOT EXISTS coffees (
+    user_id INTEGER NOT NULL,
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    origin VARCHAR(100),
+    roaster VARCHAR(255),
+    description TEXT,
+    photo_path VARCHAR(500),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS brew_logs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    coffee_id INTEGER NOT NULL,
+    brew_method VARCHAR(100) NOT NULL,
+    coffee_weight REAL,
+    water_weight REAL,
+    grind_size VARCHAR(50),
+    water_temperature REAL,
+    brew_time INTEGER, -- in seconds
+    tasting_notes TEXT,
+    rating INTEGER CHECK (rating >= 1 AND rating <= 5),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (coffee_id) REFERENCES coffees(id) ON DELETE CASCADE
+);
+
+-- Indexes
+CREATE INDEX IF NOT EXISTS idx_coffees_roaster ON coffees(roaster);
+CREATE INDEX IF NOT EXISTS idx_coffees_origin ON coffees(origin);
+CREATE INDEX IF NOT EXISTS idx_coffees_user_id ON coffees(user_id);
+CREATE INDEX IF NOT EXISTS idx_coffees_user_name ON coffees(user_id, name);
+CREATE INDEX IF NOT EXISTS idx_brew_logs_user_id ON brew_logs(user_id);
+CREATE INDEX IF NOT EXISTS idx_brew_logs_coffee_id ON brew_logs(coffee_id);
+CREATE INDEX IF NOT EXISTS idx_brew_logs_created_at ON brew_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_brew_logs_brew_method ON brew_logs(brew_method);
+
+-- Trigger for updated_at
+CREATE TRIGGER IF NOT EXISTS update_coffees_updated_at 
+    AFTER UPDATE ON coffees
+    BEGIN
+        UPDATE coffees SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+    END;
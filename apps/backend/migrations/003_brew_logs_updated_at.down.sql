@@ -0,0 +1,3 @@
+-- Brew logs updated_at (down)
+DROP TRIGGER IF EXISTS update_brew_logs_updated_at;
+ALTER TABLE brew_logs DROP COLUMN updated_at;
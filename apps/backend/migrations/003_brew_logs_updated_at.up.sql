@@ -0,0 +1,11 @@
+-- Brew logs updated_at (up)
+-- SQLite cannot add a column with a CURRENT_TIMESTAMP default, so existing rows
+-- are backfilled from created_at and the trigger keeps it current on edits.
+ALTER TABLE brew_logs ADD COLUMN updated_at DATETIME;
+UPDATE brew_logs SET updated_at = created_at;
+
+CREATE TRIGGER IF NOT EXISTS update_brew_logs_updated_at 
+    AFTER UPDATE ON brew_logs
+    BEGIN
+        UPDATE brew_logs SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+    END;
@@ -0,0 +1,6 @@
+-- Brew log visibility (down)
+DROP INDEX IF EXISTS idx_brew_logs_user_created_at;
+DROP INDEX IF EXISTS idx_follows_followee_id;
+DROP TABLE IF EXISTS follows;
+ALTER TABLE brew_logs DROP COLUMN visibility;
+ALTER TABLE users DROP COLUMN brew_log_visibility;
@@ -0,0 +1,22 @@
+-- Brew log visibility (up)
+-- Each user picks who can see their brew journal; a brew log may override it.
+-- A NULL brew_logs.visibility means "use the owner's setting".
+ALTER TABLE users ADD COLUMN brew_log_visibility VARCHAR(20) NOT NULL DEFAULT 'private'
+    CHECK (brew_log_visibility IN ('private', 'followers', 'public'));
+
+ALTER TABLE brew_logs ADD COLUMN visibility VARCHAR(20)
+    CHECK (visibility IN ('private', 'followers', 'public'));
+
+-- Follow graph used by the 'followers' visibility level
+CREATE TABLE IF NOT EXISTS follows (
+    follower_id INTEGER NOT NULL,
+    followee_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followee_id),
+    FOREIGN KEY (follower_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (followee_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (follower_id != followee_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_follows_followee_id ON follows(followee_id);
+CREATE INDEX IF NOT EXISTS idx_brew_logs_user_created_at ON brew_logs(user_id, created_at);
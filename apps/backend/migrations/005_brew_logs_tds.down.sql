@@ -0,0 +1,3 @@
+-- Brew logs refractometer readings (down)
+ALTER TABLE brew_logs DROP COLUMN beverage_weight;
+ALTER TABLE brew_logs DROP COLUMN tds;
@@ -0,0 +1,6 @@
+-- Brew logs refractometer readings (up)
+-- tds is total dissolved solids in percent; beverage_weight is the brewed
+-- liquid in grams. Ratio, extraction yield and strength are derived from
+-- these at read time rather than stored.
+ALTER TABLE brew_logs ADD COLUMN tds REAL CHECK (tds >= 0 AND tds <= 25);
+ALTER TABLE brew_logs ADD COLUMN beverage_weight REAL CHECK (beverage_weight >= 0);
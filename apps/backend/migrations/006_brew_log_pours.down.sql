@@ -0,0 +1,2 @@
+-- Brew log pours (down)
+DROP TABLE IF EXISTS brew_log_pours;
@@ -0,0 +1,13 @@
+-- Brew log pours (up)
+-- Ordered pour stages (bloom, pulses) of a brew. time_offset is seconds from
+-- the start of the brew; the water amounts add up to brew_logs.water_weight.
+CREATE TABLE IF NOT EXISTS brew_log_pours (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    brew_log_id INTEGER NOT NULL,
+    position INTEGER NOT NULL CHECK (position >= 1),
+    time_offset INTEGER NOT NULL CHECK (time_offset >= 0),
+    water_amount REAL NOT NULL CHECK (water_amount > 0),
+    water_temperature REAL,
+    FOREIGN KEY (brew_log_id) REFERENCES brew_logs(id) ON DELETE CASCADE,
+    UNIQUE (brew_log_id, position)
+);
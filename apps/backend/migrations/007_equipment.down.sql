@@ -0,0 +1,8 @@
+-- Equipment inventory (down)
+DROP INDEX IF EXISTS idx_brew_logs_brewer_id;
+DROP INDEX IF EXISTS idx_brew_logs_grinder_id;
+ALTER TABLE brew_logs DROP COLUMN grind_setting;
+ALTER TABLE brew_logs DROP COLUMN brewer_id;
+ALTER TABLE brew_logs DROP COLUMN grinder_id;
+DROP TRIGGER IF EXISTS update_equipment_updated_at;
+DROP TABLE IF EXISTS equipment;
@@ -0,0 +1,36 @@
+-- Equipment inventory (up)
+-- Grinders, brewers, kettles and filters owned by a user. Grinders may declare
+-- the numeric scale of their adjustment (e.g. 0-40 clicks in steps of 1) so a
+-- brew log's grind_setting can be compared across brews.
+CREATE TABLE IF NOT EXISTS equipment (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('grinder', 'brewer', 'kettle', 'filter')),
+    name VARCHAR(255) NOT NULL,
+    brand VARCHAR(255),
+    model VARCHAR(255),
+    notes TEXT,
+    grind_scale_min REAL,
+    grind_scale_max REAL,
+    grind_scale_step REAL CHECK (grind_scale_step > 0),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_equipment_user_id ON equipment(user_id, type);
+
+CREATE TRIGGER IF NOT EXISTS update_equipment_updated_at 
+    AFTER UPDATE ON equipment
+    BEGIN
+        UPDATE equipment SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+    END;
+
+-- grind_setting is a position on the referenced grinder's scale; the legacy
+-- free-form grind_size column is kept as is.
+ALTER TABLE brew_logs ADD COLUMN grinder_id INTEGER REFERENCES equipment(id) ON DELETE SET NULL;
+ALTER TABLE brew_logs ADD COLUMN brewer_id INTEGER REFERENCES equipment(id) ON DELETE SET NULL;
+ALTER TABLE brew_logs ADD COLUMN grind_setting REAL;
+
+CREATE INDEX IF NOT EXISTS idx_brew_logs_grinder_id ON brew_logs(grinder_id);
+CREATE INDEX IF NOT EXISTS idx_brew_logs_brewer_id ON brew_logs(brewer_id);
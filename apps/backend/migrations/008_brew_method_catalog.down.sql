@@ -0,0 +1,4 @@
+-- Brew method catalog (down)
+-- The original free-text values are not kept, so there is nothing to restore.
+-- Canonical IDs remain valid free-text brew methods.
+SELECT 1;
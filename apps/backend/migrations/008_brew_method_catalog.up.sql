@@ -0,0 +1,50 @@
+-- Brew method catalog (up)
+-- brew_method now holds a canonical catalog ID (services.brewMethodCatalog).
+-- Existing free-text values are lower-cased, stripped of spaces, '-', '_',
+-- '.' and '/' and mapped through the catalog's aliases. Values that match
+-- nothing are left untouched; migration 019 folds those again the way
+-- services.brewMethodKey does.
+UPDATE brew_logs
+SET brew_method = CASE replace(replace(replace(replace(replace(lower(trim(brew_method)), ' ', ''), '-', ''), '_', ''), '.', ''), '/', '')
+    WHEN 'v60' THEN 'v60'
+    WHEN 'hariov60' THEN 'v60'
+    WHEN 'v6001' THEN 'v60'
+    WHEN 'v6002' THEN 'v60'
+    WHEN 'v6003' THEN 'v60'
+    WHEN 'kalitawave' THEN 'kalita-wave'
+    WHEN 'kalita' THEN 'kalita-wave'
+    WHEN 'wave' THEN 'kalita-wave'
+    WHEN 'kalitawave155' THEN 'kalita-wave'
+    WHEN 'kalitawave185' THEN 'kalita-wave'
+    WHEN 'chemex' THEN 'chemex'
+    WHEN 'chemexclassic' THEN 'chemex'
+    WHEN 'origami' THEN 'origami'
+    WHEN 'origamidripper' THEN 'origami'
+    WHEN 'clever' THEN 'clever'
+    WHEN 'cleverdripper' THEN 'clever'
+    WHEN 'aeropress' THEN 'aeropress'
+    WHEN 'aeropressgo' THEN 'aeropress'
+    WHEN 'aeropressxl' THEN 'aeropress'
+    WHEN 'frenchpress' THEN 'french-press'
+    WHEN 'cafetiere' THEN 'french-press'
+    WHEN 'cafetière' THEN 'french-press'
+    WHEN 'presspot' THEN 'french-press'
+    WHEN 'plunger' THEN 'french-press'
+    WHEN 'siphon' THEN 'siphon'
+    WHEN 'syphon' THEN 'siphon'
+    WHEN 'vacuumpot' THEN 'siphon'
+    WHEN 'vacpot' THEN 'siphon'
+    WHEN 'mokapot' THEN 'moka-pot'
+    WHEN 'moka' THEN 'moka-pot'
+    WHEN 'stovetop' THEN 'moka-pot'
+    WHEN 'bialetti' THEN 'moka-pot'
+    WHEN 'espresso' THEN 'espresso'
+    WHEN 'shot' THEN 'espresso'
+    WHEN 'ristretto' THEN 'espresso'
+    WHEN 'lungo' THEN 'espresso'
+    WHEN 'coldbrew' THEN 'cold-brew'
+    WHEN 'toddy' THEN 'cold-brew'
+    WHEN 'coldsteep' THEN 'cold-brew'
+    WHEN 'other' THEN 'other'
+    ELSE brew_method
+END;
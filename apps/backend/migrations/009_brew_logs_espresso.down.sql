@@ -0,0 +1,5 @@
+-- Espresso brew log fields (down)
+ALTER TABLE brew_logs DROP COLUMN basket_size;
+ALTER TABLE brew_logs DROP COLUMN pre_infusion_time;
+ALTER TABLE brew_logs DROP COLUMN pressure_profile;
+ALTER TABLE brew_logs DROP COLUMN yield_weight;
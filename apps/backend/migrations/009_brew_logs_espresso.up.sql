@@ -0,0 +1,8 @@
+-- Espresso brew log fields (up)
+-- Only used when brew_method is 'espresso'. yield_weight is the beverage out
+-- (the "36 g out" of an 18 g dose); pressure_profile is a JSON array of
+-- {"timeOffset": seconds, "pressure": bar} points.
+ALTER TABLE brew_logs ADD COLUMN yield_weight REAL CHECK (yield_weight >= 0);
+ALTER TABLE brew_logs ADD COLUMN pressure_profile TEXT;
+ALTER TABLE brew_logs ADD COLUMN pre_infusion_time INTEGER CHECK (pre_infusion_time >= 0);
+ALTER TABLE brew_logs ADD COLUMN basket_size REAL CHECK (basket_size > 0);
@@ -0,0 +1,3 @@
+-- Brew log lineage (down)
+DROP INDEX IF EXISTS idx_brew_logs_parent_brew_log_id;
+ALTER TABLE brew_logs DROP COLUMN parent_brew_log_id;
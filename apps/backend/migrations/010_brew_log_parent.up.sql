@@ -0,0 +1,5 @@
+-- Brew log lineage (up)
+-- A brew log created by "brew again" points at the log it was copied from.
+ALTER TABLE brew_logs ADD COLUMN parent_brew_log_id INTEGER REFERENCES brew_logs(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_brew_logs_parent_brew_log_id ON brew_logs(parent_brew_log_id);
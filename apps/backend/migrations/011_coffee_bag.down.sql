@@ -0,0 +1,7 @@
+-- Coffee bag tracking (down)
+ALTER TABLE coffees DROP COLUMN remaining_weight;
+ALTER TABLE coffees DROP COLUMN price;
+ALTER TABLE coffees DROP COLUMN bag_size;
+ALTER TABLE coffees DROP COLUMN opened_date;
+ALTER TABLE coffees DROP COLUMN purchase_date;
+ALTER TABLE coffees DROP COLUMN roast_date;
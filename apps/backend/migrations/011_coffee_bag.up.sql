@@ -0,0 +1,9 @@
+-- Coffee bag tracking (up)
+-- Dates are 'YYYY-MM-DD' text. remaining_weight is only tracked once bag_size
+-- is known and goes down by each brew log's coffee_weight.
+ALTER TABLE coffees ADD COLUMN roast_date TEXT;
+ALTER TABLE coffees ADD COLUMN purchase_date TEXT;
+ALTER TABLE coffees ADD COLUMN opened_date TEXT;
+ALTER TABLE coffees ADD COLUMN bag_size REAL CHECK (bag_size > 0);
+ALTER TABLE coffees ADD COLUMN price REAL CHECK (price >= 0);
+ALTER TABLE coffees ADD COLUMN remaining_weight REAL CHECK (remaining_weight >= 0);
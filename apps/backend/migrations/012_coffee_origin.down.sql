@@ -0,0 +1,9 @@
+-- Structured coffee origin (down)
+DROP INDEX IF EXISTS idx_coffees_country;
+ALTER TABLE coffees DROP COLUMN altitude_max;
+ALTER TABLE coffees DROP COLUMN altitude_min;
+ALTER TABLE coffees DROP COLUMN varietals;
+ALTER TABLE coffees DROP COLUMN process;
+ALTER TABLE coffees DROP COLUMN producer;
+ALTER TABLE coffees DROP COLUMN region;
+ALTER TABLE coffees DROP COLUMN country;
@@ -0,0 +1,118 @@
+-- Structured coffee origin (up)
+-- country is an ISO 3166-1 alpha-2 code, varietals a JSON array of names and
+-- altitudes metres above sea level. origin stays as the free-text label that
+-- older clients send and show.
+ALTER TABLE coffees ADD COLUMN country TEXT CHECK (length(country) = 2);
+ALTER TABLE coffees ADD COLUMN region TEXT;
+ALTER TABLE coffees ADD COLUMN producer TEXT;
+ALTER TABLE coffees ADD COLUMN process TEXT CHECK (process IN ('washed', 'natural', 'honey', 'anaerobic', 'wet-hulled', 'other'));
+ALTER TABLE coffees ADD COLUMN varietals TEXT;
+ALTER TABLE coffees ADD COLUMN altitude_min INTEGER CHECK (altitude_min > 0);
+ALTER TABLE coffees ADD COLUMN altitude_max INTEGER CHECK (altitude_max >= altitude_min);
+
+CREATE INDEX IF NOT EXISTS idx_coffees_country ON coffees(country);
+
+-- Backfill country, region and process from the free-text origin with the
+-- rules the API applies to new coffees (internal/services/coffee_origin.go):
+-- a process keyword and a single recognised country or growing region are
+-- picked out and whatever text is left becomes the region. Names are matched
+-- as whole words on a copy of the origin lowercased with separators turned
+-- into spaces, which keeps character positions. Origins with digits (usually
+-- altitudes) get no region.
+CREATE TEMP TABLE origin_names (name TEXT PRIMARY KEY, code TEXT NOT NULL, is_region INTEGER NOT NULL);
+INSERT INTO origin_names (name, code, is_region) VALUES
+    ('angola', 'AO', 0), ('australia', 'AU', 0), ('burundi', 'BI', 0), ('bolivia', 'BO', 0),
+    ('brazil', 'BR', 0), ('brasil', 'BR', 0), ('minas gerais', 'BR', 1), ('cerrado mineiro', 'BR', 1),
+    ('mogiana', 'BR', 1), ('dr congo', 'CD', 0), ('drc', 'CD', 0), ('democratic republic of the congo', 'CD', 0),
+    ('congo', 'CD', 0), ('kivu', 'CD', 1), ('ivory coast', 'CI', 0), ('côte d''ivoire', 'CI', 0),
+    ('cote d''ivoire', 'CI', 0), ('cameroon', 'CM', 0), ('china', 'CN', 0), ('yunnan', 'CN', 1),
+    ('colombia', 'CO', 0), ('huila', 'CO', 1), ('nariño', 'CO', 1), ('narino', 'CO', 1),
+    ('cauca', 'CO', 1), ('tolima', 'CO', 1), ('costa rica', 'CR', 0), ('tarrazu', 'CR', 1),
+    ('tarrazú', 'CR', 1), ('west valley', 'CR', 1), ('cuba', 'CU', 0), ('dominican republic', 'DO', 0),
+    ('ecuador', 'EC', 0), ('galapagos', 'EC', 1), ('galápagos', 'EC', 1), ('ethiopia', 'ET', 0),
+    ('yirgacheffe', 'ET', 1), ('yirgachefe', 'ET', 1), ('sidamo', 'ET', 1), ('sidama', 'ET', 1),
+    ('guji', 'ET', 1), ('harrar', 'ET', 1), ('harar', 'ET', 1), ('limu', 'ET', 1),
+    ('jimma', 'ET', 1), ('guatemala', 'GT', 0), ('huehuetenango', 'GT', 1), ('antigua', 'GT', 1),
+    ('atitlan', 'GT', 1), ('atitlán', 'GT', 1), ('honduras', 'HN', 0), ('marcala', 'HN', 1),
+    ('haiti', 'HT', 0), ('indonesia', 'ID', 0), ('sumatra', 'ID', 1), ('java', 'ID', 1),
+    ('sulawesi', 'ID', 1), ('toraja', 'ID', 1), ('flores', 'ID', 1), ('bali', 'ID', 1),
+    ('aceh', 'ID', 1), ('gayo', 'ID', 1), ('india', 'IN', 0), ('karnataka', 'IN', 1),
+    ('chikmagalur', 'IN', 1), ('monsooned malabar', 'IN', 1), ('jamaica', 'JM', 0), ('blue mountain', 'JM', 1),
+    ('kenya', 'KE', 0), ('nyeri', 'KE', 1), ('kirinyaga', 'KE', 1), ('kiambu', 'KE', 1),
+    ('embu', 'KE', 1), ('murang''a', 'KE', 1), ('laos', 'LA', 0), ('madagascar', 'MG', 0),
+    ('myanmar', 'MM', 0), ('burma', 'MM', 0), ('malawi', 'MW', 0), ('mexico', 'MX', 0),
+    ('méxico', 'MX', 0), ('chiapas', 'MX', 1), ('oaxaca', 'MX', 1), ('veracruz', 'MX', 1),
+    ('nicaragua', 'NI', 0), ('jinotega', 'NI', 1), ('nueva segovia', 'NI', 1), ('nepal', 'NP', 0),
+    ('panama', 'PA', 0), ('panamá', 'PA', 0), ('boquete', 'PA', 1), ('volcan', 'PA', 1),
+    ('volcán', 'PA', 1), ('peru', 'PE', 0), ('perú', 'PE', 0), ('cajamarca', 'PE', 1),
+    ('papua new guinea', 'PG', 0), ('png', 'PG', 0), ('philippines', 'PH', 0), ('puerto rico', 'PR', 0),
+    ('paraguay', 'PY', 0), ('rwanda', 'RW', 0), ('el salvador', 'SV', 0), ('thailand', 'TH', 0),
+    ('timor leste', 'TL', 0), ('east timor', 'TL', 0), ('timor', 'TL', 0), ('taiwan', 'TW', 0),
+    ('tanzania', 'TZ', 0), ('kilimanjaro', 'TZ', 1), ('uganda', 'UG', 0), ('mount elgon', 'UG', 1),
+    ('rwenzori', 'UG', 1), ('usa', 'US', 0), ('hawaii', 'US', 1), ('kona', 'US', 1),
+    ('ka''u', 'US', 1), ('venezuela', 'VE', 0), ('vietnam', 'VN', 0), ('viet nam', 'VN', 0),
+    ('yemen', 'YE', 0), ('zambia', 'ZM', 0), ('zimbabwe', 'ZW', 0);
+
+CREATE TEMP TABLE origin_processes (phrase TEXT PRIMARY KEY, process TEXT NOT NULL);
+INSERT INTO origin_processes (phrase, process) VALUES
+    ('washed', 'washed'), ('fully washed', 'washed'), ('wet processed', 'washed'), ('semi washed', 'wet-hulled'),
+    ('natural', 'natural'), ('dry processed', 'natural'), ('sun dried', 'natural'), ('honey', 'honey'),
+    ('pulped natural', 'honey'), ('red honey', 'honey'), ('yellow honey', 'honey'), ('black honey', 'honey'),
+    ('anaerobic', 'anaerobic'), ('carbonic', 'anaerobic'), ('wet hulled', 'wet-hulled'), ('giling basah', 'wet-hulled'),
+    ('experimental', 'other'), ('double fermented', 'other');
+
+CREATE TEMP TABLE origin_backfill AS
+SELECT id, origin AS rest,
+    ' ' || lower(replace(replace(replace(replace(replace(replace(replace(replace(replace(
+        origin, ',', ' '), '/', ' '), ';', ' '), '|', ' '), '(', ' '), ')', ' '), '-', ' '), '.', ' '), '&', ' ')) || ' ' AS words,
+    CAST(NULL AS TEXT) AS phrase, CAST(NULL AS TEXT) AS process,
+    CAST(NULL AS TEXT) AS name, CAST(NULL AS TEXT) AS code, 0 AS is_region
+FROM coffees
+WHERE origin IS NOT NULL AND trim(origin) != '';
+
+UPDATE origin_backfill SET phrase = (
+    SELECT p.phrase FROM origin_processes p
+    WHERE instr(words, ' ' || p.phrase || ' ') > 0
+    ORDER BY length(p.phrase) DESC, p.phrase
+    LIMIT 1
+);
+UPDATE origin_backfill SET process = (SELECT p.process FROM origin_processes p WHERE p.phrase = origin_backfill.phrase);
+
+-- Blank out the process so it is neither a country nor part of the region
+UPDATE origin_backfill SET
+    rest = substr(rest, 1, instr(words, ' ' || phrase || ' ') - 1) || printf('%*s', length(phrase), '')
+        || substr(rest, instr(words, ' ' || phrase || ' ') + length(phrase)),
+    words = substr(words, 1, instr(words, ' ' || phrase || ' ')) || printf('%*s', length(phrase), '')
+        || substr(words, instr(words, ' ' || phrase || ' ') + 1 + length(phrase))
+WHERE phrase IS NOT NULL;
+
+-- Country names win over regions; several countries mean a blend
+UPDATE origin_backfill SET name = (
+    SELECT n.name FROM origin_names n
+    WHERE instr(words, ' ' || n.name || ' ') > 0
+    ORDER BY n.is_region, length(n.name) DESC
+    LIMIT 1
+)
+WHERE (SELECT COUNT(DISTINCT n.code) FROM origin_names n WHERE instr(words, ' ' || n.name || ' ') > 0) = 1;
+UPDATE origin_backfill SET
+    code = (SELECT n.code FROM origin_names n WHERE n.name = origin_backfill.name),
+    is_region = (SELECT n.is_region FROM origin_names n WHERE n.name = origin_backfill.name)
+WHERE name IS NOT NULL;
+
+UPDATE origin_backfill SET
+    rest = substr(rest, 1, instr(words, ' ' || name || ' ') - 1) || printf('%*s', length(name), '')
+        || substr(rest, instr(words, ' ' || name || ' ') + length(name))
+WHERE name IS NOT NULL AND is_region = 0;
+
+UPDATE coffees SET
+    country = b.code,
+    region = CASE WHEN b.code IS NULL OR b.rest GLOB '*[0-9]*' THEN NULL ELSE NULLIF(trim(
+        replace(replace(replace(replace(replace(b.rest, '        ', ' '), '    ', ' '), '  ', ' '), '  ', ' '), ', ,', ','),
+        ' ,;/|-()'), '') END,
+    process = b.process
+FROM origin_backfill b
+WHERE b.id = coffees.id;
+
+DROP TABLE origin_backfill;
+DROP TABLE origin_processes;
+DROP TABLE origin_names;
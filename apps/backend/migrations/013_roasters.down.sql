@@ -0,0 +1,6 @@
+-- Shared roaster directory (down)
+DROP INDEX IF EXISTS idx_coffees_roaster_id;
+ALTER TABLE coffees DROP COLUMN roaster_id;
+DROP TRIGGER IF EXISTS update_roasters_updated_at;
+DROP INDEX IF EXISTS idx_roasters_name;
+DROP TABLE IF EXISTS roasters;
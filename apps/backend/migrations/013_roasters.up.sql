@@ -0,0 +1,37 @@
+-- Shared roaster directory (up)
+-- Roasters are shared by all users; coffees link to one with roaster_id and
+-- keep its name in coffees.roaster for older clients and search.
+CREATE TABLE IF NOT EXISTS roasters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    website VARCHAR(500),
+    location VARCHAR(255),
+    created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_roasters_name ON roasters(name COLLATE NOCASE);
+
+CREATE TRIGGER IF NOT EXISTS update_roasters_updated_at 
+    AFTER UPDATE ON roasters
+    BEGIN
+        UPDATE roasters SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+    END;
+
+ALTER TABLE coffees ADD COLUMN roaster_id INTEGER REFERENCES roasters(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_coffees_roaster_id ON coffees(roaster_id);
+
+-- Every roaster already typed on a coffee becomes a directory entry; names
+-- that differ only in case or surrounding spaces share one. Closer spellings
+-- are only merged by the API's fuzzy matching from now on.
+INSERT INTO roasters (name)
+SELECT MIN(trim(roaster))
+FROM coffees
+WHERE trim(IFNULL(roaster, '')) != ''
+GROUP BY lower(trim(roaster));
+
+UPDATE coffees
+SET roaster_id = (SELECT r.id FROM roasters r WHERE r.name = trim(coffees.roaster) COLLATE NOCASE)
+WHERE trim(IFNULL(roaster, '')) != '';
@@ -0,0 +1,5 @@
+-- Tasting assistant sessions (down)
+DROP TRIGGER IF EXISTS update_tasting_sessions_updated_at;
+DROP INDEX IF EXISTS idx_tasting_sessions_brew_log_id;
+DROP INDEX IF EXISTS idx_tasting_sessions_user_id;
+DROP TABLE IF EXISTS tasting_sessions;
@@ -0,0 +1,26 @@
+-- Tasting assistant sessions (up)
+-- A session walks the user through the tasting question graph. answers is
+-- the JSON list of answers so far; note is the JSON tasting note written when
+-- the session completes.
+CREATE TABLE IF NOT EXISTS tasting_sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    brew_log_id INTEGER REFERENCES brew_logs(id) ON DELETE SET NULL,
+    brew_method VARCHAR(50),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed')),
+    current_question_id VARCHAR(100),
+    answers TEXT NOT NULL DEFAULT '[]',
+    note TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_tasting_sessions_user_id ON tasting_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_tasting_sessions_brew_log_id ON tasting_sessions(brew_log_id);
+
+CREATE TRIGGER IF NOT EXISTS update_tasting_sessions_updated_at 
+    AFTER UPDATE ON tasting_sessions
+    BEGIN
+        UPDATE tasting_sessions SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+    END;
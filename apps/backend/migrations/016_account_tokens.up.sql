@@ -0,0 +1,18 @@
+-- E-mail verification and password reset (up)
+-- user_tokens holds the single-use tokens mailed to users, as SHA-256 hashes
+-- of their random part. email is the address a token was sent to: a
+-- verification token no longer verifies once the user's e-mail changed.
+ALTER TABLE users ADD COLUMN email_verified_at DATETIME;
+
+CREATE TABLE IF NOT EXISTS user_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('verify-email', 'reset-password')),
+    email VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens(user_id, purpose);
@@ -0,0 +1,5 @@
+-- Login brute-force protection (down)
+DROP TABLE IF EXISTS login_throttles;
+DROP INDEX IF EXISTS idx_login_attempts_ip_address;
+DROP INDEX IF EXISTS idx_login_attempts_email;
+DROP TABLE IF EXISTS login_attempts;
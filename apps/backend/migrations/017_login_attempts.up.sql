@@ -0,0 +1,25 @@
+-- Login brute-force protection (up)
+-- login_attempts is the audit trail of every sign-in attempt. login_throttles
+-- counts recent failures per e-mail address and per client IP; a subject with
+-- blocked_until in the future is refused without checking the password.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    email VARCHAR(255) NOT NULL,
+    ip_address VARCHAR(45) NOT NULL,
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    user_id INTEGER,
+    outcome VARCHAR(20) NOT NULL CHECK (outcome IN ('success', 'failure', 'blocked')),
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_email ON login_attempts(email, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_address ON login_attempts(ip_address, created_at);
+
+CREATE TABLE IF NOT EXISTS login_throttles (
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('email', 'ip')),
+    subject VARCHAR(255) NOT NULL,
+    failures INTEGER NOT NULL,
+    last_failure_at DATETIME NOT NULL,
+    blocked_until DATETIME,
+    PRIMARY KEY (kind, subject)
+);
@@ -0,0 +1,2 @@
+-- Follow approval (down)
+ALTER TABLE follows DROP COLUMN approved_at;
@@ -0,0 +1,5 @@
+-- Follow approval (up)
+-- A follow is a request until the followee approves it, and only approved
+-- follows unlock 'followers' brew logs. Follows made before this needed no
+-- consent, so they start out as requests too.
+ALTER TABLE follows ADD COLUMN approved_at DATETIME;
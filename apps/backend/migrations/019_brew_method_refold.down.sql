@@ -0,0 +1,3 @@
+-- Brew method folding (down)
+-- The original free-text values are not kept, so there is nothing to restore.
+SELECT 1;
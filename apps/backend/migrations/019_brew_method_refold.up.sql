@@ -0,0 +1,46 @@
+-- Brew method folding (up)
+-- Migration 008 only dropped spaces, '-', '_', '.' and '/' before matching
+-- the catalog's aliases, so values such as 'V60 (02)' were left unmapped
+-- although services.brewMethodKey resolves them. Rows that still hold
+-- something other than a catalog ID are folded again the way brewMethodKey
+-- does it: letters are lower-cased and every other ASCII character that is
+-- not a letter or digit is dropped. SQLite's lower() and character classes
+-- only know ASCII, so non-ASCII characters are kept as they are and the
+-- accented alias is listed in both cases. Values that still match nothing
+-- are left untouched.
+CREATE TEMP TABLE brew_method_aliases (key TEXT PRIMARY KEY, method_id TEXT NOT NULL);
+INSERT INTO brew_method_aliases (key, method_id) VALUES
+    ('v60', 'v60'), ('hariov60', 'v60'), ('v6001', 'v60'), ('v6002', 'v60'), ('v6003', 'v60'),
+    ('kalitawave', 'kalita-wave'), ('kalita', 'kalita-wave'), ('wave', 'kalita-wave'),
+    ('kalitawave155', 'kalita-wave'), ('kalitawave185', 'kalita-wave'),
+    ('chemex', 'chemex'), ('chemexclassic', 'chemex'),
+    ('origami', 'origami'), ('origamidripper', 'origami'),
+    ('clever', 'clever'), ('cleverdripper', 'clever'),
+    ('aeropress', 'aeropress'), ('aeropressgo', 'aeropress'), ('aeropressxl', 'aeropress'),
+    ('frenchpress', 'french-press'), ('cafetiere', 'french-press'), ('cafetière', 'french-press'),
+    ('cafetiÈre', 'french-press'), ('presspot', 'french-press'), ('plunger', 'french-press'),
+    ('siphon', 'siphon'), ('syphon', 'siphon'), ('vacuumpot', 'siphon'), ('vacpot', 'siphon'),
+    ('mokapot', 'moka-pot'), ('moka', 'moka-pot'), ('stovetop', 'moka-pot'), ('bialetti', 'moka-pot'),
+    ('espresso', 'espresso'), ('shot', 'espresso'), ('ristretto', 'espresso'), ('lungo', 'espresso'),
+    ('coldbrew', 'cold-brew'), ('toddy', 'cold-brew'), ('coldsteep', 'cold-brew'),
+    ('other', 'other');
+
+-- fold walks each value one character at a time; the row with nothing left
+-- to read holds the folded key
+WITH RECURSIVE fold (brew_log_id, rest, key) AS (
+    SELECT id, lower(brew_method), ''
+    FROM brew_logs
+    WHERE brew_method NOT IN (SELECT method_id FROM brew_method_aliases)
+    UNION ALL
+    SELECT brew_log_id, substr(rest, 2),
+        key || CASE WHEN substr(rest, 1, 1) GLOB '[a-z0-9]' OR unicode(rest) > 127 THEN substr(rest, 1, 1) ELSE '' END
+    FROM fold
+    WHERE rest != ''
+)
+UPDATE brew_logs
+SET brew_method = a.method_id
+FROM fold
+JOIN brew_method_aliases a ON a.key = fold.key
+WHERE fold.brew_log_id = brew_logs.id AND fold.rest = '';
+
+DROP TABLE brew_method_aliases;
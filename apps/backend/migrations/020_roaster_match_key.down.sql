@@ -0,0 +1,3 @@
+-- Roaster match keys (down)
+DROP INDEX IF EXISTS idx_roasters_match_key_length;
+ALTER TABLE roasters DROP COLUMN match_key;
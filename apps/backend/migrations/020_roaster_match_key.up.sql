@@ -0,0 +1,38 @@
+-- Roaster match keys (up)
+-- match_key is the name the way services.roasterMatchKey folds it for
+-- matching a coffee's roaster: the lower-cased words, split at every ASCII
+-- character that is not a letter or digit, without generic words such as
+-- 'the', 'coffee' or 'roasters' (unless the name has no other words), run
+-- together. Matching only reads roasters whose keys are about as long as the
+-- one looked for, so the length is indexed.
+ALTER TABLE roasters ADD COLUMN match_key VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_roasters_match_key_length ON roasters(length(match_key));
+
+CREATE TEMP TABLE roaster_noise (noise TEXT PRIMARY KEY);
+INSERT INTO roaster_noise (noise) VALUES
+    ('the'), ('and'), ('coffee'), ('coffees'), ('cafe'), ('roasters'), ('roaster'),
+    ('roastery'), ('roasting'), ('roastworks'), ('co'), ('company'), ('ltd'), ('inc'), ('llc'),
+    ('gmbh');
+
+-- fold walks each name one character at a time, collecting the word being
+-- read; at a word break the word goes to all_words, and to key unless it is
+-- generic. A trailing space ends the last word, and the row with nothing
+-- left to read holds the folded keys.
+WITH RECURSIVE fold (roaster_id, rest, word, key, all_words) AS (
+    SELECT id, lower(name) || ' ', '', '', ''
+    FROM roasters
+    UNION ALL
+    SELECT roaster_id, substr(rest, 2),
+        CASE WHEN substr(rest, 1, 1) GLOB '[a-z0-9]' OR unicode(rest) > 127 THEN word || substr(rest, 1, 1) ELSE '' END,
+        CASE WHEN substr(rest, 1, 1) GLOB '[a-z0-9]' OR unicode(rest) > 127 OR word IN (SELECT noise FROM roaster_noise) THEN key ELSE key || word END,
+        CASE WHEN substr(rest, 1, 1) GLOB '[a-z0-9]' OR unicode(rest) > 127 THEN all_words ELSE all_words || word END
+    FROM fold
+    WHERE rest != ''
+)
+UPDATE roasters
+SET match_key = CASE WHEN fold.key != '' THEN fold.key ELSE fold.all_words END
+FROM fold
+WHERE fold.roaster_id = roasters.id AND fold.rest = '';
+
+DROP TABLE roaster_noise;
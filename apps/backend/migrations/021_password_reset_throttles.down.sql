@@ -0,0 +1,2 @@
+-- Password reset throttling (down)
+DROP TABLE IF EXISTS password_reset_throttles;
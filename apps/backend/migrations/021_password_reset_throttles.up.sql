@@ -0,0 +1,10 @@
+-- Password reset throttling (up)
+-- Counts password reset requests per e-mail address and per client IP since
+-- window_started_at; requests past the limit of the window send nothing.
+CREATE TABLE IF NOT EXISTS password_reset_throttles (
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('email', 'ip')),
+    subject VARCHAR(255) NOT NULL,
+    requests INTEGER NOT NULL,
+    window_started_at DATETIME NOT NULL,
+    PRIMARY KEY (kind, subject)
+);